@@ -0,0 +1,200 @@
+package astisub
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSV columns
+const (
+	CSVColumnEnd     = "end"
+	CSVColumnIndex   = "index"
+	CSVColumnSpeaker = "speaker" // voice name of the item's lines
+	CSVColumnStart   = "start"
+	CSVColumnText    = "text" // lines joined by "\n"
+)
+
+var csvDefaultColumns = []string{CSVColumnIndex, CSVColumnStart, CSVColumnEnd, CSVColumnText}
+
+// ReadFromCSV parses a .csv content whose first row names its columns
+// Unknown columns are ignored and time boundaries are expected in "00:00:00,000" format
+func ReadFromCSV(i io.Reader) (o *Subtitles, err error) {
+	// Read rows
+	var r = csv.NewReader(i)
+	r.FieldsPerRecord = -1
+	var rows [][]string
+	if rows, err = r.ReadAll(); err != nil {
+		err = fmt.Errorf("astisub: reading csv failed: %w", err)
+		return
+	}
+
+	// Init
+	o = NewSubtitles()
+	if len(rows) == 0 {
+		return
+	}
+
+	// Parse header
+	var columns = make(map[string]int)
+	for idx, c := range rows[0] {
+		c = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(c, string(BytesBOM))))
+		if _, ok := columns[c]; !ok {
+			columns[c] = idx
+		}
+	}
+	for _, c := range []string{CSVColumnStart, CSVColumnEnd} {
+		if _, ok := columns[c]; !ok {
+			err = fmt.Errorf("astisub: csv column %s is missing", c)
+			return
+		}
+	}
+
+	// Loop through rows
+	for idx, row := range rows[1:] {
+		// Get value
+		var value = func(c string) string {
+			if i, ok := columns[c]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+
+		// Skip empty rows
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+
+		// Parse time boundaries
+		var item = &Item{}
+		if item.StartAt, err = parseCSVDuration(value(CSVColumnStart)); err != nil {
+			err = fmt.Errorf("astisub: parsing csv start of row %d failed: %w", idx+2, err)
+			return
+		}
+		if item.EndAt, err = parseCSVDuration(value(CSVColumnEnd)); err != nil {
+			err = fmt.Errorf("astisub: parsing csv end of row %d failed: %w", idx+2, err)
+			return
+		}
+
+		// Parse index
+		if v := strings.TrimSpace(value(CSVColumnIndex)); v != "" {
+			if item.Index, err = strconv.Atoi(v); err != nil {
+				err = fmt.Errorf("astisub: atoi of csv index %s of row %d failed: %w", v, idx+2, err)
+				return
+			}
+		}
+
+		// Add lines
+		var speaker = strings.TrimSpace(value(CSVColumnSpeaker))
+		if t := strings.Replace(value(CSVColumnText), "\r\n", "\n", -1); t != "" {
+			for _, l := range strings.Split(t, "\n") {
+				item.Lines = append(item.Lines, Line{
+					Items:     []LineItem{{Text: l}},
+					VoiceName: speaker,
+				})
+			}
+		}
+
+		// Append item
+		o.Items = append(o.Items, item)
+	}
+	return
+}
+
+// parseCSVDuration parses a .csv duration, "." being accepted as well as "," as millisecond separator
+func parseCSVDuration(i string) (time.Duration, error) {
+	return parseDuration(strings.Replace(i, ".", ",", 1), ",", 3)
+}
+
+// WriteToCSVOptions represents CSV write options
+type WriteToCSVOptions struct {
+	// Columns are the columns written, in order.
+	// Default is index, start, end and text.
+	Columns []string
+}
+
+// WriteToCSV writes subtitles in .csv format
+func (s Subtitles) WriteToCSV(o io.Writer) (err error) {
+	return s.WriteToCSVWithOptions(o, WriteToCSVOptions{})
+}
+
+// WriteToCSVWithOptions writes subtitles in .csv format with options
+func (s Subtitles) WriteToCSVWithOptions(o io.Writer, opts WriteToCSVOptions) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		err = ErrNoSubtitlesToWrite
+		return
+	}
+
+	// Get columns
+	var columns = opts.Columns
+	if len(columns) == 0 {
+		columns = csvDefaultColumns
+	}
+	for _, c := range columns {
+		switch c {
+		case CSVColumnEnd, CSVColumnIndex, CSVColumnSpeaker, CSVColumnStart, CSVColumnText:
+		default:
+			err = fmt.Errorf("astisub: invalid csv column %s", c)
+			return
+		}
+	}
+
+	// Add BOM header
+	if _, err = o.Write(BytesBOM); err != nil {
+		err = fmt.Errorf("astisub: writing csv bom failed: %w", err)
+		return
+	}
+
+	// Add header
+	var w = csv.NewWriter(o)
+	var rows = [][]string{columns}
+
+	// Loop through items
+	for idx, item := range s.Items {
+		var row []string
+		for _, c := range columns {
+			switch c {
+			case CSVColumnEnd:
+				row = append(row, formatDuration(item.EndAt, ",", 3))
+			case CSVColumnIndex:
+				var index = item.Index
+				if index <= 0 {
+					index = idx + 1
+				}
+				row = append(row, strconv.Itoa(index))
+			case CSVColumnSpeaker:
+				row = append(row, item.voiceName())
+			case CSVColumnStart:
+				row = append(row, formatDuration(item.StartAt, ",", 3))
+			case CSVColumnText:
+				var lines []string
+				for _, l := range item.Lines {
+					lines = append(lines, l.String())
+				}
+				row = append(row, strings.Join(lines, "\n"))
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	// Write
+	if err = w.WriteAll(rows); err != nil {
+		err = fmt.Errorf("astisub: writing csv failed: %w", err)
+		return
+	}
+	return
+}
+
+// voiceName returns the first voice name of the item's lines
+func (i Item) voiceName() string {
+	for _, l := range i.Lines {
+		if l.VoiceName != "" {
+			return l.VoiceName
+		}
+	}
+	return ""
+}
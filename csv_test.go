@@ -0,0 +1,74 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSV(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.csv")
+	require.NoError(t, err)
+	require.Len(t, s.Items, 3)
+	assert.Equal(t, 1, s.Items[0].Index)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 4*time.Second, s.Items[0].EndAt)
+	require.Len(t, s.Items[0].Lines, 2)
+	assert.Equal(t, "First line", s.Items[0].Lines[0].String())
+	assert.Equal(t, "Second line", s.Items[0].Lines[1].String())
+	assert.Equal(t, 5500*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, 7250*time.Millisecond, s.Items[1].EndAt)
+	assert.Equal(t, "Comma, separated", s.Items[1].String())
+	assert.Equal(t, 3, s.Items[2].Index)
+	assert.Equal(t, time.Hour+2*time.Minute+3400*time.Millisecond, s.Items[2].StartAt)
+	assert.Equal(t, time.Hour+2*time.Minute+5*time.Second, s.Items[2].EndAt)
+
+	// Missing and invalid columns
+	_, err = astisub.ReadFromCSV(bytes.NewBufferString("start,text\n\"00:00:01,000\",text\n"))
+	assert.EqualError(t, err, "astisub: csv column end is missing")
+	_, err = astisub.ReadFromCSV(bytes.NewBufferString("start,end\ninvalid,\"00:00:01,000\"\n"))
+	assert.Error(t, err)
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToCSV(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	s.Items[2].Index = 7
+	err = s.WriteToCSV(w)
+	require.NoError(t, err)
+	assert.Equal(t, "\ufeff"+`index,start,end,text
+1,"00:00:01,000","00:00:04,000","First line
+Second line"
+2,"00:00:05,500","00:00:07,250","Comma, separated"
+7,"01:02:03,400","01:02:05,000",Long one
+`, w.String())
+
+	// Speaker
+	s.Items[1].Lines[0].VoiceName = "Bob"
+	w.Reset()
+	err = s.WriteToCSVWithOptions(w, astisub.WriteToCSVOptions{Columns: []string{astisub.CSVColumnStart, astisub.CSVColumnEnd, astisub.CSVColumnSpeaker, astisub.CSVColumnText}})
+	require.NoError(t, err)
+	assert.Equal(t, "\ufeff"+`start,end,speaker,text
+"00:00:01,000","00:00:04,000",,"First line
+Second line"
+"00:00:05,500","00:00:07,250",Bob,"Comma, separated"
+"01:02:03,400","01:02:05,000",,Long one
+`, w.String())
+	s2, err := astisub.ReadFromCSV(w)
+	require.NoError(t, err)
+	require.Len(t, s2.Items, 3)
+	assert.Equal(t, 0, s2.Items[1].Index)
+	assert.Equal(t, "Bob", s2.Items[1].Lines[0].VoiceName)
+	assert.Equal(t, "", s2.Items[0].Lines[0].VoiceName)
+
+	// Invalid column
+	err = s.WriteToCSVWithOptions(w, astisub.WriteToCSVOptions{Columns: []string{"invalid"}})
+	assert.EqualError(t, err, "astisub: invalid csv column invalid")
+}
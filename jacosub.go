@@ -0,0 +1,313 @@
+package astisub
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Constants
+const (
+	jacoSubDefaultTimeResolution = 30
+	jacoSubTimeResolution        = 100
+)
+
+// Vars
+var (
+	jacoSubTextReplacer    = strings.NewReplacer("\\", "\\\\", "{", "\\{")
+	jacoSubRegexpDirective = regexp.MustCompile(`^(?:J[LCRF]|V[BMT]|[CFEK]\d+)+$`)
+)
+
+// ReadFromJACOSub parses a .jss content
+func ReadFromJACOSub(i io.Reader) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var scanner = newScanner(i)
+	var timeRes = jacoSubDefaultTimeResolution
+	var shift time.Duration
+
+	// Scan
+	var line string
+	var lineNum int
+	for scanner.Scan() {
+		// Fetch line
+		line = strings.TrimSpace(scanner.Text())
+		lineNum++
+
+		// Remove BOM header
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, string(BytesBOM))
+		}
+
+		// Lines ending with a backslash continue on the next line
+		for strings.HasSuffix(line, "\\") && !strings.HasSuffix(line, "\\\\") && scanner.Scan() {
+			line = strings.TrimSuffix(line, "\\") + strings.TrimSpace(scanner.Text())
+			lineNum++
+		}
+
+		// Empty line
+		if line == "" {
+			continue
+		}
+
+		// Directive line
+		if strings.HasPrefix(line, "#") {
+			name, value := line[1:], ""
+			if idx := strings.IndexAny(name, " \t"); idx > 0 {
+				name, value = name[:idx], strings.TrimSpace(name[idx:])
+			}
+			switch strings.ToUpper(name) {
+			case "SHIFT":
+				if shift, err = parseDurationJACOSub(value, timeRes); err != nil {
+					err = fmt.Errorf("astisub: line %d: parsing jacosub shift %s failed: %w", lineNum, value, err)
+					return
+				}
+			case "TIMERES":
+				if timeRes, err = strconv.Atoi(value); err != nil || timeRes <= 0 {
+					err = fmt.Errorf("astisub: line %d: invalid jacosub time resolution %s", lineNum, value)
+					return
+				}
+			case "TITLE":
+				o.Metadata.Title = value
+			}
+			continue
+		}
+
+		// Split time boundaries
+		var fields = strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Parse time boundaries
+		var s = &Item{}
+		var ok bool
+		if s.StartAt, ok = parseTimeJACOSub(fields[0], timeRes); !ok {
+			// Lines that are neither directives nor timed lines are comments
+			continue
+		}
+		if s.EndAt, ok = parseTimeJACOSub(fields[1], timeRes); !ok {
+			err = fmt.Errorf("astisub: line %d: parsing jacosub end time %s failed", lineNum, fields[1])
+			return
+		}
+		s.StartAt += shift
+		s.EndAt += shift
+
+		// Get text
+		var text = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(line, fields[0])), fields[1]))
+
+		// Get directive
+		if len(fields) > 2 && jacoSubRegexpDirective.MatchString(fields[2]) {
+			s.InlineStyle = &StyleAttributes{JACOSubDirective: fields[2]}
+			s.InlineStyle.propagateJACOSubAttributes()
+			text = strings.TrimSpace(strings.TrimPrefix(text, fields[2]))
+		}
+
+		// Parse text
+		s.Lines = parseTextJACOSub(text)
+
+		// Append item
+		o.Items = append(o.Items, s)
+	}
+	return
+}
+
+// parseTimeJACOSub parses a .jss time which is either "H:MM:SS.FF" or "@frames"
+func parseTimeJACOSub(i string, timeRes int) (d time.Duration, ok bool) {
+	if strings.HasPrefix(i, "@") {
+		f, err := strconv.Atoi(i[1:])
+		if err != nil {
+			return
+		}
+		return time.Duration(f) * time.Second / time.Duration(timeRes), true
+	}
+	if strings.Count(i, ":") != 2 {
+		return
+	}
+	var err error
+	if d, err = parseDurationJACOSub(i, timeRes); err != nil {
+		return
+	}
+	return d, true
+}
+
+// parseDurationJACOSub parses a .jss duration in "[-][[H:]M:]S.F" format, F being in time resolution units
+func parseDurationJACOSub(i string, timeRes int) (d time.Duration, err error) {
+	// Sign
+	var sign = time.Duration(1)
+	if strings.HasPrefix(i, "-") {
+		sign = -1
+		i = i[1:]
+	} else {
+		i = strings.TrimPrefix(i, "+")
+	}
+
+	// Split frames
+	var frames int
+	if idx := strings.Index(i, "."); idx >= 0 {
+		if frames, err = strconv.Atoi(i[idx+1:]); err != nil {
+			err = fmt.Errorf("astisub: atoi of %s failed: %w", i[idx+1:], err)
+			return
+		}
+		i = i[:idx]
+	}
+
+	// Parse hours, minutes and seconds
+	var parts = strings.Split(i, ":")
+	if len(parts) > 3 {
+		err = fmt.Errorf("astisub: invalid jacosub duration %s", i)
+		return
+	}
+	for _, p := range parts {
+		var v int
+		if v, err = strconv.Atoi(p); err != nil {
+			err = fmt.Errorf("astisub: atoi of %s failed: %w", p, err)
+			return
+		}
+		d = d*60 + time.Duration(v)*time.Second
+	}
+	d = sign * (d + time.Duration(frames)*time.Second/time.Duration(timeRes))
+	return
+}
+
+// parseTextJACOSub parses a .jss text
+func parseTextJACOSub(i string) (o []Line) {
+	// Loop through characters
+	var l Line
+	var sa StyleAttributes
+	var b strings.Builder
+	flush := func() {
+		if b.Len() == 0 {
+			return
+		}
+		var li = LineItem{Text: b.String()}
+		if sa.JACOSubBold || sa.JACOSubItalics || sa.JACOSubUnderline {
+			li.InlineStyle = &StyleAttributes{
+				JACOSubBold:      sa.JACOSubBold,
+				JACOSubItalics:   sa.JACOSubItalics,
+				JACOSubUnderline: sa.JACOSubUnderline,
+			}
+			li.InlineStyle.propagateJACOSubAttributes()
+		}
+		l.Items = append(l.Items, li)
+		b.Reset()
+	}
+	for idx := 0; idx < len(i); idx++ {
+		// Remove comments
+		if i[idx] == '{' {
+			if end := strings.IndexByte(i[idx:], '}'); end > 0 {
+				idx += end
+				continue
+			}
+		}
+
+		if i[idx] != '\\' || idx == len(i)-1 {
+			b.WriteByte(i[idx])
+			continue
+		}
+		idx++
+		switch i[idx] {
+		case '\\', '{', '}':
+			b.WriteByte(i[idx])
+		case 'n':
+			flush()
+			o = append(o, l)
+			l = Line{}
+		case 'B':
+			flush()
+			sa.JACOSubBold = !sa.JACOSubBold
+		case 'I':
+			flush()
+			sa.JACOSubItalics = !sa.JACOSubItalics
+		case 'U':
+			flush()
+			sa.JACOSubUnderline = !sa.JACOSubUnderline
+		case 'N':
+			flush()
+			sa = StyleAttributes{}
+		}
+	}
+	flush()
+	o = append(o, l)
+	return
+}
+
+// formatTimeJACOSub formats a .jss time
+func formatTimeJACOSub(i time.Duration) string {
+	if i < 0 {
+		i = 0
+	}
+	return fmt.Sprintf("%d:%02d:%02d.%02d", int(i/time.Hour), int(i%time.Hour/time.Minute), int(i%time.Minute/time.Second), int(i%time.Second*jacoSubTimeResolution/time.Second))
+}
+
+// WriteToJACOSub writes subtitles in .jss format
+func (s Subtitles) WriteToJACOSub(o io.Writer) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		err = ErrNoSubtitlesToWrite
+		return
+	}
+
+	// Add directives
+	var c []byte
+	if s.Metadata != nil && s.Metadata.Title != "" {
+		c = appendStringToBytesWithNewLine(c, "#TITLE "+s.Metadata.Title)
+	}
+	c = appendStringToBytesWithNewLine(c, "#TIMERES "+strconv.Itoa(jacoSubTimeResolution))
+
+	// Loop through subtitles
+	for _, v := range s.Items {
+		// Add time boundaries
+		c = append(c, []byte(formatTimeJACOSub(v.StartAt)+" "+formatTimeJACOSub(v.EndAt))...)
+
+		// Add directive
+		if v.InlineStyle != nil && v.InlineStyle.JACOSubDirective != "" {
+			c = append(c, []byte(" "+v.InlineStyle.JACOSubDirective)...)
+		}
+
+		// Add lines
+		var lines []string
+		for _, l := range v.Lines {
+			if tl, ok := l.textLine(); ok {
+				lines = append(lines, tl.jacoSubString())
+			}
+		}
+		c = appendStringToBytesWithNewLine(c, " "+strings.Join(lines, "\\n"))
+	}
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = fmt.Errorf("astisub: writing failed: %w", err)
+		return
+	}
+	return
+}
+
+func (l Line) jacoSubString() string {
+	var b strings.Builder
+	var sa StyleAttributes
+	for _, li := range l.Items {
+		var n StyleAttributes
+		if li.InlineStyle != nil {
+			n = *li.InlineStyle
+		}
+		if n.JACOSubBold != sa.JACOSubBold {
+			b.WriteString("\\B")
+		}
+		if n.JACOSubItalics != sa.JACOSubItalics {
+			b.WriteString("\\I")
+		}
+		if n.JACOSubUnderline != sa.JACOSubUnderline {
+			b.WriteString("\\U")
+		}
+		sa = n
+		b.WriteString(jacoSubTextReplacer.Replace(li.Text))
+	}
+	if sa.JACOSubBold || sa.JACOSubItalics || sa.JACOSubUnderline {
+		b.WriteString("\\N")
+	}
+	return b.String()
+}
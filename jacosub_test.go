@@ -0,0 +1,51 @@
+package astisub_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJACOSub(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.jss")
+	assert.NoError(t, err)
+	assertSubtitleItems(t, s)
+	// Metadata
+	assert.Equal(t, "JACOSub test", s.Metadata.Title)
+	// Directives
+	assert.Equal(t, "VTJL", s.Items[1].InlineStyle.JACOSubDirective)
+	assert.Equal(t, "left", s.Items[1].InlineStyle.WebVTTAlign)
+	assert.Equal(t, "0%", s.Items[1].InlineStyle.WebVTTLine)
+	// Inline styles
+	assert.Len(t, s.Items[2].Lines[0].Items, 3)
+	assert.True(t, s.Items[2].Lines[0].Items[1].InlineStyle.JACOSubItalics)
+	assert.True(t, s.Items[2].Lines[0].Items[1].InlineStyle.SRTItalics)
+	assert.Nil(t, s.Items[2].Lines[0].Items[2].InlineStyle)
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToJACOSub(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	c, err := ioutil.ReadFile("./testdata/example-out.jss")
+	assert.NoError(t, err)
+	err = s.WriteToJACOSub(w)
+	assert.NoError(t, err)
+	assert.Equal(t, string(c), w.String())
+
+	// Braces and backslashes are escaped so that they're not read as comments or commands
+	s = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "a {b} \\c"}}}}}}}
+	w.Reset()
+	err = s.WriteToJACOSub(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), " a \\{b} \\\\c\n")
+	s, err = astisub.ReadFromJACOSub(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "a {b} \\c", s.Items[0].String())
+}
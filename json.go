@@ -0,0 +1,355 @@
+package astisub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// jsonSubtitles represents the JSON serialization of subtitles
+// Styles and regions are referenced by their ID to avoid cycles
+type jsonSubtitles struct {
+	Items         []jsonItem
+	Metadata      *jsonMetadata `json:",omitempty"`
+	PreserveOrder bool          `json:",omitempty"`
+	Regions       []jsonRegion  `json:",omitempty"`
+	Styles        []jsonStyle   `json:",omitempty"`
+}
+
+type jsonItem struct {
+	Comments    []string         `json:",omitempty"`
+	EndAt       jsonDuration     `json:",omitempty"`
+	ID          string           `json:",omitempty"`
+	Index       int              `json:",omitempty"`
+	InlineStyle *StyleAttributes `json:",omitempty"`
+	Lines       []jsonLine       `json:",omitempty"`
+	RawPayload  string           `json:",omitempty"`
+	Region      string           `json:",omitempty"`
+	StartAt     jsonDuration
+	Style       string `json:",omitempty"`
+}
+
+type jsonLine struct {
+	Items        []jsonLineItem `json:",omitempty"`
+	VoiceClasses []string       `json:",omitempty"`
+	VoiceName    string         `json:",omitempty"`
+}
+
+type jsonLineItem struct {
+	InlineStyle *StyleAttributes `json:",omitempty"`
+	Language    string           `json:",omitempty"`
+	RubyText    string           `json:",omitempty"`
+	StartAt     jsonDuration     `json:",omitempty"`
+	Style       string           `json:",omitempty"`
+	Text        string
+}
+
+// jsonMetadata only differs from Metadata by the way its durations are serialized
+type jsonMetadata struct {
+	jsonMetadataFields
+	SSAComments                 []jsonSSAComment        `json:",omitempty"`
+	STLTimecodeStartOfProgramme jsonDuration            `json:",omitempty"`
+	WebVTTTimestampMap          *jsonWebVTTTimestampMap `json:",omitempty"`
+}
+
+type jsonMetadataFields Metadata
+
+type jsonWebVTTTimestampMap struct {
+	Local  jsonDuration
+	MpegTS int64
+}
+
+type jsonSSAComment struct {
+	jsonSSACommentFields
+	EndAt   jsonDuration
+	StartAt jsonDuration
+}
+
+type jsonSSACommentFields SSAComment
+
+type jsonRegion struct {
+	ID          string
+	InlineStyle *StyleAttributes `json:",omitempty"`
+	Style       string           `json:",omitempty"`
+}
+
+type jsonStyle struct {
+	ID          string
+	InlineStyle *StyleAttributes `json:",omitempty"`
+	Style       string           `json:",omitempty"`
+}
+
+// jsonDuration represents a duration serialized as "00:00:00.000"
+type jsonDuration time.Duration
+
+// MarshalJSON implements the json.Marshaler interface
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	var s string
+	if d < 0 {
+		s = "-" + formatDuration(-time.Duration(d), ".", 3)
+	} else {
+		s = formatDuration(time.Duration(d), ".", 3)
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (d *jsonDuration) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return
+	}
+	var negative = strings.HasPrefix(s, "-")
+	var v time.Duration
+	if v, err = parseDuration(strings.TrimPrefix(s, "-"), ".", 3); err != nil {
+		err = fmt.Errorf("astisub: parsing duration %s failed: %w", s, err)
+		return
+	}
+	if negative {
+		v = -v
+	}
+	*d = jsonDuration(v)
+	return
+}
+
+// MarshalJSON implements the json.Marshaler interface
+// Durations are serialized as "00:00:00.000" and styles and regions are referenced by their ID
+func (s Subtitles) MarshalJSON() ([]byte, error) {
+	var j = jsonSubtitles{PreserveOrder: s.PreserveOrder}
+
+	// Metadata
+	if s.Metadata != nil {
+		j.Metadata = &jsonMetadata{
+			jsonMetadataFields:          jsonMetadataFields(*s.Metadata),
+			STLTimecodeStartOfProgramme: jsonDuration(s.Metadata.STLTimecodeStartOfProgramme),
+		}
+		for _, c := range s.Metadata.SSAComments {
+			j.Metadata.SSAComments = append(j.Metadata.SSAComments, jsonSSAComment{
+				jsonSSACommentFields: jsonSSACommentFields(c),
+				EndAt:                jsonDuration(c.EndAt),
+				StartAt:              jsonDuration(c.StartAt),
+			})
+		}
+		if t := s.Metadata.WebVTTTimestampMap; t != nil {
+			j.Metadata.WebVTTTimestampMap = &jsonWebVTTTimestampMap{
+				Local:  jsonDuration(t.Local),
+				MpegTS: t.MpegTS,
+			}
+		}
+	}
+
+	// Regions
+	var k []string
+	for id := range s.Regions {
+		k = append(k, id)
+	}
+	sort.Strings(k)
+	for _, id := range k {
+		var r = jsonRegion{
+			ID:          s.Regions[id].ID,
+			InlineStyle: s.Regions[id].InlineStyle,
+		}
+		if s.Regions[id].Style != nil {
+			r.Style = s.Regions[id].Style.ID
+		}
+		j.Regions = append(j.Regions, r)
+	}
+
+	// Styles
+	k = []string{}
+	for id := range s.Styles {
+		k = append(k, id)
+	}
+	sort.Strings(k)
+	for _, id := range k {
+		var st = jsonStyle{
+			ID:          s.Styles[id].ID,
+			InlineStyle: s.Styles[id].InlineStyle,
+		}
+		if s.Styles[id].Style != nil {
+			st.Style = s.Styles[id].Style.ID
+		}
+		j.Styles = append(j.Styles, st)
+	}
+
+	// Items
+	j.Items = []jsonItem{}
+	for _, item := range s.Items {
+		var i = jsonItem{
+			Comments:    item.Comments,
+			EndAt:       jsonDuration(item.EndAt),
+			ID:          item.ID,
+			Index:       item.Index,
+			InlineStyle: item.InlineStyle,
+			RawPayload:  item.RawPayload,
+			StartAt:     jsonDuration(item.StartAt),
+		}
+		if item.Region != nil {
+			i.Region = item.Region.ID
+		}
+		if item.Style != nil {
+			i.Style = item.Style.ID
+		}
+		for _, line := range item.Lines {
+			var l = jsonLine{VoiceClasses: line.VoiceClasses, VoiceName: line.VoiceName}
+			for _, li := range line.Items {
+				var jli = jsonLineItem{
+					InlineStyle: li.InlineStyle,
+					Language:    li.Language,
+					RubyText:    li.RubyText,
+					StartAt:     jsonDuration(li.StartAt),
+					Text:        li.Text,
+				}
+				if li.Style != nil {
+					jli.Style = li.Style.ID
+				}
+				l.Items = append(l.Items, jli)
+			}
+			i.Lines = append(i.Lines, l)
+		}
+		j.Items = append(j.Items, i)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+// Styles and regions referenced by their ID are relinked
+func (s *Subtitles) UnmarshalJSON(b []byte) (err error) {
+	// Unmarshal
+	var j jsonSubtitles
+	if err = json.Unmarshal(b, &j); err != nil {
+		return
+	}
+
+	// Init subtitles
+	var o = Subtitles{
+		PreserveOrder: j.PreserveOrder,
+		Regions:       make(map[string]*Region),
+		Styles:        make(map[string]*Style),
+	}
+
+	// Metadata
+	if j.Metadata != nil {
+		var m = Metadata(j.Metadata.jsonMetadataFields)
+		m.STLTimecodeStartOfProgramme = time.Duration(j.Metadata.STLTimecodeStartOfProgramme)
+		m.SSAComments = nil
+		for _, jc := range j.Metadata.SSAComments {
+			var c = SSAComment(jc.jsonSSACommentFields)
+			c.EndAt = time.Duration(jc.EndAt)
+			c.StartAt = time.Duration(jc.StartAt)
+			m.SSAComments = append(m.SSAComments, c)
+		}
+		m.WebVTTTimestampMap = nil
+		if t := j.Metadata.WebVTTTimestampMap; t != nil {
+			m.WebVTTTimestampMap = &WebVTTTimestampMap{
+				Local:  time.Duration(t.Local),
+				MpegTS: t.MpegTS,
+			}
+		}
+		o.Metadata = &m
+	}
+
+	// Styles are created before being linked since they can reference each other
+	for _, st := range j.Styles {
+		o.Styles[st.ID] = &Style{
+			ID:          st.ID,
+			InlineStyle: st.InlineStyle,
+		}
+	}
+	for _, st := range j.Styles {
+		if o.Styles[st.ID].Style, err = o.jsonStyle(st.Style, "style "+st.ID); err != nil {
+			return
+		}
+	}
+
+	// Regions
+	for _, r := range j.Regions {
+		var region = &Region{
+			ID:          r.ID,
+			InlineStyle: r.InlineStyle,
+		}
+		if region.Style, err = o.jsonStyle(r.Style, "region "+r.ID); err != nil {
+			return
+		}
+		o.Regions[r.ID] = region
+	}
+
+	// Items
+	for idx, i := range j.Items {
+		var item = &Item{
+			Comments:    i.Comments,
+			EndAt:       time.Duration(i.EndAt),
+			ID:          i.ID,
+			Index:       i.Index,
+			InlineStyle: i.InlineStyle,
+			RawPayload:  i.RawPayload,
+			StartAt:     time.Duration(i.StartAt),
+		}
+		var requester = fmt.Sprintf("item #%d", idx+1)
+		if i.Region != "" {
+			var ok bool
+			if item.Region, ok = o.Regions[i.Region]; !ok {
+				err = fmt.Errorf("astisub: Region %s requested by %s doesn't exist", i.Region, requester)
+				return
+			}
+		}
+		if item.Style, err = o.jsonStyle(i.Style, requester); err != nil {
+			return
+		}
+		for _, l := range i.Lines {
+			var line = Line{VoiceClasses: l.VoiceClasses, VoiceName: l.VoiceName}
+			for _, jli := range l.Items {
+				var li = LineItem{
+					InlineStyle: jli.InlineStyle,
+					Language:    jli.Language,
+					RubyText:    jli.RubyText,
+					StartAt:     time.Duration(jli.StartAt),
+					Text:        jli.Text,
+				}
+				if li.Style, err = o.jsonStyle(jli.Style, requester); err != nil {
+					return
+				}
+				line.Items = append(line.Items, li)
+			}
+			item.Lines = append(item.Lines, line)
+		}
+		o.Items = append(o.Items, item)
+	}
+	*s = o
+	return
+}
+
+// jsonStyle returns the style with the provided ID, if any
+func (s Subtitles) jsonStyle(id, requester string) (st *Style, err error) {
+	if id == "" {
+		return
+	}
+	var ok bool
+	if st, ok = s.Styles[id]; !ok {
+		err = fmt.Errorf("astisub: Style %s requested by %s doesn't exist", id, requester)
+	}
+	return
+}
+
+// ReadFromJSON parses subtitles serialized with WriteToJSON
+func ReadFromJSON(i io.Reader) (o *Subtitles, err error) {
+	o = &Subtitles{}
+	if err = json.NewDecoder(i).Decode(o); err != nil {
+		err = fmt.Errorf("astisub: unmarshaling json failed: %w", err)
+		return
+	}
+	return
+}
+
+// WriteToJSON serializes subtitles in JSON
+// Durations are serialized as "00:00:00.000" and styles and regions are referenced by their ID
+func (s Subtitles) WriteToJSON(o io.Writer) (err error) {
+	if err = json.NewEncoder(o).Encode(s); err != nil {
+		err = fmt.Errorf("astisub: marshaling json failed: %w", err)
+		return
+	}
+	return
+}
@@ -0,0 +1,70 @@
+package astisub_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSON(t *testing.T) {
+	for _, p := range []string{"./testdata/example-in.ttml", "./testdata/example-in.ssa", "./testdata/example-in.vtt"} {
+		// Open
+		s, err := astisub.OpenFile(p)
+		require.NoError(t, err)
+
+		// Write
+		w := &bytes.Buffer{}
+		err = s.WriteToJSON(w)
+		require.NoError(t, err)
+
+		// Read
+		b := w.Bytes()
+		s2, err := astisub.ReadFromJSON(w)
+		require.NoError(t, err)
+		b2, err := s2.MarshalJSON()
+		require.NoError(t, err)
+		assert.Equal(t, strings.TrimSpace(string(b)), string(b2), p)
+		if p != "./testdata/example-in.vtt" {
+			// Empty comments read from .vtt are unmarshaled as nil
+			assert.Equal(t, s, s2, p)
+		}
+	}
+
+	// Durations and references
+	s := astisub.NewSubtitles()
+	s.Styles["parent"] = &astisub.Style{ID: "parent"}
+	s.Styles["child"] = &astisub.Style{ID: "child", Style: s.Styles["parent"]}
+	s.Regions["r"] = &astisub.Region{ID: "r", Style: s.Styles["child"]}
+	s.Items = []*astisub.Item{{
+		EndAt:   time.Minute + 23*time.Second + 456*time.Millisecond,
+		Lines:   []astisub.Line{{Items: []astisub.LineItem{{Style: s.Styles["parent"], Text: "text"}}}},
+		Region:  s.Regions["r"],
+		StartAt: -time.Second,
+	}}
+	s.Metadata = &astisub.Metadata{WebVTTTimestampMap: &astisub.WebVTTTimestampMap{Local: 2 * time.Second, MpegTS: 900000}}
+	b, err := s.MarshalJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"WebVTTTimestampMap":{"Local":"00:00:02.000","MpegTS":900000}`)
+	assert.Contains(t, string(b), `"EndAt":"00:01:23.456"`)
+	assert.Contains(t, string(b), `"StartAt":"-00:00:01.000"`)
+	assert.Contains(t, string(b), `"Region":"r"`)
+	assert.Contains(t, string(b), `{"ID":"child","Style":"parent"}`)
+	s2 := &astisub.Subtitles{}
+	err = s2.UnmarshalJSON(b)
+	require.NoError(t, err)
+	assert.Equal(t, s, s2)
+	assert.True(t, s2.Items[0].Region == s2.Regions["r"])
+	assert.True(t, s2.Items[0].Lines[0].Items[0].Style == s2.Styles["parent"])
+	assert.True(t, s2.Regions["r"].Style.Style == s2.Styles["parent"])
+
+	// Invalid references
+	_, err = astisub.ReadFromJSON(strings.NewReader(`{"Items":[{"StartAt":"00:00:00.000","Style":"unknown"}]}`))
+	assert.EqualError(t, err, "astisub: unmarshaling json failed: astisub: Style unknown requested by item #1 doesn't exist")
+	_, err = astisub.ReadFromJSON(strings.NewReader(`{"Items":[{"StartAt":"invalid"}]}`))
+	assert.Error(t, err)
+}
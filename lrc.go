@@ -0,0 +1,283 @@
+package astisub
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://en.wikipedia.org/wiki/LRC_(file_format)
+
+// Constants
+const (
+	// lrcDefaultDuration is the duration of the last item when it's not followed by an empty line
+	lrcDefaultDuration = 2 * time.Second
+)
+
+// Vars
+var (
+	lrcRegexpIDTag   = regexp.MustCompile(`^\[([a-zA-Z#]+):(.*)\]$`)
+	lrcRegexpTimeTag = regexp.MustCompile(`^\[(\d+:\d{1,2}(?:[.:]\d{1,3})?)\]`)
+	lrcRegexpWordTag = regexp.MustCompile(`<(\d+:\d{1,2}(?:[.:]\d{1,3})?)>`)
+)
+
+// lrcLine represents a timed LRC line
+type lrcLine struct {
+	// shift is the difference with the first time tag of the line, which word time tags are relative to
+	shift   time.Duration
+	startAt time.Duration
+	text    string
+}
+
+// ReadFromLRC parses an .lrc content
+// Items end when the next one starts, lines without text only marking the end of the previous item.
+// Word timings of enhanced LRC are stored in line items.
+func ReadFromLRC(i io.Reader) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var scanner = newScanner(i)
+	var offset time.Duration
+	var ls []lrcLine
+
+	// Scan
+	var line string
+	var lineNum int
+	for scanner.Scan() {
+		// Fetch line
+		line = strings.TrimSpace(scanner.Text())
+		lineNum++
+
+		// Remove BOM header
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, string(BytesBOM))
+		}
+
+		// Empty line
+		if line == "" {
+			continue
+		}
+
+		// ID tag
+		if m := lrcRegexpIDTag.FindStringSubmatch(line); m != nil {
+			var v = strings.TrimSpace(m[2])
+			switch strings.ToLower(m[1]) {
+			case "al":
+				o.Metadata.Album = v
+			case "ar":
+				o.Metadata.Artist = v
+			case "by":
+				o.Metadata.Author = v
+			case "offset":
+				// Positive offsets make lyrics appear sooner
+				var ms int
+				if ms, err = strconv.Atoi(strings.TrimPrefix(v, "+")); err != nil {
+					err = fmt.Errorf("astisub: line %d: atoi of lrc offset %s failed: %w", lineNum, v, err)
+					return
+				}
+				offset = time.Duration(ms) * time.Millisecond
+			case "ti":
+				o.Metadata.Title = v
+			}
+			continue
+		}
+
+		// Several time tags may share the same text
+		var startAts []time.Duration
+		for {
+			var m = lrcRegexpTimeTag.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			var d time.Duration
+			if d, err = parseDurationLRC(m[1]); err != nil {
+				err = fmt.Errorf("astisub: line %d: parsing lrc time tag %s failed: %w", lineNum, m[1], err)
+				return
+			}
+			startAts = append(startAts, d)
+			line = line[len(m[0]):]
+		}
+		for _, d := range startAts {
+			ls = append(ls, lrcLine{shift: d - startAts[0], startAt: d, text: strings.TrimSpace(line)})
+		}
+	}
+
+	// Order lines
+	sort.SliceStable(ls, func(i, j int) bool { return ls[i].startAt < ls[j].startAt })
+
+	// Loop through lines
+	for idx, l := range ls {
+		// Lines without text only mark the end of the previous item
+		if l.text == "" {
+			continue
+		}
+
+		// Create item
+		var endAt = l.startAt + lrcDefaultDuration
+		if idx < len(ls)-1 {
+			endAt = ls[idx+1].startAt
+		}
+		var s = &Item{
+			EndAt:   endAt - offset,
+			StartAt: l.startAt - offset,
+		}
+
+		// Positive offsets may move items before 0, in which case they're clamped or dropped as Add does
+		if s.EndAt <= 0 {
+			continue
+		} else if s.StartAt < 0 {
+			s.StartAt = 0
+		}
+
+		// Parse text
+		var li Line
+		if li, err = parseTextLRC(l.text, l.shift-offset); err != nil {
+			err = fmt.Errorf("astisub: parsing lrc text %s failed: %w", l.text, err)
+			return
+		}
+		for idx := range li.Items {
+			if li.Items[idx].StartAt < 0 {
+				li.Items[idx].StartAt = 0
+			}
+		}
+		s.Lines = []Line{li}
+
+		// Append item
+		o.Items = append(o.Items, s)
+	}
+	return
+}
+
+// parseTextLRC parses an .lrc text, which may contain word time tags that are shifted by shift
+func parseTextLRC(i string, shift time.Duration) (o Line, err error) {
+	// Loop through word time tags
+	var previous int
+	var startAt time.Duration
+	for _, idxs := range lrcRegexpWordTag.FindAllStringSubmatchIndex(i, -1) {
+		// Append previous text
+		if t := i[previous:idxs[0]]; t != "" {
+			o.Items = append(o.Items, LineItem{StartAt: startAt, Text: t})
+		}
+		previous = idxs[1]
+
+		// Parse time tag
+		if startAt, err = parseDurationLRC(i[idxs[2]:idxs[3]]); err != nil {
+			err = fmt.Errorf("astisub: parsing lrc word time tag %s failed: %w", i[idxs[2]:idxs[3]], err)
+			return
+		}
+		startAt += shift
+	}
+
+	// Append remaining text
+	if t := i[previous:]; t != "" {
+		o.Items = append(o.Items, LineItem{StartAt: startAt, Text: t})
+	}
+	return
+}
+
+// parseDurationLRC parses an .lrc "mm:ss.xx" duration, fractional part being optional and possibly separated by ":"
+func parseDurationLRC(i string) (d time.Duration, err error) {
+	// Split minutes
+	var idx = strings.Index(i, ":")
+	var minutes int
+	if minutes, err = strconv.Atoi(i[:idx]); err != nil {
+		err = fmt.Errorf("astisub: atoi of %s failed: %w", i[:idx], err)
+		return
+	}
+	i = i[idx+1:]
+
+	// Split fraction
+	var fraction time.Duration
+	if idx = strings.IndexAny(i, ".:"); idx >= 0 {
+		var f = i[idx+1:]
+		var v int
+		if v, err = strconv.Atoi(f); err != nil {
+			err = fmt.Errorf("astisub: atoi of %s failed: %w", f, err)
+			return
+		}
+		fraction = time.Duration(v) * time.Second
+		for range f {
+			fraction /= 10
+		}
+		i = i[:idx]
+	}
+
+	// Parse seconds
+	var seconds int
+	if seconds, err = strconv.Atoi(i); err != nil {
+		err = fmt.Errorf("astisub: atoi of %s failed: %w", i, err)
+		return
+	}
+	d = time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second + fraction
+	return
+}
+
+// formatDurationLRC formats an .lrc "mm:ss.xx" duration
+func formatDurationLRC(d time.Duration) string {
+	var cs = d.Round(10*time.Millisecond) / (10 * time.Millisecond)
+	return fmt.Sprintf("%02d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
+// WriteToLRC writes subtitles in .lrc format
+// Lines of an item are joined with a space and an empty line is added when the next item doesn't start right away
+func (s Subtitles) WriteToLRC(o io.Writer) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		return ErrNoSubtitlesToWrite
+	}
+
+	// Add ID tags
+	var c []byte
+	if s.Metadata != nil {
+		for _, t := range []struct {
+			name  string
+			value string
+		}{
+			{name: "ti", value: s.Metadata.Title},
+			{name: "ar", value: s.Metadata.Artist},
+			{name: "al", value: s.Metadata.Album},
+			{name: "by", value: s.Metadata.Author},
+		} {
+			if t.value != "" {
+				c = append(c, []byte("["+t.name+":"+t.value+"]")...)
+				c = append(c, bytesLineSeparator...)
+			}
+		}
+	}
+
+	// Loop through items
+	for idx, item := range s.Items {
+		// Add time tag
+		c = append(c, []byte("["+formatDurationLRC(item.StartAt)+"]")...)
+
+		// Add text
+		for idxLine, l := range item.Lines {
+			if idxLine > 0 {
+				c = append(c, ' ')
+			}
+			for _, li := range l.Items {
+				if li.StartAt > 0 {
+					c = append(c, []byte("<"+formatDurationLRC(li.StartAt)+">")...)
+				}
+				c = append(c, []byte(li.Text)...)
+			}
+		}
+		c = append(c, bytesLineSeparator...)
+
+		// Add empty line
+		if idx == len(s.Items)-1 || s.Items[idx+1].StartAt > item.EndAt {
+			c = append(c, []byte("["+formatDurationLRC(item.EndAt)+"]")...)
+			c = append(c, bytesLineSeparator...)
+		}
+	}
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = fmt.Errorf("astisub: writing failed: %w", err)
+		return
+	}
+	return
+}
@@ -0,0 +1,65 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRC(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.lrc")
+	require.NoError(t, err)
+	// Metadata
+	assert.Equal(t, "LRC test", s.Metadata.Title)
+	assert.Equal(t, "Artist", s.Metadata.Artist)
+	assert.Equal(t, "Album", s.Metadata.Album)
+	assert.Equal(t, "asticode", s.Metadata.Author)
+	// Items
+	require.Len(t, s.Items, 3)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 4*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, "First line", s.Items[0].String())
+	assert.Equal(t, 4*time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 7*time.Second, s.Items[1].EndAt)
+	assert.Equal(t, []astisub.LineItem{{StartAt: 4 * time.Second, Text: "Cho"}, {StartAt: 4500 * time.Millisecond, Text: "rus"}}, s.Items[1].Lines[0].Items)
+	assert.Equal(t, 10*time.Second, s.Items[2].StartAt)
+	assert.Equal(t, 12*time.Second, s.Items[2].EndAt)
+
+	// Word timings are dialogue
+	assert.False(t, s.Items[1].IsSign())
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToLRC(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	err = s.WriteToLRC(w)
+	require.NoError(t, err)
+	assert.Equal(t, `[ti:LRC test]
+[ar:Artist]
+[al:Album]
+[by:asticode]
+[00:01.00]First line
+[00:04.00]<00:04.00>Cho<00:04.50>rus
+[00:07.00]
+[00:10.00]<00:10.00>Cho<00:10.50>rus
+[00:12.00]
+`, w.String())
+}
+
+func TestLRCPositiveOffset(t *testing.T) {
+	s, err := astisub.ReadFromLRC(bytes.NewBufferString("[offset:+1500]\n[00:01.00]Before\n[00:01.50]<00:01.50>Clam<00:02.00>ped\n[00:03.00]Shifted\n[00:04.00]\n"))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, time.Duration(0), s.Items[0].StartAt)
+	assert.Equal(t, 1500*time.Millisecond, s.Items[0].EndAt)
+	assert.Equal(t, []astisub.LineItem{{Text: "Clam"}, {StartAt: 500 * time.Millisecond, Text: "ped"}}, s.Items[0].Lines[0].Items)
+	assert.Equal(t, 1500*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, "Shifted", s.Items[1].String())
+}
@@ -0,0 +1,290 @@
+package astisub
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+// https://en.wikipedia.org/wiki/MicroDVD
+
+// Constants
+const (
+	// microDVDDefaultDuration is the duration of the last item when it has no end frame
+	microDVDDefaultDuration = 2 * time.Second
+)
+
+// Errors
+var (
+	ErrNoMicroDVDFramerate = errors.New("astisub: no microdvd framerate")
+)
+
+// Vars
+var (
+	microDVDRegexpCode = regexp.MustCompile(`^\{([a-zA-Z]):([^}]*)\}`)
+	microDVDRegexpLine = regexp.MustCompile(`^\{(\d+)\}\{(\d*)\}(.*)$`)
+)
+
+// ReadFromMicroDVD parses a .sub MicroDVD content using the provided framerate
+// If fps is not strictly positive, the framerate announced by a "{1}{1}fps" first line is used instead.
+// Lowercase control codes apply to their line whereas uppercase ones apply to the following lines of the cue as well.
+func ReadFromMicroDVD(i io.Reader, fps float64) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var scanner = newScanner(i)
+
+	// Scan
+	var line string
+	var lineNum int
+	for scanner.Scan() {
+		// Fetch line
+		line = strings.TrimSpace(scanner.Text())
+		lineNum++
+
+		// Remove BOM header
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, string(BytesBOM))
+		}
+
+		// Empty line
+		if line == "" {
+			continue
+		}
+
+		// Match line
+		var m = microDVDRegexpLine.FindStringSubmatch(line)
+		if m == nil {
+			err = fmt.Errorf("astisub: line %d: invalid microdvd line %s", lineNum, line)
+			return
+		}
+
+		// Parse frames
+		var startFrame, endFrame int
+		if startFrame, err = strconv.Atoi(m[1]); err != nil {
+			err = fmt.Errorf("astisub: line %d: atoi of %s failed: %w", lineNum, m[1], err)
+			return
+		}
+		if m[2] != "" {
+			if endFrame, err = strconv.Atoi(m[2]); err != nil {
+				err = fmt.Errorf("astisub: line %d: atoi of %s failed: %w", lineNum, m[2], err)
+				return
+			}
+		}
+
+		// Framerate line
+		if len(o.Items) == 0 && startFrame == 1 && endFrame == 1 {
+			if f, errParse := strconv.ParseFloat(strings.TrimSpace(m[3]), 64); errParse == nil && f > 0 {
+				if fps <= 0 {
+					fps = f
+				}
+				continue
+			}
+		}
+
+		// No framerate
+		if fps <= 0 {
+			err = ErrNoMicroDVDFramerate
+			return
+		}
+
+		// Create item
+		var s = &Item{StartAt: durationFromMicroDVDFrame(startFrame, fps)}
+		if m[2] != "" {
+			s.EndAt = durationFromMicroDVDFrame(endFrame, fps)
+		}
+
+		// Parse text
+		if s.Lines, err = parseTextMicroDVD(m[3]); err != nil {
+			err = fmt.Errorf("astisub: line %d: parsing microdvd text %s failed: %w", lineNum, m[3], err)
+			return
+		}
+
+		// Append item
+		o.Items = append(o.Items, s)
+	}
+
+	// Items without end frame end when the next one starts, the last one lasting a default duration
+	for idx, s := range o.Items {
+		if s.EndAt == 0 {
+			if idx < len(o.Items)-1 {
+				s.EndAt = o.Items[idx+1].StartAt
+			} else {
+				s.EndAt = s.StartAt + microDVDDefaultDuration
+			}
+		}
+	}
+
+	// Framerate is only an integer in the metadata
+	o.Metadata.MicroDVDFramerate = fps
+	if fps == math.Trunc(fps) {
+		o.Metadata.Framerate = int(fps)
+	}
+	return
+}
+
+// durationFromMicroDVDFrame converts a frame index into a duration
+func durationFromMicroDVDFrame(frame int, fps float64) time.Duration {
+	return time.Duration(math.Round(float64(frame) / fps * float64(time.Second)))
+}
+
+// microDVDFrameFromDuration converts a duration into a frame index
+func microDVDFrameFromDuration(d time.Duration, fps float64) int {
+	return int(math.Round(d.Seconds() * fps))
+}
+
+// parseTextMicroDVD parses a .sub MicroDVD text, "|" separating lines
+func parseTextMicroDVD(i string) (o []Line, err error) {
+	// Loop through lines
+	var cue StyleAttributes
+	for _, t := range strings.Split(i, "|") {
+		// Parse control codes
+		var sa = cue
+		for {
+			var m = microDVDRegexpCode.FindStringSubmatch(t)
+			if m == nil {
+				break
+			}
+			t = t[len(m[0]):]
+
+			// Uppercase codes apply to the rest of the cue
+			var name = strings.ToLower(m[1])
+			if err = sa.parseMicroDVDCode(name, m[2]); err != nil {
+				return
+			}
+			if m[1] != name {
+				if err = cue.parseMicroDVDCode(name, m[2]); err != nil {
+					return
+				}
+			}
+		}
+
+		// Append line
+		var li = LineItem{Text: t}
+		if sa.MicroDVDBold || sa.MicroDVDColor != nil || sa.MicroDVDFontName != "" || sa.MicroDVDFontSize != nil ||
+			sa.MicroDVDItalics || sa.MicroDVDStrikeout || sa.MicroDVDUnderline {
+			var c = sa
+			li.InlineStyle = &c
+			li.InlineStyle.propagateMicroDVDAttributes()
+		}
+		o = append(o, Line{Items: []LineItem{li}})
+	}
+	return
+}
+
+// parseMicroDVDCode parses a lowercase MicroDVD control code
+// Unsupported codes such as positions and charsets are ignored.
+func (sa *StyleAttributes) parseMicroDVDCode(name, value string) (err error) {
+	value = strings.TrimSpace(value)
+	switch name {
+	case "c":
+		if sa.MicroDVDColor, err = newColorFromSSAString(strings.TrimPrefix(value, "$"), 16); err != nil {
+			err = fmt.Errorf("astisub: parsing microdvd color %s failed: %w", value, err)
+			return
+		}
+	case "f":
+		sa.MicroDVDFontName = value
+	case "s":
+		var size int
+		if size, err = strconv.Atoi(value); err != nil {
+			err = fmt.Errorf("astisub: atoi of %s failed: %w", value, err)
+			return
+		}
+		sa.MicroDVDFontSize = astikit.IntPtr(size)
+	case "y":
+		for _, v := range strings.Split(strings.ToLower(value), ",") {
+			switch strings.TrimSpace(v) {
+			case "b":
+				sa.MicroDVDBold = true
+			case "i":
+				sa.MicroDVDItalics = true
+			case "s":
+				sa.MicroDVDStrikeout = true
+			case "u":
+				sa.MicroDVDUnderline = true
+			}
+		}
+	}
+	return
+}
+
+// microDVDCodes returns lowercase MicroDVD control codes of the style attributes
+func (sa *StyleAttributes) microDVDCodes() (o string) {
+	var ys []string
+	if sa.MicroDVDBold {
+		ys = append(ys, "b")
+	}
+	if sa.MicroDVDItalics {
+		ys = append(ys, "i")
+	}
+	if sa.MicroDVDStrikeout {
+		ys = append(ys, "s")
+	}
+	if sa.MicroDVDUnderline {
+		ys = append(ys, "u")
+	}
+	if len(ys) > 0 {
+		o += "{y:" + strings.Join(ys, ",") + "}"
+	}
+	if sa.MicroDVDColor != nil {
+		o += fmt.Sprintf("{c:$%.2X%.2X%.2X}", sa.MicroDVDColor.Blue, sa.MicroDVDColor.Green, sa.MicroDVDColor.Red)
+	}
+	if sa.MicroDVDFontName != "" {
+		o += "{f:" + sa.MicroDVDFontName + "}"
+	}
+	if sa.MicroDVDFontSize != nil {
+		o += "{s:" + strconv.Itoa(*sa.MicroDVDFontSize) + "}"
+	}
+	return
+}
+
+// WriteToMicroDVD writes subtitles in .sub MicroDVD format using the provided framerate
+// If fps is not strictly positive, Metadata.MicroDVDFramerate or else Metadata.Framerate is used instead.
+func (s Subtitles) WriteToMicroDVD(o io.Writer, fps float64) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		return ErrNoSubtitlesToWrite
+	}
+
+	// Get framerate
+	if fps <= 0 && s.Metadata != nil {
+		if fps = s.Metadata.MicroDVDFramerate; fps <= 0 {
+			fps = float64(s.Metadata.Framerate)
+		}
+	}
+	if fps <= 0 {
+		return ErrNoMicroDVDFramerate
+	}
+
+	// Loop through items
+	var c []byte
+	for _, item := range s.Items {
+		// Add frames
+		c = append(c, []byte("{"+strconv.Itoa(microDVDFrameFromDuration(item.StartAt, fps))+"}{"+strconv.Itoa(microDVDFrameFromDuration(item.EndAt, fps))+"}")...)
+
+		// Loop through lines
+		for idx, l := range item.Lines {
+			if idx > 0 {
+				c = append(c, '|')
+			}
+			if len(l.Items) > 0 && l.Items[0].InlineStyle != nil {
+				c = append(c, []byte(l.Items[0].InlineStyle.microDVDCodes())...)
+			}
+			c = append(c, []byte(l.String())...)
+		}
+		c = append(c, bytesLineSeparator...)
+	}
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = fmt.Errorf("astisub: writing failed: %w", err)
+		return
+	}
+	return
+}
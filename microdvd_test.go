@@ -0,0 +1,82 @@
+package astisub_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/asticode/go-astikit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMicroDVD(t *testing.T) {
+	// Open
+	f, err := os.Open("./testdata/example-in.sub")
+	require.NoError(t, err)
+	defer f.Close()
+	s, err := astisub.ReadFromMicroDVD(f, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 25, s.Metadata.Framerate)
+	require.Len(t, s.Items, 4)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 4*time.Second, s.Items[0].EndAt)
+	require.Len(t, s.Items[0].Lines, 2)
+	assert.Equal(t, "Italic line", s.Items[0].Lines[0].String())
+	assert.True(t, s.Items[0].Lines[0].Items[0].InlineStyle.MicroDVDItalics)
+	assert.True(t, s.Items[0].Lines[0].Items[0].InlineStyle.SRTItalics)
+	assert.Nil(t, s.Items[0].Lines[1].Items[0].InlineStyle)
+	assert.True(t, s.Items[1].Lines[0].Items[0].InlineStyle.MicroDVDBold)
+	assert.Equal(t, &astisub.Color{Red: 0xff}, s.Items[1].Lines[0].Items[0].InlineStyle.MicroDVDColor)
+	assert.Equal(t, astikit.StrPtr("#ff0000"), s.Items[1].Lines[0].Items[0].InlineStyle.SRTColor)
+	assert.True(t, s.Items[1].Lines[1].Items[0].InlineStyle.MicroDVDBold)
+	assert.True(t, s.Items[1].Lines[1].Items[0].InlineStyle.MicroDVDUnderline)
+	assert.Nil(t, s.Items[1].Lines[1].Items[0].InlineStyle.MicroDVDColor)
+	assert.Equal(t, 8*time.Second, s.Items[2].StartAt)
+	assert.Equal(t, 10*time.Second, s.Items[2].EndAt)
+
+	// Provided framerate wins
+	s2, err := astisub.ReadFromMicroDVD(bytes.NewBufferString("{1}{1}25\n{50}{100}Text\n"), 50)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, s2.Items[0].StartAt)
+
+	// Last item without end frame lasts a default duration and fractional framerates are kept
+	s3, err := astisub.ReadFromMicroDVD(bytes.NewBufferString("{1}{1}23.976\n{0}{24}First\n{48}{}Last\n"), 0)
+	require.NoError(t, err)
+	require.Len(t, s3.Items, 2)
+	assert.Equal(t, 0, s3.Metadata.Framerate)
+	assert.Equal(t, 23.976, s3.Metadata.MicroDVDFramerate)
+	assert.Equal(t, s3.Items[1].StartAt+2*time.Second, s3.Items[1].EndAt)
+
+	// No framerate
+	_, err = astisub.ReadFromMicroDVD(bytes.NewBufferString("{50}{100}Text\n"), 0)
+	assert.EqualError(t, err, astisub.ErrNoMicroDVDFramerate.Error())
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToMicroDVD(w, 25)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write with metadata framerate
+	err = s.WriteToMicroDVD(w, 0)
+	require.NoError(t, err)
+	assert.Equal(t, `{25}{100}{y:i}Italic line|Normal line
+{125}{175}{y:b}{c:$0000FF}Bold red|{y:b,u}Still bold
+{200}{250}Open ended
+{250}{300}Last
+`, w.String())
+
+	// Write with provided framerate
+	w.Reset()
+	err = s2.WriteToMicroDVD(w, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "{10}{20}Text\n", w.String())
+
+	// Write with fractional metadata framerate
+	w.Reset()
+	err = s3.WriteToMicroDVD(w, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "{0}{24}First\n{48}{96}Last\n", w.String())
+}
@@ -0,0 +1,150 @@
+package astisub
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// http://lists.mplayerhq.hu/pipermail/mplayer-users/2003-February/030222.html
+
+// Constants
+const (
+	// mpl2DefaultDuration is the duration of the last item when it has no end time
+	mpl2DefaultDuration = 2 * time.Second
+)
+
+// Vars
+var (
+	mpl2RegexpLine = regexp.MustCompile(`^\[(\d+)\]\[(\d*)\](.*)$`)
+)
+
+// ReadFromMPL2 parses a .mpl MPL2 content, time boundaries being expressed in deciseconds
+// A leading "/" on a line denotes italics.
+func ReadFromMPL2(i io.Reader) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var scanner = newScanner(i)
+
+	// Scan
+	var line string
+	var lineNum int
+	for scanner.Scan() {
+		// Fetch line
+		line = strings.TrimSpace(scanner.Text())
+		lineNum++
+
+		// Remove BOM header
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, string(BytesBOM))
+		}
+
+		// Empty line
+		if line == "" {
+			continue
+		}
+
+		// Match line
+		var m = mpl2RegexpLine.FindStringSubmatch(line)
+		if m == nil {
+			err = fmt.Errorf("astisub: line %d: invalid mpl2 line %s", lineNum, line)
+			return
+		}
+
+		// Parse time boundaries
+		var s = &Item{}
+		var start, end int
+		if start, err = strconv.Atoi(m[1]); err != nil {
+			err = fmt.Errorf("astisub: line %d: atoi of %s failed: %w", lineNum, m[1], err)
+			return
+		}
+		s.StartAt = durationFromMPL2Deciseconds(start)
+		if m[2] != "" {
+			if end, err = strconv.Atoi(m[2]); err != nil {
+				err = fmt.Errorf("astisub: line %d: atoi of %s failed: %w", lineNum, m[2], err)
+				return
+			}
+			s.EndAt = durationFromMPL2Deciseconds(end)
+		}
+
+		// Parse text
+		s.Lines = parseTextMPL2(m[3])
+
+		// Append item
+		o.Items = append(o.Items, s)
+	}
+
+	// Items without end time end when the next one starts, the last one lasting a default duration
+	for idx, s := range o.Items {
+		if s.EndAt == 0 {
+			if idx < len(o.Items)-1 {
+				s.EndAt = o.Items[idx+1].StartAt
+			} else {
+				s.EndAt = s.StartAt + mpl2DefaultDuration
+			}
+		}
+	}
+	return
+}
+
+// durationFromMPL2Deciseconds converts deciseconds into a duration
+func durationFromMPL2Deciseconds(i int) time.Duration {
+	return time.Duration(i) * 100 * time.Millisecond
+}
+
+// mpl2DecisecondsFromDuration converts a duration into deciseconds, rounded half up
+func mpl2DecisecondsFromDuration(d time.Duration) int {
+	return int((d + 50*time.Millisecond) / (100 * time.Millisecond))
+}
+
+// parseTextMPL2 parses a .mpl MPL2 text, "|" separating lines
+func parseTextMPL2(i string) (o []Line) {
+	for _, t := range strings.Split(i, "|") {
+		var li = LineItem{Text: t}
+		if strings.HasPrefix(t, "/") {
+			li.Text = t[1:]
+			li.InlineStyle = &StyleAttributes{SRTItalics: true}
+			li.InlineStyle.propagateSRTAttributes()
+		}
+		o = append(o, Line{Items: []LineItem{li}})
+	}
+	return
+}
+
+// WriteToMPL2 writes subtitles in .mpl MPL2 format
+// Lines whose first line item is in italics are written in italics.
+func (s Subtitles) WriteToMPL2(o io.Writer) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		return ErrNoSubtitlesToWrite
+	}
+
+	// Loop through items
+	var c []byte
+	for _, item := range s.Items {
+		// Add time boundaries
+		c = append(c, []byte("["+strconv.Itoa(mpl2DecisecondsFromDuration(item.StartAt))+"]["+strconv.Itoa(mpl2DecisecondsFromDuration(item.EndAt))+"]")...)
+
+		// Loop through lines
+		for idx, l := range item.Lines {
+			if idx > 0 {
+				c = append(c, '|')
+			}
+			if len(l.Items) > 0 && l.Items[0].InlineStyle != nil && l.Items[0].InlineStyle.SRTItalics {
+				c = append(c, '/')
+			}
+			c = append(c, []byte(l.String())...)
+		}
+		c = append(c, bytesLineSeparator...)
+	}
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = fmt.Errorf("astisub: writing failed: %w", err)
+		return
+	}
+	return
+}
@@ -0,0 +1,55 @@
+package astisub_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMPL2(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromMPL2(strings.NewReader("\ufeff[10][25]/Italic line|Normal line\n\n[123][456]Hello\n[500][]Open ended\n[520][540]Before last\n[600][]Last\n"))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 5)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 2500*time.Millisecond, s.Items[0].EndAt)
+	require.Len(t, s.Items[0].Lines, 2)
+	assert.Equal(t, "Italic line", s.Items[0].Lines[0].String())
+	assert.True(t, s.Items[0].Lines[0].Items[0].InlineStyle.SRTItalics)
+	assert.True(t, s.Items[0].Lines[0].Items[0].InlineStyle.WebVTTItalics)
+	assert.Equal(t, "Normal line", s.Items[0].Lines[1].String())
+	assert.Nil(t, s.Items[0].Lines[1].Items[0].InlineStyle)
+	assert.Equal(t, 12300*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, 45600*time.Millisecond, s.Items[1].EndAt)
+	assert.Equal(t, 52*time.Second, s.Items[2].EndAt)
+	assert.Equal(t, 62*time.Second, s.Items[4].EndAt)
+
+	// Invalid line
+	_, err = astisub.ReadFromMPL2(strings.NewReader("[10][25]Text\n{10}{25}Text\n"))
+	assert.EqualError(t, err, "astisub: line 2: invalid mpl2 line {10}{25}Text")
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToMPL2(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	err = s.WriteToMPL2(w)
+	require.NoError(t, err)
+	assert.Equal(t, "[10][25]/Italic line|Normal line\n[123][456]Hello\n[500][520]Open ended\n[520][540]Before last\n[600][620]Last\n", w.String())
+
+	// Time boundaries are rounded to the nearest decisecond
+	w.Reset()
+	err = astisub.Subtitles{Items: []*astisub.Item{{
+		StartAt: time.Second + 50*time.Millisecond,
+		EndAt:   2*time.Second + 49*time.Millisecond,
+		Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: "Rounded"}}}},
+	}}}.WriteToMPL2(w)
+	require.NoError(t, err)
+	assert.Equal(t, "[11][20]Rounded\n", w.String())
+}
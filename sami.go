@@ -0,0 +1,402 @@
+package astisub
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// https://learn.microsoft.com/en-us/previous-versions/windows/desktop/dnacc/understanding-sami-1.0
+
+// Constants
+const (
+	// samiDefaultClass is the class of paragraphs whose item has no class style
+	samiDefaultClass = "SUBTTL"
+	// samiDefaultDuration is the duration of the last item when it's not followed by a blank paragraph
+	samiDefaultDuration = 2 * time.Second
+	// samiParagraphStyleID is the ID of the style read from the "P" CSS rule, which is the parent of class styles
+	samiParagraphStyleID = "P"
+)
+
+// Vars
+var (
+	samiRegexpBreakLine = regexp.MustCompile(`(?i)<br\s*/?>`)
+	samiRegexpCSSRule   = regexp.MustCompile(`([^{}]+)\{([^}]*)\}`)
+)
+
+// samiParagraph represents the raw content of a SAMI paragraph
+type samiParagraph struct {
+	class string
+	raw   strings.Builder
+}
+
+// ReadFromSAMI parses a .smi/.sami content
+// Items end when the next SYNC block starts, blank paragraphs (e.g. "&nbsp;") only marking the end of the previous
+// items. Each paragraph of a SYNC block results in a different item, whose style is the paragraph class.
+func ReadFromSAMI(i io.Reader) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var tr = html.NewTokenizer(i)
+	var paragraphs []*samiParagraph
+	var start, end *time.Duration
+	var inStyle, inTitle bool
+
+	// flush adds items of the current SYNC block
+	flush := func(endAt time.Duration) {
+		if start != nil {
+			for _, p := range paragraphs {
+				if item := newSAMIItem(p, *start, endAt, o.Styles); item != nil {
+					o.Items = append(o.Items, item)
+				}
+			}
+		}
+		paragraphs = nil
+	}
+
+	// Loop
+	for {
+		// Get next token
+		var t = tr.Next()
+		if t == html.ErrorToken {
+			if tr.Err() != io.EOF {
+				err = fmt.Errorf("astisub: tokenizing sami failed: %w", tr.Err())
+				return
+			}
+			break
+		}
+		var raw = string(tr.Raw())
+		var token = tr.Token()
+
+		switch t {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch token.Data {
+			case "style":
+				inStyle = true
+			case "title":
+				inTitle = true
+			case "sync":
+				// Parse time boundaries
+				var d time.Duration
+				if v := htmlTokenAttribute(&token, "start"); v != nil {
+					if d, err = parseDurationSAMI(*v); err != nil {
+						err = fmt.Errorf("astisub: parsing sami start %s failed: %w", *v, err)
+						return
+					}
+				}
+				if end != nil {
+					flush(*end)
+				} else {
+					flush(d)
+				}
+				start, end = &d, nil
+				if v := htmlTokenAttribute(&token, "end"); v != nil {
+					var e time.Duration
+					if e, err = parseDurationSAMI(*v); err != nil {
+						err = fmt.Errorf("astisub: parsing sami end %s failed: %w", *v, err)
+						return
+					}
+					end = &e
+				}
+			case "p":
+				var p = &samiParagraph{}
+				if v := htmlTokenAttribute(&token, "class"); v != nil {
+					p.class = *v
+				}
+				paragraphs = append(paragraphs, p)
+			default:
+				if len(paragraphs) > 0 {
+					paragraphs[len(paragraphs)-1].raw.WriteString(raw)
+				}
+			}
+		case html.EndTagToken:
+			switch token.Data {
+			case "style":
+				inStyle = false
+			case "title":
+				inTitle = false
+			case "body", "p", "sami", "sync":
+			default:
+				if len(paragraphs) > 0 {
+					paragraphs[len(paragraphs)-1].raw.WriteString(raw)
+				}
+			}
+		case html.TextToken:
+			switch {
+			case inStyle:
+				parseSAMIStyles(raw, o.Styles)
+			case inTitle:
+				o.Metadata.Title = strings.TrimSpace(raw)
+			case start != nil:
+				// Text may follow the SYNC tag without paragraph
+				if len(paragraphs) == 0 {
+					paragraphs = append(paragraphs, &samiParagraph{})
+				}
+				paragraphs[len(paragraphs)-1].raw.WriteString(raw)
+			}
+		}
+	}
+
+	// Last SYNC block
+	if start != nil {
+		if end != nil {
+			flush(*end)
+		} else {
+			flush(*start + samiDefaultDuration)
+		}
+	}
+	return
+}
+
+// newSAMIItem converts a SAMI paragraph into an item, returning nil if it has nothing to display
+func newSAMIItem(p *samiParagraph, startAt, endAt time.Duration, styles map[string]*Style) (i *Item) {
+	// Parse lines
+	i = &Item{
+		EndAt:   endAt,
+		StartAt: startAt,
+	}
+	var sa = &StyleAttributes{}
+	for _, s := range samiRegexpBreakLine.Split(p.raw.String(), -1) {
+		if l := parseTextSrt(strings.TrimSpace(s), sa); len(l.Items) > 0 && strings.TrimSpace(l.String()) != "" {
+			i.Lines = append(i.Lines, l)
+		}
+	}
+
+	// Nothing to display
+	if len(i.Lines) == 0 {
+		return nil
+	}
+
+	// Add style
+	// Classes may not be declared in the STYLE block
+	if p.class != "" {
+		if _, ok := styles[p.class]; !ok {
+			styles[p.class] = &Style{ID: p.class, InlineStyle: &StyleAttributes{}, Style: styles[samiParagraphStyleID]}
+		}
+		i.Style = styles[p.class]
+	} else if s, ok := styles[samiParagraphStyleID]; ok {
+		i.Style = s
+	}
+	return
+}
+
+// parseDurationSAMI parses a SAMI duration, which is in milliseconds
+func parseDurationSAMI(i string) (d time.Duration, err error) {
+	var ms int
+	if ms, err = strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(i), "ms")); err != nil {
+		err = fmt.Errorf("astisub: atoi of %s failed: %w", i, err)
+		return
+	}
+	d = time.Duration(ms) * time.Millisecond
+	return
+}
+
+// parseSAMIStyles parses the CSS of a SAMI STYLE block
+// Only "P" and class selectors are supported
+func parseSAMIStyles(i string, styles map[string]*Style) {
+	// Remove comment markers hiding the CSS from old browsers
+	i = strings.NewReplacer("<!--", "", "-->", "").Replace(i)
+
+	// Loop through rules
+	var ss []*Style
+	for _, m := range samiRegexpCSSRule.FindAllStringSubmatch(i, -1) {
+		// Parse declarations
+		var sa = &StyleAttributes{}
+		for _, d := range strings.Split(m[2], ";") {
+			var idx = strings.Index(d, ":")
+			if idx < 0 {
+				continue
+			}
+			var v = strings.TrimSpace(d[idx+1:])
+			switch strings.ToLower(strings.TrimSpace(d[:idx])) {
+			case "color":
+				sa.SAMIColor = &v
+			case "font-family":
+				sa.SAMIFontFamily = &v
+			case "font-size":
+				sa.SAMIFontSize = &v
+			case "font-style":
+				sa.SAMIFontStyle = &v
+			case "font-weight":
+				sa.SAMIFontWeight = &v
+			case "lang":
+				sa.SAMILang = v
+			case "name":
+				sa.SAMIName = v
+			case "text-align":
+				sa.SAMITextAlign = &v
+			}
+		}
+		sa.propagateSAMIAttributes()
+
+		// Loop through selectors
+		for _, selector := range strings.Split(m[1], ",") {
+			var id string
+			if selector = strings.TrimSpace(selector); strings.EqualFold(selector, samiParagraphStyleID) {
+				id = samiParagraphStyleID
+			} else if strings.HasPrefix(selector, ".") {
+				id = selector[1:]
+			} else {
+				continue
+			}
+			var s = &Style{ID: id, InlineStyle: &StyleAttributes{}}
+			*s.InlineStyle = *sa
+			styles[id] = s
+			ss = append(ss, s)
+		}
+	}
+
+	// Class styles inherit from the "P" style
+	if p, ok := styles[samiParagraphStyleID]; ok {
+		for _, s := range ss {
+			if s != p {
+				s.Style = p
+			}
+		}
+	}
+}
+
+// WriteToSAMI writes subtitles in .smi/.sami format
+// Items starting at the same time are written in the same SYNC block, a blank paragraph being added when the next
+// item doesn't start right away. Inline styles are written using SRT attributes.
+func (s Subtitles) WriteToSAMI(o io.Writer) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		return ErrNoSubtitlesToWrite
+	}
+
+	// Head
+	var b = &bytes.Buffer{}
+	b.WriteString("<SAMI>\n<HEAD>\n")
+	if s.Metadata != nil && s.Metadata.Title != "" {
+		b.WriteString("<TITLE>" + escapeHTML(s.Metadata.Title) + "</TITLE>\n")
+	}
+
+	// Styles
+	var ids []string
+	for id := range s.Styles {
+		if id != samiParagraphStyleID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	var classes = samiClassNames(ids)
+	b.WriteString("<STYLE TYPE=\"text/css\">\n<!--\n")
+	if p, ok := s.Styles[samiParagraphStyleID]; ok {
+		b.WriteString("P {" + samiCSSDeclarations(p.InlineStyle) + " }\n")
+	}
+	var defaultClass = len(ids) == 0
+	for _, i := range s.Items {
+		if samiClass(i, s.Styles, classes) == samiDefaultClass {
+			defaultClass = true
+			break
+		}
+	}
+	if defaultClass {
+		b.WriteString("." + samiDefaultClass + " { Name: Subtitles; }\n")
+	}
+	for _, id := range ids {
+		b.WriteString("." + classes[id] + " {" + samiCSSDeclarations(s.Styles[id].InlineStyle) + " }\n")
+	}
+	b.WriteString("-->\n</STYLE>\n</HEAD>\n<BODY>\n")
+
+	// Loop through items
+	for idx := 0; idx < len(s.Items); {
+		// Write paragraphs of items starting at the same time
+		var startAt, endAt = s.Items[idx].StartAt, s.Items[idx].EndAt
+		var class string
+		b.WriteString("<SYNC Start=" + formatDurationSAMI(startAt) + ">\n")
+		for ; idx < len(s.Items) && s.Items[idx].StartAt == startAt; idx++ {
+			class = samiClass(s.Items[idx], s.Styles, classes)
+			if s.Items[idx].EndAt > endAt {
+				endAt = s.Items[idx].EndAt
+			}
+			b.WriteString("<P Class=\"" + class + "\">")
+			for idxLine, l := range s.Items[idx].Lines {
+				if idxLine > 0 {
+					b.WriteString("<br>")
+				}
+				for _, li := range l.Items {
+					b.Write(li.srtBytes(WriteToSRTOptions{}))
+				}
+			}
+			b.WriteString("\n")
+		}
+
+		// Write blank paragraph
+		if idx == len(s.Items) || s.Items[idx].StartAt > endAt {
+			b.WriteString("<SYNC Start=" + formatDurationSAMI(endAt) + ">\n<P Class=\"" + class + "\">&nbsp;\n")
+		}
+	}
+	b.WriteString("</BODY>\n</SAMI>\n")
+
+	// Write
+	if _, err = o.Write(b.Bytes()); err != nil {
+		err = fmt.Errorf("astisub: writing failed: %w", err)
+		return
+	}
+	return
+}
+
+// samiClass returns the class of the paragraph of an item, items without class style using the default class
+func samiClass(i *Item, styles map[string]*Style, classes map[string]string) string {
+	if i.Style != nil && i.Style.ID != samiParagraphStyleID {
+		if _, ok := styles[i.Style.ID]; ok {
+			return classes[i.Style.ID]
+		}
+	}
+	return samiDefaultClass
+}
+
+// samiClassNames returns the class names of style IDs sanitized into valid CSS identifiers, suffixed when several
+// IDs result in the same class name
+func samiClassNames(ids []string) (o map[string]string) {
+	o = make(map[string]string)
+	var used = make(map[string]bool)
+	for _, id := range ids {
+		var name = concatID(webVTTClassName(id), func(v string) bool { return used[v] })
+		used[name] = true
+		o[id] = name
+	}
+	return
+}
+
+// samiCSSDeclarations returns the CSS declarations of style attributes
+func samiCSSDeclarations(sa *StyleAttributes) (o string) {
+	if sa == nil {
+		return
+	}
+	if sa.SAMIName != "" {
+		o += " Name: " + sa.SAMIName + ";"
+	}
+	if sa.SAMILang != "" {
+		o += " lang: " + sa.SAMILang + ";"
+	}
+	for _, d := range []struct {
+		name  string
+		value *string
+	}{
+		{name: "color", value: sa.SAMIColor},
+		{name: "font-family", value: sa.SAMIFontFamily},
+		{name: "font-size", value: sa.SAMIFontSize},
+		{name: "font-style", value: sa.SAMIFontStyle},
+		{name: "font-weight", value: sa.SAMIFontWeight},
+		{name: "text-align", value: sa.SAMITextAlign},
+	} {
+		if d.value != nil {
+			o += " " + d.name + ": " + *d.value + ";"
+		}
+	}
+	return
+}
+
+// formatDurationSAMI formats a SAMI duration, which is in milliseconds
+func formatDurationSAMI(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
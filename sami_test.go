@@ -0,0 +1,95 @@
+package astisub_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/asticode/go-astikit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSAMI(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.smi")
+	assert.NoError(t, err)
+	assertSubtitleItems(t, s)
+	// Metadata
+	assert.Equal(t, "SAMI test", s.Metadata.Title)
+	// Styles
+	require.Len(t, s.Styles, 2)
+	assert.Equal(t, astikit.StrPtr("Arial"), s.Styles["P"].InlineStyle.SAMIFontFamily)
+	assert.Equal(t, astikit.StrPtr("white"), s.Styles["P"].InlineStyle.TTMLColor)
+	assert.Equal(t, "en-US", s.Styles["ENUSCC"].InlineStyle.SAMILang)
+	assert.Equal(t, s.Styles["P"], s.Styles["ENUSCC"].Style)
+	assert.Equal(t, s.Styles["ENUSCC"], s.Items[0].Style)
+	// Inline styles
+	require.Len(t, s.Items[1].Lines[1].Items, 3)
+	assert.True(t, s.Items[1].Lines[1].Items[1].InlineStyle.SRTItalics)
+	assert.Nil(t, s.Items[1].Lines[1].Items[2].InlineStyle)
+	assert.Equal(t, astikit.StrPtr("#ff0000"), s.Items[2].Lines[0].Items[0].InlineStyle.SRTColor)
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToSAMI(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	c, err := ioutil.ReadFile("./testdata/example-out.smi")
+	assert.NoError(t, err)
+	err = s.WriteToSAMI(w)
+	assert.NoError(t, err)
+	assert.Equal(t, string(c), w.String())
+}
+
+func TestSAMIWithoutEndTime(t *testing.T) {
+	s, err := astisub.ReadFromSAMI(strings.NewReader(`<SAMI><BODY>
+<SYNC Start=1000><P Class=KRCC>First
+<SYNC Start=3000><P Class=KRCC>Second<P Class=ENCC>Deuxième
+</BODY></SAMI>`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 3)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 3*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, 3*time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 5*time.Second, s.Items[1].EndAt)
+	assert.Equal(t, "Deuxième", s.Items[2].String())
+	assert.Equal(t, 5*time.Second, s.Items[2].EndAt)
+	assert.Equal(t, "ENCC", s.Items[2].Style.ID)
+
+	// Items start at the same time are written in the same SYNC block
+	w := &bytes.Buffer{}
+	err = s.WriteToSAMI(w)
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), "<SYNC Start=1000>\n<P Class=\"KRCC\">First\n<SYNC Start=3000>\n<P Class=\"KRCC\">Second\n<P Class=\"ENCC\">Deuxième\n<SYNC Start=5000>\n")
+}
+
+func TestWriteToSAMIClasses(t *testing.T) {
+	st := &astisub.Style{ID: `my "class" > 1`, InlineStyle: &astisub.StyleAttributes{SAMILang: "en-US"}}
+	s := &astisub.Subtitles{
+		Items: []*astisub.Item{
+			{EndAt: 2 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Styled"}}}}, StartAt: time.Second, Style: st},
+			{EndAt: 4 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Unstyled"}}}}, StartAt: 3 * time.Second},
+		},
+		Styles: map[string]*astisub.Style{st.ID: st},
+	}
+	w := &bytes.Buffer{}
+	require.NoError(t, s.WriteToSAMI(w))
+	assert.Contains(t, w.String(), ".SUBTTL { Name: Subtitles; }\n")
+	assert.Contains(t, w.String(), ".my__class____1 {")
+	assert.Contains(t, w.String(), "<SYNC Start=1000>\n<P Class=\"my__class____1\">Styled\n")
+	assert.Contains(t, w.String(), "<SYNC Start=3000>\n<P Class=\"SUBTTL\">Unstyled\n")
+
+	// Colliding class names are suffixed
+	st2 := &astisub.Style{ID: `my_"class"_>_1`, InlineStyle: &astisub.StyleAttributes{SAMILang: "fr-FR"}}
+	s.Items[1].Style = st2
+	s.Styles[st2.ID] = st2
+	w.Reset()
+	require.NoError(t, s.WriteToSAMI(w))
+	assert.Contains(t, w.String(), "<P Class=\"my__class____1\">Styled\n")
+	assert.Contains(t, w.String(), "<P Class=\"my__class____1_2\">Unstyled\n")
+}
@@ -0,0 +1,119 @@
+package astisub
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// https://support.google.com/youtube/answer/2734698
+
+// Vars
+var (
+	sbvRegexpTimestamps = regexp.MustCompile(`^(\d+:\d{1,2}:\d{1,2}(?:[.,]\d{1,3})?)\s*,\s*(\d+:\d{1,2}:\d{1,2}(?:[.,]\d{1,3})?)$`)
+)
+
+// ReadFromSBV parses a .sbv content
+// Each cue is a "H:MM:SS.mmm,H:MM:SS.mmm" line followed by text lines until an empty line.
+// Milliseconds may be separated by either a period or a comma.
+func ReadFromSBV(i io.Reader) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var scanner = newScanner(i)
+
+	// Scan
+	var line string
+	var lineNum int
+	var s *Item
+	for scanner.Scan() {
+		// Fetch line
+		line = strings.TrimSpace(scanner.Text())
+		lineNum++
+
+		// Remove BOM header
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, string(BytesBOM))
+		}
+
+		// Empty line ends the cue
+		if line == "" {
+			s = nil
+			continue
+		}
+
+		// Text line
+		if s != nil {
+			s.Lines = append(s.Lines, Line{Items: []LineItem{{Text: line}}})
+			continue
+		}
+
+		// Timestamps line
+		var m = sbvRegexpTimestamps.FindStringSubmatch(line)
+		if m == nil {
+			// Lines outside of cues, such as headers, are ignored
+			continue
+		}
+
+		// Parse time boundaries
+		s = &Item{}
+		if s.StartAt, err = parseDurationSBV(m[1]); err != nil {
+			err = fmt.Errorf("astisub: line %d: parsing sbv start time %s failed: %w", lineNum, m[1], err)
+			return
+		}
+		if s.EndAt, err = parseDurationSBV(m[2]); err != nil {
+			err = fmt.Errorf("astisub: line %d: parsing sbv end time %s failed: %w", lineNum, m[2], err)
+			return
+		}
+
+		// Append item
+		o.Items = append(o.Items, s)
+	}
+	return
+}
+
+// parseDurationSBV parses a .sbv "H:MM:SS.mmm" duration, milliseconds being possibly separated by a comma
+func parseDurationSBV(i string) (time.Duration, error) {
+	return parseDuration(strings.Replace(i, ",", ".", 1), ".", 3)
+}
+
+// formatDurationSBV formats a .sbv "H:MM:SS.mmm" duration
+func formatDurationSBV(d time.Duration) string {
+	var ms = d.Round(time.Millisecond) / time.Millisecond
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// WriteToSBV writes subtitles in .sbv format
+func (s Subtitles) WriteToSBV(o io.Writer) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		return ErrNoSubtitlesToWrite
+	}
+
+	// Loop through items
+	var c []byte
+	for idx, item := range s.Items {
+		// Add empty line
+		if idx > 0 {
+			c = append(c, bytesLineSeparator...)
+		}
+
+		// Add time boundaries
+		c = append(c, []byte(formatDurationSBV(item.StartAt)+","+formatDurationSBV(item.EndAt))...)
+		c = append(c, bytesLineSeparator...)
+
+		// Loop through lines
+		for _, l := range item.Lines {
+			c = append(c, []byte(l.String())...)
+			c = append(c, bytesLineSeparator...)
+		}
+	}
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = fmt.Errorf("astisub: writing failed: %w", err)
+		return
+	}
+	return
+}
@@ -0,0 +1,53 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSBV(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.sbv")
+	require.NoError(t, err)
+	require.Len(t, s.Items, 3)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 4*time.Second, s.Items[0].EndAt)
+	require.Len(t, s.Items[0].Lines, 2)
+	assert.Equal(t, "First line", s.Items[0].Lines[0].String())
+	assert.Equal(t, "Second line", s.Items[0].Lines[1].String())
+	assert.Equal(t, 5500*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, 7250*time.Millisecond, s.Items[1].EndAt)
+	assert.Equal(t, "Comma separated", s.Items[1].String())
+	assert.Equal(t, time.Hour+2*time.Minute+3400*time.Millisecond, s.Items[2].StartAt)
+	assert.Equal(t, time.Hour+2*time.Minute+5*time.Second, s.Items[2].EndAt)
+
+	// Lines outside of cues
+	s2, err := astisub.ReadFromSBV(bytes.NewBufferString("[INFORMATION]\n\n0:00:01.000,0:00:02.000\ntext\n"))
+	require.NoError(t, err)
+	require.Len(t, s2.Items, 1)
+	assert.Equal(t, "text", s2.Items[0].String())
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToSBV(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	err = s.WriteToSBV(w)
+	require.NoError(t, err)
+	assert.Equal(t, `0:00:01.000,0:00:04.000
+First line
+Second line
+
+0:00:05.500,0:00:07.250
+Comma separated
+
+1:02:03.400,1:02:05.000
+Long one
+`, w.String())
+}
@@ -0,0 +1,520 @@
+package astisub
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://en.wikipedia.org/wiki/EIA-608
+// http://www.theneitherworld.com/mcpoodle/SCC_TOOLS/DOCS/SCC_FORMAT.HTML
+// http://www.theneitherworld.com/mcpoodle/SCC_TOOLS/DOCS/CC_CODES.HTML
+
+// Constants
+const (
+	sccColumns = 32
+	// sccDefaultDuration is the duration of the last item when it's not erased
+	sccDefaultDuration = 2 * time.Second
+	sccRows            = 15
+)
+
+// SCC caption modes
+const (
+	sccModePopOn = iota
+	sccModePaintOn
+	sccModeRollUp
+)
+
+// Vars
+var (
+	sccRegexpTimecode = regexp.MustCompile(`^(\d{2}):(\d{2}):(\d{2})([:;.,])(\d{2})$`)
+)
+
+// SCC characters
+var (
+	// sccBasicCharacters are the characters that differ from ASCII
+	sccBasicCharacters = map[byte]rune{
+		0x27: '’',
+		0x2a: 'á',
+		0x5c: 'é',
+		0x5e: 'í',
+		0x5f: 'ó',
+		0x60: 'ú',
+		0x7b: 'ç',
+		0x7c: '÷',
+		0x7d: 'Ñ',
+		0x7e: 'ñ',
+		0x7f: '█',
+	}
+	// sccExtendedCharacters are indexed by the first byte and replace the previous character
+	sccExtendedCharacters = map[byte][]rune{
+		0x12: []rune("ÁÉÓÚÜü‘¡*'—©℠•“”ÀÂÇÈÊËëÎÏïÔÙùÛ«»"),
+		0x13: []rune("ÃãÍÌìÒòÕõ{}\\^_|~ÄäÖöß¥¤¦ÅåØø┌┐└┘"),
+	}
+	sccSpecialCharacters = []rune("®°½¿™¢£♪à èâêîôû")
+)
+
+// SCC colors indexed by attribute code / 2
+var sccColors = []string{"white", "green", "blue", "cyan", "red", "yellow", "magenta"}
+
+// SCC preamble address code rows indexed by the first byte, second bytes above 0x5f targeting the next row
+var sccPreambleAddressCodeRows = map[byte]int{
+	0x10: 11,
+	0x11: 1,
+	0x12: 3,
+	0x13: 12,
+	0x14: 14,
+	0x15: 5,
+	0x16: 7,
+	0x17: 9,
+}
+
+// sccStyle represents the style of an SCC character
+type sccStyle struct {
+	color     string
+	italics   bool
+	underline bool
+}
+
+// styleAttributes returns the style attributes of an SCC character, nil meaning the default style
+func (s sccStyle) styleAttributes() *StyleAttributes {
+	if s == (sccStyle{}) {
+		return nil
+	}
+	var sa = &StyleAttributes{
+		SCCColor:     s.color,
+		SCCItalics:   s.italics,
+		SCCUnderline: s.underline,
+	}
+	sa.propagateSCCAttributes()
+	return sa
+}
+
+// newSCCStyle creates a new SCC style based on an attribute code, which is a color or italics with an underline bit
+func newSCCStyle(code byte) (s sccStyle) {
+	s.underline = code&0x1 > 0
+	if code = code & 0xe >> 1; code == 7 {
+		s.italics = true
+	} else if code > 0 {
+		s.color = sccColors[code]
+	}
+	return
+}
+
+// sccCell represents an SCC screen cell
+type sccCell struct {
+	r     rune // 0 means the cell is empty
+	style sccStyle
+}
+
+// sccMemory represents an SCC caption memory
+type sccMemory [sccRows][sccColumns]sccCell
+
+// item converts the memory into an item, nil meaning the memory is empty
+func (m *sccMemory) item() (i *Item) {
+	// Loop through rows
+	var firstRow, firstColumn, lastColumn = -1, 0, 0
+	var lines []Line
+	for row := range m {
+		// Get boundaries
+		var first, last = -1, -1
+		for column, c := range m[row] {
+			if c.r != 0 && c.r != ' ' {
+				if first < 0 {
+					first = column
+				}
+				last = column
+			}
+		}
+		if first < 0 {
+			continue
+		}
+
+		// Store position
+		if firstRow < 0 {
+			firstRow, firstColumn, lastColumn = row, first, last
+		}
+
+		// Loop through cells
+		var l Line
+		var b strings.Builder
+		var style sccStyle
+		for column := first; column <= last; column++ {
+			var c = m[row][column]
+			if c.r == 0 {
+				c.r = ' '
+			}
+			if b.Len() > 0 && c.style != style {
+				l.Items = append(l.Items, LineItem{InlineStyle: style.styleAttributes(), Text: b.String()})
+				b.Reset()
+			}
+			style = c.style
+			b.WriteRune(c.r)
+		}
+		l.Items = append(l.Items, LineItem{InlineStyle: style.styleAttributes(), Text: b.String()})
+		lines = append(lines, l)
+	}
+
+	// Memory is empty
+	if len(lines) == 0 {
+		return
+	}
+
+	// Get position
+	var position byte
+	switch {
+	case firstRow < 5:
+		position = 7
+	case firstRow < 10:
+		position = 4
+	default:
+		position = 1
+	}
+	if center := (firstColumn + lastColumn) / 2; center > 19 {
+		position += 2
+	} else if center >= 12 {
+		position++
+	}
+
+	// Create item
+	i = &Item{
+		InlineStyle: &StyleAttributes{
+			SRTPosition: position,
+			WebVTTLine:  fmt.Sprintf("%d%%", firstRow*100/sccRows),
+		},
+		Lines: lines,
+	}
+
+	// SRT positions are written from line items, bottom center being the default
+	if position != 2 {
+		var li = &i.Lines[0].Items[0]
+		if li.InlineStyle == nil {
+			li.InlineStyle = &StyleAttributes{}
+		}
+		li.InlineStyle.SRTPosition = position
+	}
+	return
+}
+
+// sccDecoder decodes SCC words into items
+type sccDecoder struct {
+	column, row             int // 0-based cursor
+	dirty                   bool
+	dirtyAt                 time.Duration
+	displayed, nonDisplayed sccMemory
+	displayedItem           *Item
+	ignored                 bool // whether data targets a channel other than the first one
+	items                   []*Item
+	mode                    int
+	previous                uint16 // last control code since they're usually sent twice
+	rollUpRows              int
+	style                   sccStyle
+}
+
+// newSCCDecoder creates a new SCC decoder
+func newSCCDecoder() *sccDecoder {
+	return &sccDecoder{row: sccRows - 1}
+}
+
+// memory returns the memory characters are written to
+func (d *sccDecoder) memory() *sccMemory {
+	if d.mode == sccModePopOn {
+		return &d.nonDisplayed
+	}
+	return &d.displayed
+}
+
+// changed marks the displayed memory as changed
+func (d *sccDecoder) changed(t time.Duration) {
+	if d.mode == sccModePopOn {
+		return
+	}
+	if !d.dirty {
+		d.dirty = true
+		d.dirtyAt = t
+	}
+}
+
+// flush displays the displayed memory if it has changed
+func (d *sccDecoder) flush() {
+	if !d.dirty {
+		return
+	}
+	d.dirty = false
+	d.display(d.dirtyAt)
+}
+
+// display replaces the displayed item with the content of the displayed memory
+func (d *sccDecoder) display(t time.Duration) {
+	d.erase(t)
+	if i := d.displayed.item(); i != nil {
+		i.StartAt = t
+		d.displayedItem = i
+		d.items = append(d.items, i)
+	}
+}
+
+// erase ends the displayed item
+func (d *sccDecoder) erase(t time.Duration) {
+	if d.displayedItem == nil {
+		return
+	}
+	if t > d.displayedItem.StartAt {
+		d.displayedItem.EndAt = t
+	} else {
+		// Item has been replaced as soon as it has been displayed
+		d.items = d.items[:len(d.items)-1]
+	}
+	d.displayedItem = nil
+}
+
+// writeRune writes a character at the cursor position
+func (d *sccDecoder) writeRune(r rune, t time.Duration) {
+	if d.column >= sccColumns {
+		d.column = sccColumns - 1
+	}
+	d.memory()[d.row][d.column] = sccCell{r: r, style: d.style}
+	d.column++
+	d.changed(t)
+}
+
+// backspace deletes the character before the cursor position
+func (d *sccDecoder) backspace(t time.Duration) {
+	if d.column == 0 {
+		return
+	}
+	d.column--
+	d.memory()[d.row][d.column] = sccCell{}
+	d.changed(t)
+}
+
+// decode decodes an SCC word
+func (d *sccDecoder) decode(w uint16, t time.Duration) {
+	// Remove parity bits
+	var b1, b2 = byte(w>>8) & 0x7f, byte(w) & 0x7f
+
+	// Characters
+	if b1 < 0x10 || b1 >= 0x20 {
+		d.previous = 0
+		if d.ignored {
+			return
+		}
+		for _, b := range []byte{b1, b2} {
+			if b < 0x20 {
+				continue
+			}
+			if r, ok := sccBasicCharacters[b]; ok {
+				d.writeRune(r, t)
+			} else {
+				d.writeRune(rune(b), t)
+			}
+		}
+		return
+	}
+
+	// Control codes are usually sent twice
+	if w&0x7f7f == d.previous {
+		d.previous = 0
+		return
+	}
+	d.previous = w & 0x7f7f
+
+	// Channel
+	if d.ignored = b1&0x08 > 0; d.ignored {
+		return
+	}
+
+	// Switch on control code
+	switch {
+	case (b1 == 0x14 || b1 == 0x15) && b2 >= 0x20 && b2 <= 0x2f:
+		d.decodeMiscellaneousControlCode(b2, t)
+	case b1 == 0x17 && b2 >= 0x21 && b2 <= 0x23:
+		// Tab offset
+		d.column += int(b2 - 0x20)
+		if d.column >= sccColumns {
+			d.column = sccColumns - 1
+		}
+	case b1 == 0x11 && b2 >= 0x20 && b2 <= 0x2f:
+		// Mid-row codes are displayed as a space
+		d.writeRune(' ', t)
+		d.style = newSCCStyle(b2)
+	case b1 == 0x11 && b2 >= 0x30 && b2 <= 0x3f:
+		d.writeRune(sccSpecialCharacters[b2-0x30], t)
+	case (b1 == 0x12 || b1 == 0x13) && b2 >= 0x20 && b2 <= 0x3f:
+		d.backspace(t)
+		d.writeRune(sccExtendedCharacters[b1][b2-0x20], t)
+	case b2 >= 0x40:
+		// Preamble address code
+		row, ok := sccPreambleAddressCodeRows[b1]
+		if !ok {
+			return
+		}
+		if b2 >= 0x60 && b1 != 0x10 {
+			row++
+		}
+		d.row = row - 1
+		d.column = 0
+		d.style = sccStyle{}
+		if code := b2 & 0x1f; code < 0x10 {
+			d.style = newSCCStyle(code)
+		} else {
+			d.column = int(code-0x10) / 2 * 4
+			d.style.underline = code&0x1 > 0
+		}
+	}
+}
+
+// decodeMiscellaneousControlCode decodes a miscellaneous control code
+func (d *sccDecoder) decodeMiscellaneousControlCode(b2 byte, t time.Duration) {
+	switch b2 {
+	case 0x20:
+		// Resume caption loading
+		d.mode = sccModePopOn
+	case 0x21:
+		// Backspace
+		d.backspace(t)
+	case 0x24:
+		// Delete to end of row
+		for column := d.column; column < sccColumns; column++ {
+			d.memory()[d.row][column] = sccCell{}
+		}
+		d.changed(t)
+	case 0x25, 0x26, 0x27:
+		// Roll-up captions
+		if d.mode != sccModeRollUp {
+			d.flush()
+			d.displayed = sccMemory{}
+			d.nonDisplayed = sccMemory{}
+			d.erase(t)
+			d.mode = sccModeRollUp
+			d.row = sccRows - 1
+			d.column = 0
+		}
+		d.rollUpRows = int(b2-0x25) + 2
+	case 0x29:
+		// Resume direct captioning
+		d.mode = sccModePaintOn
+	case 0x2c:
+		// Erase displayed memory
+		d.flush()
+		d.displayed = sccMemory{}
+		d.erase(t)
+	case 0x2d:
+		// Carriage return
+		if d.mode != sccModeRollUp {
+			return
+		}
+		var top = d.row - d.rollUpRows + 1
+		for row := 0; row < d.row; row++ {
+			if row >= top {
+				d.displayed[row] = d.displayed[row+1]
+			} else {
+				d.displayed[row] = [sccColumns]sccCell{}
+			}
+		}
+		d.displayed[d.row] = [sccColumns]sccCell{}
+		d.column = 0
+		d.changed(t)
+	case 0x2e:
+		// Erase non-displayed memory
+		d.nonDisplayed = sccMemory{}
+	case 0x2f:
+		// End of caption
+		d.flush()
+		d.displayed, d.nonDisplayed = d.nonDisplayed, d.displayed
+		d.mode = sccModePopOn
+		d.display(t)
+	}
+}
+
+// end ends the decoding
+func (d *sccDecoder) end() []*Item {
+	d.flush()
+	if d.displayedItem != nil {
+		d.displayedItem.EndAt = d.displayedItem.StartAt + sccDefaultDuration
+	}
+	return d.items
+}
+
+// parseTimecodeSCC parses an SCC "HH:MM:SS:FF" timecode, ";" announcing a drop frame timecode whereas non drop frame
+// timecodes count 30 frames per second for 29.97 frames per second videos
+func parseTimecodeSCC(i string) (d time.Duration, err error) {
+	// Match
+	var m = sccRegexpTimecode.FindStringSubmatch(i)
+	if m == nil {
+		err = fmt.Errorf("astisub: invalid scc timecode %s", i)
+		return
+	}
+
+	// Drop frame
+	var hms = m[1] + ":" + m[2] + ":" + m[3]
+	if m[4] == ";" || m[4] == "," {
+		return parseDurationWithFramerate(hms+";"+m[5], ".", 3, 30)
+	}
+
+	// Non drop frame
+	if d, err = parseDurationWithFramerate(hms+":"+m[5], ".", 3, 30); err != nil {
+		return
+	}
+	d = d * 1001 / 1000
+	return
+}
+
+// ReadFromSCC parses a Scenarist .scc content
+// Only the first channel is decoded and all the codes of a line take effect at its timecode. Pop-on captions are displayed
+// on "end of caption" codes whereas roll-up and paint-on captions are displayed whenever a line changes them.
+func ReadFromSCC(i io.Reader) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var scanner = newScanner(i)
+	var d = newSCCDecoder()
+
+	// Scan
+	var line string
+	var lineNum int
+	for scanner.Scan() {
+		// Fetch line
+		line = strings.TrimSpace(scanner.Text())
+		lineNum++
+
+		// Remove BOM header
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, string(BytesBOM))
+		}
+
+		// Empty line or header
+		var fields = strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(line, "Scenarist_SCC") {
+			continue
+		}
+
+		// Parse timecode
+		var t time.Duration
+		if t, err = parseTimecodeSCC(fields[0]); err != nil {
+			err = fmt.Errorf("astisub: line %d: parsing scc timecode %s failed: %w", lineNum, fields[0], err)
+			return
+		}
+
+		// Loop through words
+		for _, f := range fields[1:] {
+			if len(f) != 4 {
+				err = fmt.Errorf("astisub: line %d: invalid scc word %s", lineNum, f)
+				return
+			}
+			var w uint64
+			if w, err = strconv.ParseUint(f, 16, 16); err != nil {
+				err = fmt.Errorf("astisub: line %d: parsing scc word %s failed: %w", lineNum, f, err)
+				return
+			}
+			d.decode(uint16(w), t)
+		}
+
+		// Roll-up and paint-on changes are displayed once the line has been decoded
+		d.flush()
+	}
+	o.Items = d.end()
+	return
+}
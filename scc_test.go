@@ -0,0 +1,72 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSCC(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.scc")
+	require.NoError(t, err)
+	require.Len(t, s.Items, 4)
+
+	// Pop-on, drop frame timecodes counting frames of a 29.97 fps video
+	assert.Equal(t, 2002*time.Millisecond, s.Items[0].StartAt)
+	assert.Equal(t, 4004*time.Millisecond, s.Items[0].EndAt)
+	require.Len(t, s.Items[0].Lines, 1)
+	require.Len(t, s.Items[0].Lines[0].Items, 2)
+	assert.Equal(t, "Hello ", s.Items[0].Lines[0].Items[0].Text)
+	assert.Equal(t, byte(1), s.Items[0].Lines[0].Items[0].InlineStyle.SRTPosition)
+	assert.Equal(t, "world", s.Items[0].Lines[0].Items[1].Text)
+	assert.True(t, s.Items[0].Lines[0].Items[1].InlineStyle.SCCItalics)
+	assert.True(t, s.Items[0].Lines[0].Items[1].InlineStyle.SRTItalics)
+	assert.Equal(t, byte(1), s.Items[0].InlineStyle.SRTPosition)
+	assert.Equal(t, "93%", s.Items[0].InlineStyle.WebVTTLine)
+	assert.Equal(t, 4004*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, 5005*time.Millisecond, s.Items[1].EndAt)
+	assert.Equal(t, "Top café ©", s.Items[1].String())
+	assert.Equal(t, byte(7), s.Items[1].InlineStyle.SRTPosition)
+	assert.Equal(t, byte(7), s.Items[1].Lines[0].Items[0].InlineStyle.SRTPosition)
+	assert.Equal(t, "0%", s.Items[1].InlineStyle.WebVTTLine)
+
+	// Roll-up
+	assert.Equal(t, 6006*time.Millisecond, s.Items[2].StartAt)
+	assert.Equal(t, 7007*time.Millisecond, s.Items[2].EndAt)
+	assert.Equal(t, "Roll one", s.Items[2].String())
+	assert.Equal(t, 7007*time.Millisecond, s.Items[3].StartAt)
+	assert.Equal(t, 8008*time.Millisecond, s.Items[3].EndAt)
+	require.Len(t, s.Items[3].Lines, 2)
+	assert.Equal(t, "Roll one", s.Items[3].Lines[0].String())
+	assert.Equal(t, "Roll two", s.Items[3].Lines[1].String())
+
+	// Non drop frame timecodes
+	s, err = astisub.ReadFromSCC(bytes.NewBufferString("Scenarist_SCC V1.0\n\n00:00:10:00\t9420 9420 c8e5 ecec ef80 942f 942f\n"))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	assert.Equal(t, 10010*time.Millisecond, s.Items[0].StartAt)
+	assert.Equal(t, 12010*time.Millisecond, s.Items[0].EndAt)
+
+	// Drop frame timecodes skip frame numbers every minute but every tenth minute
+	s, err = astisub.ReadFromSCC(bytes.NewBufferString("Scenarist_SCC V1.0\n\n00:10:00;00\t9420 9420 c8e5 ecec ef80 942f 942f\n"))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	assert.Equal(t, 10*time.Minute-600*time.Microsecond, s.Items[0].StartAt)
+	s, err = astisub.ReadFromSCC(bytes.NewBufferString("Scenarist_SCC V1.0\n\n00:01:00;02\t9420 9420 c8e5 ecec ef80 942f 942f\n"))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	assert.Equal(t, 60060*time.Millisecond, s.Items[0].StartAt)
+
+	// Invalid frames
+	_, err = astisub.ReadFromSCC(bytes.NewBufferString("00:00:10:30\t9420\n"))
+	assert.EqualError(t, err, "astisub: line 1: parsing scc timecode 00:00:10:30 failed: astisub: frames of 00:00:10:30 exceed framerate 30")
+
+	// Invalid word
+	_, err = astisub.ReadFromSCC(bytes.NewBufferString("00:00:10:00\t94\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,172 @@
+package astisub
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Constants
+const (
+	spruceDefaultFramerate = 25
+)
+
+// Vars
+var (
+	spruceRegexpDetect   = regexp.MustCompile(`^(?:\x{feff})?\s*(?:\$|//|\d{1,2}:\d{2}:\d{2}[:;]\d{2}\s*,)`)
+	spruceTapeFramerates = map[string]int{
+		"EBU":  25,
+		"FILM": 24,
+		"NTSC": 30,
+		"PAL":  25,
+	}
+)
+
+// isSpruceSTL checks whether the beginning of a .stl content is a Spruce text file rather than an EBU binary file
+func isSpruceSTL(b []byte) bool {
+	return spruceRegexpDetect.Match(b)
+}
+
+// ReadFromSpruceSTL parses a Spruce .stl content
+// Each subtitle is a "HH:MM:SS:FF , HH:MM:SS:FF , text" line where "|" separates lines and
+// "^B", "^I" and "^U" toggle bold, italics and underline
+func ReadFromSpruceSTL(i io.Reader) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var scanner = newScanner(i)
+	var framerate = spruceDefaultFramerate
+
+	// Scan
+	var line string
+	var lineNum int
+	for scanner.Scan() {
+		// Fetch line
+		line = strings.TrimSpace(scanner.Text())
+		lineNum++
+
+		// Remove BOM header
+		if lineNum == 1 {
+			line = strings.TrimSpace(strings.TrimPrefix(line, string(BytesBOM)))
+		}
+
+		// Empty line or comment
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		// Directive line
+		if strings.HasPrefix(line, "$") {
+			name, value := line[1:], ""
+			if idx := strings.Index(name, "="); idx >= 0 {
+				name, value = strings.TrimSpace(name[:idx]), strings.TrimSpace(name[idx+1:])
+			}
+			if strings.EqualFold(name, "TapeType") {
+				if f, ok := spruceTapeFramerates[strings.ToUpper(value)]; ok {
+					framerate = f
+				}
+			}
+			continue
+		}
+
+		// Split fields
+		var fields = strings.SplitN(line, ",", 3)
+		if len(fields) < 3 {
+			err = fmt.Errorf("astisub: line %d: invalid spruce line %s", lineNum, line)
+			return
+		}
+
+		// Parse time boundaries
+		var s = &Item{}
+		if s.StartAt, err = parseDurationSpruce(strings.TrimSpace(fields[0]), framerate); err != nil {
+			err = fmt.Errorf("astisub: line %d: parsing spruce start time %s failed: %w", lineNum, fields[0], err)
+			return
+		}
+		if s.EndAt, err = parseDurationSpruce(strings.TrimSpace(fields[1]), framerate); err != nil {
+			err = fmt.Errorf("astisub: line %d: parsing spruce end time %s failed: %w", lineNum, fields[1], err)
+			return
+		}
+
+		// Parse text
+		s.Lines = parseTextSpruce(strings.TrimSpace(fields[2]))
+
+		// Append item
+		o.Items = append(o.Items, s)
+	}
+	o.Metadata.Framerate = framerate
+	return
+}
+
+// parseDurationSpruce parses a Spruce "HH:MM:SS:FF" duration, "HH:MM:SS;FF" being a drop frame duration
+func parseDurationSpruce(i string, framerate int) (d time.Duration, err error) {
+	// Split
+	var dropFrame = strings.Contains(i, ";")
+	var parts = strings.Split(strings.Replace(i, ";", ":", 1), ":")
+	if len(parts) != 4 {
+		err = fmt.Errorf("astisub: invalid spruce duration %s", i)
+		return
+	}
+
+	// Parse hours, minutes, seconds and frames
+	var vs [4]int
+	for idx, p := range parts {
+		if vs[idx], err = strconv.Atoi(p); err != nil {
+			err = fmt.Errorf("astisub: atoi of %s failed: %w", p, err)
+			return
+		}
+	}
+	d = timecodeToDuration(vs[0], vs[1], vs[2], vs[3], framerate, dropFrame)
+	return
+}
+
+// parseTextSpruce parses a Spruce text
+func parseTextSpruce(i string) (o []Line) {
+	// Loop through lines
+	var sa StyleAttributes
+	for _, t := range strings.Split(i, "|") {
+		// Loop through characters
+		var l Line
+		var b strings.Builder
+		flush := func() {
+			if b.Len() == 0 {
+				return
+			}
+			var li = LineItem{Text: b.String()}
+			if sa.SpruceBold || sa.SpruceItalics || sa.SpruceUnderline {
+				li.InlineStyle = &StyleAttributes{
+					SpruceBold:      sa.SpruceBold,
+					SpruceItalics:   sa.SpruceItalics,
+					SpruceUnderline: sa.SpruceUnderline,
+				}
+				li.InlineStyle.propagateSpruceAttributes()
+			}
+			l.Items = append(l.Items, li)
+			b.Reset()
+		}
+		for idx := 0; idx < len(t); idx++ {
+			if t[idx] == '^' && idx+1 < len(t) && strings.ContainsRune("BIUbiu", rune(t[idx+1])) {
+				flush()
+				switch t[idx+1] {
+				case 'B', 'b':
+					sa.SpruceBold = !sa.SpruceBold
+				case 'I', 'i':
+					sa.SpruceItalics = !sa.SpruceItalics
+				case 'U', 'u':
+					sa.SpruceUnderline = !sa.SpruceUnderline
+				}
+				idx++
+				continue
+			}
+			b.WriteByte(t[idx])
+		}
+		flush()
+
+		// Append line
+		if len(l.Items) > 0 {
+			o = append(o, l)
+		}
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package astisub_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpruceSTL(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in-spruce.stl")
+	require.NoError(t, err)
+	assert.Equal(t, 24, s.Metadata.Framerate)
+	require.Len(t, s.Items, 3)
+	assert.Equal(t, time.Minute+39*time.Second, s.Items[0].StartAt)
+	assert.Equal(t, time.Minute+41*time.Second+time.Second/24, s.Items[0].EndAt)
+	assert.Equal(t, "(deep rumbling)", s.Items[0].String())
+	require.Len(t, s.Items[1].Lines, 2)
+	assert.Equal(t, "MAN:", s.Items[1].Lines[0].String())
+	require.Len(t, s.Items[1].Lines[1].Items, 3)
+	assert.Equal(t, "we", s.Items[1].Lines[1].Items[1].Text)
+	assert.True(t, s.Items[1].Lines[1].Items[1].InlineStyle.SpruceItalics)
+	assert.True(t, s.Items[1].Lines[1].Items[1].InlineStyle.WebVTTItalics)
+	assert.Nil(t, s.Items[1].Lines[1].Items[2].InlineStyle)
+	assert.True(t, s.Items[2].Lines[0].Items[0].InlineStyle.SRTBold)
+
+	// Dispatch from ReadFromSTL with the default framerate
+	s, err = astisub.ReadFromSTL(strings.NewReader("00:00:01:00 , 00:00:02:05 , Hello|World\n"), astisub.STLOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 25, s.Metadata.Framerate)
+	require.Len(t, s.Items, 1)
+	assert.Equal(t, 2*time.Second+200*time.Millisecond, s.Items[0].EndAt)
+	assert.Len(t, s.Items[0].Lines, 2)
+
+	// Drop frame
+	s, err = astisub.ReadFromSTL(strings.NewReader("$TapeType = NTSC\n00:10:00;00 , 00:10:01;00 , Hello\n"), astisub.STLOptions{})
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	assert.Equal(t, 10*time.Minute-600*time.Microsecond, s.Items[0].StartAt)
+	assert.Equal(t, 10*time.Minute+1*time.Second+400*time.Microsecond, s.Items[0].EndAt)
+
+	// Invalid line
+	_, err = astisub.ReadFromSpruceSTL(bytes.NewReader([]byte("00:00:01:00 , invalid\n")))
+	assert.Error(t, err)
+}
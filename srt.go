@@ -1,6 +1,7 @@
 package astisub
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"strconv"
@@ -57,36 +58,137 @@ func isTimeBoundariesLine(line string) bool {
 
 // ReadFromSRT parses an .srt content
 func ReadFromSRT(i io.Reader) (o *Subtitles, err error) {
+	return ReadFromSRTWithOptions(i, SRTOptions{})
+}
+
+// SRTOptions represents SRT read options
+type SRTOptions struct {
+	// OnInvalidIndex is called for each item whose index is missing, not a number, duplicate or doesn't follow the
+	// previous item's one.
+	// Default is to accept them silently.
+	OnInvalidIndex func(w SRTIndexWarning)
+	// KeepEmptyLines keeps the empty or whitespace-only lines of items as lines without text, only the trailing ones
+	// being removed, instead of dropping them along with the lines following them
+	KeepEmptyLines bool
+	// Renumber sets items' Index to their position, starting at 1, once they've been read. Invalid indexes are
+	// still reported against the indexes found in the content.
+	Renumber bool
+}
+
+// SRTIndexWarning represents an item whose index is not the one expected
+type SRTIndexWarning struct {
+	Expected int // previous item's index + 1
+	Index    int // 0 if missing or not a number
+	Position int // position of the item, starting at 0
+}
+
+// String implements the Stringer interface
+func (w SRTIndexWarning) String() string {
+	return fmt.Sprintf("astisub: item #%d has index %d whereas %d was expected", w.Position+1, w.Index, w.Expected)
+}
+
+// ReadFromSRTWithOptions parses an .srt content with options
+func ReadFromSRTWithOptions(i io.Reader, opts SRTOptions) (o *Subtitles, err error) {
 	// Init
 	o = NewSubtitles()
-	var scanner = newScanner(i)
+	var d = NewSRTDecoderWithOptions(i, opts)
+
+	// Loop through items
+	var expected = 1
+	for {
+		var s *Item
+		if s, err = d.Next(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			break
+		}
+
+		// Check index
+		if s.Index != expected && opts.OnInvalidIndex != nil {
+			opts.OnInvalidIndex(SRTIndexWarning{
+				Expected: expected,
+				Index:    s.Index,
+				Position: len(o.Items),
+			})
+		}
+		if s.Index > 0 {
+			expected = s.Index + 1
+		} else {
+			expected++
+		}
+
+		// Append item
+		o.Items = append(o.Items, s)
+	}
+
+	// Renumber
+	if err == nil && opts.Renumber {
+		o.Renumber()
+	}
+	return
+}
+
+// SRTDecoder decodes an .srt content one item at a time so that it doesn't have to be held in memory
+type SRTDecoder struct {
+	err            error
+	item           *Item // item whose lines are being read, its index being the last line read before the next time boundaries
+	keepEmptyLines bool
+	lineNum        int
+	sa             *StyleAttributes
+	scanner        *bufio.Scanner
+	started        bool // whether time boundaries have been read
+}
+
+// NewSRTDecoder creates a new .srt decoder
+func NewSRTDecoder(i io.Reader) *SRTDecoder {
+	return NewSRTDecoderWithOptions(i, SRTOptions{})
+}
+
+// NewSRTDecoderWithOptions creates a new .srt decoder with options
+// Only KeepEmptyLines applies since other options require all items to be read.
+func NewSRTDecoderWithOptions(i io.Reader, opts SRTOptions) *SRTDecoder {
+	return &SRTDecoder{
+		item:           &Item{},
+		keepEmptyLines: opts.KeepEmptyLines,
+		sa:             &StyleAttributes{},
+		scanner:        newScanner(i),
+	}
+}
+
+// Next returns the next item and io.EOF once all items have been returned
+// Since an item ends when the next time boundaries are read, the previous item is returned at that moment.
+func (d *SRTDecoder) Next() (o *Item, err error) {
+	// Previous call failed
+	if d.err != nil {
+		return nil, d.err
+	}
+	defer func() { d.err = err }()
 
 	// Scan
 	var line string
-	var lineNum int
-	var s = &Item{}
-	var sa = &StyleAttributes{}
-	for scanner.Scan() {
+	for d.scanner.Scan() {
 		// Fetch line
-		line = strings.TrimSpace(scanner.Text())
-		lineNum++
+		line = strings.TrimSpace(d.scanner.Text())
+		d.lineNum++
 		if !utf8.ValidString(line) {
-			err = fmt.Errorf("astisub: line %d is not valid utf-8", lineNum)
+			err = fmt.Errorf("astisub: line %d is not valid utf-8", d.lineNum)
 			return
 		}
 
 		// Remove BOM header
-		if lineNum == 1 {
+		if d.lineNum == 1 {
 			line = strings.TrimPrefix(line, string(BytesBOM))
 		}
 
 		// Line contains time boundaries
 		if isTimeBoundariesLine(line) {
 			// Reset style attributes
-			sa = &StyleAttributes{}
+			d.sa = &StyleAttributes{}
 
 			// Remove last item of previous subtitle since it should be the index.
 			// If the last line is empty then the item is missing an index.
+			var s = d.item
 			var index string
 			if len(s.Lines) != 0 {
 				index = s.Lines[len(s.Lines)-1].String()
@@ -96,7 +198,9 @@ func ReadFromSRT(i io.Reader) (o *Subtitles, err error) {
 			}
 
 			// Remove trailing empty lines
-			if len(s.Lines) > 0 {
+			if d.keepEmptyLines {
+				s.Lines = trimTrailingEmptySRTLines(s.Lines)
+			} else if len(s.Lines) > 0 {
 				for i := len(s.Lines) - 1; i >= 0; i-- {
 					if len(s.Lines[i].Items) > 0 {
 						for j := len(s.Lines[i].Items) - 1; j >= 0; j-- {
@@ -125,7 +229,7 @@ func ReadFromSRT(i io.Reader) (o *Subtitles, err error) {
 			// Extract time boundaries
 			s1 := strings.Split(line, srtTimeBoundariesSeparator)
 			if l := len(s1); l < 2 {
-				err = fmt.Errorf("astisub: line %d: time boundaries has only %d element(s)", lineNum, l)
+				err = fmt.Errorf("astisub: line %d: time boundaries has only %d element(s)", d.lineNum, l)
 				return
 			}
 			// We do this to eliminate extra stuff like positions which are not documented anywhere
@@ -133,26 +237,48 @@ func ReadFromSRT(i io.Reader) (o *Subtitles, err error) {
 
 			// Parse time boundaries
 			if s.StartAt, err = parseDurationSRT(s1[0]); err != nil {
-				err = fmt.Errorf("astisub: line %d: parsing srt duration %s failed: %w", lineNum, s1[0], err)
+				err = fmt.Errorf("astisub: line %d: parsing srt duration %s failed: %w", d.lineNum, s1[0], err)
 				return
 			}
 			if s.EndAt, err = parseDurationSRT(s2[0]); err != nil {
-				err = fmt.Errorf("astisub: line %d: parsing srt duration %s failed: %w", lineNum, s2[0], err)
+				err = fmt.Errorf("astisub: line %d: parsing srt duration %s failed: %w", d.lineNum, s2[0], err)
 				return
 			}
 
-			// Append subtitle
-			o.Items = append(o.Items, s)
+			// Return previous subtitle
+			o, d.item = d.item, s
+			if d.started {
+				return
+			}
+			o, d.started = nil, true
 		} else {
 			// Add text
-			if l := parseTextSrt(line, sa); len(l.Items) > 0 {
-				s.Lines = append(s.Lines, l)
+			if l := parseTextSrt(line, d.sa); len(l.Items) > 0 {
+				d.item.Lines = append(d.item.Lines, l)
 			}
 		}
 	}
+
+	// Return last subtitle
+	if d.started && d.item != nil {
+		o, d.item = d.item, nil
+		if d.keepEmptyLines {
+			o.Lines = trimTrailingEmptySRTLines(o.Lines)
+		}
+		return
+	}
+	err = io.EOF
 	return
 }
 
+// trimTrailingEmptySRTLines removes the empty lines separating items
+func trimTrailingEmptySRTLines(ls []Line) []Line {
+	for len(ls) > 0 && ls[len(ls)-1].String() == "" {
+		ls = ls[:len(ls)-1]
+	}
+	return ls
+}
+
 // parseTextSrt parses the input line to fill the Line
 func parseTextSrt(i string, sa *StyleAttributes) (o Line) {
 	// special handling needed for empty line
@@ -236,8 +362,58 @@ func formatDurationSRT(i time.Duration) string {
 	return formatDuration(i, ",", 3)
 }
 
+// SRTStyling represents the way inline styles are written in .srt files
+type SRTStyling int
+
+// SRT stylings
+const (
+	// <b>, <i>, <u> and <font> tags
+	SRTStylingHTML SRTStyling = iota
+	// {\b1}, {\i1}, {\u1} and {\c} tags
+	SRTStylingSSA
+	// Plain text, written as is without HTML escaping
+	SRTStylingNone
+)
+
+// SRTPositionTag represents the way positions are written in .srt files
+type SRTPositionTag int
+
+// SRT position tags
+const (
+	// {\an8} tags
+	SRTPositionTagSSA SRTPositionTag = iota
+	// Positions are not written
+	SRTPositionTagNone
+)
+
+// WriteToSRTOptions represents SRT write options
+type WriteToSRTOptions struct {
+	// KeepIndexes writes items' Index as is instead of their position. Items without index are numbered after the
+	// highest index instead.
+	KeepIndexes bool
+	// Default is SRTPositionTagSSA
+	PositionTag SRTPositionTag
+	// RoundTimecodes rounds time boundaries half up to the millisecond instead of truncating them
+	RoundTimecodes bool
+	// Default is SRTStylingHTML
+	Styling SRTStyling
+}
+
+// duration formats a duration based on options
+func (o WriteToSRTOptions) duration(d time.Duration) string {
+	if o.RoundTimecodes {
+		d = roundDuration(d, 3)
+	}
+	return formatDurationSRT(d)
+}
+
 // WriteToSRT writes subtitles in .srt format
 func (s Subtitles) WriteToSRT(o io.Writer) (err error) {
+	return s.WriteToSRTWithOptions(o, WriteToSRTOptions{})
+}
+
+// WriteToSRTWithOptions writes subtitles in .srt format with options
+func (s Subtitles) WriteToSRTWithOptions(o io.Writer, opts WriteToSRTOptions) (err error) {
 	// Do not write anything if no subtitles
 	if len(s.Items) == 0 {
 		err = ErrNoSubtitlesToWrite
@@ -248,25 +424,61 @@ func (s Subtitles) WriteToSRT(o io.Writer) (err error) {
 	var c []byte
 	c = append(c, BytesBOM...)
 
+	// Items without index are numbered after the highest kept index so that indexes are not repeated
+	var next int
+	if opts.KeepIndexes {
+		for _, v := range s.Items {
+			if v.Index > next {
+				next = v.Index
+			}
+		}
+	}
+
 	// Loop through subtitles
-	for k, v := range s.Items {
-		// Add time boundaries
-		c = append(c, []byte(strconv.Itoa(k+1))...)
+	var count int
+	for _, v := range s.Items {
+		// Drawings can't be expressed in SRT and would be written as empty cues
+		if v.isDrawingOnly() {
+			continue
+		}
+		count++
+
+		// Add index
+		var idx = count
+		if opts.KeepIndexes {
+			if v.Index > 0 {
+				idx = v.Index
+			} else {
+				next++
+				idx = next
+			}
+		}
+		c = append(c, []byte(strconv.Itoa(idx))...)
 		c = append(c, bytesLineSeparator...)
-		c = append(c, []byte(formatDurationSRT(v.StartAt))...)
+
+		// Add time boundaries
+		c = append(c, []byte(opts.duration(v.StartAt))...)
 		c = append(c, bytesSRTTimeBoundariesSeparator...)
-		c = append(c, []byte(formatDurationSRT(v.EndAt))...)
+		c = append(c, []byte(opts.duration(v.EndAt))...)
 		c = append(c, bytesLineSeparator...)
 
 		// Loop through lines
 		for _, l := range v.Lines {
-			c = append(c, []byte(l.srtBytes())...)
+			if tl, ok := l.textLine(); ok {
+				c = append(c, []byte(tl.srtBytes(opts))...)
+			}
 		}
 
 		// Add new line
 		c = append(c, bytesLineSeparator...)
 	}
 
+	// Do not write anything if only drawings
+	if count == 0 {
+		err = ErrNoSubtitlesToWrite
+		return
+	}
+
 	// Remove last new line
 	c = c[:len(c)-1]
 
@@ -278,15 +490,20 @@ func (s Subtitles) WriteToSRT(o io.Writer) (err error) {
 	return
 }
 
-func (l Line) srtBytes() (c []byte) {
+func (l Line) srtBytes(opts WriteToSRTOptions) (c []byte) {
 	for _, li := range l.Items {
-		c = append(c, li.srtBytes()...)
+		c = append(c, li.srtBytes(opts)...)
 	}
 	c = append(c, bytesLineSeparator...)
 	return
 }
 
-func (li LineItem) srtBytes() (c []byte) {
+func (li LineItem) srtBytes(opts WriteToSRTOptions) (c []byte) {
+	// Plain text
+	if opts.Styling == SRTStylingNone {
+		return []byte(li.Text)
+	}
+
 	// Get color
 	var color string
 	if li.InlineStyle != nil && li.InlineStyle.SRTColor != nil {
@@ -300,10 +517,15 @@ func (li LineItem) srtBytes() (c []byte) {
 
 	// Get position
 	var pos byte
-	if li.InlineStyle != nil {
+	if li.InlineStyle != nil && opts.PositionTag == SRTPositionTagSSA {
 		pos = li.InlineStyle.SRTPosition
 	}
 
+	// SSA tags
+	if opts.Styling == SRTStylingSSA {
+		return li.srtSSABytes(color, b, i, u, pos)
+	}
+
 	// Append
 	if color != "" {
 		c = append(c, []byte("<font color=\""+color+"\">")...)
@@ -335,3 +557,43 @@ func (li LineItem) srtBytes() (c []byte) {
 	}
 	return
 }
+
+func (li LineItem) srtSSABytes(color string, b, i, u bool, pos byte) (c []byte) {
+	// Only hexadecimal colors can be expressed
+	var ssaColor string
+	if v := strings.TrimPrefix(color, "#"); len(v) == 6 && v != color {
+		if rgb, err := strconv.ParseUint(v, 16, 32); err == nil {
+			ssaColor = fmt.Sprintf("&H%.2x%.2x%.2x&", rgb&0xff, rgb>>8&0xff, rgb>>16)
+		}
+	}
+
+	// Append
+	var start, end string
+	if pos != 0 {
+		start += fmt.Sprintf(`\an%d`, pos)
+	}
+	if ssaColor != "" {
+		start += `\c` + ssaColor
+		end = `\c` + end
+	}
+	if b {
+		start += `\b1`
+		end = `\b0` + end
+	}
+	if i {
+		start += `\i1`
+		end = `\i0` + end
+	}
+	if u {
+		start += `\u1`
+		end = `\u0` + end
+	}
+	if start != "" {
+		c = append(c, []byte("{"+start+"}")...)
+	}
+	c = append(c, []byte(escapeHTML(li.Text))...)
+	if end != "" {
+		c = append(c, []byte("{"+end+"}")...)
+	}
+	return
+}
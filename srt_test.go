@@ -2,6 +2,7 @@ package astisub_test
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/5rahim/go-astisub"
+	"github.com/asticode/go-astikit"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -52,7 +54,37 @@ func TestSRTMissingSequence(t *testing.T) {
 }
 
 func TestNonUTF8SRT(t *testing.T) {
-	_, err := astisub.OpenFile("./testdata/example-in-non-utf8.srt")
+	// UTF-16 is detected with its BOM
+	e, err := astisub.OpenFile("./testdata/example-in.srt")
+	require.NoError(t, err)
+	s, err := astisub.OpenFile("./testdata/example-in-non-utf8.srt")
+	require.NoError(t, err)
+	assert.Equal(t, e.Items, s.Items)
+
+	// BOM takes precedence over charset
+	s, err = astisub.Open(astisub.Options{Charset: "windows-1252", Filename: "./testdata/example-in-non-utf8.srt"})
+	require.NoError(t, err)
+	assert.Equal(t, e.Items, s.Items)
+
+	// Windows-1252 is the fallback
+	s, err = astisub.OpenFile("./testdata/example-in-windows-1252.srt")
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	assert.Equal(t, "Café crème €", s.Items[0].String())
+
+	// Charset
+	s, err = astisub.Open(astisub.Options{Charset: "iso-8859-1", Filename: "./testdata/example-in-windows-1252.srt"})
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	assert.Equal(t, "Café crème €", s.Items[0].String())
+	_, err = astisub.Open(astisub.Options{Charset: "invalid", Filename: "./testdata/example-in-windows-1252.srt"})
+	assert.Error(t, err)
+
+	// Without decoding
+	f, err := os.Open("./testdata/example-in-windows-1252.srt")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = astisub.ReadFromSRT(f)
 	assert.Error(t, err)
 }
 
@@ -149,6 +181,92 @@ func TestSRTStyled(t *testing.T) {
 	assert.Equal(t, string(c), w.String())
 }
 
+func TestSRTDecoder(t *testing.T) {
+	d := astisub.NewSRTDecoder(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\nFirst\nline\n\n2\n00:00:03,000 --> 00:00:04,000\n<i>Second</i>\n"))
+
+	// First item
+	i, err := d.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 1, i.Index)
+	assert.Equal(t, time.Second, i.StartAt)
+	assert.Equal(t, 2*time.Second, i.EndAt)
+	require.Len(t, i.Lines, 2)
+	assert.Equal(t, "First", i.Lines[0].String())
+	assert.Equal(t, "line", i.Lines[1].String())
+
+	// Second item
+	i, err = d.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 2, i.Index)
+	assert.Equal(t, 3*time.Second, i.StartAt)
+	assert.Equal(t, "Second", i.String())
+	assert.True(t, i.Lines[0].Items[0].InlineStyle.SRTItalics)
+
+	// End
+	_, err = d.Next()
+	assert.Equal(t, io.EOF, err)
+	_, err = d.Next()
+	assert.Equal(t, io.EOF, err)
+
+	// Error
+	d = astisub.NewSRTDecoder(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\n\xff\n"))
+	_, err = d.Next()
+	assert.EqualError(t, err, "astisub: line 3 is not valid utf-8")
+	_, err = d.Next()
+	assert.Error(t, err)
+}
+
+func TestReadFromSRTWithOptions(t *testing.T) {
+	const c = "2\n00:00:01,000 --> 00:00:02,000\n1\n\n3\n00:00:03,000 --> 00:00:04,000\n2\n\n3\n00:00:05,000 --> 00:00:06,000\n3\n\n7\n00:00:07,000 --> 00:00:08,000\n4\n\nx\n00:00:09,000 --> 00:00:10,000\n5\n\n9\n00:00:11,000 --> 00:00:12,000\n6\n"
+
+	// Warnings
+	var ws []astisub.SRTIndexWarning
+	s, err := astisub.ReadFromSRTWithOptions(strings.NewReader(c), astisub.SRTOptions{OnInvalidIndex: func(w astisub.SRTIndexWarning) { ws = append(ws, w) }})
+	require.NoError(t, err)
+	require.Len(t, s.Items, 6)
+	assert.Equal(t, []astisub.SRTIndexWarning{
+		{Expected: 1, Index: 2, Position: 0},
+		{Expected: 4, Index: 3, Position: 2},
+		{Expected: 4, Index: 7, Position: 3},
+		{Expected: 8, Index: 0, Position: 4},
+	}, ws)
+	assert.Equal(t, "astisub: item #4 has index 7 whereas 4 was expected", ws[2].String())
+	var is []int
+	for _, i := range s.Items {
+		is = append(is, i.Index)
+	}
+	assert.Equal(t, []int{2, 3, 3, 7, 0, 9}, is)
+
+	// Renumber
+	s, err = astisub.ReadFromSRTWithOptions(strings.NewReader(c), astisub.SRTOptions{Renumber: true})
+	require.NoError(t, err)
+	is = []int{}
+	for _, i := range s.Items {
+		is = append(is, i.Index)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, is)
+
+	// Empty lines
+	const e = "1\n00:00:01,000 --> 00:00:02,000\na\n\nb\n\n2\n00:00:03,000 --> 00:00:04,000\nc\n \nd\n\n\n"
+	var lines = func(s *astisub.Subtitles) (o [][]string) {
+		for _, i := range s.Items {
+			var ls []string
+			for _, l := range i.Lines {
+				ls = append(ls, l.String())
+			}
+			o = append(o, ls)
+		}
+		return
+	}
+	s, err = astisub.ReadFromSRTWithOptions(strings.NewReader(e), astisub.SRTOptions{KeepEmptyLines: true})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "", "b"}, {"c", "", "d"}}, lines(s))
+	assert.Equal(t, []int{1, 2}, []int{s.Items[0].Index, s.Items[1].Index})
+	s, err = astisub.ReadFromSRT(strings.NewReader(e))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, lines(s)[0])
+}
+
 func TestSRTParseDuration(t *testing.T) {
 	testData := `
 	1
@@ -177,3 +295,64 @@ Inage <--> Shinagawa`
 	assert.Equal(t, 9*time.Second+675*time.Millisecond, s.Items[0].EndAt)
 	assert.Equal(t, "Inage <--> Shinagawa", s.Items[0].Lines[0].String())
 }
+
+func TestWriteToSRTWithOptions(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 2 * time.Second,
+		Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{InlineStyle: &astisub.StyleAttributes{SRTBold: true, SRTPosition: 8}, Text: "Bold"},
+			{Text: " and "},
+			{InlineStyle: &astisub.StyleAttributes{SRTColor: astikit.StrPtr("#ff8000"), SRTItalics: true}, Text: "orange italics"},
+		}}},
+		StartAt: time.Second,
+	}}}
+
+	for _, v := range []struct {
+		expected    string
+		positionTag astisub.SRTPositionTag
+		styling     astisub.SRTStyling
+	}{
+		{expected: `<b>{\an8}Bold</b> and <font color="#ff8000"><i>orange italics</i></font>`, styling: astisub.SRTStylingHTML},
+		{expected: `{\an8\b1}Bold{\b0} and {\c&H0080ff&\i1}orange italics{\i0\c}`, styling: astisub.SRTStylingSSA},
+		{expected: `Bold and orange italics`, styling: astisub.SRTStylingNone},
+		{expected: `<b>Bold</b> and <font color="#ff8000"><i>orange italics</i></font>`, positionTag: astisub.SRTPositionTagNone, styling: astisub.SRTStylingHTML},
+		{expected: `{\b1}Bold{\b0} and {\c&H0080ff&\i1}orange italics{\i0\c}`, positionTag: astisub.SRTPositionTagNone, styling: astisub.SRTStylingSSA},
+	} {
+		w := &bytes.Buffer{}
+		err := s.WriteToSRTWithOptions(w, astisub.WriteToSRTOptions{PositionTag: v.positionTag, Styling: v.styling})
+		require.NoError(t, err)
+		require.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000\n"+v.expected+"\n", w.String())
+	}
+
+	// Plain text is not escaped
+	w := &bytes.Buffer{}
+	s.Items[0].Lines[0].Items[1].Text = " < & "
+	require.NoError(t, s.WriteToSRTWithOptions(w, astisub.WriteToSRTOptions{Styling: astisub.SRTStylingNone}))
+	require.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000\nBold < & orange italics\n", w.String())
+
+	// Indexes
+	s = &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, Index: 5, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "1"}}}}, StartAt: time.Second},
+		{EndAt: 4 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "2"}}}}, StartAt: 3 * time.Second},
+	}}
+	w.Reset()
+	require.NoError(t, s.WriteToSRTWithOptions(w, astisub.WriteToSRTOptions{KeepIndexes: true}))
+	require.Equal(t, "\ufeff5\n00:00:01,000 --> 00:00:02,000\n1\n\n6\n00:00:03,000 --> 00:00:04,000\n2\n", w.String())
+	w.Reset()
+	require.NoError(t, s.WriteToSRTWithOptions(w, astisub.WriteToSRTOptions{}))
+	require.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000\n1\n\n2\n00:00:03,000 --> 00:00:04,000\n2\n", w.String())
+	assert.Equal(t, []int{5, 0}, []int{s.Items[0].Index, s.Items[1].Index})
+	s.Items[0].Index, s.Items[1].Index = 0, 1
+	w.Reset()
+	require.NoError(t, s.WriteToSRTWithOptions(w, astisub.WriteToSRTOptions{KeepIndexes: true}))
+	require.Equal(t, "\ufeff2\n00:00:01,000 --> 00:00:02,000\n1\n\n1\n00:00:03,000 --> 00:00:04,000\n2\n", w.String())
+
+	// Rounded timecodes
+	s.Items[0].EndAt = 2*time.Second + 999600*time.Microsecond
+	w.Reset()
+	require.NoError(t, s.WriteToSRTWithOptions(w, astisub.WriteToSRTOptions{}))
+	require.Contains(t, w.String(), "00:00:01,000 --> 00:00:02,999\n")
+	w.Reset()
+	require.NoError(t, s.WriteToSRTWithOptions(w, astisub.WriteToSRTOptions{RoundTimecodes: true}))
+	require.Contains(t, w.String(), "00:00:01,000 --> 00:00:03,000\n")
+}
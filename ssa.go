@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/asticode/go-astikit"
 )
@@ -49,6 +50,19 @@ const (
 	ssaEventCategorySound    = "Sound"
 )
 
+// ssaDefaultMargin is the margin, in pixels, of the default style of usual SSA editors
+const ssaDefaultMargin = 10
+
+// SSA special characters
+const (
+	ssaHardSpace     = "\\h"
+	ssaLineBreak     = "\\N"
+	ssaSoftLineBreak = "\\n"
+)
+
+// SSA wrap styles where \n is a soft line break, i.e. a space, \n breaking lines otherwise as it did in v4.00 scripts
+var ssaWrapStylesSoftLineBreak = map[string]bool{"0": true, "1": true, "3": true}
+
 // SSA event format names
 const (
 	ssaEventFormatNameEffect  = "Effect"
@@ -122,7 +136,15 @@ const (
 )
 
 // SSA regexp
-var ssaRegexpEffect = regexp.MustCompile(`\{[^\{]+\}`)
+var (
+	ssaRegexpDrawingMode            = regexp.MustCompile(`\\p(\d+)`)
+	ssaRegexpEffect                 = regexp.MustCompile(`\{[^\{]+\}`)
+	ssaRegexpInvalidClassCharacters = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+	ssaRegexpKaraoke                = regexp.MustCompile(`\\(?:kf|ko|k|K)(\d+)`)
+	ssaRegexpOverride               = regexp.MustCompile(`\\(fn|fs|1?c|2c|r|b|i|s|u)([^\\}]*)`)
+	ssaRegexpPositioning            = regexp.MustCompile(`\\(?:pos|move|org|i?clip)\(|\\fr[xyz]?-?\d`)
+	ssaRegexpSignStyleName          = regexp.MustCompile(`(?i)^(?:signs?|typeset(?:ting)?|kfx|karaoke)(?:[^a-z]|$)`)
+)
 
 // ReadFromSSA parses an .ssa content
 func ReadFromSSA(i io.Reader) (o *Subtitles, err error) {
@@ -254,15 +276,29 @@ func ReadFromSSAWithOptions(i io.Reader, opts SSAOptions) (o *Subtitles, err err
 	}
 
 	// Loop through events
+	var karaokeStyles = make(map[*Style]bool)
 	for _, e := range es {
+		// Comments are not displayed and are therefore kept apart from items
+		if e.category == ssaEventCategoryComment {
+			o.Metadata.SSAComments = append(o.Metadata.SSAComments, e.comment())
+			continue
+		}
+
 		// Only process dialogues
 		if e.category == ssaEventCategoryDialogue {
 			// Build item
 			var item *Item
-			if item, err = e.item(o.Styles); err != nil {
+			var karaoke bool
+			if item, karaoke, err = e.item(o.Styles, o.Metadata.SSAWrapStyle); err != nil {
 				return
 			}
 
+			// Karaoke colours are converted once per style
+			if karaoke && item.Style != nil && item.Style.InlineStyle != nil && !karaokeStyles[item.Style] {
+				item.Style.InlineStyle.propagateSSAKaraokeAttributes(ssaKaraokeClass(item.Style.ID))
+				karaokeStyles[item.Style] = true
+			}
+
 			// Append item
 			o.Items = append(o.Items, item)
 		}
@@ -900,8 +936,58 @@ type ssaEvent struct {
 	text           string
 }
 
+// SSAComment represents an SSA Comment event
+type SSAComment struct {
+	Effect         string
+	EndAt          time.Duration
+	Layer          *int
+	MarginLeft     *int // pixels
+	MarginRight    *int // pixels
+	MarginVertical *int // pixels
+	Marked         *bool
+	Name           string
+	StartAt        time.Duration
+	Style          string // style name
+	Text           string // raw text, overrides included
+}
+
+// comment returns the SSA comment of the event
+func (e ssaEvent) comment() SSAComment {
+	return SSAComment{
+		Effect:         e.effect,
+		EndAt:          e.end,
+		Layer:          e.layer,
+		MarginLeft:     e.marginLeft,
+		MarginRight:    e.marginRight,
+		MarginVertical: e.marginVertical,
+		Marked:         e.marked,
+		Name:           e.name,
+		StartAt:        e.start,
+		Style:          e.style,
+		Text:           e.text,
+	}
+}
+
+// newSSAEventFromComment returns an SSA event based on an input comment
+func newSSAEventFromComment(c SSAComment) *ssaEvent {
+	return &ssaEvent{
+		category:       ssaEventCategoryComment,
+		effect:         c.Effect,
+		end:            c.EndAt,
+		layer:          c.Layer,
+		marginLeft:     c.MarginLeft,
+		marginRight:    c.MarginRight,
+		marginVertical: c.MarginVertical,
+		marked:         c.Marked,
+		name:           c.Name,
+		start:          c.StartAt,
+		style:          c.Style,
+		text:           c.Text,
+	}
+}
+
 // newSSAEventFromItem returns an SSA Event based on an input item
-func newSSAEventFromItem(i Item) (e *ssaEvent) {
+func newSSAEventFromItem(i Item, opts WriteToSSAOptions) (e *ssaEvent) {
 	// Init
 	e = &ssaEvent{
 		category: ssaEventCategoryDialogue,
@@ -926,25 +1012,109 @@ func newSSAEventFromItem(i Item) (e *ssaEvent) {
 
 	// Text
 	var lines []string
+	var state ssaInlineState
 	for _, l := range i.Lines {
 		var items []string
 		for _, item := range l.Items {
 			var s string
 			if item.InlineStyle != nil && len(item.InlineStyle.SSAEffect) > 0 {
 				s += item.InlineStyle.SSAEffect
+				state.update(item.InlineStyle.SSAEffect)
+			} else if o := state.overrides(item, i.Style); o != "" {
+				// Overrides have been set without effect
+				s += o
+				state.update(o)
 			}
-			s += item.Text
+			s += strings.ReplaceAll(item.Text, "\u00a0", ssaHardSpace)
 			items = append(items, s)
 		}
 		if len(l.VoiceName) > 0 {
 			e.name = l.VoiceName
 		}
-		lines = append(lines, strings.Join(items, " "))
+		lines = append(lines, strings.Join(items, ""))
+	}
+
+	// Make sure sentences are separated by a space when lines are joined
+	if opts.SpaceAfterPunctuation {
+		for idx := 0; idx < len(lines)-1; idx++ {
+			if strings.HasSuffix(lines[idx], " ") {
+				continue
+			}
+			if r, _ := utf8.DecodeLastRuneInString(lines[idx]); strings.ContainsRune(".!?…", r) {
+				lines[idx] += " "
+			}
+		}
+	}
+
+	// Join lines
+	lineBreak := opts.LineBreak
+	if lineBreak == "" {
+		lineBreak = ssaLineBreak
+	}
+	e.text = strings.Join(lines, lineBreak)
+
+	// Position
+	if a, sa := i.ssaAlignment(); a > 0 {
+		var d = 2
+		if i.Style != nil && i.Style.InlineStyle != nil && i.Style.InlineStyle.SSAAlignment != nil {
+			d = *i.Style.InlineStyle.SSAAlignment
+		}
+		if a != d {
+			e.text = fmt.Sprintf("{\\an%d}", a) + e.text
+		}
+
+		// Margins set along the position are carried through, the style ones being used otherwise. When the cue is
+		// moved away from its style alignment, the usual default ones are used if the style has none so that the cue
+		// keeps its distance to the edges it's aligned to.
+		var st = &StyleAttributes{}
+		if i.Style != nil && i.Style.InlineStyle != nil {
+			st = i.Style.InlineStyle
+		}
+		var defaults = a != d && st.SSAMarginLeft == nil && st.SSAMarginRight == nil && st.SSAMarginVertical == nil
+		var margin = func(e, sa *int) *int {
+			if e != nil {
+				return e
+			} else if sa != nil {
+				return sa
+			} else if defaults {
+				return astikit.IntPtr(ssaDefaultMargin)
+			}
+			return nil
+		}
+		e.marginLeft = margin(e.marginLeft, sa.SSAMarginLeft)
+		e.marginRight = margin(e.marginRight, sa.SSAMarginRight)
+		e.marginVertical = margin(e.marginVertical, sa.SSAMarginVertical)
 	}
-	e.text = strings.Join(lines, "\\N")
 	return
 }
 
+// ssaAlignment returns the numpad alignment of the item and the attributes it comes from, either its first line
+// item's or its own, 0 meaning none. A first line item with an effect is assumed to position the item itself.
+func (i Item) ssaAlignment() (int, *StyleAttributes) {
+	var sas []*StyleAttributes
+	if len(i.Lines) > 0 && len(i.Lines[0].Items) > 0 {
+		if sa := i.Lines[0].Items[0].InlineStyle; sa != nil {
+			if sa.SSAEffect != "" {
+				return 0, nil
+			}
+			sas = append(sas, sa)
+		}
+	}
+	sas = append(sas, i.InlineStyle)
+	for _, sa := range sas {
+		if sa == nil {
+			continue
+		}
+		if sa.SSAAlignment != nil && *sa.SSAAlignment >= 1 && *sa.SSAAlignment <= 9 {
+			return *sa.SSAAlignment, sa
+		}
+		if sa.SRTPosition >= 1 && sa.SRTPosition <= 9 {
+			return int(sa.SRTPosition), sa
+		}
+	}
+	return 0, nil
+}
+
 // newSSAEventFromString returns an SSA event based on an input string and a format
 func newSSAEventFromString(header, content string, format map[int]string) (e *ssaEvent, err error) {
 	// Split content
@@ -1041,7 +1211,8 @@ func newSSAEventFromString(header, content string, format map[int]string) (e *ss
 }
 
 // item converts an SSA event to an Item
-func (e *ssaEvent) item(styles map[string]*Style) (i *Item, err error) {
+// karaoke is true if the event contains syllable timings
+func (e *ssaEvent) item(styles map[string]*Style, wrapStyle string) (i *Item, karaoke bool, err error) {
 	// Init item
 	i = &Item{
 		EndAt: e.end,
@@ -1067,13 +1238,23 @@ func (e *ssaEvent) item(styles map[string]*Style) (i *Item, err error) {
 		}
 	}
 
-	// \N and \n are both valid new line characters in SSA
-	text := strings.ReplaceAll(e.text, "\\n", "\\N")
+	// \N is a hard line break whereas \n is a soft one, which is a space only when the wrap style is explicitly set to
+	// a word wrapping one and breaks lines otherwise
+	text := e.text
+	if ssaWrapStylesSoftLineBreak[strings.TrimSpace(wrapStyle)] {
+		text = strings.ReplaceAll(text, ssaSoftLineBreak, " ")
+	} else {
+		text = strings.ReplaceAll(text, ssaSoftLineBreak, ssaLineBreak)
+	}
 
 	// Loop through lines
+	// Drawing mode and overrides last until they're changed, even across lines
+	var state ssaInlineState
+	var karaokeOffset time.Duration
 	for _, s := range strings.Split(text, "\\N") {
 		// Init
-		s = strings.TrimSpace(s)
+		// Hard spaces are replaced after trimming since they are meant to be kept
+		s = strings.ReplaceAll(strings.TrimSpace(s), ssaHardSpace, "\u00a0")
 		var l = Line{VoiceName: e.name}
 
 		// Extract effects
@@ -1087,15 +1268,34 @@ func (e *ssaEvent) item(styles map[string]*Style) (i *Item, err error) {
 					lineItem.Text = s[previousEffectEndOffset:idxs[0]]
 					l.Items = append(l.Items, *lineItem)
 				} else if idxs[0] > 0 {
-					l.Items = append(l.Items, LineItem{Text: s[previousEffectEndOffset:idxs[0]]})
+					l.Items = append(l.Items, newSSALineItem(s[previousEffectEndOffset:idxs[0]], state, styles))
 				}
 				previousEffectEndOffset = idxs[1]
 				lineItem = &LineItem{InlineStyle: &StyleAttributes{SSAEffect: s[idxs[0]:idxs[1]]}}
+				state.update(lineItem.InlineStyle.SSAEffect)
+				state.apply(lineItem.InlineStyle)
+				lineItem.Style = styles[state.style]
+
+				// Syllables start once previous syllables have been sung, durations being in centiseconds
+				if ms := ssaRegexpKaraoke.FindAllStringSubmatch(lineItem.InlineStyle.SSAEffect, -1); len(ms) > 0 {
+					karaoke = true
+					lineItem.StartAt = e.start + karaokeOffset
+					for _, m := range ms {
+						d, _ := strconv.Atoi(m[1])
+						karaokeOffset += time.Duration(d) * 10 * time.Millisecond
+					}
+					if i.Style != nil {
+						lineItem.InlineStyle.WebVTTTags = append(lineItem.InlineStyle.WebVTTTags, WebVTTTag{
+							Name:    "c",
+							Classes: []string{ssaKaraokeClass(i.Style.ID)},
+						})
+					}
+				}
 			}
 			lineItem.Text = s[previousEffectEndOffset:]
 			l.Items = append(l.Items, *lineItem)
 		} else {
-			l.Items = append(l.Items, LineItem{Text: s})
+			l.Items = append(l.Items, newSSALineItem(s, state, styles))
 		}
 
 		// Add line
@@ -1104,6 +1304,194 @@ func (e *ssaEvent) item(styles map[string]*Style) (i *Item, err error) {
 	return
 }
 
+// ssaKaraokeClass returns the WebVTT class of karaoke line items using the style
+func ssaKaraokeClass(styleID string) string {
+	return "karaoke-" + ssaRegexpInvalidClassCharacters.ReplaceAllString(styleID, "-")
+}
+
+// newSSALineItem returns a line item without effect
+func newSSALineItem(text string, state ssaInlineState, styles map[string]*Style) (li LineItem) {
+	li.Style = styles[state.style]
+	li.Text = text
+
+	// Style is not an inline attribute
+	state.style = ""
+	if state != (ssaInlineState{}) {
+		li.InlineStyle = &StyleAttributes{}
+		state.apply(li.InlineStyle)
+	}
+	return
+}
+
+// ssaInlineState represents the drawing mode and overrides applying to line items
+type ssaInlineState struct {
+	bold            *bool
+	drawing         bool
+	fontName        string
+	fontSize        *float64
+	italic          *bool
+	primaryColour   *Color
+	secondaryColour *Color
+	strikeout       *bool
+	style           string // set with {\rStyleName}, empty means the item's style
+	underline       *bool
+}
+
+// update updates the state based on an effect
+func (s *ssaInlineState) update(effect string) {
+	// {\p1} (or any scale above 0) enables drawing mode, {\p0} disables it
+	for _, m := range ssaRegexpDrawingMode.FindAllStringSubmatch(effect, -1) {
+		s.drawing = m[1] != "0"
+	}
+
+	// An override without value resets it to the style's
+	for _, m := range ssaRegexpOverride.FindAllStringSubmatch(effect, -1) {
+		switch m[1] {
+		case "fn":
+			s.fontName = strings.TrimSpace(m[2])
+		case "fs":
+			if m[2] == "" {
+				s.fontSize = nil
+			} else if f, err := strconv.ParseFloat(m[2], 64); err == nil {
+				s.fontSize = astikit.Float64Ptr(f)
+			}
+		case "c", "1c":
+			s.primaryColour = ssaOverrideColour(m[2], s.primaryColour)
+		case "2c":
+			s.secondaryColour = ssaOverrideColour(m[2], s.secondaryColour)
+		case "b":
+			// Bold can also be set with a font weight
+			if m[2] == "" {
+				s.bold = nil
+			} else if w, err := strconv.Atoi(m[2]); err == nil {
+				s.bold = astikit.BoolPtr(w == 1 || w >= 700)
+			}
+		case "i":
+			s.italic = ssaOverrideToggle(m[2], s.italic)
+		case "s":
+			s.strikeout = ssaOverrideToggle(m[2], s.strikeout)
+		case "u":
+			s.underline = ssaOverrideToggle(m[2], s.underline)
+		case "r":
+			s.bold, s.fontName, s.fontSize, s.italic, s.primaryColour, s.secondaryColour, s.strikeout, s.underline = nil, "", nil, nil, nil, nil, nil, nil
+			s.style = strings.TrimSpace(m[2])
+		}
+	}
+}
+
+// ssaOverrideToggle returns the value set by a toggle override value, an empty value resetting it. Other values such as
+// the ones of tags sharing the same prefix (e.g. \shad or \iclip) leave it untouched.
+func ssaOverrideToggle(v string, current *bool) *bool {
+	switch v {
+	case "":
+		return nil
+	case "0":
+		return astikit.BoolPtr(false)
+	case "1":
+		return astikit.BoolPtr(true)
+	}
+	return current
+}
+
+// ssaOverrideColour returns the colour set by a colour override value, an empty value resetting it
+func ssaOverrideColour(v string, current *Color) *Color {
+	if v == "" {
+		return nil
+	} else if strings.HasPrefix(v, "&H") {
+		if c, err := newColorFromSSAColor(strings.TrimSuffix(v, "&")); err == nil {
+			return c
+		}
+	}
+	return current
+}
+
+// apply applies the state to style attributes
+func (s ssaInlineState) apply(sa *StyleAttributes) {
+	sa.SSABold = s.bold
+	sa.SSADrawing = s.drawing
+	sa.SSAItalic = s.italic
+	sa.SSAStrikeout = s.strikeout
+	sa.SSAUnderline = s.underline
+	sa.SSAFontName = s.fontName
+	sa.SSAFontSize = s.fontSize
+	sa.SSAPrimaryColour = s.primaryColour
+	sa.SSASecondaryColour = s.secondaryColour
+}
+
+// overrides returns the override tags needed to go from the state to the line item's style
+func (s ssaInlineState) overrides(li LineItem, itemStyle *Style) (o string) {
+	var n ssaInlineState
+	if li.InlineStyle != nil {
+		n = ssaInlineState{
+			bold:            li.InlineStyle.SSABold,
+			fontName:        li.InlineStyle.SSAFontName,
+			fontSize:        li.InlineStyle.SSAFontSize,
+			italic:          li.InlineStyle.SSAItalic,
+			primaryColour:   li.InlineStyle.SSAPrimaryColour,
+			secondaryColour: li.InlineStyle.SSASecondaryColour,
+			strikeout:       li.InlineStyle.SSAStrikeout,
+			underline:       li.InlineStyle.SSAUnderline,
+		}
+	}
+	if li.Style != nil && li.Style != itemStyle {
+		n.style = li.Style.ID
+	}
+
+	// Switching styles resets overrides
+	if n.style != s.style {
+		o += "\\r" + n.style
+		s = ssaInlineState{drawing: s.drawing, style: n.style}
+	}
+	if n.fontName != s.fontName {
+		o += "\\fn" + n.fontName
+	}
+	o += ssaOverrideToggleString("b", n.bold, s.bold)
+	o += ssaOverrideToggleString("i", n.italic, s.italic)
+	o += ssaOverrideToggleString("u", n.underline, s.underline)
+	o += ssaOverrideToggleString("s", n.strikeout, s.strikeout)
+	if (n.fontSize == nil) != (s.fontSize == nil) || (n.fontSize != nil && *n.fontSize != *s.fontSize) {
+		o += "\\fs"
+		if n.fontSize != nil {
+			o += strconv.FormatFloat(*n.fontSize, 'f', -1, 64)
+		}
+	}
+	if (n.primaryColour == nil) != (s.primaryColour == nil) || (n.primaryColour != nil && *n.primaryColour != *s.primaryColour) {
+		o += "\\c" + ssaOverrideColourString(n.primaryColour)
+	}
+	if (n.secondaryColour == nil) != (s.secondaryColour == nil) || (n.secondaryColour != nil && *n.secondaryColour != *s.secondaryColour) {
+		o += "\\2c" + ssaOverrideColourString(n.secondaryColour)
+	}
+	if o != "" {
+		o = "{" + o + "}"
+	}
+	return
+}
+
+// ssaOverrideToggleString returns the toggle override needed to go from the current value to the new one, a tag
+// without value resetting it
+func ssaOverrideToggleString(tag string, n, current *bool) string {
+	if (n == nil) == (current == nil) && (n == nil || *n == *current) {
+		return ""
+	}
+	o := "\\" + tag
+	if n != nil {
+		if *n {
+			o += "1"
+		} else {
+			o += "0"
+		}
+	}
+	return o
+}
+
+// ssaOverrideColourString returns the value of a colour override, an empty value resetting it
+func ssaOverrideColourString(c *Color) string {
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf("&H%.6x&", uint32(c.Blue)<<16|uint32(c.Green)<<8|uint32(c.Red))
+}
+
 // formatDurationSSA formats an .ssa duration
 func formatDurationSSA(i time.Duration) string {
 	return formatDuration(i, ".", 2)
@@ -1176,8 +1564,26 @@ func parseDurationSSA(i string) (time.Duration, error) {
 	return parseDuration(i, ".", 3)
 }
 
+// WriteToSSAOptions represents SSA write options
+type WriteToSSAOptions struct {
+	// LineBreak separates the lines of an item. Default is "\N".
+	// Some tools expect a literal new line instead, even though it's not standard.
+	LineBreak string
+	// SpaceAfterPunctuation adds a space after sentence-ending punctuation preceding a line break
+	SpaceAfterPunctuation bool
+}
+
+func defaultWriteToSSAOptions() WriteToSSAOptions {
+	return WriteToSSAOptions{LineBreak: ssaLineBreak}
+}
+
 // WriteToSSA writes subtitles in .ssa format
 func (s Subtitles) WriteToSSA(o io.Writer) (err error) {
+	return s.WriteToSSAWithOptions(o, defaultWriteToSSAOptions())
+}
+
+// WriteToSSAWithOptions writes subtitles in .ssa format with options
+func (s Subtitles) WriteToSSAWithOptions(o io.Writer, opts WriteToSSAOptions) (err error) {
 	// Do not write anything if no subtitles
 	if len(s.Items) == 0 {
 		err = ErrNoSubtitlesToWrite
@@ -1250,14 +1656,26 @@ func (s Subtitles) WriteToSSA(o io.Writer) (err error) {
 		}
 		var events []*ssaEvent
 		for _, i := range s.Items {
-			events = append(events, newSSAEventFromItem(*i))
+			events = append(events, newSSAEventFromItem(*i, opts))
+		}
+
+		// Comments are inserted before the first dialogue starting at or after them
+		for _, c := range s.Metadata.SSAComments {
+			var idx = len(events)
+			for k, e := range events {
+				if e.category == ssaEventCategoryDialogue && e.start >= c.StartAt {
+					idx = k
+					break
+				}
+			}
+			events = append(events[:idx], append([]*ssaEvent{newSSAEventFromComment(c)}, events[idx:]...)...)
 		}
 		format = append(format, ssaEventFormatNameText)
 		b = append(b, []byte("Format: "+strings.Join(format, ", ")+"\n")...)
 
 		// Styles
 		for _, e := range events {
-			b = append(b, []byte(ssaEventCategoryDialogue+": "+e.string(format)+"\n")...)
+			b = append(b, []byte(e.category+": "+e.string(format)+"\n")...)
 		}
 
 		// Write
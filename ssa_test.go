@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/5rahim/go-astisub"
 	"github.com/asticode/go-astikit"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func assertSSAStyle(t *testing.T, e, a astisub.Style) {
@@ -134,3 +136,376 @@ Dialogue: Marked=0,0:01:39.00,0:01:41.04,,Cher,1234,2345,3456,test,First item{\p
 		Text:        "Second item",
 	}, s.Items[0].Lines[0].Items[1])
 }
+
+func TestSSADrawing(t *testing.T) {
+	s, err := astisub.ReadFromSSA(bytes.NewReader([]byte(`[Events]
+Format: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: Marked=0,0:01:39.00,0:01:41.04,,Cher,0,0,0,,{\pos(10,10)\p1}m 0 0 l 100 0 100 100 0 100{\p0}Text`)))
+	assert.NoError(t, err)
+	assert.Len(t, s.Items[0].Lines[0].Items, 2)
+	assert.Equal(t, astisub.LineItem{
+		InlineStyle: &astisub.StyleAttributes{SSADrawing: true, SSAEffect: "{\\pos(10,10)\\p1}"},
+		Text:        "m 0 0 l 100 0 100 100 0 100",
+	}, s.Items[0].Lines[0].Items[0])
+	assert.Equal(t, astisub.LineItem{
+		InlineStyle: &astisub.StyleAttributes{SSAEffect: "{\\p0}"},
+		Text:        "Text",
+	}, s.Items[0].Lines[0].Items[1])
+	assert.Equal(t, "Text", s.Items[0].Lines[0].String())
+
+	// Drawings are not written to text only formats
+	w := &bytes.Buffer{}
+	err = s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n00:01:39,000 --> 00:01:41,040\nText\n", w.String())
+
+	// Drawings are preserved when writing to SSA
+	w = &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `{\pos(10,10)\p1}m 0 0 l 100 0 100 100 0 100{\p0}Text`)
+
+	// Drawing only items are not written to SRT
+	s, err = astisub.ReadFromSSA(bytes.NewReader([]byte(`[Events]
+Format: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: Marked=0,0:01:37.00,0:01:38.00,,Cher,0,0,0,,{\p1}m 0 0 l 100 0 100 100 0 100
+Dialogue: Marked=0,0:01:39.00,0:01:41.04,,Cher,0,0,0,,Text`)))
+	assert.NoError(t, err)
+	w = &bytes.Buffer{}
+	err = s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n00:01:39,000 --> 00:01:41,040\nText\n", w.String())
+	s.Items = s.Items[:1]
+	err = s.WriteToSRT(&bytes.Buffer{})
+	assert.Equal(t, astisub.ErrNoSubtitlesToWrite, err)
+}
+
+func TestSSAInlineOverrides(t *testing.T) {
+	s, err := astisub.ReadFromSSA(bytes.NewReader([]byte(`[Events]
+Format: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: Marked=0,0:01:39.00,0:01:41.04,,Cher,0,0,0,,{\fnArial\fs24}Hello {\c&H00FF00&}world\N{\r}Reset`)))
+	assert.NoError(t, err)
+	assert.Len(t, s.Items[0].Lines, 2)
+	assert.Equal(t, []astisub.LineItem{
+		{InlineStyle: &astisub.StyleAttributes{SSAEffect: "{\\fnArial\\fs24}", SSAFontName: "Arial", SSAFontSize: astikit.Float64Ptr(24)}, Text: "Hello "},
+		{InlineStyle: &astisub.StyleAttributes{SSAEffect: "{\\c&H00FF00&}", SSAFontName: "Arial", SSAFontSize: astikit.Float64Ptr(24), SSAPrimaryColour: &astisub.Color{Green: 255}}, Text: "world"},
+	}, s.Items[0].Lines[0].Items)
+	assert.Equal(t, []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{SSAEffect: "{\\r}"}, Text: "Reset"}}, s.Items[0].Lines[1].Items)
+	assert.Equal(t, "Hello world", s.Items[0].Lines[0].String())
+
+	// Overrides without effect are written as override tags
+	s = &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 2 * time.Second,
+		Lines: []astisub.Line{
+			{Items: []astisub.LineItem{
+				{Text: "Default"},
+				{InlineStyle: &astisub.StyleAttributes{SSAFontName: "Arial", SSAFontSize: astikit.Float64Ptr(24.5)}, Text: "Arial"},
+			}},
+			{Items: []astisub.LineItem{
+				{InlineStyle: &astisub.StyleAttributes{SSAFontName: "Arial", SSAPrimaryColour: &astisub.Color{Blue: 255}}, Text: "Blue"},
+			}},
+		},
+		StartAt: time.Second,
+	}}, Metadata: &astisub.Metadata{}}
+	w := &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `Default{\fnArial\fs24.5}Arial\N{\fs\c&Hff0000&}Blue`)
+}
+
+func TestSSAInlineToggleOverrides(t *testing.T) {
+	s, err := astisub.ReadFromSSA(bytes.NewReader([]byte(`[Events]
+Format: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: Marked=0,0:01:39.00,0:01:41.04,,Cher,0,0,0,,{\i1}Hello {\b700\u1}world{\i0\shad2\b0}!{\u\s1}?`)))
+	require.NoError(t, err)
+	require.Len(t, s.Items[0].Lines, 1)
+	assert.Equal(t, []astisub.LineItem{
+		{InlineStyle: &astisub.StyleAttributes{SSAEffect: "{\\i1}", SSAItalic: astikit.BoolPtr(true)}, Text: "Hello "},
+		{InlineStyle: &astisub.StyleAttributes{SSABold: astikit.BoolPtr(true), SSAEffect: "{\\b700\\u1}", SSAItalic: astikit.BoolPtr(true), SSAUnderline: astikit.BoolPtr(true)}, Text: "world"},
+		{InlineStyle: &astisub.StyleAttributes{SSABold: astikit.BoolPtr(false), SSAEffect: "{\\i0\\shad2\\b0}", SSAItalic: astikit.BoolPtr(false), SSAUnderline: astikit.BoolPtr(true)}, Text: "!"},
+		{InlineStyle: &astisub.StyleAttributes{SSABold: astikit.BoolPtr(false), SSAEffect: "{\\u\\s1}", SSAItalic: astikit.BoolPtr(false), SSAStrikeout: astikit.BoolPtr(true)}, Text: "?"},
+	}, s.Items[0].Lines[0].Items)
+
+	// Overrides without effect are written as override tags
+	s = &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 2 * time.Second,
+		Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{InlineStyle: &astisub.StyleAttributes{SSAItalic: astikit.BoolPtr(true)}, Text: "Italic"},
+			{InlineStyle: &astisub.StyleAttributes{SSABold: astikit.BoolPtr(true), SSAItalic: astikit.BoolPtr(true)}, Text: "Both"},
+			{InlineStyle: &astisub.StyleAttributes{SSAUnderline: astikit.BoolPtr(true)}, Text: "Underline"},
+			{Text: "Default"},
+		}}},
+		StartAt: time.Second,
+	}}, Metadata: &astisub.Metadata{}}
+	w := &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `,{\i1}Italic{\b1}Both{\b\i\u1}Underline{\u}Default`+"\n")
+}
+
+func TestWriteToSSAWithOptions(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 2 * time.Second,
+		Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "First sentence."}}},
+			{Items: []astisub.LineItem{{Text: "Second sentence"}}},
+			{Items: []astisub.LineItem{{Text: "continued"}}},
+		},
+		StartAt: time.Second,
+	}}, Metadata: &astisub.Metadata{}}
+
+	// Default
+	w := &bytes.Buffer{}
+	err := s.WriteToSSAWithOptions(w, astisub.WriteToSSAOptions{})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `,First sentence.\NSecond sentence\Ncontinued`+"\n")
+
+	// Custom
+	w.Reset()
+	err = s.WriteToSSAWithOptions(w, astisub.WriteToSSAOptions{LineBreak: "\n", SpaceAfterPunctuation: true})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), ",First sentence. \nSecond sentence\ncontinued\n")
+}
+
+func TestSSAInlineStyleSwitch(t *testing.T) {
+	s, err := astisub.ReadFromSSA(bytes.NewReader([]byte(`[V4+ Styles]
+Format: Name, Fontname, Fontsize
+Style: Default,Arial,20
+Style: Sign,Verdana,30
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:01:39.00,0:01:41.04,Default,,0,0,0,,Hello {\rSign}world\N{\r}!`)))
+	assert.NoError(t, err)
+	assert.Equal(t, s.Styles["Default"], s.Items[0].Style)
+	assert.Nil(t, s.Items[0].Lines[0].Items[0].Style)
+	assert.Equal(t, s.Styles["Sign"], s.Items[0].Lines[0].Items[1].Style)
+	assert.Nil(t, s.Items[0].Lines[1].Items[0].Style)
+
+	// Styles referenced by line items are kept
+	s.Optimize()
+	assert.Len(t, s.Styles, 2)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "Style: Sign,")
+	assert.Contains(t, w.String(), `,Hello {\rSign}world\N{\r}!`)
+
+	// Styles set without effect are written as style switches
+	s.Items[0].Lines = []astisub.Line{{Items: []astisub.LineItem{
+		{Text: "Hello "},
+		{Style: s.Styles["Sign"], Text: "world"},
+		{InlineStyle: &astisub.StyleAttributes{SSAFontName: "Arial"}, Text: "!"},
+	}}}
+	w.Reset()
+	err = s.WriteToSSA(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `,Hello {\rSign}world{\r\fnArial}!`)
+}
+
+func TestSSAKaraoke(t *testing.T) {
+	s, err := astisub.ReadFromSSA(bytes.NewReader([]byte(`[V4+ Styles]
+Format: Name, PrimaryColour, SecondaryColour
+Style: Lyrics 1,&H0000FFFF,&H00FF0000
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:03.00,Lyrics 1,,0,0,0,,{\k50}Ka{\K30}ra{\kf20\2c&H00FF00&}oke`)))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	items := s.Items[0].Lines[0].Items
+	require.Len(t, items, 3)
+	assert.Equal(t, time.Second, items[0].StartAt)
+	assert.Equal(t, 1500*time.Millisecond, items[1].StartAt)
+	assert.Equal(t, 1800*time.Millisecond, items[2].StartAt)
+	assert.Nil(t, items[1].InlineStyle.SSASecondaryColour)
+	assert.Equal(t, &astisub.Color{Green: 255}, items[2].InlineStyle.SSASecondaryColour)
+
+	// Both colours are converted to WebVTT
+	w := &bytes.Buffer{}
+	err = s.WriteToWebVTT(w)
+	require.NoError(t, err)
+	assert.Equal(t, `WEBVTT
+
+STYLE
+::cue(.karaoke-Lyrics-1:past) { color: #ffff00; }
+::cue(.karaoke-Lyrics-1:future) { color: #0000ff; }
+
+1
+00:00:01.000 --> 00:00:03.000
+<00:00:01.000><c.karaoke-Lyrics-1>Ka<00:00:01.500>ra<00:00:01.800>oke</c>
+`, w.String())
+
+	// Effects are written back as is
+	w.Reset()
+	err = s.WriteToSSA(w)
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `,{\k50}Ka{\K30}ra{\kf20\2c&H00FF00&}oke`)
+
+	// Secondary colours set without effect are written as overrides
+	s.Items[0].Lines[0].Items = []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{SSASecondaryColour: &astisub.Color{Red: 255}}, Text: "Ka"}}
+	w.Reset()
+	err = s.WriteToSSA(w)
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `,{\2c&H0000ff&}Ka`)
+}
+
+func TestSSADialogueOnly(t *testing.T) {
+	s, err := astisub.ReadFromSSA(bytes.NewReader([]byte(`[V4+ Styles]
+Format: Name
+Style: Default
+Style: Signs
+Style: Tsubasa
+Style: Title card
+Style: OP-dialogue
+Style: Typesetting - Top
+Style: KFX romaji
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\i1}Hello{\i0}
+Dialogue: 0,0:00:01.00,0:00:02.00,Signs,,0,0,0,,Station
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\pos(100,200)}Shop
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\frz15}Sign
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\p1}m 0 0 l 10 0 10 10{\p0}
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,Scroll up;10;100;1,Credits
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\k50}La{\k50}la
+Dialogue: 0,0:00:02.00,0:00:03.00,Tsubasa,,0,0,0,,World
+Dialogue: 0,0:00:03.00,0:00:04.00,Title card,,0,0,0,,Narrated
+Dialogue: 0,0:00:03.00,0:00:04.00,OP-dialogue,,0,0,0,,Sung
+Dialogue: 0,0:00:03.00,0:00:04.00,Typesetting - Top,,0,0,0,,Typeset
+Dialogue: 0,0:00:03.00,0:00:04.00,KFX romaji,,0,0,0,,Karaoke`)))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 12)
+	assert.False(t, s.Items[0].IsSign())
+	assert.True(t, s.Items[1].IsSign())
+	assert.False(t, s.Items[8].IsSign())
+	assert.False(t, s.Items[9].IsSign())
+	assert.True(t, s.Items[10].IsSign())
+	assert.True(t, s.Items[11].IsSign())
+	assert.Equal(t, 8, s.DialogueOnly())
+	require.Len(t, s.Items, 4)
+	assert.Equal(t, "Hello", s.Items[0].String())
+	assert.Equal(t, "World", s.Items[1].String())
+	assert.Equal(t, "Narrated", s.Items[2].String())
+	assert.Equal(t, "Sung", s.Items[3].String())
+}
+
+func TestSSAComments(t *testing.T) {
+	s, err := astisub.ReadFromSSA(bytes.NewReader([]byte(`[Script Info]
+ScriptType: v4.00+
+
+[V4+ Styles]
+Format: Name
+Style: Default
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Comment: 1,0:00:01.00,0:00:02.00,Default,Reviewer,0,0,0,,{\i1}Original line, to check
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Hello
+Dialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,World
+Comment: 0,0:00:05.00,0:00:06.00,Default,,0,0,0,,Last comment`)))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 2)
+	require.Len(t, s.Metadata.SSAComments, 2)
+	assert.Equal(t, astisub.SSAComment{
+		EndAt:          2 * time.Second,
+		Layer:          astikit.IntPtr(1),
+		MarginLeft:     astikit.IntPtr(0),
+		MarginRight:    astikit.IntPtr(0),
+		MarginVertical: astikit.IntPtr(0),
+		Name:           "Reviewer",
+		StartAt:        time.Second,
+		Style:          "Default",
+		Text:           `{\i1}Original line, to check`,
+	}, s.Metadata.SSAComments[0])
+
+	// Comments are written back
+	w := &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `Comment: 1,00:00:01.00,00:00:02.00,Default,Reviewer,0,0,0,,{\i1}Original line, to check
+Dialogue: 0,00:00:01.00,00:00:02.00,Default,,0,0,0,,Hello
+Dialogue: 0,00:00:03.00,00:00:04.00,Default,,0,0,0,,World
+Comment: 0,00:00:05.00,00:00:06.00,Default,,0,0,0,,Last comment
+`)
+}
+
+func TestSSALineBreaks(t *testing.T) {
+	const events = `
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,\hFirst\Nsecond\nthird 10\hkm`
+
+	// Soft line breaks are spaces with word wrapping wrap styles
+	s, err := astisub.ReadFromSSA(bytes.NewReader([]byte("[Script Info]\nWrapStyle: 0\n" + events)))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	require.Len(t, s.Items[0].Lines, 2)
+	assert.Equal(t, " First", s.Items[0].Lines[0].String())
+	assert.Equal(t, "second third 10 km", s.Items[0].Lines[1].String())
+
+	// Soft line breaks are line breaks when no wrap style is set
+	s, err = astisub.ReadFromSSA(bytes.NewReader([]byte(events)))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	require.Len(t, s.Items[0].Lines, 3)
+
+	// Soft line breaks are line breaks with the "no word wrapping" wrap style
+	s, err = astisub.OpenFile("./testdata/example-in-wrap-style.ssa")
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	require.Len(t, s.Items[0].Lines, 3)
+	assert.Equal(t, "second", s.Items[0].Lines[1].String())
+	assert.Equal(t, "third 10 km", s.Items[0].Lines[2].String())
+
+	// Hard line breaks and hard spaces are written back
+	w := &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `,\hFirst\Nsecond\Nthird 10\hkm`+"\n")
+}
+
+func TestSSAPositions(t *testing.T) {
+	item := func(sa *astisub.StyleAttributes, st *astisub.Style) *astisub.Item {
+		return &astisub.Item{
+			EndAt:   2 * time.Second,
+			Lines:   []astisub.Line{{Items: []astisub.LineItem{{InlineStyle: sa, Text: "Text"}}}},
+			StartAt: time.Second,
+			Style:   st,
+		}
+	}
+	top := &astisub.Style{ID: "Top", InlineStyle: &astisub.StyleAttributes{SSAAlignment: astikit.IntPtr(8)}}
+	margins := &astisub.Style{ID: "Margins", InlineStyle: &astisub.StyleAttributes{SSAMarginVertical: astikit.IntPtr(50)}}
+	s := &astisub.Subtitles{
+		Items: []*astisub.Item{
+			item(&astisub.StyleAttributes{SRTPosition: 7}, nil),
+			item(&astisub.StyleAttributes{SSAAlignment: astikit.IntPtr(9)}, nil),
+			item(&astisub.StyleAttributes{SRTPosition: 2}, nil),
+			item(&astisub.StyleAttributes{SRTPosition: 8}, top),
+			item(&astisub.StyleAttributes{SRTPosition: 2}, top),
+			item(&astisub.StyleAttributes{SRTPosition: 8, SSAEffect: "{\\pos(10,10)}"}, nil),
+			item(nil, nil),
+			item(&astisub.StyleAttributes{SRTPosition: 9, SSAMarginLeft: astikit.IntPtr(20), SSAMarginRight: astikit.IntPtr(30), SSAMarginVertical: astikit.IntPtr(40)}, nil),
+			item(&astisub.StyleAttributes{SRTPosition: 8}, margins),
+		},
+		Metadata: &astisub.Metadata{},
+		Styles:   map[string]*astisub.Style{"Margins": margins, "Top": top},
+	}
+	s.Items[6].InlineStyle = &astisub.StyleAttributes{SRTPosition: 4}
+	w := &bytes.Buffer{}
+	require.NoError(t, s.WriteToSSA(w))
+	assert.Contains(t, w.String(), `[Events]
+Format: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: Marked=0,00:00:01.00,00:00:02.00,,,10,10,10,,{\an7}Text
+Dialogue: Marked=0,00:00:01.00,00:00:02.00,,,10,10,10,,{\an9}Text
+Dialogue: Marked=0,00:00:01.00,00:00:02.00,,,0,0,0,,Text
+Dialogue: Marked=0,00:00:01.00,00:00:02.00,Top,,0,0,0,,Text
+Dialogue: Marked=0,00:00:01.00,00:00:02.00,Top,,10,10,10,,{\an2}Text
+Dialogue: Marked=0,00:00:01.00,00:00:02.00,,,0,0,0,,{\pos(10,10)}Text
+Dialogue: Marked=0,00:00:01.00,00:00:02.00,,,10,10,10,,{\an4}Text
+Dialogue: Marked=0,00:00:01.00,00:00:02.00,,,20,30,40,,{\an9}Text
+Dialogue: Marked=0,00:00:01.00,00:00:02.00,Margins,,0,0,0,,{\an8}Text`)
+}
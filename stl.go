@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -178,6 +178,8 @@ type STLPosition struct {
 
 // STLOptions represents STL parsing options
 type STLOptions struct {
+	// DropFrame - parse timecodes of 30 fps files as 29.97 fps drop frame timecodes
+	DropFrame bool
 	// IgnoreTimecodeStartOfProgramme - set STLTimecodeStartOfProgramme to zero before parsing
 	IgnoreTimecodeStartOfProgramme bool
 }
@@ -189,13 +191,19 @@ func ReadFromSTL(i io.Reader, opts STLOptions) (o *Subtitles, err error) {
 
 	// Read GSI block
 	var b []byte
-	if b, err = readNBytes(i, stlBlockSizeGSI); err != nil {
+	b, err = readNBytes(i, stlBlockSizeGSI)
+
+	// Spruce STL is text based and may be shorter than a GSI block
+	if isSpruceSTL(b) {
+		return ReadFromSpruceSTL(io.MultiReader(bytes.NewReader(b), i))
+	}
+	if err != nil {
 		return
 	}
 
 	// Parse GSI block
 	var g *gsiBlock
-	if g, err = parseGSIBlock(b); err != nil {
+	if g, err = parseGSIBlock(b, opts.DropFrame); err != nil {
 		err = fmt.Errorf("astisub: building gsi block failed: %w", err)
 		return
 	}
@@ -248,7 +256,7 @@ func ReadFromSTL(i io.Reader, opts STLOptions) (o *Subtitles, err error) {
 		}
 
 		// Parse TTI block
-		var t = parseTTIBlock(b, g.framerate)
+		var t = parseTTIBlock(b, g.framerate, g.dropFrame)
 
 		// Do not process reserved user data
 		if t.extensionBlockNumber == extensionBlockNumberReservedUserData {
@@ -270,6 +278,11 @@ func ReadFromSTL(i io.Reader, opts STLOptions) (o *Subtitles, err error) {
 		}
 		styleAttributes.propagateSTLAttributes()
 
+		// Teletext vertical position is the row of the first subtitle row
+		if isTeletextDisplayStandardCode(g.displayStandardCode) {
+			styleAttributes.TeletextRow = astikit.IntPtr(t.verticalPosition)
+		}
+
 		// Create item
 		var i = &Item{
 			EndAt:       t.timecodeOut - o.Metadata.STLTimecodeStartOfProgramme,
@@ -296,19 +309,75 @@ func ReadFromSTL(i io.Reader, opts STLOptions) (o *Subtitles, err error) {
 	return
 }
 
+// OpenSTLParts opens STL files containing consecutive parts of the same programme and concatenates them.
+// Each part is positioned on the first part's timeline based on its STLTimecodeStartOfProgramme, and the first part's
+// metadata is used. An error is returned if there are no filenames.
+func OpenSTLParts(filenames []string, opts STLOptions) (o *Subtitles, err error) {
+	// No parts
+	if len(filenames) == 0 {
+		err = errors.New("astisub: no stl parts to open")
+		return
+	}
+
+	// Loop through filenames
+	for idx, filename := range filenames {
+		// Open part
+		var s *Subtitles
+		if s, err = openSTLPart(filename, opts); err != nil {
+			return
+		}
+
+		// First part
+		if idx == 0 {
+			o = s
+			continue
+		}
+
+		// Position part on the first part's timeline
+		var offset = s.Metadata.STLTimecodeStartOfProgramme - o.Metadata.STLTimecodeStartOfProgramme
+		for _, i := range s.Items {
+			i.EndAt += offset
+			i.StartAt += offset
+		}
+
+		// Merge
+		o.Merge(s)
+	}
+	return
+}
+
+// openSTLPart opens an STL file
+func openSTLPart(filename string, opts STLOptions) (s *Subtitles, err error) {
+	// Open the file
+	var f *os.File
+	if f, err = os.Open(filename); err != nil {
+		err = fmt.Errorf("astisub: opening %s failed: %w", filename, err)
+		return
+	}
+	defer f.Close()
+
+	// Parse the content
+	if s, err = ReadFromSTL(f, opts); err != nil {
+		err = fmt.Errorf("astisub: reading %s failed: %w", filename, err)
+		return
+	}
+	return
+}
+
 // readNBytes reads n bytes
+// Bytes read before an error are returned
 func readNBytes(i io.Reader, c int) (o []byte, err error) {
 	o = make([]byte, c)
 	var n int
-	if n, err = i.Read(o); err != nil || n != len(o) {
-		if err != nil {
-			if err == io.EOF {
-				return
-			}
+	if n, err = io.ReadFull(i, o); err != nil {
+		o = o[:n]
+		switch err {
+		case io.EOF:
+		case io.ErrUnexpectedEOF:
+			err = fmt.Errorf("astisub: read %d bytes, should have read %d", n, c)
+		default:
 			err = fmt.Errorf("astisub: reading %d bytes failed: %w", c, err)
-			return
 		}
-		err = fmt.Errorf("astisub: read %d bytes, should have read %d", n, c)
 		return
 	}
 	return
@@ -324,6 +393,7 @@ type gsiBlock struct {
 	displayStandardCode                              string
 	editorContactDetails                             string
 	editorName                                       string
+	dropFrame                                        bool
 	framerate                                        int
 	languageCode                                     string
 	maximumNumberOfDisplayableCharactersInAnyTextRow int
@@ -414,7 +484,7 @@ func newGSIBlock(s Subtitles) (g *gsiBlock) {
 }
 
 // parseGSIBlock parses a GSI block
-func parseGSIBlock(b []byte) (g *gsiBlock, err error) {
+func parseGSIBlock(b []byte, dropFrame bool) (g *gsiBlock, err error) {
 	// Init
 	g = &gsiBlock{
 		characterCodeTableNumber:  binary.BigEndian.Uint16(b[12:14]),
@@ -440,6 +510,7 @@ func parseGSIBlock(b []byte) (g *gsiBlock, err error) {
 	if v, ok := stlFramerateMapping.Get(string(b[3:11])); ok {
 		g.framerate = v.(int)
 	}
+	g.dropFrame = dropFrame && g.framerate == 30
 
 	// Creation date
 	if v := strings.TrimSpace(string(b[224:230])); len(v) > 0 {
@@ -507,7 +578,7 @@ func parseGSIBlock(b []byte) (g *gsiBlock, err error) {
 
 	// Timecode start of programme
 	if v := strings.TrimSpace(string(b[256:264])); len(v) > 0 {
-		if g.timecodeStartOfProgramme, err = parseDurationSTL(v, g.framerate); err != nil {
+		if g.timecodeStartOfProgramme, err = parseDurationSTL(v, g.framerate, g.dropFrame); err != nil {
 			err = fmt.Errorf("astisub: parsing of stl duration %s failed: %w", v, err)
 			return
 		}
@@ -515,7 +586,7 @@ func parseGSIBlock(b []byte) (g *gsiBlock, err error) {
 
 	// Timecode first in cue
 	if v := strings.TrimSpace(string(b[264:272])); len(v) > 0 {
-		if g.timecodeFirstInCue, err = parseDurationSTL(v, g.framerate); err != nil {
+		if g.timecodeFirstInCue, err = parseDurationSTL(v, g.framerate, g.dropFrame); err != nil {
 			err = fmt.Errorf("astisub: parsing of stl duration %s failed: %w", v, err)
 			return
 		}
@@ -552,38 +623,38 @@ func (b gsiBlock) bytes() (o []byte) {
 	o = append(o, astikit.BytesPad([]byte(f), ' ', 8, astikit.PadRight, astikit.PadCut)...)
 	o = append(o, astikit.BytesPad([]byte(b.displayStandardCode), ' ', 1, astikit.PadRight, astikit.PadCut)...) // Display standard code
 	binary.BigEndian.PutUint16(bs, b.characterCodeTableNumber)
-	o = append(o, astikit.BytesPad(bs[:2], ' ', 2, astikit.PadRight, astikit.PadCut)...)                                                             // Character code table number
-	o = append(o, astikit.BytesPad([]byte(b.languageCode), ' ', 2, astikit.PadRight, astikit.PadCut)...)                                             // Language code
-	o = append(o, astikit.BytesPad([]byte(b.originalProgramTitle), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                    // Original program title
-	o = append(o, astikit.BytesPad([]byte(b.originalEpisodeTitle), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                    // Original episode title
-	o = append(o, astikit.BytesPad([]byte(b.translatedProgramTitle), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                  // Translated program title
-	o = append(o, astikit.BytesPad([]byte(b.translatedEpisodeTitle), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                  // Translated episode title
-	o = append(o, astikit.BytesPad([]byte(b.translatorName), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                          // Translator's name
-	o = append(o, astikit.BytesPad([]byte(b.translatorContactDetails), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                // Translator's contact details
-	o = append(o, astikit.BytesPad([]byte(b.subtitleListReferenceCode), ' ', 16, astikit.PadRight, astikit.PadCut)...)                               // Subtitle list reference code
-	o = append(o, astikit.BytesPad([]byte(b.creationDate.Format("060102")), ' ', 6, astikit.PadRight, astikit.PadCut)...)                            // Creation date
-	o = append(o, astikit.BytesPad([]byte(b.revisionDate.Format("060102")), ' ', 6, astikit.PadRight, astikit.PadCut)...)                            // Revision date
-	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.revisionNumber)), '0', 2, astikit.PadCut)...)                                               // Revision number
-	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.totalNumberOfTTIBlocks)), '0', 5, astikit.PadCut)...)                                       // Total number of TTI blocks
-	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.totalNumberOfSubtitles)), '0', 5, astikit.PadCut)...)                                       // Total number of subtitles
-	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.totalNumberOfSubtitleGroups)), '0', 3, astikit.PadCut)...)                                  // Total number of subtitle groups
-	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.maximumNumberOfDisplayableCharactersInAnyTextRow)), '0', 2, astikit.PadCut)...)             // Maximum number of displayable characters in any text row
-	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.maximumNumberOfDisplayableRows)), '0', 2, astikit.PadCut)...)                               // Maximum number of displayable rows
-	o = append(o, astikit.BytesPad([]byte(b.timecodeStatus), ' ', 1, astikit.PadRight, astikit.PadCut)...)                                           // Timecode status
-	o = append(o, astikit.BytesPad([]byte(formatDurationSTL(b.timecodeStartOfProgramme, b.framerate)), ' ', 8, astikit.PadRight, astikit.PadCut)...) // Timecode start of a programme
-	o = append(o, astikit.BytesPad([]byte(formatDurationSTL(b.timecodeFirstInCue, b.framerate)), ' ', 8, astikit.PadRight, astikit.PadCut)...)       // Timecode first in cue
-	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.totalNumberOfDisks)), ' ', 1, astikit.PadRight, astikit.PadCut)...)                         // Total number of disks
-	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.diskSequenceNumber)), ' ', 1, astikit.PadRight, astikit.PadCut)...)                         // Disk sequence number
-	o = append(o, astikit.BytesPad([]byte(b.countryOfOrigin), ' ', 3, astikit.PadRight, astikit.PadCut)...)                                          // Country of origin
-	o = append(o, astikit.BytesPad([]byte(b.publisher), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                               // Publisher
-	o = append(o, astikit.BytesPad([]byte(b.editorName), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                              // Editor's name
-	o = append(o, astikit.BytesPad([]byte(b.editorContactDetails), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                    // Editor's contact details
-	o = append(o, astikit.BytesPad([]byte{}, ' ', 75+576, astikit.PadRight, astikit.PadCut)...)                                                      // Spare bytes + user defined area                                                                                           //                                                                                                                      // Editor's contact details
+	o = append(o, astikit.BytesPad(bs[:2], ' ', 2, astikit.PadRight, astikit.PadCut)...)                                                                          // Character code table number
+	o = append(o, astikit.BytesPad([]byte(b.languageCode), ' ', 2, astikit.PadRight, astikit.PadCut)...)                                                          // Language code
+	o = append(o, astikit.BytesPad([]byte(b.originalProgramTitle), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                                 // Original program title
+	o = append(o, astikit.BytesPad([]byte(b.originalEpisodeTitle), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                                 // Original episode title
+	o = append(o, astikit.BytesPad([]byte(b.translatedProgramTitle), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                               // Translated program title
+	o = append(o, astikit.BytesPad([]byte(b.translatedEpisodeTitle), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                               // Translated episode title
+	o = append(o, astikit.BytesPad([]byte(b.translatorName), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                                       // Translator's name
+	o = append(o, astikit.BytesPad([]byte(b.translatorContactDetails), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                             // Translator's contact details
+	o = append(o, astikit.BytesPad([]byte(b.subtitleListReferenceCode), ' ', 16, astikit.PadRight, astikit.PadCut)...)                                            // Subtitle list reference code
+	o = append(o, astikit.BytesPad([]byte(b.creationDate.Format("060102")), ' ', 6, astikit.PadRight, astikit.PadCut)...)                                         // Creation date
+	o = append(o, astikit.BytesPad([]byte(b.revisionDate.Format("060102")), ' ', 6, astikit.PadRight, astikit.PadCut)...)                                         // Revision date
+	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.revisionNumber)), '0', 2, astikit.PadCut)...)                                                            // Revision number
+	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.totalNumberOfTTIBlocks)), '0', 5, astikit.PadCut)...)                                                    // Total number of TTI blocks
+	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.totalNumberOfSubtitles)), '0', 5, astikit.PadCut)...)                                                    // Total number of subtitles
+	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.totalNumberOfSubtitleGroups)), '0', 3, astikit.PadCut)...)                                               // Total number of subtitle groups
+	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.maximumNumberOfDisplayableCharactersInAnyTextRow)), '0', 2, astikit.PadCut)...)                          // Maximum number of displayable characters in any text row
+	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.maximumNumberOfDisplayableRows)), '0', 2, astikit.PadCut)...)                                            // Maximum number of displayable rows
+	o = append(o, astikit.BytesPad([]byte(b.timecodeStatus), ' ', 1, astikit.PadRight, astikit.PadCut)...)                                                        // Timecode status
+	o = append(o, astikit.BytesPad([]byte(formatDurationSTL(b.timecodeStartOfProgramme, b.framerate, b.dropFrame)), ' ', 8, astikit.PadRight, astikit.PadCut)...) // Timecode start of a programme
+	o = append(o, astikit.BytesPad([]byte(formatDurationSTL(b.timecodeFirstInCue, b.framerate, b.dropFrame)), ' ', 8, astikit.PadRight, astikit.PadCut)...)       // Timecode first in cue
+	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.totalNumberOfDisks)), ' ', 1, astikit.PadRight, astikit.PadCut)...)                                      // Total number of disks
+	o = append(o, astikit.BytesPad([]byte(strconv.Itoa(b.diskSequenceNumber)), ' ', 1, astikit.PadRight, astikit.PadCut)...)                                      // Disk sequence number
+	o = append(o, astikit.BytesPad([]byte(b.countryOfOrigin), ' ', 3, astikit.PadRight, astikit.PadCut)...)                                                       // Country of origin
+	o = append(o, astikit.BytesPad([]byte(b.publisher), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                                            // Publisher
+	o = append(o, astikit.BytesPad([]byte(b.editorName), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                                           // Editor's name
+	o = append(o, astikit.BytesPad([]byte(b.editorContactDetails), ' ', 32, astikit.PadRight, astikit.PadCut)...)                                                 // Editor's contact details
+	o = append(o, astikit.BytesPad([]byte{}, ' ', 75+576, astikit.PadRight, astikit.PadCut)...)                                                                   // Spare bytes + user defined area                                                                                           //                                                                                                                      // Editor's contact details
 	return
 }
 
 // parseDurationSTL parses a STL duration
-func parseDurationSTL(i string, framerate int) (d time.Duration, err error) {
+func parseDurationSTL(i string, framerate int, dropFrame bool) (d time.Duration, err error) {
 	// Parse hours
 	var hours, hoursString = 0, i[0:2]
 	if hours, err = strconv.Atoi(hoursString); err != nil {
@@ -613,43 +684,14 @@ func parseDurationSTL(i string, framerate int) (d time.Duration, err error) {
 	}
 
 	// Set duration
-	d = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second + time.Duration(1e9*frames/framerate)*time.Nanosecond
+	d = timecodeToDuration(hours, minutes, seconds, frames, framerate, dropFrame)
 	return
 }
 
 // formatDurationSTL formats a STL duration
-func formatDurationSTL(d time.Duration, framerate int) (o string) {
-	// Add hours
-	if d.Hours() < 10 {
-		o += "0"
-	}
-	var delta = int(math.Floor(d.Hours()))
-	o += strconv.Itoa(delta)
-	d -= time.Duration(delta) * time.Hour
-
-	// Add minutes
-	if d.Minutes() < 10 {
-		o += "0"
-	}
-	delta = int(math.Floor(d.Minutes()))
-	o += strconv.Itoa(delta)
-	d -= time.Duration(delta) * time.Minute
-
-	// Add seconds
-	if d.Seconds() < 10 {
-		o += "0"
-	}
-	delta = int(math.Floor(d.Seconds()))
-	o += strconv.Itoa(delta)
-	d -= time.Duration(delta) * time.Second
-
-	// Add frames
-	var frames = int(int(d.Nanoseconds()) * framerate / 1e9)
-	if frames < 10 {
-		o += "0"
-	}
-	o += strconv.Itoa(frames)
-	return
+func formatDurationSTL(d time.Duration, framerate int, dropFrame bool) (o string) {
+	hours, minutes, seconds, frames := durationToTimecode(d, framerate, dropFrame)
+	return fmt.Sprintf("%02d%02d%02d%02d", hours, minutes, seconds, frames)
 }
 
 // ttiBlock represents a TTI block
@@ -684,6 +726,10 @@ func newTTIBlock(i *Item, idx int) (t *ttiBlock) {
 	// Add text
 	var lines []string
 	for _, l := range i.Lines {
+		var ok bool
+		if l, ok = l.textLine(); !ok {
+			continue
+		}
 		var lineItems []string
 		for _, li := range l.Items {
 			lineItems = append(lineItems, li.STLString())
@@ -715,6 +761,8 @@ func stlJustificationCodeFromStyle(sa *StyleAttributes) byte {
 func stlVerticalPositionFromStyle(sa *StyleAttributes) int {
 	if sa != nil && sa.STLPosition != nil {
 		return sa.STLPosition.VerticalPosition
+	} else if sa != nil && sa.TeletextRow != nil {
+		return *sa.TeletextRow
 	} else {
 		return 20
 	}
@@ -737,7 +785,7 @@ func (li LineItem) STLString() string {
 }
 
 // parseTTIBlock parses a TTI block
-func parseTTIBlock(p []byte, framerate int) *ttiBlock {
+func parseTTIBlock(p []byte, framerate int, dropFrame bool) *ttiBlock {
 	return &ttiBlock{
 		commentFlag:          p[15],
 		cumulativeStatus:     p[4],
@@ -746,8 +794,8 @@ func parseTTIBlock(p []byte, framerate int) *ttiBlock {
 		subtitleGroupNumber:  int(uint8(p[0])),
 		subtitleNumber:       int(binary.LittleEndian.Uint16(p[1:3])),
 		text:                 p[16:128],
-		timecodeIn:           parseDurationSTLBytes(p[5:9], framerate),
-		timecodeOut:          parseDurationSTLBytes(p[9:13], framerate),
+		timecodeIn:           parseDurationSTLBytes(p[5:9], framerate, dropFrame),
+		timecodeOut:          parseDurationSTLBytes(p[9:13], framerate, dropFrame),
 		verticalPosition:     int(uint8(p[13])),
 	}
 }
@@ -760,8 +808,8 @@ func (t *ttiBlock) bytes(g *gsiBlock) (o []byte) {
 	o = append(o, b...)                                                                                              // Subtitle number
 	o = append(o, byte(uint8(t.extensionBlockNumber)))                                                               // Extension block number
 	o = append(o, t.cumulativeStatus)                                                                                // Cumulative status
-	o = append(o, formatDurationSTLBytes(t.timecodeIn, g.framerate)...)                                              // Timecode in
-	o = append(o, formatDurationSTLBytes(t.timecodeOut, g.framerate)...)                                             // Timecode out
+	o = append(o, formatDurationSTLBytes(t.timecodeIn, g.framerate, g.dropFrame)...)                                 // Timecode in
+	o = append(o, formatDurationSTLBytes(t.timecodeOut, g.framerate, g.dropFrame)...)                                // Timecode out
 	o = append(o, validateVerticalPosition(t.verticalPosition, g.displayStandardCode))                               // Vertical position
 	o = append(o, t.justificationCode)                                                                               // Justification code
 	o = append(o, t.commentFlag)                                                                                     // Comment flag
@@ -777,46 +825,34 @@ func (t *ttiBlock) bytes(g *gsiBlock) (o []byte) {
 // Teletext ("closed") subtitles are indicated via the Display Standard Code
 // in the GSI block.
 func validateVerticalPosition(vp int, dsc string) byte {
-	closed := false
-	switch dsc {
-	case stlDisplayStandardCodeLevel1Teletext, stlDisplayStandardCodeLevel2Teletext:
-		closed = true
-	}
+	closed := isTeletextDisplayStandardCode(dsc)
 	if vp < 1 && closed {
 		vp = 1
 	}
-	if vp > 23 && closed {
-		vp = 23
+	if vp > teletextMaxRows && closed {
+		vp = teletextMaxRows
 	}
 	return byte(uint8(vp))
 }
 
+// isTeletextDisplayStandardCode returns whether the display standard code indicates teletext subtitles
+func isTeletextDisplayStandardCode(dsc string) bool {
+	switch dsc {
+	case stlDisplayStandardCodeLevel1Teletext, stlDisplayStandardCodeLevel2Teletext:
+		return true
+	}
+	return false
+}
+
 // formatDurationSTLBytes formats a STL duration in bytes
-func formatDurationSTLBytes(d time.Duration, framerate int) (o []byte) {
-	// Add hours
-	var hours = int(math.Floor(d.Hours()))
-	o = append(o, byte(uint8(hours)))
-	d -= time.Duration(hours) * time.Hour
-
-	// Add minutes
-	var minutes = int(math.Floor(d.Minutes()))
-	o = append(o, byte(uint8(minutes)))
-	d -= time.Duration(minutes) * time.Minute
-
-	// Add seconds
-	var seconds = int(math.Floor(d.Seconds()))
-	o = append(o, byte(uint8(seconds)))
-	d -= time.Duration(seconds) * time.Second
-
-	// Add frames
-	var frames = int(int(d.Nanoseconds()) * framerate / 1e9)
-	o = append(o, byte(uint8(frames)))
-	return
+func formatDurationSTLBytes(d time.Duration, framerate int, dropFrame bool) (o []byte) {
+	hours, minutes, seconds, frames := durationToTimecode(d, framerate, dropFrame)
+	return []byte{byte(uint8(hours)), byte(uint8(minutes)), byte(uint8(seconds)), byte(uint8(frames))}
 }
 
 // parseDurationSTLBytes parses a STL duration in bytes
-func parseDurationSTLBytes(b []byte, framerate int) time.Duration {
-	return time.Duration(uint8(b[0]))*time.Hour + time.Duration(uint8(b[1]))*time.Minute + time.Duration(uint8(b[2]))*time.Second + time.Duration(1e9*int(uint8(b[3]))/framerate)*time.Nanosecond
+func parseDurationSTLBytes(b []byte, framerate int, dropFrame bool) time.Duration {
+	return timecodeToDuration(int(uint8(b[0])), int(uint8(b[1])), int(uint8(b[2])), int(uint8(b[3])), framerate, dropFrame)
 }
 
 type stlCharacterHandler struct {
@@ -910,16 +946,42 @@ func (s *stlStyler) update(sa *StyleAttributes) {
 	}
 }
 
+// WriteToSTLOptions represents STL write options
+type WriteToSTLOptions struct {
+	// DropFrame writes timecodes of 30 fps subtitles as 29.97 fps drop frame timecodes
+	DropFrame bool
+	// TimecodeOffset is added to all emitted timecodes
+	TimecodeOffset time.Duration
+	// TimecodeRelativeToStartOfProgramme adds Metadata.STLTimecodeStartOfProgramme to all emitted timecodes
+	TimecodeRelativeToStartOfProgramme bool
+}
+
 // WriteToSTL writes subtitles in .stl format
 func (s Subtitles) WriteToSTL(o io.Writer) (err error) {
+	return s.WriteToSTLWithOptions(o, WriteToSTLOptions{})
+}
+
+// WriteToSTLWithOptions writes subtitles in .stl format with options
+// Items are not modified when timecodes are offset
+func (s Subtitles) WriteToSTLWithOptions(o io.Writer, opts WriteToSTLOptions) (err error) {
 	// Do not write anything if no subtitles
 	if len(s.Items) == 0 {
 		err = ErrNoSubtitlesToWrite
 		return
 	}
 
-	// Write GSI block
+	// Create GSI block
 	var g = newGSIBlock(s)
+	g.dropFrame = opts.DropFrame && g.framerate == 30
+
+	// Get timecode offset
+	var offset = opts.TimecodeOffset
+	if opts.TimecodeRelativeToStartOfProgramme {
+		offset += g.timecodeStartOfProgramme
+	}
+	g.timecodeFirstInCue += offset
+
+	// Write GSI block
 	if _, err = o.Write(g.bytes()); err != nil {
 		err = fmt.Errorf("astisub: writing gsi block failed: %w", err)
 		return
@@ -927,8 +989,13 @@ func (s Subtitles) WriteToSTL(o io.Writer) (err error) {
 
 	// Loop through items
 	for idx, item := range s.Items {
+		// Create tti block
+		var t = newTTIBlock(item, idx+1)
+		t.timecodeIn += offset
+		t.timecodeOut += offset
+
 		// Write tti block
-		if _, err = o.Write(newTTIBlock(item, idx+1).bytes(g)); err != nil {
+		if _, err = o.Write(t.bytes(g)); err != nil {
 			err = fmt.Errorf("astisub: writing tti block #%d failed: %w", idx+1, err)
 			return
 		}
@@ -1017,6 +1084,22 @@ var stlUnicodeMapping = astikit.NewBiMap().
 	Set(byte('\xfe'), "\u014B"). // ŋ
 	Set(byte('\xff'), "\u00AD")  // Soft hyphen
 
+// CharacterSetSTLLatin contains characters of the Latin alphabet STL files can encode (ISO 6937), which is also the
+// teletext Latin alphabet
+var CharacterSetSTLLatin CharacterSet = func(r rune) bool {
+	if CharacterSetASCII(r) {
+		return true
+	}
+	if r == '\n' {
+		return false
+	}
+	if _, ok := stlUnicodeMapping.GetInverse(string(r)); ok {
+		return true
+	}
+	_, ok := stlUnicodeDiacritic.GetInverse(string(r))
+	return ok
+}
+
 // encodeTextSTL encodes the STL text
 func encodeTextSTL(i string) (o []byte) {
 	i = string(norm.NFD.Bytes([]byte(i)))
@@ -10,17 +10,38 @@ import (
 
 func TestSTLDuration(t *testing.T) {
 	// Default
-	d, err := parseDurationSTL("12345678", 100)
+	d, err := parseDurationSTL("12345678", 100, false)
 	assert.NoError(t, err)
 	assert.Equal(t, 12*time.Hour+34*time.Minute+56*time.Second+780*time.Millisecond, d)
-	s := formatDurationSTL(d, 100)
+	s := formatDurationSTL(d, 100, false)
 	assert.Equal(t, "12345678", s)
 
 	// Bytes
-	b := formatDurationSTLBytes(d, 100)
+	b := formatDurationSTLBytes(d, 100, false)
 	assert.Equal(t, []byte{0xc, 0x22, 0x38, 0x4e}, b)
-	d2 := parseDurationSTLBytes([]byte{0xc, 0x22, 0x38, 0x4e}, 100)
+	d2 := parseDurationSTLBytes([]byte{0xc, 0x22, 0x38, 0x4e}, 100, false)
 	assert.Equal(t, d, d2)
+
+	// Drop frame
+	for _, v := range []struct {
+		d  time.Duration
+		tc string
+	}{
+		{d: 60*time.Second + 26633333*time.Nanosecond, tc: "00005929"},
+		{d: 60*time.Second + 60*time.Millisecond, tc: "00010002"},
+		{d: 10*time.Minute - 600*time.Microsecond, tc: "00100000"},
+		{d: time.Hour - 3600*time.Microsecond, tc: "01000000"},
+	} {
+		d, err = parseDurationSTL(v.tc, 30, true)
+		assert.NoError(t, err)
+		assert.Equal(t, v.d, d)
+		assert.Equal(t, v.tc, formatDurationSTL(d, 30, true))
+	}
+
+	// Drop frame is ignored for other framerates
+	d, err = parseDurationSTL("00100000", 25, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, d)
 }
 
 func TestSTLCharacterHandler(t *testing.T) {
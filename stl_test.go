@@ -2,14 +2,17 @@ package astisub_test
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/5rahim/go-astisub"
 	"github.com/asticode/go-astikit"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSTL(t *testing.T) {
@@ -96,3 +99,129 @@ func TestIgnoreTimecodeStartOfProgramme(t *testing.T) {
 	firstStart := 99 * time.Second
 	assert.Equal(t, firstStart, s.Items[0].StartAt, "first start at 0")
 }
+
+func TestWriteToSTLWithOptions(t *testing.T) {
+	r, err := os.Open("./testdata/example-in-nonzero-offset.stl")
+	assert.NoError(t, err)
+	defer r.Close()
+	s, err := astisub.ReadFromSTL(r, astisub.STLOptions{})
+	assert.NoError(t, err)
+	startAt := s.Items[0].StartAt
+
+	// Relative to start of programme
+	w := &bytes.Buffer{}
+	err = s.WriteToSTLWithOptions(w, astisub.WriteToSTLOptions{TimecodeRelativeToStartOfProgramme: true})
+	assert.NoError(t, err)
+	assert.Equal(t, startAt, s.Items[0].StartAt)
+	s2, err := astisub.ReadFromSTL(w, astisub.STLOptions{IgnoreTimecodeStartOfProgramme: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 99*time.Second, s2.Items[0].StartAt)
+
+	// Caller-supplied offset
+	w.Reset()
+	err = s.WriteToSTLWithOptions(w, astisub.WriteToSTLOptions{
+		TimecodeOffset:                     time.Minute,
+		TimecodeRelativeToStartOfProgramme: true,
+	})
+	assert.NoError(t, err)
+	s2, err = astisub.ReadFromSTL(w, astisub.STLOptions{IgnoreTimecodeStartOfProgramme: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 159*time.Second, s2.Items[0].StartAt)
+}
+
+func TestSTLDropFrame(t *testing.T) {
+	s := &astisub.Subtitles{
+		Items: []*astisub.Item{{
+			EndAt:   10*time.Minute + time.Second + 400*time.Microsecond,
+			Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: "test"}}}},
+			StartAt: 10*time.Minute - 600*time.Microsecond,
+		}},
+		Metadata: &astisub.Metadata{Framerate: 30},
+	}
+	w := &bytes.Buffer{}
+	require.NoError(t, s.WriteToSTLWithOptions(w, astisub.WriteToSTLOptions{DropFrame: true}))
+	b := w.Bytes()
+	assert.Equal(t, []byte{0x0, 0xa, 0x0, 0x0}, b[1024+5:1024+9])
+	assert.Equal(t, []byte{0x0, 0xa, 0x1, 0x0}, b[1024+9:1024+13])
+
+	s2, err := astisub.ReadFromSTL(bytes.NewReader(b), astisub.STLOptions{DropFrame: true})
+	require.NoError(t, err)
+	require.Len(t, s2.Items, 1)
+	assert.Equal(t, s.Items[0].StartAt, s2.Items[0].StartAt)
+	assert.Equal(t, s.Items[0].EndAt, s2.Items[0].EndAt)
+
+	// Non drop frame
+	s2, err = astisub.ReadFromSTL(bytes.NewReader(b), astisub.STLOptions{})
+	require.NoError(t, err)
+	require.Len(t, s2.Items, 1)
+	assert.Equal(t, 10*time.Minute, s2.Items[0].StartAt)
+}
+
+func TestSTLTeletextRow(t *testing.T) {
+	// Teletext rows are used as vertical positions
+	s := &astisub.Subtitles{
+		Items: []*astisub.Item{{
+			EndAt:       2 * time.Second,
+			InlineStyle: &astisub.StyleAttributes{TeletextRow: astikit.IntPtr(21)},
+			Lines:       []astisub.Line{{Items: []astisub.LineItem{{Text: "test"}}}},
+			StartAt:     time.Second,
+		}},
+		Metadata: &astisub.Metadata{Framerate: 25, STLDisplayStandardCode: "1"},
+	}
+	w := &bytes.Buffer{}
+	err := s.WriteToSTL(w)
+	assert.NoError(t, err)
+
+	// Vertical positions are read as teletext rows
+	s2, err := astisub.ReadFromSTL(w, astisub.STLOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, s2.Items, 1)
+	assert.Equal(t, 21, s2.Items[0].InlineStyle.STLPosition.VerticalPosition)
+	assert.Equal(t, astikit.IntPtr(21), s2.Items[0].InlineStyle.TeletextRow)
+}
+
+func TestOpenSTLParts(t *testing.T) {
+	// Create parts
+	dir, err := ioutil.TempDir("", "astisub")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	var filenames []string
+	for idx, v := range []struct {
+		startOfProgramme time.Duration
+		text             string
+	}{
+		{startOfProgramme: 10 * time.Hour, text: "part 1"},
+		{startOfProgramme: 10*time.Hour + 30*time.Minute, text: "part 2"},
+	} {
+		s := &astisub.Subtitles{
+			Items: []*astisub.Item{{
+				EndAt:   2 * time.Second,
+				Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: v.text}}}},
+				StartAt: time.Second,
+			}},
+			Metadata: &astisub.Metadata{Framerate: 25, STLTimecodeStartOfProgramme: v.startOfProgramme},
+		}
+		w := &bytes.Buffer{}
+		require.NoError(t, s.WriteToSTLWithOptions(w, astisub.WriteToSTLOptions{TimecodeRelativeToStartOfProgramme: true}))
+		filename := filepath.Join(dir, fmt.Sprintf("part-%d.stl", idx+1))
+		require.NoError(t, ioutil.WriteFile(filename, w.Bytes(), 0644))
+		filenames = append(filenames, filename)
+	}
+
+	// Open parts
+	s, err := astisub.OpenSTLParts(filenames, astisub.STLOptions{})
+	require.NoError(t, err)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, 10*time.Hour, s.Metadata.STLTimecodeStartOfProgramme)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 30*time.Minute+time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 30*time.Minute+2*time.Second, s.Items[1].EndAt)
+
+	// Invalid part
+	_, err = astisub.OpenSTLParts([]string{filenames[0], filepath.Join(dir, "invalid.stl")}, astisub.STLOptions{})
+	assert.Error(t, err)
+
+	// No parts
+	_, err = astisub.OpenSTLParts(nil, astisub.STLOptions{})
+	assert.EqualError(t, err, "astisub: no stl parts to open")
+}
@@ -3,19 +3,32 @@ package astisub
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/asticode/go-astikit"
 	"golang.org/x/net/html"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	textunicode "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Bytes
@@ -53,7 +66,7 @@ var (
 
 // HTML Escape
 var (
-	htmlEscaper   = strings.NewReplacer("&", "&amp;", "<", "&lt;", "\u00A0", "&nbsp;")
+	htmlEscaper   = strings.NewReplacer("<", "&lt;", "\u00A0", "&nbsp;")
 	htmlUnescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&nbsp;", "\u00A0")
 )
 
@@ -64,9 +77,14 @@ var Now = func() time.Time {
 
 // Options represents open or write options
 type Options struct {
+	// Charset is the charset of plain text formats such as .srt or .ssa (e.g. "windows-1252" or "iso-8859-1"), it is
+	// detected when empty. See NewUTF8Reader. WebVTT is always UTF-8.
+	Charset  string
 	Filename string
+	SRT      SRTOptions
 	Teletext TeletextOptions
 	STL      STLOptions
+	TTML     TTMLOptions
 }
 
 // Open opens a subtitle reader based on options
@@ -79,18 +97,43 @@ func Open(o Options) (s *Subtitles, err error) {
 	}
 	defer f.Close()
 
+	// Decode plain text formats
+	var ext = filepath.Ext(strings.ToLower(o.Filename))
+	var r io.Reader = f
+	switch ext {
+	case ".ass", ".csv", ".jss", ".lrc", ".mpl", ".sami", ".sbv", ".smi", ".srt", ".ssa":
+		if r, err = NewUTF8Reader(f, o.Charset); err != nil {
+			err = fmt.Errorf("astisub: decoding %s failed: %w", o.Filename, err)
+			return
+		}
+	}
+
 	// Parse the content
-	switch filepath.Ext(strings.ToLower(o.Filename)) {
+	switch ext {
+	case ".csv":
+		s, err = ReadFromCSV(r)
+	case ".jss":
+		s, err = ReadFromJACOSub(r)
+	case ".lrc":
+		s, err = ReadFromLRC(r)
+	case ".mpl":
+		s, err = ReadFromMPL2(r)
+	case ".sbv":
+		s, err = ReadFromSBV(r)
+	case ".scc":
+		s, err = ReadFromSCC(f)
+	case ".smi", ".sami":
+		s, err = ReadFromSAMI(r)
 	case ".srt":
-		s, err = ReadFromSRT(f)
+		s, err = ReadFromSRTWithOptions(r, o.SRT)
 	case ".ssa", ".ass":
-		s, err = ReadFromSSA(f)
+		s, err = ReadFromSSA(r)
 	case ".stl":
 		s, err = ReadFromSTL(f, o.STL)
 	case ".ts":
 		s, err = ReadFromTeletext(f, o.Teletext)
-	case ".ttml":
-		s, err = ReadFromTTML(f)
+	case ".ttml", ".dfxp", ".itt":
+		s, err = ReadFromTTMLWithOptions(f, o.TTML)
 	case ".vtt":
 		s, err = ReadFromWebVTT(f)
 	default:
@@ -99,6 +142,47 @@ func Open(o Options) (s *Subtitles, err error) {
 	return
 }
 
+// NewUTF8Reader returns a reader decoding i to UTF-8 based on its charset, using names such as "windows-1252",
+// "iso-8859-1" or "utf-16le".
+// If charset is empty, UTF-16 is detected with its BOM, valid UTF-8 is kept as is and anything else is considered
+// Windows-1252, which is a superset of ISO-8859-1 printable characters.
+// A BOM always takes precedence over charset and is removed.
+func NewUTF8Reader(i io.Reader, charset string) (o io.Reader, err error) {
+	// Charset is provided
+	if charset != "" {
+		var e encoding.Encoding
+		if e, err = htmlindex.Get(charset); err != nil {
+			err = fmt.Errorf("astisub: getting encoding of charset %s failed: %w", charset, err)
+			return
+		}
+		o = transform.NewReader(i, textunicode.BOMOverride(e.NewDecoder()))
+		return
+	}
+
+	// Read content
+	var b []byte
+	if b, err = ioutil.ReadAll(i); err != nil {
+		err = fmt.Errorf("astisub: reading failed: %w", err)
+		return
+	}
+
+	// Detect charset
+	var d transform.Transformer
+	switch {
+	case bytes.HasPrefix(b, BytesBOM):
+		b = b[len(BytesBOM):]
+	case bytes.HasPrefix(b, []byte{0xff, 0xfe}), bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+		d = textunicode.UTF16(textunicode.LittleEndian, textunicode.ExpectBOM).NewDecoder()
+	case !utf8.Valid(b):
+		d = charmap.Windows1252.NewDecoder()
+	}
+	o = bytes.NewReader(b)
+	if d != nil {
+		o = transform.NewReader(o, d)
+	}
+	return
+}
+
 // OpenFile opens a file regardless of other options
 func OpenFile(filename string) (*Subtitles, error) {
 	return Open(Options{Filename: filename})
@@ -108,8 +192,10 @@ func OpenFile(filename string) (*Subtitles, error) {
 type Subtitles struct {
 	Items    []*Item
 	Metadata *Metadata
-	Regions  map[string]*Region
-	Styles   map[string]*Style
+	// PreserveOrder prevents Fragment, Merge and Unfragment from ordering items by their start time
+	PreserveOrder bool
+	Regions       map[string]*Region
+	Styles        map[string]*Style
 }
 
 // NewSubtitles creates new subtitles
@@ -123,14 +209,19 @@ func NewSubtitles() *Subtitles {
 
 // Item represents a text to show between 2 time boundaries with formatting
 type Item struct {
-	Comments    []string
+	Comments []string
+	// ID is the cue identifier, such as the WebVTT cue identifier or the TTML xml:id
+	ID          string
 	Index       int
 	EndAt       time.Duration
 	InlineStyle *StyleAttributes
 	Lines       []Line
-	Region      *Region
-	StartAt     time.Duration
-	Style       *Style
+	// RawPayload is the untouched cue text, lines being separated by "\n", as read by ReadFromWebVTT so that tags
+	// that are not modeled can be written back with WriteToWebVTTOptions.RawPayload
+	RawPayload string
+	Region     *Region
+	StartAt    time.Duration
+	Style      *Style
 }
 
 // String implements the Stringer interface
@@ -142,6 +233,128 @@ func (i Item) String() string {
 	return strings.Join(os, " - ")
 }
 
+// IsSign returns whether the item is a sign or typesetting rather than spoken dialogue.
+// Items with a scrolling effect, drawings, positioning, rotation or karaoke ("\k", "\kf", "\ko") overrides or a style
+// whose name starts with a well-known signs name ("Sign", "Signs", "Typeset", "Typesetting", "KFX" or "Karaoke",
+// e.g. "Signs - Top") are considered signs.
+func (i Item) IsSign() bool {
+	if i.InlineStyle != nil && i.InlineStyle.SSAEffect != "" {
+		return true
+	}
+	if i.Style != nil && ssaRegexpSignStyleName.MatchString(i.Style.ID) {
+		return true
+	}
+	for _, l := range i.Lines {
+		for _, li := range l.Items {
+			if li.isDrawing() {
+				return true
+			}
+			if li.InlineStyle != nil && (ssaRegexpPositioning.MatchString(li.InlineStyle.SSAEffect) || ssaRegexpKaraoke.MatchString(li.InlineStyle.SSAEffect)) {
+				return true
+			}
+			if li.Style != nil && ssaRegexpSignStyleName.MatchString(li.Style.ID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CharacterCount returns the number of visible characters of the item.
+// Drawings, line breaks, control characters and combining marks are not counted so that each count roughly
+// matches a grapheme, and leftover markup such as "<i>" or "{\an8}" is ignored.
+func (i Item) CharacterCount() (n int) {
+	for _, l := range i.Lines {
+		for _, li := range l.Items {
+			if li.isDrawing() {
+				continue
+			}
+			n += VisibleLength(markupRegexpTag.ReplaceAllString(li.Text, ""))
+		}
+	}
+	return
+}
+
+// VisibleLength returns the number of user-perceived characters of a text, control characters such as line breaks
+// not being counted.
+// Combining marks, variation selectors, emoji modifiers and tags, zero width joiner sequences, regional indicator
+// pairs (flags) and Hangul syllables made of jamos are counted as a single character.
+func VisibleLength(text string) (n int) {
+	var join, regionalIndicator bool
+	var prev rune
+	for _, r := range text {
+		// Control characters are not visible
+		if unicode.IsControl(r) {
+			join, regionalIndicator, prev = false, false, 0
+			continue
+		}
+
+		// Check whether the rune extends the previous character
+		var extends bool
+		switch {
+		case prev == 0:
+		case join:
+			extends = true
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+			extends = true
+		case r == 0x200d:
+			extends = true
+		case r >= 0x1f3fb && r <= 0x1f3ff, r >= 0xe0020 && r <= 0xe007f:
+			extends = true
+		case isRegionalIndicator(r) && regionalIndicator:
+			extends = true
+		case r >= 0x1160 && isHangulJamo(r) && (isHangulJamo(prev) || isHangulSyllable(prev)):
+			extends = true
+		}
+
+		// Update state
+		join = r == 0x200d
+		regionalIndicator = isRegionalIndicator(r) && !(extends && regionalIndicator)
+		prev = r
+		if !extends {
+			n++
+		}
+	}
+	return
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+func isHangulJamo(r rune) bool {
+	return (r >= 0x1100 && r <= 0x11ff) || (r >= 0xa960 && r <= 0xa97f) || (r >= 0xd7b0 && r <= 0xd7ff)
+}
+
+func isHangulSyllable(r rune) bool {
+	return r >= 0xac00 && r <= 0xd7a3
+}
+
+// PlainText returns the text of the item's lines joined with a space, markup tags being removed and HTML entities
+// being unescaped. Drawings and empty lines are skipped.
+func (i Item) PlainText() string {
+	var ts []string
+	for _, l := range i.Lines {
+		tl, ok := l.textLine()
+		if !ok {
+			continue
+		}
+		var t string
+		for _, li := range tl.Items {
+			t += stripTags(li.Text)
+		}
+		if t = strings.TrimSpace(t); t != "" {
+			ts = append(ts, t)
+		}
+	}
+	return strings.Join(ts, " ")
+}
+
+// stripTags removes markup tags such as "<i>" or "{\an8}" and unescapes HTML entities
+func stripTags(i string) string {
+	return unescapeHTML(markupRegexpTag.ReplaceAllString(i, ""))
+}
+
 // Color represents a color
 type Color struct {
 	Alpha, Blue, Green, Red uint8
@@ -173,6 +386,88 @@ func (c *Color) TTMLString() string {
 	return fmt.Sprintf("%.6x", uint32(c.Red)<<16|uint32(c.Green)<<8|uint32(c.Blue))
 }
 
+// colorsByName indexes named colors by their lowercase CSS name
+var colorsByName = map[string]*Color{
+	"aqua":    ColorCyan,
+	"black":   ColorBlack,
+	"blue":    ColorBlue,
+	"cyan":    ColorCyan,
+	"fuchsia": ColorMagenta,
+	"gray":    ColorGray,
+	"green":   ColorGreen,
+	"grey":    ColorGray,
+	"lime":    ColorLime,
+	"magenta": ColorMagenta,
+	"maroon":  ColorMaroon,
+	"navy":    ColorNavy,
+	"olive":   ColorOlive,
+	"purple":  ColorPurple,
+	"red":     ColorRed,
+	"silver":  ColorSilver,
+	"teal":    ColorTeal,
+	"white":   ColorWhite,
+	"yellow":  ColorYellow,
+}
+
+// NewColorFromName builds a new color based on a case insensitive CSS color name such as "red"
+func NewColorFromName(name string) (*Color, bool) {
+	c, ok := colorsByName[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, false
+	}
+	var o = *c
+	return &o, true
+}
+
+// NewColorFromHexString builds a new color based on a "#RRGGBB" or "#RRGGBBAA" string, AA being the opacity
+func NewColorFromHexString(s string) (c *Color, err error) {
+	// Check length
+	var h = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(h) != 6 && len(h) != 8 {
+		err = fmt.Errorf("astisub: invalid hex color %s", s)
+		return
+	}
+
+	// Parse
+	var i uint64
+	if i, err = strconv.ParseUint(h, 16, 32); err != nil {
+		err = fmt.Errorf("astisub: parsing hex color %s failed: %w", s, err)
+		return
+	}
+	if len(h) == 6 {
+		i = i<<8 | 0xff
+	}
+	c = &Color{
+		Alpha: 0xff - uint8(i),
+		Blue:  uint8(i >> 8),
+		Green: uint8(i >> 16),
+		Red:   uint8(i >> 24),
+	}
+	return
+}
+
+// HexString expresses the color as a "#rrggbb" string, or "#rrggbbaa" if it's not opaque
+func (c *Color) HexString() string {
+	if c.Alpha == 0 {
+		return "#" + c.TTMLString()
+	}
+	return fmt.Sprintf("#%s%.2x", c.TTMLString(), 0xff-c.Alpha)
+}
+
+// Equal returns whether both colors have the same components, nil colors being only equal to each other
+func (c *Color) Equal(o *Color) bool {
+	if c == nil || o == nil {
+		return c == o
+	}
+	return *c == *o
+}
+
+// IsZero returns whether the color is nil, meaning no color is set. A color with only zero components is opaque black
+// and is therefore not zero.
+func (c *Color) IsZero() bool {
+	return c == nil
+}
+
 type Justification int
 
 var (
@@ -184,6 +479,30 @@ var (
 
 // StyleAttributes represents style attributes
 type StyleAttributes struct {
+	EBUTTLinePadding     *string
+	EBUTTMultiRowAlign   *string
+	JACOSubBold          bool
+	JACOSubDirective     string // e.g. "VTJL" for top left
+	JACOSubItalics       bool
+	JACOSubUnderline     bool
+	MicroDVDBold         bool
+	MicroDVDColor        *Color
+	MicroDVDFontName     string
+	MicroDVDFontSize     *int
+	MicroDVDItalics      bool
+	MicroDVDStrikeout    bool
+	MicroDVDUnderline    bool
+	SAMIColor            *string
+	SAMIFontFamily       *string
+	SAMIFontSize         *string
+	SAMIFontStyle        *string
+	SAMIFontWeight       *string
+	SAMILang             string // e.g. "en-US"
+	SAMIName             string // e.g. "English"
+	SAMITextAlign        *string
+	SCCColor             string // e.g. "red", empty meaning white
+	SCCItalics           bool
+	SCCUnderline         bool
 	SRTBold              bool
 	SRTColor             *string
 	SRTItalics           bool
@@ -195,6 +514,7 @@ type StyleAttributes struct {
 	SSABackColour        *Color
 	SSABold              *bool
 	SSABorderStyle       *int
+	SSADrawing           bool // line item contains drawing commands ({\p1}) rather than text
 	SSAEffect            string
 	SSAEncoding          *int
 	SSAFontName          string
@@ -220,12 +540,18 @@ type StyleAttributes struct {
 	STLJustification     *Justification
 	STLPosition          *STLPosition
 	STLUnderline         *bool
+	SpruceBold           bool
+	SpruceItalics        bool
+	SpruceUnderline      bool
 	TeletextColor        *Color
+	TeletextColumn       *int // 0-based column of the first displayed character
 	TeletextDoubleHeight *bool
 	TeletextDoubleSize   *bool
 	TeletextDoubleWidth  *bool
+	TeletextRow          *int // 1-23
 	TeletextSpacesAfter  *int
 	TeletextSpacesBefore *int
+	TTMLAnimations       []TTMLAnimation // TTML2 <animate> children
 	// TODO Use pointers with real types below
 	TTMLBackgroundColor  *string // https://htmlcolorcodes.com/fr/
 	TTMLColor            *string
@@ -298,6 +624,77 @@ func (t WebVTTTag) endTag() string {
 	return "</" + t.Name + ">"
 }
 
+// propagateBIU copies bold, italics and underline to SRT and WebVTT attributes and rebuilds the matching WebVTT tags
+func (sa *StyleAttributes) propagateBIU(b, i, u bool) {
+	sa.SRTBold = b
+	sa.SRTItalics = i
+	sa.SRTUnderline = u
+	sa.WebVTTBold = b
+	sa.WebVTTItalics = i
+	sa.WebVTTUnderline = u
+
+	sa.WebVTTTags = make([]WebVTTTag, 0)
+	if sa.WebVTTBold {
+		sa.WebVTTTags = append(sa.WebVTTTags, WebVTTTag{Name: "b"})
+	}
+	if sa.WebVTTItalics {
+		sa.WebVTTTags = append(sa.WebVTTTags, WebVTTTag{Name: "i"})
+	}
+	if sa.WebVTTUnderline {
+		sa.WebVTTTags = append(sa.WebVTTTags, WebVTTTag{Name: "u"})
+	}
+}
+
+func (sa *StyleAttributes) propagateJACOSubAttributes() {
+	// copy relevant attrs to WebVTT ones
+	switch {
+	case strings.Contains(sa.JACOSubDirective, "JL"):
+		sa.WebVTTAlign = "left"
+	case strings.Contains(sa.JACOSubDirective, "JR"):
+		sa.WebVTTAlign = "right"
+	}
+	switch {
+	case strings.Contains(sa.JACOSubDirective, "VT"):
+		sa.WebVTTLine = "0%"
+	case strings.Contains(sa.JACOSubDirective, "VM"):
+		sa.WebVTTLine = "50%"
+	}
+
+	// copy relevant attrs to SRT and WebVTT ones
+	sa.propagateBIU(sa.JACOSubBold, sa.JACOSubItalics, sa.JACOSubUnderline)
+}
+
+func (sa *StyleAttributes) propagateMicroDVDAttributes() {
+	// copy relevant attrs to SRT, TTML and WebVTT ones
+	if sa.MicroDVDColor != nil {
+		sa.SRTColor = astikit.StrPtr("#" + sa.MicroDVDColor.TTMLString())
+		sa.TTMLColor = astikit.StrPtr("#" + sa.MicroDVDColor.TTMLString())
+	}
+	if sa.MicroDVDFontName != "" {
+		sa.TTMLFontFamily = astikit.StrPtr(sa.MicroDVDFontName)
+	}
+	sa.propagateBIU(sa.MicroDVDBold, sa.MicroDVDItalics, sa.MicroDVDUnderline)
+}
+
+func (sa *StyleAttributes) propagateSAMIAttributes() {
+	// copy relevant attrs to TTML ones
+	sa.TTMLColor = sa.SAMIColor
+	sa.TTMLFontFamily = sa.SAMIFontFamily
+	sa.TTMLFontSize = sa.SAMIFontSize
+	sa.TTMLFontStyle = sa.SAMIFontStyle
+	sa.TTMLFontWeight = sa.SAMIFontWeight
+	sa.TTMLTextAlign = sa.SAMITextAlign
+}
+
+func (sa *StyleAttributes) propagateSCCAttributes() {
+	// copy relevant attrs to SRT, TTML and WebVTT ones
+	if sa.SCCColor != "" {
+		sa.SRTColor = astikit.StrPtr(sa.SCCColor)
+		sa.TTMLColor = astikit.StrPtr(sa.SCCColor)
+	}
+	sa.propagateBIU(false, sa.SCCItalics, sa.SCCUnderline)
+}
+
 func (sa *StyleAttributes) propagateSRTAttributes() {
 	// copy relevant attrs to WebVTT ones
 	if sa.SRTColor != nil {
@@ -332,24 +729,27 @@ func (sa *StyleAttributes) propagateSRTAttributes() {
 		sa.WebVTTPosition = "90%"
 	}
 
-	sa.WebVTTBold = sa.SRTBold
-	sa.WebVTTItalics = sa.SRTItalics
-	sa.WebVTTUnderline = sa.SRTUnderline
-
-	sa.WebVTTTags = make([]WebVTTTag, 0)
-	if sa.WebVTTBold {
-		sa.WebVTTTags = append(sa.WebVTTTags, WebVTTTag{Name: "b"})
-	}
-	if sa.WebVTTItalics {
-		sa.WebVTTTags = append(sa.WebVTTTags, WebVTTTag{Name: "i"})
-	}
-	if sa.WebVTTUnderline {
-		sa.WebVTTTags = append(sa.WebVTTTags, WebVTTTag{Name: "u"})
+	// SSA alignments use the same numpad layout
+	if sa.SRTPosition >= 1 && sa.SRTPosition <= 9 {
+		sa.SSAAlignment = astikit.IntPtr(int(sa.SRTPosition))
 	}
+
+	sa.propagateBIU(sa.SRTBold, sa.SRTItalics, sa.SRTUnderline)
 }
 
 func (sa *StyleAttributes) propagateSSAAttributes() {}
 
+// propagateSSAKaraokeAttributes converts the primary (sung) and secondary (not sung yet) colours into WebVTT styles
+// applying to karaoke line items tagged with class
+func (sa *StyleAttributes) propagateSSAKaraokeAttributes(class string) {
+	if sa.SSAPrimaryColour != nil {
+		sa.WebVTTStyles = append(sa.WebVTTStyles, fmt.Sprintf("::cue(.%s:past) { color: #%s; }", class, sa.SSAPrimaryColour.TTMLString()))
+	}
+	if sa.SSASecondaryColour != nil {
+		sa.WebVTTStyles = append(sa.WebVTTStyles, fmt.Sprintf("::cue(.%s:future) { color: #%s; }", class, sa.SSASecondaryColour.TTMLString()))
+	}
+}
+
 func (sa *StyleAttributes) propagateSTLAttributes() {
 	if sa.STLJustification != nil {
 		switch *sa.STLJustification {
@@ -375,10 +775,19 @@ func (sa *StyleAttributes) propagateSTLAttributes() {
 	}
 }
 
+func (sa *StyleAttributes) propagateSpruceAttributes() {
+	// copy relevant attrs to SRT and WebVTT ones
+	sa.propagateBIU(sa.SpruceBold, sa.SpruceItalics, sa.SpruceUnderline)
+}
+
 func (sa *StyleAttributes) propagateTeletextAttributes() {
 	if sa.TeletextColor != nil {
 		sa.TTMLColor = astikit.StrPtr("#" + sa.TeletextColor.TTMLString())
 	}
+	// converts teletext row to WebVTT line percentage the same way teletext STL vertical positions are
+	if sa.TeletextRow != nil && *sa.TeletextRow > 0 {
+		sa.WebVTTLine = fmt.Sprintf("%d%%", (*sa.TeletextRow-1)*100/teletextMaxRows)
+	}
 }
 
 // reference for migration: https://w3c.github.io/ttml-webvtt-mapping/
@@ -434,10 +843,15 @@ func (sa *StyleAttributes) propagateWebVTTAttributes() {
 // Metadata represents metadata
 // TODO Merge attributes
 type Metadata struct {
+	Album                                               string
+	Artist                                              string
+	Author                                              string // e.g. the creator of an .lrc file
 	Comments                                            []string
 	Framerate                                           int
 	Language                                            string
+	MicroDVDFramerate                                   float64 // e.g. 23.976, Framerate being only set when it's an integer
 	SSACollisions                                       string
+	SSAComments                                         []SSAComment // retimed along items by timing operations such as Add or Stretch
 	SSAOriginalEditing                                  string
 	SSAOriginalScript                                   string
 	SSAOriginalTiming                                   string
@@ -470,7 +884,11 @@ type Metadata struct {
 	STLTranslatorName                                   string
 	Title                                               string
 	TTMLCopyright                                       string
+	TTMLProfile                                         string // e.g. "http://www.w3.org/ns/ttml/profile/dfxp-full"
+	TTMLTickrate                                        int
 	WebVTTTimestampMap                                  *WebVTTTimestampMap
+	WebVTTTitle                                         string   // description following "WEBVTT" in the header line, also set as Title
+	WebVTTTrailingComments                              []string // NOTE blocks following the last cue
 }
 
 // Region represents a subtitle's region
@@ -489,26 +907,71 @@ type Style struct {
 
 // Line represents a set of formatted line items
 type Line struct {
-	Items     []LineItem
-	VoiceName string
+	Items        []LineItem
+	VoiceClasses []string // classes of the WebVTT voice span
+	VoiceName    string
 }
 
 // String implement the Stringer interface
 func (l Line) String() string {
 	var texts []string
 	for _, i := range l.Items {
+		if i.isDrawing() {
+			continue
+		}
 		texts = append(texts, i.Text)
 	}
 	// Don't add spaces here since items must contain their own space
 	return strings.Join(texts, "")
 }
 
+// isEmpty returns whether the line has neither text nor drawing to display
+func (l Line) isEmpty() bool {
+	for _, li := range l.Items {
+		if li.isDrawing() || strings.TrimSpace(li.Text) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// textLine returns the line without its drawing items and whether there's something left to display
+func (l Line) textLine() (o Line, ok bool) {
+	o = Line{VoiceClasses: l.VoiceClasses, VoiceName: l.VoiceName}
+	for _, li := range l.Items {
+		if !li.isDrawing() {
+			o.Items = append(o.Items, li)
+		}
+	}
+	ok = len(o.Items) > 0 || len(l.Items) == 0
+	return
+}
+
+// isDrawingOnly returns whether the item has lines but all of them only contain drawings
+func (i Item) isDrawingOnly() bool {
+	for _, l := range i.Lines {
+		if _, ok := l.textLine(); ok {
+			return false
+		}
+	}
+	return len(i.Lines) > 0
+}
+
 // LineItem represents a formatted line item
 type LineItem struct {
 	InlineStyle *StyleAttributes
-	StartAt     time.Duration
-	Style       *Style
-	Text        string
+	// Language is the BCP 47 language tag of the text when it differs from the subtitles' one
+	Language string
+	// RubyText is the ruby annotation (e.g. furigana) displayed alongside Text, which is the base text
+	RubyText string
+	StartAt  time.Duration
+	Style    *Style
+	Text     string
+}
+
+// isDrawing returns whether the line item contains drawing commands rather than text
+func (li LineItem) isDrawing() bool {
+	return li.InlineStyle != nil && li.InlineStyle.SSADrawing
 }
 
 // Add adds a duration to each time boundaries. As in the time package, duration can be negative.
@@ -523,220 +986,2682 @@ func (s *Subtitles) Add(d time.Duration) {
 			s.Items[idx].StartAt = time.Duration(0)
 		}
 	}
+	s.retimeSSAComments(func(_, t time.Duration) time.Duration { return t + d })
 }
 
-// Duration returns the subtitles duration
-func (s Subtitles) Duration() time.Duration {
-	if len(s.Items) == 0 {
-		return time.Duration(0)
-	}
-	return s.Items[len(s.Items)-1].EndAt
-}
-
-// ForceDuration updates the subtitles duration.
-// If requested duration is bigger, then we create a dummy item.
-// If requested duration is smaller, then we remove useless items and we cut the last item or add a dummy item.
-func (s *Subtitles) ForceDuration(d time.Duration, addDummyItem bool) {
-	// Requested duration is the same as the subtitles'one
-	if s.Duration() == d {
+// retimeSSAComments maps the time boundaries of SSA comments with fn, which receives the comment start time as well,
+// negative results being clamped to 0
+func (s *Subtitles) retimeSSAComments(fn func(startAt, t time.Duration) time.Duration) {
+	if s.Metadata == nil {
 		return
 	}
-
-	// Requested duration is bigger than subtitles'one
-	if s.Duration() > d {
-		// Find last item before input duration and update end at
-		var lastIndex = -1
-		for index, i := range s.Items {
-			// Start at is bigger than input duration, we've found the last item
-			if i.StartAt >= d {
-				lastIndex = index
-				break
-			} else if i.EndAt > d {
-				s.Items[index].EndAt = d
+	for idx := range s.Metadata.SSAComments {
+		c := &s.Metadata.SSAComments[idx]
+		var startAt = c.StartAt
+		for _, t := range []*time.Duration{&c.EndAt, &c.StartAt} {
+			if *t = fn(startAt, *t); *t < 0 {
+				*t = 0
 			}
 		}
-
-		// Last index has been found
-		if lastIndex != -1 {
-			s.Items = s.Items[:lastIndex]
-		}
-	}
-
-	// Add dummy item with the minimum duration possible
-	if addDummyItem && s.Duration() < d {
-		s.Items = append(s.Items, &Item{EndAt: d, Lines: []Line{{Items: []LineItem{{Text: "..."}}}}, StartAt: d - time.Millisecond})
 	}
 }
 
-// Fragment fragments subtitles with a specific fragment duration
-func (s *Subtitles) Fragment(f time.Duration) {
-	// Nothing to fragment
+// ShiftToStartAt shifts the time boundaries of all items so that the earliest item starts at d, as Add would do with
+// the offset between d and the earliest start time
+func (s *Subtitles) ShiftToStartAt(d time.Duration) {
+	// Get earliest start time
 	if len(s.Items) == 0 {
 		return
 	}
-
-	// Here we want to simulate fragments of duration f until there are no subtitles left in that period of time
-	var fragmentStartAt, fragmentEndAt = time.Duration(0), f
-	for fragmentStartAt < s.Items[len(s.Items)-1].EndAt {
-		// We loop through subtitles and process the ones that either contain the fragment start at,
-		// or contain the fragment end at
-		//
-		// It's useless processing subtitles contained between fragment start at and end at
-		//             |____________________|             <- subtitle
-		//           |                        |
-		//   fragment start at        fragment end at
-		for i, sub := range s.Items {
-			// Init
-			var newSub = &Item{}
-			*newSub = *sub
-
-			// A switch is more readable here
-			switch {
-			// Subtitle contains fragment start at
-			// |____________________|                         <- subtitle
-			//           |                        |
-			//   fragment start at        fragment end at
-			case sub.StartAt < fragmentStartAt && sub.EndAt > fragmentStartAt:
-				sub.StartAt = fragmentStartAt
-				newSub.EndAt = fragmentStartAt
-			// Subtitle contains fragment end at
-			//                         |____________________| <- subtitle
-			//           |                        |
-			//   fragment start at        fragment end at
-			case sub.StartAt < fragmentEndAt && sub.EndAt > fragmentEndAt:
-				sub.StartAt = fragmentEndAt
-				newSub.EndAt = fragmentEndAt
-			default:
-				continue
-			}
-
-			// Insert new sub
-			s.Items = append(s.Items[:i], append([]*Item{newSub}, s.Items[i:]...)...)
+	var start = s.Items[0].StartAt
+	for _, i := range s.Items[1:] {
+		if i.StartAt < start {
+			start = i.StartAt
 		}
-
-		// Update fragments boundaries
-		fragmentStartAt += f
-		fragmentEndAt += f
 	}
 
-	// Order
-	s.Order()
+	// Shift
+	if d != start {
+		s.Add(d - start)
+	}
 }
 
-// IsEmpty returns whether the subtitles are empty
-func (s Subtitles) IsEmpty() bool {
-	return len(s.Items) == 0
+// ShiftFrom adds a duration to the time boundaries of each item starting at or after t.
+// As in Add, duration can be negative and items ending before 0 are removed.
+func (s *Subtitles) ShiftFrom(t, d time.Duration) {
+	for idx := 0; idx < len(s.Items); idx++ {
+		if s.Items[idx].StartAt < t {
+			continue
+		}
+		s.Items[idx].EndAt += d
+		s.Items[idx].StartAt += d
+		if s.Items[idx].EndAt <= 0 && s.Items[idx].StartAt <= 0 {
+			s.Items = append(s.Items[:idx], s.Items[idx+1:]...)
+			idx--
+		} else if s.Items[idx].StartAt <= 0 {
+			s.Items[idx].StartAt = time.Duration(0)
+		}
+	}
+	s.retimeSSAComments(func(startAt, v time.Duration) time.Duration {
+		if startAt < t {
+			return v
+		}
+		return v + d
+	})
 }
 
-// Merge merges subtitles i into subtitles
-func (s *Subtitles) Merge(i *Subtitles) {
-	// Append items
-	s.Items = append(s.Items, i.Items...)
-	s.Order()
-
-	// Add regions
+// ShiftRange adds a duration to the time boundaries of each item starting in [from, to).
+// As in Add, duration can be negative and items ending before 0 are removed.
+// Items are ordered afterwards unless PreserveOrder is set.
+func (s *Subtitles) ShiftRange(from, to, d time.Duration) {
+	for idx := 0; idx < len(s.Items); idx++ {
+		if s.Items[idx].StartAt < from || s.Items[idx].StartAt >= to {
+			continue
+		}
+		s.Items[idx].EndAt += d
+		s.Items[idx].StartAt += d
+		if s.Items[idx].EndAt <= 0 && s.Items[idx].StartAt <= 0 {
+			s.Items = append(s.Items[:idx], s.Items[idx+1:]...)
+			idx--
+		} else if s.Items[idx].StartAt <= 0 {
+			s.Items[idx].StartAt = time.Duration(0)
+		}
+	}
+	s.retimeSSAComments(func(startAt, t time.Duration) time.Duration {
+		if startAt < from || startAt >= to {
+			return t
+		}
+		return t + d
+	})
+	s.autoOrder()
+}
+
+// RetimeSegment maps an old time range to a new one
+type RetimeSegment struct {
+	NewFrom, NewTo time.Duration
+	OldFrom, OldTo time.Duration
+}
+
+// contains returns whether the segment contains t in its old time range
+func (s RetimeSegment) contains(t time.Duration) bool {
+	return t >= s.OldFrom && t < s.OldTo
+}
+
+// retime maps t from the old time range to the new one, t being clamped to the old time range beforehand
+func (s RetimeSegment) retime(t time.Duration) time.Duration {
+	if t < s.OldFrom {
+		t = s.OldFrom
+	} else if t > s.OldTo {
+		t = s.OldTo
+	}
+	return s.NewFrom + time.Duration(float64(t-s.OldFrom)*float64(s.NewTo-s.NewFrom)/float64(s.OldTo-s.OldFrom))
+}
+
+// Retime conforms subtitles to a re-edited video based on an edit decision list.
+// Items are moved and scaled according to the segment their StartAt falls in, their EndAt being truncated to the end
+// of that segment. Items falling in none of the segments, such as the ones in removed scenes, are dropped and returned.
+// Items are ordered afterwards unless PreserveOrder is set.
+func (s *Subtitles) Retime(segments []RetimeSegment) (dropped []*Item) {
+	for idx := 0; idx < len(s.Items); idx++ {
+		// Get segment
+		i := s.Items[idx]
+		var sg *RetimeSegment
+		for idxSegment := range segments {
+			if segments[idxSegment].contains(i.StartAt) {
+				sg = &segments[idxSegment]
+				break
+			}
+		}
+
+		// Drop
+		if sg == nil {
+			dropped = append(dropped, i)
+			s.Items = append(s.Items[:idx], s.Items[idx+1:]...)
+			idx--
+			continue
+		}
+
+		// Retime
+		i.EndAt = sg.retime(i.EndAt)
+		i.StartAt = sg.retime(i.StartAt)
+		for idxLine := range i.Lines {
+			for idxLineItem := range i.Lines[idxLine].Items {
+				if li := &i.Lines[idxLine].Items[idxLineItem]; li.StartAt > 0 {
+					li.StartAt = sg.retime(li.StartAt)
+				}
+			}
+		}
+	}
+
+	// Comments falling in none of the segments are kept as is
+	s.retimeSSAComments(func(startAt, t time.Duration) time.Duration {
+		for _, sg := range segments {
+			if sg.contains(startAt) {
+				return sg.retime(t)
+			}
+		}
+		return t
+	})
+
+	// Order
+	s.autoOrder()
+	return
+}
+
+// ClampToDuration truncates items ending after d and removes items starting at or after d.
+// Unlike ForceDuration, it never adds items.
+// It returns the number of truncated items and the number of removed items.
+func (s *Subtitles) ClampToDuration(d time.Duration) (truncated, removed int) {
+	for idx := 0; idx < len(s.Items); idx++ {
+		if s.Items[idx].StartAt >= d {
+			s.Items = append(s.Items[:idx], s.Items[idx+1:]...)
+			removed++
+			idx--
+		} else if s.Items[idx].EndAt > d {
+			s.Items[idx].EndAt = d
+			truncated++
+		}
+	}
+	return
+}
+
+// ClampDurationsReport represents an item whose duration was out of the bounds given to ClampDurations
+type ClampDurationsReport struct {
+	Index int
+	// LimitedByNextItem is true when the item couldn't be extended up to the min duration without going past the
+	// next item's StartAt
+	LimitedByNextItem bool
+}
+
+// ClampDurations extends items lasting less than min, without going past the next item's StartAt, and shortens items
+// lasting more than max. A non-positive min or max disables the corresponding bound.
+// Items are ordered beforehand unless PreserveOrder is set. It returns a report for each item that was out of bounds.
+func (s *Subtitles) ClampDurations(min, max time.Duration) (rs []ClampDurationsReport) {
+	// Order
+	s.autoOrder()
+
+	// Loop through items
+	for idx, i := range s.Items {
+		switch d := i.EndAt - i.StartAt; {
+		case min > 0 && d < min:
+			// Extend item
+			var r = ClampDurationsReport{Index: idx}
+			var endAt = i.StartAt + min
+			if idx < len(s.Items)-1 && s.Items[idx+1].StartAt < endAt {
+				endAt = s.Items[idx+1].StartAt
+				r.LimitedByNextItem = true
+			}
+			if endAt > i.EndAt {
+				i.EndAt = endAt
+			}
+			rs = append(rs, r)
+		case max > 0 && d > max:
+			// Shorten item
+			i.EndAt = i.StartAt + max
+			rs = append(rs, ClampDurationsReport{Index: idx})
+		}
+	}
+	return
+}
+
+// EnforceMinGap pulls back the EndAt of items followed by an item starting less than gap after they end, so that
+// there's at least gap between them. Items that would then last less than minDuration are left untouched and their
+// indexes are returned instead.
+// Items are ordered beforehand unless PreserveOrder is set.
+func (s *Subtitles) EnforceMinGap(gap, minDuration time.Duration) (offending []int) {
+	// Order
+	s.autoOrder()
+
+	// Loop through items
+	for idx := 0; idx < len(s.Items)-1; idx++ {
+		// Gap is big enough
+		var i, n = s.Items[idx], s.Items[idx+1]
+		if n.StartAt-i.EndAt >= gap {
+			continue
+		}
+
+		// Item would be too short
+		if endAt := n.StartAt - gap; endAt-i.StartAt < minDuration {
+			offending = append(offending, idx)
+		} else {
+			i.EndAt = endAt
+		}
+	}
+	return
+}
+
+// Filter removes items for which keep returns false, the order of the other items being preserved.
+// Regions and styles are left untouched, see Optimize to remove unused ones. It returns the number of removed items.
+func (s *Subtitles) Filter(keep func(*Item) bool) (removed int) {
+	for idx := 0; idx < len(s.Items); idx++ {
+		if !keep(s.Items[idx]) {
+			s.Items = append(s.Items[:idx], s.Items[idx+1:]...)
+			removed++
+			idx--
+		}
+	}
+	return
+}
+
+// FilterEmpty returns a Filter predicate removing items without text or drawings
+func FilterEmpty() func(*Item) bool {
+	return func(i *Item) bool {
+		for _, l := range i.Lines {
+			if !l.isEmpty() {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterShorterThan returns a Filter predicate removing items lasting less than d
+func FilterShorterThan(d time.Duration) func(*Item) bool {
+	return func(i *Item) bool {
+		return i.EndAt-i.StartAt >= d
+	}
+}
+
+// DialogueOnly removes signs, typesetting and songs, which mostly come from .ssa/.ass files, so that only spoken
+// dialogue is left. See Item.IsSign for the heuristics. It returns the number of removed items.
+func (s *Subtitles) DialogueOnly() (removed int) {
+	for idx := 0; idx < len(s.Items); idx++ {
+		if s.Items[idx].IsSign() {
+			s.Items = append(s.Items[:idx], s.Items[idx+1:]...)
+			removed++
+			idx--
+		}
+	}
+	return
+}
+
+// Duration returns the subtitles duration
+func (s Subtitles) Duration() time.Duration {
+	if len(s.Items) == 0 {
+		return time.Duration(0)
+	}
+	return s.Items[len(s.Items)-1].EndAt
+}
+
+// DeduplicateStyles merges styles having the same attributes once resolved against their parent styles, keeping the
+// one whose ID comes first alphabetically unless it inherits from the other one. Items, line items, regions and
+// styles referencing removed styles are updated.
+// It returns the IDs of the removed styles mapped to the IDs of the styles they've been merged into.
+func (s *Subtitles) DeduplicateStyles() (o map[string]string) {
+	// Get sorted IDs
+	var ids []string
+	for id := range s.Styles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Get replacements
+	var replacements = make(map[*Style]*Style)
+	resolve := func(st *Style) *Style {
+		for r, ok := replacements[st]; ok; r, ok = replacements[st] {
+			st = r
+		}
+		return st
+	}
+	inherits := func(st, from *Style) bool {
+		var visited = make(map[*Style]bool)
+		for p := resolve(st.Style); p != nil && !visited[p]; p = resolve(p.Style) {
+			if p == from {
+				return true
+			}
+			visited[p] = true
+		}
+		return false
+	}
+
+	// Merging styles doesn't change resolved attributes, so a single pass is enough
+	var kept []*Style
+	var attributes = make(map[*Style]StyleAttributes)
+	for _, id := range ids {
+		// Look for an identical style
+		var st = s.Styles[id]
+		attributes[st] = st.resolvedAttributes()
+		var identical *Style
+		for _, k := range kept {
+			if styleAttributesEqual(attributes[k], attributes[st]) && !inherits(k, st) {
+				identical = k
+				break
+			}
+		}
+
+		// Merge style
+		if identical != nil {
+			replacements[st] = identical
+		} else {
+			kept = append(kept, st)
+		}
+	}
+
+	// Nothing to merge
+	o = make(map[string]string)
+	if len(replacements) == 0 {
+		return
+	}
+
+	// Remove styles
+	for _, id := range ids {
+		if r := resolve(s.Styles[id]); r != s.Styles[id] {
+			o[id] = r.ID
+			delete(s.Styles, id)
+		}
+	}
+
+	// Update references
+	for _, st := range s.Styles {
+		if st.Style != nil {
+			st.Style = resolve(st.Style)
+		}
+	}
+	for _, r := range s.Regions {
+		if r.Style != nil {
+			r.Style = resolve(r.Style)
+		}
+	}
+	for _, i := range s.Items {
+		if i.Style != nil {
+			i.Style = resolve(i.Style)
+		}
+		for idxLine := range i.Lines {
+			for idxLineItem, li := range i.Lines[idxLine].Items {
+				if li.Style != nil {
+					i.Lines[idxLine].Items[idxLineItem].Style = resolve(li.Style)
+				}
+			}
+		}
+	}
+	return
+}
+
+// Common framerate conversion factors
+var framerateConversionFactors = []float64{
+	25 / 23.976,
+	23.976 / 25,
+	25.0 / 24,
+	24.0 / 25,
+	29.97 / 25,
+	25 / 29.97,
+	29.97 / 23.976,
+	23.976 / 29.97,
+}
+
+// Maximum relative difference between a ratio and a framerate conversion factor for them to be considered equal
+const framerateConversionFactorTolerance = 0.005
+
+// DetectFramerateMismatch compares the subtitles duration to the media duration and returns whether their
+// ratio suggests subtitles were timed for another framerate. When a mismatch is suspected, the returned ratio is
+// the closest common framerate conversion factor (e.g. 1.0427 for 25 -> 23.976 fps), otherwise it's the measured
+// ratio. Time boundaries should be divided by the ratio for the subtitles to match the media.
+func (s Subtitles) DetectFramerateMismatch(mediaDuration time.Duration) (ratio float64, suspected bool) {
+	// Nothing to compare
+	d := s.Duration()
+	if d <= 0 || mediaDuration <= 0 {
+		return
+	}
+
+	// Get measured ratio
+	measured := float64(d) / float64(mediaDuration)
+	ratio = measured
+
+	// Find closest conversion factor
+	var delta = framerateConversionFactorTolerance
+	for _, f := range framerateConversionFactors {
+		if v := math.Abs(measured-f) / f; v <= delta {
+			delta = v
+			ratio = f
+			suspected = true
+		}
+	}
+	return
+}
+
+// Fingerprint returns a hash of the items' time boundaries and plain text.
+// It doesn't depend on indexes, styles or whitespace differences, which makes it suitable for deduplication.
+func (s Subtitles) Fingerprint() string {
+	h := sha256.New()
+	for _, i := range s.Items {
+		// Get normalized lines
+		var ls []string
+		for _, l := range i.Lines {
+			if t := strings.Join(strings.Fields(l.String()), " "); t != "" {
+				ls = append(ls, t)
+			}
+		}
+
+		// Write item
+		fmt.Fprintf(h, "%d\x00%d\x00%s\x00", i.StartAt, i.EndAt, strings.Join(ls, "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// frameDuration returns the duration of a frame, falling back on the metadata framerate if fps is not positive
+func (s Subtitles) frameDuration(fps float64) time.Duration {
+	if fps <= 0 && s.Metadata != nil {
+		fps = float64(s.Metadata.Framerate)
+	}
+	if fps <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / fps)
+}
+
+// SubFrameCues returns items displayed for less than a frame, which means they're never displayed.
+// If fps is not positive, the metadata framerate is used.
+func (s Subtitles) SubFrameCues(fps float64) (o []*Item) {
+	var f = s.frameDuration(fps)
+	for _, i := range s.Items {
+		if i.EndAt-i.StartAt < f {
+			o = append(o, i)
+		}
+	}
+	return
+}
+
+// FixSubFrameCues extends items displayed for less than a frame so that they last at least one frame, without
+// overlapping the next item. Items are expected to be ordered. It returns the number of items that have been extended.
+// If fps is not positive, the metadata framerate is used.
+func (s *Subtitles) FixSubFrameCues(fps float64) (fixed int) {
+	var f = s.frameDuration(fps)
+	for idx, i := range s.Items {
+		// Item lasts long enough
+		if i.EndAt-i.StartAt >= f {
+			continue
+		}
+
+		// Extend item
+		var endAt = i.StartAt + f
+		if idx < len(s.Items)-1 && s.Items[idx+1].StartAt < endAt {
+			endAt = s.Items[idx+1].StartAt
+		}
+		if endAt > i.EndAt {
+			i.EndAt = endAt
+			fixed++
+		}
+	}
+	return
+}
+
+// RoundToFrame rounds a duration to the nearest frame boundary at the given framerate
+func RoundToFrame(d time.Duration, fps float64) time.Duration {
+	return snapToFrame(d, fps, math.Round)
+}
+
+// snapToFrame snaps a duration to a frame boundary, fn choosing the frame. Frame counts are nudged so that durations
+// already on a boundary don't move because of floating point errors.
+func snapToFrame(d time.Duration, fps float64, fn func(float64) float64) time.Duration {
+	if fps <= 0 {
+		return d
+	}
+	var frames = d.Seconds() * fps
+	if r := math.Round(frames); math.Abs(frames-r) < 1e-6 {
+		frames = r
+	}
+	return time.Duration(math.Round(fn(frames) * float64(time.Second) / fps))
+}
+
+// SnapToFramesMode represents the way SnapToFrames rounds time boundaries
+type SnapToFramesMode int
+
+// Snap to frames modes
+const (
+	// Time boundaries are rounded to the nearest frame
+	SnapToFramesModeNearest SnapToFramesMode = iota
+	// StartAt is rounded down and EndAt is rounded up so that items are never shortened
+	SnapToFramesModeExpand
+)
+
+// SnapToFrames rounds items' time boundaries to frame boundaries.
+// If fps is not positive, the metadata framerate is used.
+func (s *Subtitles) SnapToFrames(fps float64, mode SnapToFramesMode) {
+	if fps <= 0 && s.Metadata != nil {
+		fps = float64(s.Metadata.Framerate)
+	}
+	var start, end = math.Round, math.Round
+	if mode == SnapToFramesModeExpand {
+		start, end = math.Floor, math.Ceil
+	}
+	for _, i := range s.Items {
+		i.StartAt = snapToFrame(i.StartAt, fps, start)
+		i.EndAt = snapToFrame(i.EndAt, fps, end)
+	}
+}
+
+// ForceDuration updates the subtitles duration.
+// If requested duration is bigger, then we create a dummy item.
+// If requested duration is smaller, then we remove useless items and we cut the last item or add a dummy item.
+func (s *Subtitles) ForceDuration(d time.Duration, addDummyItem bool) {
+	// Requested duration is the same as the subtitles'one
+	if s.Duration() == d {
+		return
+	}
+
+	// Requested duration is bigger than subtitles'one
+	if s.Duration() > d {
+		// Find last item before input duration and update end at
+		var lastIndex = -1
+		for index, i := range s.Items {
+			// Start at is bigger than input duration, we've found the last item
+			if i.StartAt >= d {
+				lastIndex = index
+				break
+			} else if i.EndAt > d {
+				s.Items[index].EndAt = d
+			}
+		}
+
+		// Last index has been found
+		if lastIndex != -1 {
+			s.Items = s.Items[:lastIndex]
+		}
+	}
+
+	// Add dummy item with the minimum duration possible
+	if addDummyItem && s.Duration() < d {
+		s.Items = append(s.Items, &Item{EndAt: d, Lines: []Line{{Items: []LineItem{{Text: "..."}}}}, StartAt: d - time.Millisecond})
+	}
+}
+
+// Fragment fragments subtitles with a specific fragment duration
+// Items are ordered afterwards unless PreserveOrder is set
+func (s *Subtitles) Fragment(f time.Duration) {
+	// Nothing to fragment
+	if len(s.Items) == 0 {
+		return
+	}
+
+	// Here we want to simulate fragments of duration f until there are no subtitles left in that period of time
+	var fragmentStartAt, fragmentEndAt = time.Duration(0), f
+	for fragmentStartAt < s.Items[len(s.Items)-1].EndAt {
+		// We loop through subtitles and process the ones that either contain the fragment start at,
+		// or contain the fragment end at
+		//
+		// It's useless processing subtitles contained between fragment start at and end at
+		//             |____________________|             <- subtitle
+		//           |                        |
+		//   fragment start at        fragment end at
+		for i, sub := range s.Items {
+			// Init
+			var newSub = &Item{}
+			*newSub = *sub
+
+			// A switch is more readable here
+			switch {
+			// Subtitle contains fragment start at
+			// |____________________|                         <- subtitle
+			//           |                        |
+			//   fragment start at        fragment end at
+			case sub.StartAt < fragmentStartAt && sub.EndAt > fragmentStartAt:
+				sub.StartAt = fragmentStartAt
+				newSub.EndAt = fragmentStartAt
+			// Subtitle contains fragment end at
+			//                         |____________________| <- subtitle
+			//           |                        |
+			//   fragment start at        fragment end at
+			case sub.StartAt < fragmentEndAt && sub.EndAt > fragmentEndAt:
+				sub.StartAt = fragmentEndAt
+				newSub.EndAt = fragmentEndAt
+			default:
+				continue
+			}
+
+			// Insert new sub
+			s.Items = append(s.Items[:i], append([]*Item{newSub}, s.Items[i:]...)...)
+		}
+
+		// Update fragments boundaries
+		fragmentStartAt += f
+		fragmentEndAt += f
+	}
+
+	// Order
+	s.autoOrder()
+}
+
+// InferEndTimesOptions represents InferEndTimes options
+type InferEndTimesOptions struct {
+	// Default is 15 characters per second
+	CharactersPerSecond float64
+	// Default is 7 seconds
+	MaxDuration time.Duration
+	// Default is 1 second
+	MinDuration time.Duration
+}
+
+// InferEndTimes sets the end time of items whose end time is missing (i.e. not after their start time)
+// based on the time needed to read their text.
+// The end time never goes past the next item's start time so that no overlaps are created.
+func (s *Subtitles) InferEndTimes(o InferEndTimesOptions) {
+	// Default options
+	if o.CharactersPerSecond <= 0 {
+		o.CharactersPerSecond = 15
+	}
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = 7 * time.Second
+	}
+	if o.MinDuration <= 0 {
+		o.MinDuration = time.Second
+	}
+
+	// Loop through items
+	for idx, i := range s.Items {
+		// End time is valid
+		if i.EndAt > i.StartAt {
+			continue
+		}
+
+		// Get reading duration
+		var count int
+		for _, l := range i.Lines {
+			count += VisibleLength(l.String())
+		}
+		d := time.Duration(float64(count) / o.CharactersPerSecond * float64(time.Second))
+		if d < o.MinDuration {
+			d = o.MinDuration
+		}
+		if d > o.MaxDuration {
+			d = o.MaxDuration
+		}
+		i.EndAt = i.StartAt + d
+
+		// Cap by next item
+		if idx < len(s.Items)-1 && s.Items[idx+1].StartAt > i.StartAt && s.Items[idx+1].StartAt < i.EndAt {
+			i.EndAt = s.Items[idx+1].StartAt
+		}
+	}
+}
+
+// ReadingSpeedOptions represents ReadingSpeed options
+type ReadingSpeedOptions struct {
+	// Default is 17 characters per second
+	MaxCharactersPerSecond float64
+}
+
+// ReadingSpeedReport represents the reading speed of an item
+type ReadingSpeedReport struct {
+	// CPS is the number of visible characters per second, +Inf if the item has text but no duration
+	CPS      float64
+	Exceeded bool
+	Index    int
+}
+
+// ReadingSpeed returns the reading speed of every item and whether it exceeds the max characters per second
+func (s Subtitles) ReadingSpeed(o ReadingSpeedOptions) (rs []ReadingSpeedReport) {
+	// Default options
+	if o.MaxCharactersPerSecond <= 0 {
+		o.MaxCharactersPerSecond = 17
+	}
+
+	// Loop through items
+	for idx, i := range s.Items {
+		var r = ReadingSpeedReport{Index: idx}
+		if c := i.CharacterCount(); c > 0 {
+			if d := i.EndAt - i.StartAt; d > 0 {
+				r.CPS = float64(c) / d.Seconds()
+			} else {
+				r.CPS = math.Inf(1)
+			}
+		}
+		r.Exceeded = r.CPS > o.MaxCharactersPerSecond
+		rs = append(rs, r)
+	}
+	return
+}
+
+// ValidationErrorCode represents the kind of problem reported by Validate
+type ValidationErrorCode string
+
+// Validation error codes
+const (
+	ValidationErrorCodeEmptyItem        ValidationErrorCode = "empty_item"
+	ValidationErrorCodeEmptyLine        ValidationErrorCode = "empty_line"
+	ValidationErrorCodeInvalidTimeRange ValidationErrorCode = "invalid_time_range"
+	ValidationErrorCodeNegativeTime     ValidationErrorCode = "negative_time"
+	ValidationErrorCodeOutOfOrder       ValidationErrorCode = "out_of_order"
+	ValidationErrorCodeOverlap          ValidationErrorCode = "overlap"
+	ValidationErrorCodeSubFrame         ValidationErrorCode = "sub_frame"
+	ValidationErrorCodeTooLong          ValidationErrorCode = "too_long"
+	ValidationErrorCodeTooShort         ValidationErrorCode = "too_short"
+)
+
+// ValidationError represents a problem found by Validate
+type ValidationError struct {
+	Code    ValidationErrorCode
+	Index   int
+	Message string
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("astisub: item #%d: %s", e.Index+1, e.Message)
+}
+
+// ValidateOptions represents Validate options
+type ValidateOptions struct {
+	// Items displayed for less than a frame at this framerate are reported, see SubFrameCues.
+	// Default is 0 which disables the check.
+	Framerate float64
+	// Default is 7 seconds
+	MaxDuration time.Duration
+	// Default is 1 second
+	MinDuration time.Duration
+}
+
+// Validate reports problems with default options, see ValidateWithOptions
+func (s Subtitles) Validate() []ValidationError {
+	return s.ValidateWithOptions(ValidateOptions{})
+}
+
+// ValidateWithOptions reports items ending before they start, overlapping, empty, out of order, with negative times or
+// lasting less than a frame, less than the min duration or more than the max duration. Unlike Optimize, nothing is
+// modified.
+// Errors are ordered by item index, overlaps being reported on the later item.
+func (s Subtitles) ValidateWithOptions(o ValidateOptions) (es []ValidationError) {
+	// Default options
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = 7 * time.Second
+	}
+	if o.MinDuration <= 0 {
+		o.MinDuration = time.Second
+	}
+
+	// Index overlaps
+	var overlaps = make(map[int][]int)
+	for _, v := range s.Overlaps() {
+		overlaps[v[1]] = append(overlaps[v[1]], v[0])
+	}
+
+	// Index sub-frame cues
+	var subFrames = make(map[*Item]bool)
+	if o.Framerate > 0 {
+		for _, i := range s.SubFrameCues(o.Framerate) {
+			subFrames[i] = true
+		}
+	}
+
+	// Loop through items
+	for idx, i := range s.Items {
+		var add = func(c ValidationErrorCode, format string, args ...interface{}) {
+			es = append(es, ValidationError{Code: c, Index: idx, Message: fmt.Sprintf(format, args...)})
+		}
+
+		// Time boundaries
+		if i.StartAt < 0 || i.EndAt < 0 {
+			add(ValidationErrorCodeNegativeTime, "negative time boundaries %s --> %s", i.StartAt, i.EndAt)
+		}
+		if d := i.EndAt - i.StartAt; d <= 0 {
+			add(ValidationErrorCodeInvalidTimeRange, "ends at %s which is not after its start at %s", i.EndAt, i.StartAt)
+		} else if subFrames[i] {
+			add(ValidationErrorCodeSubFrame, "lasts %s which is less than a frame at %g fps", d, o.Framerate)
+		} else if d < o.MinDuration {
+			add(ValidationErrorCodeTooShort, "lasts %s which is less than %s", d, o.MinDuration)
+		} else if d > o.MaxDuration {
+			add(ValidationErrorCodeTooLong, "lasts %s which is more than %s", d, o.MaxDuration)
+		}
+
+		// Order
+		if idx > 0 && i.StartAt < s.Items[idx-1].StartAt {
+			add(ValidationErrorCodeOutOfOrder, "starts at %s which is before the previous item's start at %s", i.StartAt, s.Items[idx-1].StartAt)
+		}
+		for _, v := range overlaps[idx] {
+			add(ValidationErrorCodeOverlap, "overlaps item #%d", v+1)
+		}
+
+		// Text
+		if len(i.Lines) == 0 {
+			add(ValidationErrorCodeEmptyItem, "has no lines")
+		}
+		for idxLine, l := range i.Lines {
+			// Drawings are not text but are displayed nonetheless
+			var empty = strings.TrimSpace(l.String()) == ""
+			for _, li := range l.Items {
+				if li.isDrawing() {
+					empty = false
+				}
+			}
+			if empty {
+				add(ValidationErrorCodeEmptyLine, "line %d is empty", idxLine+1)
+			}
+		}
+	}
+	return
+}
+
+// IsEmpty returns whether the subtitles are empty
+func (s Subtitles) IsEmpty() bool {
+	return len(s.Items) == 0
+}
+
+// LimitSimultaneousMode represents the way LimitSimultaneous handles excess items
+type LimitSimultaneousMode int
+
+// Limit simultaneous modes
+const (
+	// Excess items are removed, starting with the ones with the lowest SSA layer
+	LimitSimultaneousModeDrop LimitSimultaneousMode = iota
+	// Excess items' lines are appended to the displayed item with the lowest SSA layer, which then spans both items
+	LimitSimultaneousModeMerge
+)
+
+// LimitSimultaneous makes sure no more than max items are displayed at the same time
+func (s *Subtitles) LimitSimultaneous(max int, mode LimitSimultaneousMode) {
+	// At least one item must be displayed
+	if max < 1 {
+		max = 1
+	}
+
+	// Items are processed by start time without reordering them
+	var items = append([]*Item{}, s.Items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].StartAt < items[j].StartAt
+	})
+
+	// Loop through items
+	var active []*Item
+	var removed = make(map[*Item]bool)
+	for _, i := range items {
+		// Remove items that are not displayed anymore
+		var displayed []*Item
+		for _, a := range active {
+			if a.EndAt > i.StartAt {
+				displayed = append(displayed, a)
+			}
+		}
+		active = displayed
+
+		// Limit is not reached
+		if len(active) < max {
+			active = append(active, i)
+			continue
+		}
+
+		// Handle excess item
+		switch mode {
+		case LimitSimultaneousModeMerge:
+			a := lowestPriorityItem(active)
+			a.Lines = append(a.Lines, i.Lines...)
+			if i.EndAt > a.EndAt {
+				a.EndAt = i.EndAt
+			}
+			removed[i] = true
+		default:
+			l := lowestPriorityItem(append(active, i))
+			removed[l] = true
+			for idx, a := range active {
+				if a == l {
+					active[idx] = i
+				}
+			}
+		}
+	}
+
+	// Remove items
+	var kept []*Item
+	for _, i := range s.Items {
+		if !removed[i] {
+			kept = append(kept, i)
+		}
+	}
+	s.Items = kept
+}
+
+// lowestPriorityItem returns the item with the lowest SSA layer, the latest one winning ties
+func lowestPriorityItem(items []*Item) (o *Item) {
+	layer := func(i *Item) int {
+		if i.InlineStyle != nil && i.InlineStyle.SSALayer != nil {
+			return *i.InlineStyle.SSALayer
+		}
+		return 0
+	}
+	for _, i := range items {
+		if o == nil || layer(i) <= layer(o) {
+			o = i
+		}
+	}
+	return
+}
+
+// Concat appends subtitles i after subtitles, i's items being shifted by the subtitles duration plus gap.
+// i is not modified: its items, regions and styles are copied, and copied regions and styles whose ID is already used
+// are renamed. Items are renumbered starting at 1.
+func (s *Subtitles) Concat(i *Subtitles, gap time.Duration) {
+	// Copy subtitles
+	var c = copySubtitles(*i)
+
+	// Add regions
+	if s.Regions == nil {
+		s.Regions = make(map[string]*Region)
+	}
+	var ids []string
+	for id := range c.Regions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		var r = c.Regions[id]
+		r.ID = concatID(id, func(v string) bool {
+			_, ok1 := s.Regions[v]
+			_, ok2 := c.Regions[v]
+			return ok1 || (ok2 && v != id)
+		})
+		s.Regions[r.ID] = r
+	}
+
+	// Add styles
+	if s.Styles == nil {
+		s.Styles = make(map[string]*Style)
+	}
+	ids = []string{}
+	for id := range c.Styles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		var st = c.Styles[id]
+		st.ID = concatID(id, func(v string) bool {
+			_, ok1 := s.Styles[v]
+			_, ok2 := c.Styles[v]
+			return ok1 || (ok2 && v != id)
+		})
+		s.Styles[st.ID] = st
+	}
+
+	// Shift items
+	var offset = s.Duration() + gap
+	for _, item := range c.Items {
+		item.StartAt += offset
+		item.EndAt += offset
+		for idxLine := range item.Lines {
+			for idxLineItem := range item.Lines[idxLine].Items {
+				if li := &item.Lines[idxLine].Items[idxLineItem]; li.StartAt > 0 {
+					li.StartAt += offset
+				}
+			}
+		}
+	}
+
+	// Append items
+	s.Items = append(s.Items, c.Items...)
+	s.Renumber()
+}
+
+// concatID returns id, suffixed with the first available number starting at 2 if it is already used
+func concatID(id string, used func(string) bool) string {
+	if !used(id) {
+		return id
+	}
+	for n := 2; ; n++ {
+		if v := id + "_" + strconv.Itoa(n); !used(v) {
+			return v
+		}
+	}
+}
+
+// Merge merges subtitles i into subtitles
+// Items are ordered afterwards unless PreserveOrder is set
+func (s *Subtitles) Merge(i *Subtitles) {
+	// Append items
+	s.Items = append(s.Items, i.Items...)
+	s.autoOrder()
+
+	// Add regions
 	for _, region := range i.Regions {
 		if _, ok := s.Regions[region.ID]; !ok {
 			s.Regions[region.ID] = region
 		}
 	}
 
-	// Add styles
-	for _, style := range i.Styles {
-		if _, ok := s.Styles[style.ID]; !ok {
-			s.Styles[style.ID] = style
+	// Add styles
+	for _, style := range i.Styles {
+		if _, ok := s.Styles[style.ID]; !ok {
+			s.Styles[style.ID] = style
+		}
+	}
+}
+
+// NormalizeLineBreaks removes empty lines within items. If preserveBlankLine is true, consecutive empty lines
+// between text lines are collapsed into a single one instead of being removed.
+// Leading and trailing empty lines are always removed.
+func (s *Subtitles) NormalizeLineBreaks(preserveBlankLine bool) {
+	for _, i := range s.Items {
+		var lines []Line
+		var blank bool
+		for _, l := range i.Lines {
+			// Empty line
+			if l.isEmpty() {
+				blank = len(lines) > 0
+				continue
+			}
+
+			// Add blank line
+			if blank && preserveBlankLine {
+				lines = append(lines, Line{})
+			}
+			blank = false
+			lines = append(lines, l)
+		}
+		i.Lines = lines
+	}
+}
+
+// MergeLines concatenates the lines of the item into a single line, sep being inserted between them as an unstyled
+// line item. Empty lines are dropped and line items keep their styling.
+// Lines with different voice names are left untouched, in which case false is returned.
+func (i *Item) MergeLines(sep string) bool {
+	// Nothing to do
+	if len(i.Lines) <= 1 {
+		return true
+	}
+
+	// Voice names must be uniform
+	for _, l := range i.Lines[1:] {
+		if l.VoiceName != i.Lines[0].VoiceName {
+			return false
+		}
+	}
+
+	// Merge
+	var line = Line{VoiceClasses: i.Lines[0].VoiceClasses, VoiceName: i.Lines[0].VoiceName}
+	for _, l := range i.Lines {
+		if l.isEmpty() {
+			continue
+		}
+		if len(line.Items) > 0 && sep != "" {
+			line.Items = append(line.Items, LineItem{Text: sep})
+		}
+		line.Items = append(line.Items, l.Items...)
+	}
+	i.Lines = []Line{line}
+	return true
+}
+
+// MergeLines concatenates the lines of each item into a single line, see Item.MergeLines.
+// It returns the indexes of the items whose lines have different voice names and were therefore left untouched.
+func (s *Subtitles) MergeLines(sep string) (skipped []int) {
+	for idx, i := range s.Items {
+		if !i.MergeLines(sep) {
+			skipped = append(skipped, idx)
+		}
+	}
+	return
+}
+
+// WrapLines wraps lines with no limit on the number of lines, see WrapLinesWithOptions
+func (s *Subtitles) WrapLines(maxChars int) {
+	s.WrapLinesWithOptions(WrapLinesOptions{MaxChars: maxChars})
+}
+
+// WrapLinesOptions represents WrapLines options
+type WrapLinesOptions struct {
+	// Nothing is done if not positive
+	MaxChars int
+	// Maximum number of lines each line is split into, e.g. 2 to respect the two-line convention. If a line doesn't
+	// fit in MaxLines lines, it's split into MaxLines balanced lines which exceed MaxChars.
+	// Default is 0 which doesn't limit the number of lines.
+	MaxLines int
+}
+
+// WrapLinesWithOptions splits lines longer than MaxChars characters on spaces so that no line exceeds MaxChars, words
+// longer than MaxChars being left intact on their own line.
+// Each line is split into as few lines as possible whose lengths are balanced rather than greedily filled, line items
+// styling being preserved across the split. Lines containing drawings are left untouched.
+func (s *Subtitles) WrapLinesWithOptions(o WrapLinesOptions) {
+	// Nothing to do
+	if o.MaxChars <= 0 {
+		return
+	}
+
+	// Loop through items
+	for _, i := range s.Items {
+		var lines []Line
+		for _, l := range i.Lines {
+			lines = append(lines, l.wrap(o.MaxChars, o.MaxLines)...)
+		}
+		i.Lines = lines
+	}
+}
+
+// lineWord represents a word of a line, made of one fragment per line item it spans
+type lineWord []LineItem
+
+func (w lineWord) length() (n int) {
+	for _, li := range w {
+		n += VisibleLength(li.Text)
+	}
+	return
+}
+
+func (l Line) words() (ws []lineWord) {
+	var w lineWord
+	for _, li := range l.Items {
+		var start = -1
+		for idx, r := range li.Text {
+			if !unicode.IsSpace(r) {
+				if start < 0 {
+					start = idx
+				}
+				continue
+			}
+			if start >= 0 {
+				w = append(w, li.withText(li.Text[start:idx]))
+				start = -1
+			}
+			if len(w) > 0 {
+				ws = append(ws, w)
+				w = nil
+			}
+		}
+		if start >= 0 {
+			w = append(w, li.withText(li.Text[start:]))
+		}
+	}
+	if len(w) > 0 {
+		ws = append(ws, w)
+	}
+	return
+}
+
+func (li LineItem) withText(t string) LineItem {
+	li.Text = t
+	return li
+}
+
+func (li LineItem) hasSameStyling(i LineItem) bool {
+	return li.InlineStyle == i.InlineStyle && li.Language == i.Language && li.RubyText == i.RubyText &&
+		li.StartAt == i.StartAt && li.Style == i.Style
+}
+
+// wrap splits the line in the minimum number of lines that don't exceed maxChars, or in maxLines lines if that's not
+// enough and maxLines is positive, minimizing the sum of squared line lengths so that lines are balanced
+func (l Line) wrap(maxChars, maxLines int) []Line {
+	// Line fits or contains drawings
+	if VisibleLength(l.String()) <= maxChars {
+		return []Line{l}
+	}
+	for _, li := range l.Items {
+		if li.isDrawing() {
+			return []Line{l}
+		}
+	}
+
+	// Get words
+	var ws = l.words()
+	if len(ws) <= 1 {
+		return []Line{l}
+	}
+	var lengths = make([]int, len(ws))
+	for idx, w := range ws {
+		lengths[idx] = w.length()
+	}
+
+	// Get the minimum number of lines by filling them greedily
+	var count, length int
+	for idx, n := range lengths {
+		if idx == 0 || length+1+n > maxChars {
+			count++
+			length = n
+		} else {
+			length += 1 + n
+		}
+	}
+
+	// Lines are allowed to exceed the limit when there are too many of them
+	var overflow = maxLines > 0 && count > maxLines
+	if overflow {
+		count = maxLines
+	}
+
+	// costs[c][j] is the minimum cost of laying out the j first words on c lines, starts[c][j] being the index of the
+	// first word of the last line
+	var costs = make([][]int, count+1)
+	var starts = make([][]int, count+1)
+	for c := range costs {
+		costs[c] = make([]int, len(ws)+1)
+		starts[c] = make([]int, len(ws)+1)
+		for j := range costs[c] {
+			costs[c][j] = -1
+		}
+	}
+	costs[0][0] = 0
+	for c := 1; c <= count; c++ {
+		for j := c; j <= len(ws); j++ {
+			var length = -1
+			for k := j - 1; k >= c-1; k-- {
+				// Lines exceeding the limit are only allowed for single words
+				length += 1 + lengths[k]
+				if length > maxChars && k < j-1 && !overflow {
+					break
+				}
+				if costs[c-1][k] < 0 {
+					continue
+				}
+				if cost := costs[c-1][k] + length*length; costs[c][j] < 0 || cost < costs[c][j] {
+					costs[c][j] = cost
+					starts[c][j] = k
+				}
+			}
+		}
+	}
+
+	// Build lines
+	var o = make([]Line, count)
+	for c, j := count, len(ws); c > 0; c-- {
+		var k = starts[c][j]
+		o[c-1] = Line{VoiceClasses: l.VoiceClasses, VoiceName: l.VoiceName}
+		for idx, w := range ws[k:j] {
+			for idxFragment, f := range w {
+				var last = len(o[c-1].Items) - 1
+				if idx > 0 && idxFragment == 0 {
+					o[c-1].Items[last].Text += " "
+				}
+				if last >= 0 && o[c-1].Items[last].hasSameStyling(f) {
+					o[c-1].Items[last].Text += f.Text
+				} else {
+					o[c-1].Items = append(o[c-1].Items, f)
+				}
+			}
+		}
+		j = k
+	}
+	return o
+}
+
+// Optimize optimizes subtitles
+func (s *Subtitles) Optimize() {
+	// Nothing to optimize
+	if len(s.Items) == 0 {
+		return
+	}
+
+	// Remove unused regions and style
+	s.removeUnusedRegionsAndStyles()
+}
+
+// removeUnusedRegionsAndStyles removes unused regions and styles
+func (s *Subtitles) removeUnusedRegionsAndStyles() {
+	// Loop through items
+	var usedRegions, usedStyles = make(map[string]bool), make(map[string]bool)
+	for _, item := range s.Items {
+		// Add region
+		if item.Region != nil {
+			usedRegions[item.Region.ID] = true
+		}
+
+		// Add style
+		if item.Style != nil {
+			usedStyles[item.Style.ID] = true
+		}
+
+		// Loop through lines
+		for _, line := range item.Lines {
+			// Loop through line items
+			for _, lineItem := range line.Items {
+				// Add style
+				if lineItem.Style != nil {
+					usedStyles[lineItem.Style.ID] = true
+				}
+			}
+		}
+	}
+
+	// Loop through regions
+	for id, region := range s.Regions {
+		if _, ok := usedRegions[region.ID]; ok {
+			if region.Style != nil {
+				usedStyles[region.Style.ID] = true
+			}
+		} else {
+			delete(s.Regions, id)
+		}
+	}
+
+	// Loop through style
+	for id, style := range s.Styles {
+		if _, ok := usedStyles[style.ID]; !ok {
+			delete(s.Styles, id)
+		}
+	}
+}
+
+// autoOrder orders items unless PreserveOrder is set
+func (s *Subtitles) autoOrder() {
+	if !s.PreserveOrder {
+		s.Order()
+	}
+}
+
+// Order orders items by their start time
+func (s *Subtitles) Order() {
+	// Nothing to do if less than 1 element
+	if len(s.Items) <= 1 {
+		return
+	}
+
+	// Order
+	sort.SliceStable(s.Items, func(i, j int) bool {
+		return s.Items[i].StartAt < s.Items[j].StartAt
+	})
+}
+
+// StripTags removes markup tags such as "<i>" or "{\an8}" left in line items' text and unescapes HTML entities.
+// Styles are left untouched, see RemoveStyling to remove them as well.
+func (s *Subtitles) StripTags() {
+	for _, i := range s.Items {
+		for idxLine, l := range i.Lines {
+			for idxLineItem, li := range l.Items {
+				if !li.isDrawing() {
+					i.Lines[idxLine].Items[idxLineItem].Text = stripTags(li.Text)
+				}
+			}
+		}
+	}
+}
+
+// Renumber sets items' Index to their position in the current order, starting at 1
+func (s *Subtitles) Renumber() {
+	for idx, i := range s.Items {
+		i.Index = idx + 1
+	}
+}
+
+// RemoveStyling removes the styling from the subtitles
+func (s *Subtitles) RemoveStyling() {
+	s.Regions = map[string]*Region{}
+	s.Styles = map[string]*Style{}
+	for _, i := range s.Items {
+		i.Region = nil
+		i.Style = nil
+		i.InlineStyle = nil
+		for idxLine, l := range i.Lines {
+			for idxLineItem := range l.Items {
+				i.Lines[idxLine].Items[idxLineItem].InlineStyle = nil
+				i.Lines[idxLine].Items[idxLineItem].Style = nil
+			}
+		}
+	}
+}
+
+// SetTextColor sets the text color of every item in all formats. SRT and teletext colors are set on line items as
+// well since they can't be set on items, and line items' colors that would override items' ones are replaced.
+// A nil color removes the text colors.
+func (s *Subtitles) SetTextColor(c *Color) {
+	// Get values
+	var srt, ttml *string
+	if c != nil {
+		srt = astikit.StrPtr(c.HexString())
+		ttml = astikit.StrPtr("#" + c.TTMLString())
+	}
+	var color = func() *Color {
+		if c == nil {
+			return nil
+		}
+		var o = *c
+		return &o
+	}
+
+	// Loop through items
+	for _, i := range s.Items {
+		// Item
+		if i.InlineStyle == nil {
+			if c == nil {
+				continue
+			}
+			i.InlineStyle = &StyleAttributes{}
+		}
+		i.InlineStyle.SRTColor = srt
+		i.InlineStyle.SSAPrimaryColour = color()
+		i.InlineStyle.TTMLColor = ttml
+		i.InlineStyle.TeletextColor = color()
+
+		// Line items
+		for idxLine, l := range i.Lines {
+			for idxLineItem := range l.Items {
+				li := &i.Lines[idxLine].Items[idxLineItem]
+				if li.InlineStyle == nil {
+					if c == nil {
+						continue
+					}
+					li.InlineStyle = &StyleAttributes{}
+				}
+				li.InlineStyle.SRTColor = srt
+				li.InlineStyle.TeletextColor = color()
+				if li.InlineStyle.SSAPrimaryColour != nil {
+					li.InlineStyle.SSAPrimaryColour = color()
+				}
+				if li.InlineStyle.TTMLColor != nil {
+					li.InlineStyle.TTMLColor = ttml
+				}
+			}
+		}
+	}
+}
+
+// RemoveComments removes the comments from the items and the metadata, SSA comment events included
+func (s *Subtitles) RemoveComments() {
+	if s.Metadata != nil {
+		s.Metadata.Comments = nil
+		s.Metadata.SSAComments = nil
+		s.Metadata.WebVTTTrailingComments = nil
+	}
+	for _, i := range s.Items {
+		i.Comments = nil
+	}
+}
+
+// Voices returns the distinct voice names of the items' lines, in order of first appearance
+func (s Subtitles) Voices() (o []string) {
+	var m = make(map[string]bool)
+	for _, i := range s.Items {
+		for _, l := range i.Lines {
+			if l.VoiceName == "" || m[l.VoiceName] {
+				continue
+			}
+			m[l.VoiceName] = true
+			o = append(o, l.VoiceName)
+		}
+	}
+	return
+}
+
+// AssignStyleToVoice sets the style of every line item of lines spoken by voice. The style is also set on items whose
+// lines are all spoken by voice, and it is added to the subtitles' styles if it's not already there. If a different
+// style already has its ID, a copy of the style whose ID is suffixed with "_2", "_3", etc. is assigned instead so that
+// both are kept, the provided style being left untouched.
+func (s *Subtitles) AssignStyleToVoice(voice string, style *Style) {
+	// Get style to assign
+	var add bool
+	if style != nil && style.ID != "" {
+		if v, ok := s.Styles[style.ID]; !ok {
+			add = true
+		} else if v != style {
+			var c = *style
+			c.ID = concatID(style.ID, func(v string) bool {
+				_, ok := s.Styles[v]
+				return ok
+			})
+			c.InlineStyle = copyStyleAttributes(style.InlineStyle)
+			style = &c
+			add = true
+		}
+	}
+
+	// Assign style
+	var assigned bool
+	for _, i := range s.Items {
+		var all = len(i.Lines) > 0
+		for idxLine, l := range i.Lines {
+			if l.VoiceName != voice {
+				all = false
+				continue
+			}
+			for idxLineItem := range l.Items {
+				i.Lines[idxLine].Items[idxLineItem].Style = style
+			}
+			assigned = true
+		}
+		if all {
+			i.Style = style
+		}
+	}
+
+	// Add style
+	if assigned && add {
+		if s.Styles == nil {
+			s.Styles = make(map[string]*Style)
+		}
+		s.Styles[style.ID] = style
+	}
+}
+
+// ReplaceRegexInRange replaces matches of re with repl in the text of items starting in [from, to) and returns the
+// number of replacements made. repl is expanded as in regexp.Regexp.ReplaceAllString.
+// Matches are searched for in each line item separately.
+func (s *Subtitles) ReplaceRegexInRange(from, to time.Duration, re *regexp.Regexp, repl string) (n int) {
+	for _, i := range s.Items {
+		// Item is out of range
+		if i.StartAt < from || i.StartAt >= to {
+			continue
+		}
+
+		// Loop through line items
+		for idxLine := range i.Lines {
+			for idxLineItem, li := range i.Lines[idxLine].Items {
+				if c := len(re.FindAllStringIndex(li.Text, -1)); c > 0 {
+					i.Lines[idxLine].Items[idxLineItem].Text = re.ReplaceAllString(li.Text, repl)
+					n += c
+				}
+			}
+		}
+	}
+	return
+}
+
+// CharacterSet returns whether a character can be displayed
+type CharacterSet func(r rune) bool
+
+// CharacterSetASCII contains printable ASCII characters
+var CharacterSetASCII CharacterSet = func(r rune) bool {
+	return r >= 0x20 && r <= 0x7e
+}
+
+// Transliterations of characters missing from most character sets
+var sanitizeTextTransliterations = map[rune]string{
+	'\u00a0': " ",      // No-break space
+	'\u2013': "-",      // En dash
+	'\u2014': "-",      // Em dash
+	'\u2018': "'",      // Left single quotation mark
+	'\u2019': "'",      // Right single quotation mark
+	'\u201a': "'",      // Single low-9 quotation mark
+	'\u201c': "\"",     // Left double quotation mark
+	'\u201d': "\"",     // Right double quotation mark
+	'\u201e': "\"",     // Double low-9 quotation mark
+	'\u2026': "...",    // Horizontal ellipsis
+	'\u266b': "\u266a", // Beamed eighth notes
+}
+
+// SanitizeTextOptions represents sanitize text options
+type SanitizeTextOptions struct {
+	// Default is CharacterSetSTLLatin
+	CharacterSet CharacterSet
+	// Replacement replaces characters that can't be displayed. They're stripped if empty.
+	Replacement string
+	// TabWidth is the number of spaces tabs are converted to. Tabs are stripped if it's negative and handled like any
+	// other character if it's 0.
+	TabWidth int
+	// Transliterate replaces characters that can't be displayed with similar ones when possible (e.g. "é" becomes
+	// "e" and "…" becomes "...") before falling back on Replacement.
+	Transliterate bool
+}
+
+// SanitizeText strips or replaces characters, such as emojis, that are not part of the target character set.
+// Characters whose canonical decomposition is part of the character set (e.g. "é" for a set containing "e" and the
+// combining acute accent) are kept. It returns the number of characters that have been stripped or replaced.
+func (s *Subtitles) SanitizeText(o SanitizeTextOptions) (n int) {
+	// Default character set
+	if o.CharacterSet == nil {
+		o.CharacterSet = CharacterSetSTLLatin
+	}
+
+	// Loop through line items
+	for _, i := range s.Items {
+		for idxLine := range i.Lines {
+			for idxLineItem := range i.Lines[idxLine].Items {
+				li := &i.Lines[idxLine].Items[idxLineItem]
+				var c int
+				li.Text, c = sanitizeText(li.Text, o)
+				n += c
+				li.RubyText, c = sanitizeText(li.RubyText, o)
+				n += c
+			}
+		}
+	}
+	return
+}
+
+// sanitizeText sanitizes a string and returns the number of characters that have been stripped or replaced
+func sanitizeText(i string, o SanitizeTextOptions) (_ string, n int) {
+	var b strings.Builder
+	for _, r := range i {
+		// Tab
+		if r == '\t' && o.TabWidth != 0 {
+			if o.TabWidth > 0 {
+				b.WriteString(strings.Repeat(" ", o.TabWidth))
+			}
+			n++
+			continue
+		}
+
+		// Character can be displayed
+		if displayable(string(r), o.CharacterSet) {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+
+		// Transliterate
+		if o.Transliterate {
+			// Use known transliteration
+			if t, ok := sanitizeTextTransliterations[r]; ok && displayable(t, o.CharacterSet) {
+				b.WriteString(t)
+				continue
+			}
+
+			// Remove what can't be displayed from the decomposition (e.g. diacritics)
+			var t string
+			for _, d := range norm.NFD.String(string(r)) {
+				if o.CharacterSet(d) {
+					t += string(d)
+				}
+			}
+			if t != "" {
+				b.WriteString(t)
+				continue
+			}
+		}
+
+		// Replace
+		b.WriteString(o.Replacement)
+	}
+	return b.String(), n
+}
+
+// displayable returns whether all the characters of the canonical decomposition of i are in the character set
+func displayable(i string, cs CharacterSet) bool {
+	for _, r := range norm.NFD.String(i) {
+		if !cs(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Markup
+var (
+	markupRegexpAmpersand = regexp.MustCompile(`&(?:(?:[a-zA-Z][a-zA-Z0-9]*|#[0-9]+|#[xX][0-9a-fA-F]+);)?`)
+	markupRegexpHTMLTag   = regexp.MustCompile(`(?i)<(/?)(b|c|font|i|lang|rt|ruby|s|span|u|v)(?:[\s.][^>]*)?/?>`)
+	markupRegexpTag       = regexp.MustCompile(`(?i)</?(?:b|c|font|i|lang|rt|ruby|s|span|u|v)(?:[\s.][^>]*)?/?>|\{\\[^{}]*\}`)
+)
+
+// MarkupFix represents a text whose markup has been repaired
+type MarkupFix struct {
+	Fixed    string
+	Item     *Item
+	Original string
+}
+
+// ValidateMarkup repairs markup that has leaked into line items text, which would otherwise be written verbatim:
+// stray ampersands that are not part of an entity are escaped, "b", "i" and "u" tags are converted into the inline
+// style of the line items they apply to, line items being split where they change, and other known tags are balanced,
+// unclosed ones being closed at the end of the line item and stray closing ones being removed.
+// Writers don't escape entities a second time. It returns what has been fixed, Fixed being the text of the line items
+// the original text has been split into.
+func (s *Subtitles) ValidateMarkup() (fixes []MarkupFix) {
+	// Loop through lines
+	for _, i := range s.Items {
+		for idxLine := range i.Lines {
+			var lis []LineItem
+			for _, li := range i.Lines[idxLine].Items {
+				// Ruby texts can't be styled
+				if f := balanceMarkup(escapeStrayAmpersands(li.RubyText)); f != li.RubyText {
+					fixes = append(fixes, MarkupFix{Fixed: f, Item: i, Original: li.RubyText})
+					li.RubyText = f
+				}
+
+				// Repair
+				var n = li.validateMarkup()
+				var f string
+				for _, v := range n {
+					f += v.Text
+				}
+				if len(n) != 1 || f != li.Text {
+					fixes = append(fixes, MarkupFix{Fixed: f, Item: i, Original: li.Text})
+				}
+				lis = append(lis, n...)
+			}
+			i.Lines[idxLine].Items = lis
+		}
+	}
+	return
+}
+
+// escapeStrayAmpersands escapes the ampersands that are not part of a known entity
+func escapeStrayAmpersands(i string) string {
+	return markupRegexpAmpersand.ReplaceAllStringFunc(i, func(m string) string {
+		if m != "&" && html.UnescapeString(m) != m {
+			return m
+		}
+		return "&amp;" + m[1:]
+	})
+}
+
+// balanceMarkup balances the known tags of a text
+func balanceMarkup(i string) string {
+	var b markupBalancer
+	b.write(i, nil)
+	return b.close()
+}
+
+// markupTag represents an open tag
+type markupTag struct {
+	name, raw string
+}
+
+// markupBalancer balances the known tags of the texts written to it
+type markupBalancer struct {
+	open []markupTag
+	text string
+}
+
+// write writes the text, onStyle being called before a "b", "i" or "u" tag is processed. Those tags are removed if
+// onStyle is not nil and left to be balanced otherwise.
+func (b *markupBalancer) write(i string, onStyle func(name string, closing bool)) {
+	var last int
+	for _, m := range markupRegexpHTMLTag.FindAllStringSubmatchIndex(i, -1) {
+		// Append text
+		b.text += i[last:m[0]]
+		last = m[1]
+
+		// Style tag
+		var name = strings.ToLower(i[m[4]:m[5]])
+		var closing = m[3] > m[2]
+		if onStyle != nil && (name == "b" || name == "i" || name == "u") {
+			onStyle(name, closing)
+			continue
+		}
+
+		// Opening tag
+		if !closing {
+			b.text += i[m[0]:m[1]]
+			b.open = append(b.open, markupTag{name: name, raw: i[m[0]:m[1]]})
+			continue
+		}
+
+		// Stray closing tags are removed and tags opened since the matching opening tag are closed
+		for idx := len(b.open) - 1; idx >= 0; idx-- {
+			if b.open[idx].name == name {
+				b.text += b.closingTags(idx+1) + i[m[0]:m[1]]
+				b.open = b.open[:idx]
+				break
+			}
+		}
+	}
+	b.text += i[last:]
+}
+
+// closingTags returns the closing tags of the tags opened after the idx-th one
+func (b markupBalancer) closingTags(idx int) (o string) {
+	for i := len(b.open) - 1; i >= idx; i-- {
+		o += "</" + b.open[i].name + ">"
+	}
+	return
+}
+
+// close returns the text with unclosed tags closed
+func (b *markupBalancer) close() (o string) {
+	o = b.text + b.closingTags(0)
+	b.text = ""
+	return
+}
+
+// validateMarkup escapes stray ampersands of the line item text, converts its "b", "i" and "u" tags into inline styles
+// and balances its other known tags
+func (li LineItem) validateMarkup() (o []LineItem) {
+	var b markupBalancer
+	var counts = make(map[string]int)
+	var prefix string
+	var flush = func() {
+		// Nothing has been written since the last split
+		if b.text == prefix {
+			return
+		}
+
+		// Tags still open are closed in this line item and reopened in the next one
+		var n = li
+		n.Text = b.close()
+		for _, tag := range b.open {
+			b.text += tag.raw
+		}
+		prefix = b.text
+
+		// Add style
+		if counts["b"] > 0 || counts["i"] > 0 || counts["u"] > 0 {
+			if n.InlineStyle = copyStyleAttributes(li.InlineStyle); n.InlineStyle == nil {
+				n.InlineStyle = &StyleAttributes{}
+			}
+			n.InlineStyle.SRTBold = n.InlineStyle.SRTBold || counts["b"] > 0
+			n.InlineStyle.SRTItalics = n.InlineStyle.SRTItalics || counts["i"] > 0
+			n.InlineStyle.SRTUnderline = n.InlineStyle.SRTUnderline || counts["u"] > 0
+			n.InlineStyle.propagateSRTAttributes()
+		}
+		o = append(o, n)
+	}
+	b.write(escapeStrayAmpersands(li.Text), func(name string, closing bool) {
+		// Style changes
+		if closing && counts[name] == 1 || !closing && counts[name] == 0 {
+			flush()
+		}
+
+		// Update count, stray closing tags being removed
+		if closing && counts[name] > 0 {
+			counts[name]--
+		} else if !closing {
+			counts[name]++
+		}
+	})
+	flush()
+
+	// Empty texts are kept
+	if len(o) == 0 {
+		li.Text = ""
+		o = append(o, li)
+	}
+	return
+}
+
+// SeparateByLine demultiplexes items whose lines belong to several tracks (e.g. a line and its translation).
+// The first linesPerTrack lines of each item go to the first track, the next ones to the second track, etc.
+// Regions, styles and metadata are shared with the returned subtitles.
+func (s Subtitles) SeparateByLine(linesPerTrack int) (o []*Subtitles) {
+	// Default is 1 line per track
+	if linesPerTrack <= 0 {
+		linesPerTrack = 1
+	}
+
+	// Loop through items
+	for _, i := range s.Items {
+		for idx, track := 0, 0; idx < len(i.Lines); idx, track = idx+linesPerTrack, track+1 {
+			// Create track
+			if track >= len(o) {
+				o = append(o, &Subtitles{
+					Metadata:      s.Metadata,
+					PreserveOrder: s.PreserveOrder,
+					Regions:       s.Regions,
+					Styles:        s.Styles,
+				})
+			}
+
+			// Get lines
+			end := idx + linesPerTrack
+			if end > len(i.Lines) {
+				end = len(i.Lines)
+			}
+
+			// Append item
+			n := *i
+			n.Lines = append([]Line{}, i.Lines[idx:end]...)
+			o[track].Items = append(o[track].Items, &n)
+		}
+	}
+	return
+}
+
+// Clone returns a deep copy of the subtitles that can be modified without affecting them. Regions and styles are
+// copied once and items, regions and styles of the copy reference the copied ones.
+func (s Subtitles) Clone() *Subtitles {
+	return copySubtitles(s)
+}
+
+// subtitlesCopier deep copies subtitles, making sure regions and styles shared by several items are copied once
+type subtitlesCopier struct {
+	regions map[*Region]*Region
+	styles  map[*Style]*Style
+}
+
+// copySubtitles returns a deep copy of the subtitles
+func copySubtitles(s Subtitles) (o *Subtitles) {
+	// Init
+	var c = subtitlesCopier{
+		regions: make(map[*Region]*Region),
+		styles:  make(map[*Style]*Style),
+	}
+	o = &Subtitles{
+		PreserveOrder: s.PreserveOrder,
+		Regions:       make(map[string]*Region),
+		Styles:        make(map[string]*Style),
+	}
+
+	// Copy metadata
+	if s.Metadata != nil {
+		m := *s.Metadata
+		m.Comments = append([]string(nil), s.Metadata.Comments...)
+		m.WebVTTTrailingComments = append([]string(nil), s.Metadata.WebVTTTrailingComments...)
+		m.SSAComments = append([]SSAComment(nil), s.Metadata.SSAComments...)
+		for idx := range m.SSAComments {
+			copyPointerFields(&m.SSAComments[idx])
+		}
+		copyPointerFields(&m)
+		o.Metadata = &m
+	}
+
+	// Copy regions and styles
+	for k, v := range s.Regions {
+		o.Regions[k] = c.region(v)
+	}
+	for k, v := range s.Styles {
+		o.Styles[k] = c.style(v)
+	}
+
+	// Copy items
+	for _, i := range s.Items {
+		n := *i
+		n.Comments = append([]string(nil), i.Comments...)
+		n.InlineStyle = copyStyleAttributes(i.InlineStyle)
+		n.Region = c.region(i.Region)
+		n.Style = c.style(i.Style)
+		n.Lines = make([]Line, 0, len(i.Lines))
+		for _, l := range i.Lines {
+			nl := Line{VoiceClasses: append([]string(nil), l.VoiceClasses...), VoiceName: l.VoiceName}
+			for _, li := range l.Items {
+				li.InlineStyle = copyStyleAttributes(li.InlineStyle)
+				li.Style = c.style(li.Style)
+				nl.Items = append(nl.Items, li)
+			}
+			n.Lines = append(n.Lines, nl)
+		}
+		o.Items = append(o.Items, &n)
+	}
+	return
+}
+
+// region returns the copy of a region
+func (c subtitlesCopier) region(r *Region) *Region {
+	if r == nil {
+		return nil
+	}
+	if n, ok := c.regions[r]; ok {
+		return n
+	}
+	n := &Region{ID: r.ID, InlineStyle: copyStyleAttributes(r.InlineStyle)}
+	c.regions[r] = n
+	n.Style = c.style(r.Style)
+	return n
+}
+
+// style returns the copy of a style
+func (c subtitlesCopier) style(s *Style) *Style {
+	if s == nil {
+		return nil
+	}
+	if n, ok := c.styles[s]; ok {
+		return n
+	}
+	n := &Style{ID: s.ID, InlineStyle: copyStyleAttributes(s.InlineStyle)}
+	c.styles[s] = n
+	n.Style = c.style(s.Style)
+	return n
+}
+
+// copyStyleAttributes returns a copy of style attributes whose slices are not shared
+func copyStyleAttributes(sa *StyleAttributes) *StyleAttributes {
+	if sa == nil {
+		return nil
+	}
+	n := *sa
+	copyPointerFields(&n)
+	n.WebVTTStyles = append([]string(nil), sa.WebVTTStyles...)
+	n.WebVTTTags = append([]WebVTTTag(nil), sa.WebVTTTags...)
+	for idx := range n.WebVTTTags {
+		n.WebVTTTags[idx].Classes = append([]string(nil), n.WebVTTTags[idx].Classes...)
+	}
+	n.TTMLAnimations = nil
+	for _, a := range sa.TTMLAnimations {
+		a.Style = copyStyleAttributes(a.Style)
+		n.TTMLAnimations = append(n.TTMLAnimations, a)
+	}
+	return &n
+}
+
+// resolvedAttributes returns the style attributes, the ones that are not set being inherited from its parent styles
+func (s *Style) resolvedAttributes() (o StyleAttributes) {
+	var dst = reflect.ValueOf(&o).Elem()
+	var visited = make(map[*Style]bool)
+	for st := s; st != nil && !visited[st]; st = st.Style {
+		visited[st] = true
+		if st.InlineStyle == nil {
+			continue
+		}
+		var src = reflect.ValueOf(st.InlineStyle).Elem()
+		for idx := 0; idx < src.NumField(); idx++ {
+			if dst.Field(idx).IsZero() {
+				dst.Field(idx).Set(src.Field(idx))
+			}
+		}
+	}
+	return
+}
+
+// styleAttributesEqual returns whether style attributes have the same values, colors being compared with Color.Equal
+func styleAttributesEqual(a, b StyleAttributes) bool {
+	var va, vb = reflect.ValueOf(a), reflect.ValueOf(b)
+	for idx := 0; idx < va.NumField(); idx++ {
+		if ca, ok := va.Field(idx).Interface().(*Color); ok {
+			if !ca.Equal(vb.Field(idx).Interface().(*Color)) {
+				return false
+			}
+		} else if !reflect.DeepEqual(va.Field(idx).Interface(), vb.Field(idx).Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+// copyPointerFields makes the non-nil pointer fields of the struct v points to point to copies of their values
+func copyPointerFields(v interface{}) {
+	var rv = reflect.ValueOf(v).Elem()
+	for idx := 0; idx < rv.NumField(); idx++ {
+		f := rv.Field(idx)
+		if f.Kind() != reflect.Ptr || f.IsNil() || !f.CanSet() {
+			continue
+		}
+		n := reflect.New(f.Elem().Type())
+		n.Elem().Set(f.Elem())
+		f.Set(n)
+	}
+}
+
+// Split splits subtitles at a specific time into 2 independent deep copies. Items starting before it go to the
+// first subtitles and items ending after it go to the second subtitles, whose times are rebased so that it becomes
+// zero. Items straddling it are duplicated in both with their time boundaries clamped. Both subtitles are ordered.
+func (s Subtitles) Split(at time.Duration) (left, right *Subtitles) {
+	// Copy
+	left, right = copySubtitles(s), copySubtitles(s)
+
+	// Left items
+	var items []*Item
+	for _, i := range left.Items {
+		if i.StartAt >= at {
+			continue
+		}
+		if i.EndAt > at {
+			i.EndAt = at
+		}
+		items = append(items, i)
+	}
+	left.Items = items
+	left.Order()
+
+	// Right items
+	items = nil
+	for _, i := range right.Items {
+		if i.StartAt < at && i.EndAt <= at {
+			continue
+		}
+		i.StartAt, i.EndAt = splitRebase(i.StartAt, at), splitRebase(i.EndAt, at)
+		for idxLine := range i.Lines {
+			for idxItem := range i.Lines[idxLine].Items {
+				if li := &i.Lines[idxLine].Items[idxItem]; li.StartAt > 0 {
+					li.StartAt = splitRebase(li.StartAt, at)
+				}
+			}
+		}
+		items = append(items, i)
+	}
+	right.Items = items
+	right.Order()
+	return
+}
+
+// splitRebase rebases a time on the split time
+func splitRebase(t, at time.Duration) time.Duration {
+	if t < at {
+		return 0
+	}
+	return t - at
+}
+
+// SplitAtMode represents the way Item.SplitAt allocates the text of items without word-level timings
+type SplitAtMode int
+
+// Split at modes
+const (
+	// Text is split on the space that best matches the proportion of the duration before the split time
+	SplitAtModeProportional SplitAtMode = iota
+	// Text is left on the first item
+	SplitAtModeFirst
+)
+
+// SplitAt splits the item into 2 items sharing the same styling, the first one ending at the split time and the second
+// one starting at it. If line items have word-level StartAt timings, line items starting after the split time go to
+// the second item, otherwise the text is allocated based on the mode. Spaces surrounding the split are removed.
+// The first item keeps the comments, the ID and the index.
+func (i Item) SplitAt(at time.Duration, mode SplitAtMode) (first, second *Item, err error) {
+	// Split time must be within the item
+	if at <= i.StartAt || at >= i.EndAt {
+		err = fmt.Errorf("astisub: split time %s is not between %s and %s", at, i.StartAt, i.EndAt)
+		return
+	}
+
+	// Flatten text, lines being separated by a new line, and look for word-level timings
+	var rs []rune
+	var cut = -1
+	var timed bool
+	for idx, l := range i.Lines {
+		if idx > 0 {
+			rs = append(rs, '\n')
+		}
+		for _, li := range l.Items {
+			if li.StartAt > 0 {
+				timed = true
+				if cut < 0 && li.StartAt >= at {
+					cut = len(rs)
+				}
+			}
+			rs = append(rs, []rune(li.Text)...)
+		}
+	}
+
+	// Get the position of the first rune of the second item
+	if !timed {
+		cut = -1
+		if mode == SplitAtModeProportional {
+			cut = splitAtProportionalCut(rs, float64(at-i.StartAt)/float64(i.EndAt-i.StartAt))
+		}
+	}
+	if cut < 0 {
+		cut = len(rs)
+	}
+
+	// Create items
+	first, second = &Item{
+		Comments:    i.Comments,
+		EndAt:       at,
+		ID:          i.ID,
+		Index:       i.Index,
+		InlineStyle: copyStyleAttributes(i.InlineStyle),
+		Region:      i.Region,
+		StartAt:     i.StartAt,
+		Style:       i.Style,
+	}, &Item{
+		EndAt:       i.EndAt,
+		InlineStyle: copyStyleAttributes(i.InlineStyle),
+		Region:      i.Region,
+		StartAt:     at,
+		Style:       i.Style,
+	}
+
+	// Split lines
+	var k int
+	for idxLine, l := range i.Lines {
+		if idxLine > 0 {
+			k++
+		}
+		var fl, sl = Line{VoiceClasses: l.VoiceClasses, VoiceName: l.VoiceName}, Line{VoiceClasses: l.VoiceClasses, VoiceName: l.VoiceName}
+		for _, li := range l.Items {
+			var text = []rune(li.Text)
+			var n = cut - k
+			if n < 0 {
+				n = 0
+			} else if n > len(text) {
+				n = len(text)
+			}
+			if n > 0 {
+				fl.Items = append(fl.Items, li.withText(string(text[:n])))
+			}
+			if n < len(text) {
+				nli := li.withText(string(text[n:]))
+				nli.InlineStyle = copyStyleAttributes(li.InlineStyle)
+				sl.Items = append(sl.Items, nli)
+			}
+			k += len(text)
+		}
+		if len(fl.Items) > 0 {
+			first.Lines = append(first.Lines, fl)
+		}
+		if len(sl.Items) > 0 {
+			second.Lines = append(second.Lines, sl)
+		}
+	}
+
+	// Remove spaces surrounding the split
+	if len(first.Lines) > 0 {
+		l := &first.Lines[len(first.Lines)-1]
+		li := &l.Items[len(l.Items)-1]
+		if li.Text = strings.TrimRightFunc(li.Text, unicode.IsSpace); li.Text == "" {
+			l.Items = l.Items[:len(l.Items)-1]
+		}
+		if len(l.Items) == 0 {
+			first.Lines = first.Lines[:len(first.Lines)-1]
+		}
+	}
+	if len(second.Lines) > 0 {
+		l := &second.Lines[0]
+		li := &l.Items[0]
+		if li.Text = strings.TrimLeftFunc(li.Text, unicode.IsSpace); li.Text == "" {
+			l.Items = l.Items[1:]
+		}
+		if len(l.Items) == 0 {
+			second.Lines = second.Lines[1:]
+		}
+	}
+	return
+}
+
+// splitAtProportionalCut returns the position of the word start whose proportion of non space runes before it is the
+// closest to ratio, or -1 if there's none
+func splitAtProportionalCut(rs []rune, ratio float64) (cut int) {
+	// Count non space runes
+	var total int
+	for _, r := range rs {
+		if !unicode.IsSpace(r) {
+			total++
+		}
+	}
+
+	// Loop through word starts
+	cut = -1
+	var best float64
+	var before int
+	for idx, r := range rs {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if idx > 0 && unicode.IsSpace(rs[idx-1]) {
+			if diff := math.Abs(float64(before)/float64(total) - ratio); cut < 0 || diff < best {
+				best = diff
+				cut = idx
+			}
+		}
+		before++
+	}
+	return
+}
+
+// Sentences
+var (
+	sentenceAbbreviations = map[string]bool{
+		"dr": true, "e.g": true, "etc": true, "i.e": true, "jr": true, "mr": true, "mrs": true, "ms": true,
+		"mt": true, "no": true, "prof": true, "sr": true, "st": true, "vs": true,
+	}
+	sentenceTerminatorsCJK = "。！？"
+	sentenceTerminators    = ".!?"
+	sentenceClosings       = "\"'”’)]」』）"
+	sentenceOpenings       = "\"“‘(¿¡「『（"
+)
+
+// sentenceBoundaries returns the positions, in the runes of the text, where sentences other than the first one start
+func sentenceBoundaries(rs []rune) (o []int) {
+	for k := 0; k < len(rs); k++ {
+		// CJK terminators don't need to be followed by a space
+		if strings.ContainsRune(sentenceTerminatorsCJK, rs[k]) {
+			j := k + 1
+			for j < len(rs) && (strings.ContainsRune(sentenceTerminatorsCJK, rs[j]) || strings.ContainsRune(sentenceClosings, rs[j])) {
+				j++
+			}
+			for j < len(rs) && unicode.IsSpace(rs[j]) {
+				j++
+			}
+			if j < len(rs) {
+				o = append(o, j)
+			}
+			k = j - 1
+			continue
+		}
+
+		// Not a terminator
+		if !strings.ContainsRune(sentenceTerminators, rs[k]) {
+			continue
+		}
+
+		// Get terminators
+		e := k
+		for e < len(rs) && strings.ContainsRune(sentenceTerminators, rs[e]) {
+			e++
+		}
+		run := string(rs[k:e])
+
+		// Get start of the next sentence, which must follow a space and start with an uppercase letter or a digit
+		j := e
+		for j < len(rs) && strings.ContainsRune(sentenceClosings, rs[j]) {
+			j++
+		}
+		n := j
+		for n < len(rs) && unicode.IsSpace(rs[n]) {
+			n++
+		}
+		k = e - 1
+		if n == j || n == len(rs) {
+			continue
+		}
+		if f := n; strings.ContainsRune(sentenceOpenings, rs[f]) {
+			if f++; f == len(rs) || !(unicode.IsUpper(rs[f]) || unicode.IsDigit(rs[f])) {
+				continue
+			}
+		} else if !unicode.IsUpper(rs[f]) && !unicode.IsDigit(rs[f]) {
+			continue
+		}
+
+		// Ellipses are not boundaries
+		if strings.Count(run, ".") > 1 {
+			continue
+		}
+
+		// Abbreviations and initials are not boundaries
+		if run == "." {
+			b := k
+			for b > 0 && (unicode.IsLetter(rs[b-1]) || rs[b-1] == '.') {
+				b--
+			}
+			if w := strings.ToLower(string(rs[b:k])); utf8.RuneCountInString(w) == 1 || sentenceAbbreviations[w] {
+				continue
+			}
+		}
+		o = append(o, n)
+	}
+	return
+}
+
+// SplitAtSentences splits items at sentence boundaries, which are ".", "!" and "?" followed by a space and either an
+// uppercase letter or a digit, as well as "。", "！" and "？". Abbreviations, initials and ellipses are not considered as
+// boundaries. Each item's duration is distributed proportionally to the number of characters of its sentences and
+// splits that would produce items shorter than minDuration are skipped. It returns the number of added items.
+func (s *Subtitles) SplitAtSentences(minDuration time.Duration) (added int) {
+	var items []*Item
+	for _, i := range s.Items {
+		is := i.splitAtSentences(minDuration)
+		added += len(is) - 1
+		items = append(items, is...)
+	}
+	s.Items = items
+	return
+}
+
+// splitAtSentences splits the item at sentence boundaries
+func (i *Item) splitAtSentences(minDuration time.Duration) []*Item {
+	// Flatten text, lines being separated by a new line
+	var rs []rune
+	for idx, l := range i.Lines {
+		if idx > 0 {
+			rs = append(rs, '\n')
+		}
+		for _, li := range l.Items {
+			rs = append(rs, []rune(li.Text)...)
+		}
+	}
+
+	// Get boundaries
+	bs := sentenceBoundaries(rs)
+	if len(bs) == 0 {
+		return []*Item{i}
+	}
+
+	// Count characters
+	var counts = make([]int, len(bs)+1)
+	var sentence, total int
+	for idx, r := range rs {
+		for sentence < len(bs) && idx >= bs[sentence] {
+			sentence++
+		}
+		if !unicode.IsSpace(r) {
+			counts[sentence]++
+			total++
+		}
+	}
+
+	// Only keep boundaries producing items long enough
+	var d = i.EndAt - i.StartAt
+	var cuts = make(map[int]time.Duration)
+	var prev, before int
+	for idx, b := range bs {
+		before += counts[idx]
+		t := time.Duration(float64(d) * float64(before) / float64(total))
+		if t-time.Duration(float64(d)*float64(prev)/float64(total)) < minDuration || d-t < minDuration {
+			continue
+		}
+		cuts[b] = i.StartAt + t
+		prev = before
+	}
+	if len(cuts) == 0 {
+		return []*Item{i}
+	}
+
+	// Split
+	var o []*Item
+	var current *Item
+	newItem := func(startAt time.Duration) {
+		current = &Item{
+			EndAt:   i.EndAt,
+			Region:  i.Region,
+			StartAt: startAt,
+			Style:   i.Style,
+		}
+		if i.InlineStyle != nil {
+			sa := *i.InlineStyle
+			current.InlineStyle = &sa
+		}
+		if len(o) == 0 {
+			current.Comments = i.Comments
+			current.ID = i.ID
+		} else {
+			// Remove spaces separating sentences
+			p := o[len(o)-1]
+			p.EndAt = startAt
+			if len(p.Lines) > 0 {
+				l := &p.Lines[len(p.Lines)-1]
+				li := &l.Items[len(l.Items)-1]
+				if li.Text = strings.TrimRightFunc(li.Text, unicode.IsSpace); li.Text == "" {
+					l.Items = l.Items[:len(l.Items)-1]
+				}
+				if len(l.Items) == 0 {
+					p.Lines = p.Lines[:len(p.Lines)-1]
+				}
+			}
+		}
+		o = append(o, current)
+	}
+	newItem(i.StartAt)
+	var k int
+	for idxLine, l := range i.Lines {
+		if idxLine > 0 {
+			k++
+		}
+		var line = Line{VoiceClasses: l.VoiceClasses, VoiceName: l.VoiceName}
+		for _, li := range l.Items {
+			var from int
+			var text = []rune(li.Text)
+			for idx := range text {
+				if t, ok := cuts[k+idx]; ok {
+					// Close line
+					if piece := string(text[from:idx]); piece != "" {
+						nli := li
+						nli.Text = piece
+						line.Items = append(line.Items, nli)
+					}
+					if len(line.Items) > 0 {
+						current.Lines = append(current.Lines, line)
+					}
+					line = Line{VoiceClasses: l.VoiceClasses, VoiceName: l.VoiceName}
+					from = idx
+					newItem(t)
+				}
+			}
+			if piece := string(text[from:]); piece != "" {
+				li.Text = piece
+				line.Items = append(line.Items, li)
+			}
+			k += len(text)
+		}
+		if len(line.Items) > 0 {
+			current.Lines = append(current.Lines, line)
 		}
 	}
+	return o
 }
 
-// Optimize optimizes subtitles
-func (s *Subtitles) Optimize() {
-	// Nothing to optimize
+// SplitByCount splits subtitles into chunks of itemsPerFile items, the last one containing the remaining items.
+// Items are ordered beforehand unless PreserveOrder is set. Items are copied and reindexed in each chunk, and each
+// chunk gets its own copy of the metadata as well as its own regions and styles, from which unused ones are removed.
+func (s Subtitles) SplitByCount(itemsPerFile int) (o []*Subtitles) {
+	// Nothing to split
 	if len(s.Items) == 0 {
 		return
 	}
 
-	// Remove unused regions and style
-	s.removeUnusedRegionsAndStyles()
-}
+	// Default is everything in one chunk
+	if itemsPerFile <= 0 {
+		itemsPerFile = len(s.Items)
+	}
 
-// removeUnusedRegionsAndStyles removes unused regions and styles
-func (s *Subtitles) removeUnusedRegionsAndStyles() {
-	// Loop through items
-	var usedRegions, usedStyles = make(map[string]bool), make(map[string]bool)
-	for _, item := range s.Items {
-		// Add region
-		if item.Region != nil {
-			usedRegions[item.Region.ID] = true
+	// Order items
+	var items = &Subtitles{Items: append([]*Item{}, s.Items...), PreserveOrder: s.PreserveOrder}
+	items.autoOrder()
+
+	// Loop through chunks
+	for idx := 0; idx < len(items.Items); idx += itemsPerFile {
+		// Create chunk
+		c := &Subtitles{
+			PreserveOrder: s.PreserveOrder,
+			Regions:       make(map[string]*Region),
+			Styles:        make(map[string]*Style),
+		}
+		if s.Metadata != nil {
+			m := *s.Metadata
+			c.Metadata = &m
+		}
+		for k, v := range s.Regions {
+			c.Regions[k] = v
+		}
+		for k, v := range s.Styles {
+			c.Styles[k] = v
 		}
 
-		// Add style
-		if item.Style != nil {
-			usedStyles[item.Style.ID] = true
+		// Add items
+		end := idx + itemsPerFile
+		if end > len(items.Items) {
+			end = len(items.Items)
+		}
+		for _, i := range items.Items[idx:end] {
+			n := *i
+			n.Index = len(c.Items) + 1
+			c.Items = append(c.Items, &n)
 		}
 
-		// Loop through lines
-		for _, line := range item.Lines {
-			// Loop through line items
-			for _, lineItem := range line.Items {
-				// Add style
-				if lineItem.Style != nil {
-					usedStyles[lineItem.Style.ID] = true
-				}
-			}
+		// Optimize
+		c.Optimize()
+		o = append(o, c)
+	}
+	return
+}
+
+// StackOverlaps splits overlapping items so that each overlap gets its own item containing the lines of all
+// the items displayed at that time. Portions of items that don't overlap are kept as their own items.
+// Items end up ordered by their start time.
+func (s *Subtitles) StackOverlaps() {
+	// Nothing to do if less than 1 element
+	if len(s.Items) <= 1 {
+		return
+	}
+
+	// Order
+	var items = append([]*Item{}, s.Items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].StartAt < items[j].StartAt
+	})
+
+	// Get time boundaries
+	var ts []time.Duration
+	var empty []*Item
+	for _, i := range items {
+		// Items without duration are never displayed and are kept as is
+		if i.EndAt <= i.StartAt {
+			empty = append(empty, i)
+			continue
 		}
+		ts = append(ts, i.StartAt, i.EndAt)
 	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
 
-	// Loop through regions
-	for id, region := range s.Regions {
-		if _, ok := usedRegions[region.ID]; ok {
-			if region.Style != nil {
-				usedStyles[region.Style.ID] = true
+	// Loop through time boundaries
+	var o []*Item
+	for idx := 0; idx < len(ts)-1; idx++ {
+		// Same boundary
+		if ts[idx] == ts[idx+1] {
+			continue
+		}
+
+		// Get displayed items
+		var displayed []*Item
+		for _, i := range items {
+			if i.StartAt <= ts[idx] && i.EndAt > ts[idx] {
+				displayed = append(displayed, i)
 			}
-		} else {
-			delete(s.Regions, id)
 		}
+
+		// Nothing is displayed
+		if len(displayed) == 0 {
+			continue
+		}
+
+		// Item doesn't overlap
+		if len(displayed) == 1 && displayed[0].StartAt == ts[idx] && displayed[0].EndAt == ts[idx+1] {
+			o = append(o, displayed[0])
+			continue
+		}
+
+		// Create item
+		var n = *displayed[0]
+		n.EndAt = ts[idx+1]
+		n.Lines = []Line{}
+		n.StartAt = ts[idx]
+		for _, i := range displayed {
+			n.Lines = append(n.Lines, i.Lines...)
+		}
+		o = append(o, &n)
 	}
 
-	// Loop through style
-	for id, style := range s.Styles {
-		if _, ok := usedStyles[style.ID]; !ok {
-			delete(s.Styles, id)
+	// Add items without duration
+	o = append(o, empty...)
+	sort.SliceStable(o, func(i, j int) bool {
+		return o[i].StartAt < o[j].StartAt
+	})
+	s.Items = o
+}
+
+// Overlaps returns the indexes of the items whose time ranges overlap, as pairs ordered by index.
+// Items without duration are never displayed and therefore never overlap.
+func (s Subtitles) Overlaps() (o [][2]int) {
+	for idx1, i1 := range s.Items {
+		if i1.EndAt <= i1.StartAt {
+			continue
+		}
+		for idx2 := idx1 + 1; idx2 < len(s.Items); idx2++ {
+			if i2 := s.Items[idx2]; i2.EndAt > i2.StartAt && i1.StartAt < i2.EndAt && i2.StartAt < i1.EndAt {
+				o = append(o, [2]int{idx1, idx2})
+			}
 		}
 	}
+	return
 }
 
-// Order orders items
-func (s *Subtitles) Order() {
-	// Nothing to do if less than 1 element
+// FixOverlaps removes overlaps by trimming the earlier item's EndAt to minGap before the later item's StartAt, the gap
+// being dropped if the earlier item would otherwise end before it starts. Overlapping items with the same text are
+// merged instead, and items starting at the same time are considered displayed together on purpose and are left
+// untouched.
+// Items are ordered beforehand unless PreserveOrder is set. It returns the number of trimmed or merged items.
+func (s *Subtitles) FixOverlaps(minGap time.Duration) (fixed int) {
+	// Nothing to fix with less than 2 items
 	if len(s.Items) <= 1 {
 		return
 	}
 
 	// Order
-	sort.SliceStable(s.Items, func(i, j int) bool {
-		return s.Items[i].StartAt < s.Items[j].StartAt
-	})
-}
+	s.autoOrder()
 
-// RemoveStyling removes the styling from the subtitles
-func (s *Subtitles) RemoveStyling() {
-	s.Regions = map[string]*Region{}
-	s.Styles = map[string]*Style{}
-	for _, i := range s.Items {
-		i.Region = nil
-		i.Style = nil
-		i.InlineStyle = nil
-		for idxLine, l := range i.Lines {
-			for idxLineItem := range l.Items {
-				i.Lines[idxLine].Items[idxLineItem].InlineStyle = nil
-				i.Lines[idxLine].Items[idxLineItem].Style = nil
+	// Loop through items
+	for idx1 := 0; idx1 < len(s.Items)-1; idx1++ {
+		var i1 = s.Items[idx1]
+		for idx2 := idx1 + 1; idx2 < len(s.Items); idx2++ {
+			// Items don't overlap
+			var i2 = s.Items[idx2]
+			if i1.EndAt <= i2.StartAt || i2.EndAt <= i2.StartAt || i2.StartAt <= i1.StartAt {
+				continue
+			}
+
+			// Same text
+			if i1.String() == i2.String() {
+				if i1.EndAt < i2.EndAt {
+					i1.EndAt = i2.EndAt
+				}
+				s.Items = append(s.Items[:idx2], s.Items[idx2+1:]...)
+				idx2--
+				fixed++
+				continue
+			}
+
+			// Trim
+			if i1.EndAt = i2.StartAt - minGap; i1.EndAt <= i1.StartAt {
+				i1.EndAt = i2.StartAt
 			}
+			fixed++
 		}
 	}
+	return
 }
 
 // Unfragment unfragments subtitles
+// Items are ordered beforehand unless PreserveOrder is set
 func (s *Subtitles) Unfragment() {
 	// Nothing to do if less than 1 element
 	if len(s.Items) <= 1 {
@@ -744,7 +3669,7 @@ func (s *Subtitles) Unfragment() {
 	}
 
 	// Order
-	s.Order()
+	s.autoOrder()
 
 	// Loop through items
 	for i := 0; i < len(s.Items)-1; i++ {
@@ -757,13 +3682,115 @@ func (s *Subtitles) Unfragment() {
 				}
 				s.Items = append(s.Items[:j], s.Items[j+1:]...)
 				j--
-			} else if s.Items[i].EndAt < s.Items[j].StartAt {
+			} else if !s.PreserveOrder && s.Items[i].EndAt < s.Items[j].StartAt {
+				// Following items can't be merged since they're ordered
 				break
 			}
 		}
 	}
 }
 
+// RemoveDuplicates removes items whose text and time boundaries are identical to a preceding item's, the first one
+// being kept. Items don't need to be ordered nor adjacent. It returns the number of removed items.
+func (s *Subtitles) RemoveDuplicates() (removed int) {
+	type key struct {
+		endAt   time.Duration
+		startAt time.Duration
+		text    string
+	}
+	var seen = make(map[key]bool)
+	return s.Filter(func(i *Item) bool {
+		var k = key{
+			endAt:   i.EndAt,
+			startAt: i.StartAt,
+			text:    i.String(),
+		}
+		if seen[k] {
+			return false
+		}
+		seen[k] = true
+		return true
+	})
+}
+
+// MergeAdjacentOptions represents merge adjacent options
+type MergeAdjacentOptions struct {
+	// MaxDuration is the maximum duration of a merged item, 0 meaning no maximum
+	MaxDuration time.Duration
+	// MaxGap is the maximum gap between an item's EndAt and the next item's StartAt for them to be merged
+	MaxGap time.Duration
+	// MaxLines is the maximum number of lines of a merged item, 0 meaning no maximum
+	MaxLines int
+}
+
+// MergeAdjacent merges consecutive items sharing the same style, region and inline style into a single item spanning
+// their combined time, their lines being appended one after the other. Overlapping items are not merged.
+// Unlike Unfragment, items' text doesn't need to be identical.
+// Items are ordered beforehand unless PreserveOrder is set. It returns the number of merged items.
+func (s *Subtitles) MergeAdjacent(o MergeAdjacentOptions) (merged int) {
+	// Nothing to merge with less than 2 items
+	if len(s.Items) <= 1 {
+		return
+	}
+
+	// Order
+	s.autoOrder()
+
+	// Loop through items
+	for idx := 0; idx < len(s.Items)-1; idx++ {
+		i, n := s.Items[idx], s.Items[idx+1]
+
+		// Check thresholds
+		if gap := n.StartAt - i.EndAt; gap < 0 || gap > o.MaxGap {
+			continue
+		}
+		if o.MaxDuration > 0 && n.EndAt-i.StartAt > o.MaxDuration {
+			continue
+		}
+		if o.MaxLines > 0 && len(i.Lines)+len(n.Lines) > o.MaxLines {
+			continue
+		}
+
+		// Check styling
+		if i.Style != n.Style || i.Region != n.Region || !reflect.DeepEqual(i.InlineStyle, n.InlineStyle) {
+			continue
+		}
+
+		// Merge
+		i.Comments = append(i.Comments, n.Comments...)
+		i.EndAt = n.EndAt
+		i.Lines = append(i.Lines, n.Lines...)
+		s.Items = append(s.Items[:idx+1], s.Items[idx+2:]...)
+		merged++
+		idx--
+	}
+	return
+}
+
+// ApplyCadence retimes items, in their current order, so that each of them is displayed for displayDuration with
+// gap between consecutive items, starting at the first item's StartAt. Line items' StartAt are dropped.
+// It returns whether the subtitles duration exceeds maxDuration, a non-positive maxDuration meaning no maximum.
+func (s *Subtitles) ApplyCadence(displayDuration, gap, maxDuration time.Duration) (exceeded bool) {
+	// Nothing to retime
+	if len(s.Items) == 0 {
+		return
+	}
+
+	// Loop through items
+	var startAt = s.Items[0].StartAt
+	for _, i := range s.Items {
+		i.StartAt = startAt
+		i.EndAt = startAt + displayDuration
+		for idxLine := range i.Lines {
+			for idxLineItem := range i.Lines[idxLine].Items {
+				i.Lines[idxLine].Items[idxLineItem].StartAt = 0
+			}
+		}
+		startAt = i.EndAt + gap
+	}
+	return maxDuration > 0 && s.Duration() > maxDuration
+}
+
 // ApplyLinearCorrection applies linear correction
 func (s *Subtitles) ApplyLinearCorrection(actual1, desired1, actual2, desired2 time.Duration) {
 	// Get parameters
@@ -775,6 +3802,142 @@ func (s *Subtitles) ApplyLinearCorrection(actual1, desired1, actual2, desired2 t
 		s.Items[idx].EndAt = time.Duration(a*float64(s.Items[idx].EndAt)) + b
 		s.Items[idx].StartAt = time.Duration(a*float64(s.Items[idx].StartAt)) + b
 	}
+	s.retimeSSAComments(func(_, t time.Duration) time.Duration { return time.Duration(a*float64(t)) + b })
+}
+
+// Stretch multiplies the time boundaries of each item, as well as line items' timestamps, by factor.
+// Negative results are clamped to 0 and results too large to be represented are clamped to the maximum duration.
+func (s *Subtitles) Stretch(factor float64) {
+	for _, i := range s.Items {
+		i.EndAt = stretchDuration(i.EndAt, factor)
+		i.StartAt = stretchDuration(i.StartAt, factor)
+		for idxLine, l := range i.Lines {
+			for idxLineItem, li := range l.Items {
+				if li.StartAt > 0 {
+					i.Lines[idxLine].Items[idxLineItem].StartAt = stretchDuration(li.StartAt, factor)
+				}
+			}
+		}
+	}
+	s.retimeSSAComments(func(_, t time.Duration) time.Duration { return stretchDuration(t, factor) })
+}
+
+// ConvertFramerate stretches subtitles timed against a video at from fps so that they match the same video played
+// at to fps, e.g. 25 to 23.976 when undoing a PAL speedup. Nothing is done if a framerate is not strictly positive.
+func (s *Subtitles) ConvertFramerate(from, to float64) {
+	if from <= 0 || to <= 0 {
+		return
+	}
+	s.Stretch(from / to)
+}
+
+func stretchDuration(d time.Duration, factor float64) time.Duration {
+	var v = math.Round(float64(d) * factor)
+	if v <= 0 || math.IsNaN(v) {
+		return 0
+	} else if v >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(v)
+}
+
+// ApplySafeArea remaps positions so that text stays within a safe area inset by insetPercent on each side.
+// WebVTT and TTML positions and dimensions expressed in percentages are remapped as well as SSA margins.
+func (s *Subtitles) ApplySafeArea(insetPercent float64) {
+	// Invalid inset
+	if insetPercent <= 0 || insetPercent >= 50 {
+		return
+	}
+
+	// SSA margins are relative to the play resolution whose default is 384x288
+	resX, resY := 384, 288
+	if s.Metadata != nil {
+		if s.Metadata.SSAPlayResX != nil {
+			resX = *s.Metadata.SSAPlayResX
+		}
+		if s.Metadata.SSAPlayResY != nil {
+			resY = *s.Metadata.SSAPlayResY
+		}
+	}
+
+	// Style attributes may be shared
+	var done = make(map[*StyleAttributes]bool)
+	apply := func(sa *StyleAttributes) {
+		if sa == nil || done[sa] {
+			return
+		}
+		done[sa] = true
+		sa.applySafeArea(insetPercent, resX, resY)
+	}
+
+	// Loop through style attributes
+	for _, r := range s.Regions {
+		apply(r.InlineStyle)
+	}
+	for _, st := range s.Styles {
+		apply(st.InlineStyle)
+	}
+	for _, i := range s.Items {
+		apply(i.InlineStyle)
+		for _, l := range i.Lines {
+			for _, li := range l.Items {
+				apply(li.InlineStyle)
+			}
+		}
+	}
+}
+
+func (sa *StyleAttributes) applySafeArea(inset float64, resX, resY int) {
+	scale := (100 - 2*inset) / 100
+	position := func(v float64) float64 { return inset + v*scale }
+	dimension := func(v float64) float64 { return v * scale }
+
+	// WebVTT
+	sa.WebVTTLine = remapPercentages(sa.WebVTTLine, ",", position)
+	sa.WebVTTPosition = remapPercentages(sa.WebVTTPosition, ",", position)
+	sa.WebVTTSize = remapPercentages(sa.WebVTTSize, ",", dimension)
+	sa.WebVTTViewportAnchor = remapPercentages(sa.WebVTTViewportAnchor, ",", position)
+	sa.WebVTTWidth = remapPercentages(sa.WebVTTWidth, ",", dimension)
+
+	// TTML
+	if sa.TTMLExtent != nil {
+		sa.TTMLExtent = astikit.StrPtr(remapPercentages(*sa.TTMLExtent, " ", dimension))
+	}
+	if sa.TTMLOrigin != nil {
+		sa.TTMLOrigin = astikit.StrPtr(remapPercentages(*sa.TTMLOrigin, " ", position))
+	}
+
+	// SSA
+	margin := func(m *int, res int) *int {
+		if m == nil {
+			return nil
+		}
+		return astikit.IntPtr(int(math.Round(float64(res)*inset/100 + float64(*m)*scale)))
+	}
+	sa.SSAMarginLeft = margin(sa.SSAMarginLeft, resX)
+	sa.SSAMarginRight = margin(sa.SSAMarginRight, resX)
+	sa.SSAMarginVertical = margin(sa.SSAMarginVertical, resY)
+}
+
+// remapPercentages applies fn to each percentage of a list of values separated by sep.
+// Values that are not percentages are left untouched.
+func remapPercentages(i, sep string, fn func(v float64) float64) string {
+	if i == "" {
+		return i
+	}
+	var vs = strings.Split(i, sep)
+	for idx, v := range vs {
+		p := strings.Index(v, "%")
+		if p < 0 {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(v[:p]), 64)
+		if err != nil {
+			continue
+		}
+		vs[idx] = strconv.FormatFloat(math.Round(fn(f)*100)/100, 'f', -1, 64) + v[p:]
+	}
+	return strings.Join(vs, sep)
 }
 
 // Write writes subtitles to a file
@@ -789,14 +3952,32 @@ func (s Subtitles) Write(dst string) (err error) {
 
 	// Write the content
 	switch filepath.Ext(strings.ToLower(dst)) {
+	case ".csv":
+		err = s.WriteToCSV(f)
+	case ".jss":
+		err = s.WriteToJACOSub(f)
+	case ".lrc":
+		err = s.WriteToLRC(f)
+	case ".mpl":
+		err = s.WriteToMPL2(f)
+	case ".sbv":
+		err = s.WriteToSBV(f)
+	case ".smi", ".sami":
+		err = s.WriteToSAMI(f)
 	case ".srt":
 		err = s.WriteToSRT(f)
 	case ".ssa", ".ass":
 		err = s.WriteToSSA(f)
 	case ".stl":
 		err = s.WriteToSTL(f)
-	case ".ttml":
+	case ".ts":
+		err = s.WriteToTeletext(f, TeletextOptions{})
+	case ".ttml", ".dfxp":
 		err = s.WriteToTTML(f)
+	case ".txt":
+		err = s.WriteToText(f)
+	case ".itt":
+		err = s.WriteToITT(f)
 	case ".vtt":
 		err = s.WriteToWebVTT(f)
 	default:
@@ -805,8 +3986,20 @@ func (s Subtitles) Write(dst string) (err error) {
 	return
 }
 
-// parseDuration parses a duration in "00:00:00.000", "00:00:00,000" or "0:00:00:00" format
+// parseDuration parses a duration in "00:00:00.000" or "00:00:00,000" format
 func parseDuration(i, millisecondSep string, numberOfMillisecondDigits int) (o time.Duration, err error) {
+	return parseDurationWithFramerate(i, millisecondSep, numberOfMillisecondDigits, 0)
+}
+
+// parseDurationWithFramerate parses a duration in "00:00:00.000", "00:00:00,000" or "00:00:00:00" format, the last
+// part of the latter being a number of frames at the provided framerate. A ";" before the frames announces a drop
+// frame timecode.
+func parseDurationWithFramerate(i, millisecondSep string, numberOfMillisecondDigits, framerate int) (o time.Duration, err error) {
+	// Timecode
+	if strings.Count(i, ":")+strings.Count(i, ";") == 3 {
+		return parseTimecode(i, framerate)
+	}
+
 	// Split milliseconds
 	var parts = strings.Split(i, millisecondSep)
 	var milliseconds int
@@ -876,7 +4069,39 @@ func parseDuration(i, millisecondSep string, numberOfMillisecondDigits int) (o t
 	return
 }
 
-// formatDuration formats a duration
+// parseTimecode parses a "00:00:00:00" timecode, ";" announcing a drop frame timecode
+func parseTimecode(i string, framerate int) (o time.Duration, err error) {
+	// No framerate
+	if framerate <= 0 {
+		err = fmt.Errorf("astisub: no framerate to parse frames of %s", i)
+		return
+	}
+
+	// Split hours, minutes, seconds and frames
+	var dropFrame = strings.Contains(i, ";")
+	var parts = strings.Split(strings.Replace(strings.TrimSpace(i), ";", ":", -1), ":")
+	var vs [4]int
+	for idx, p := range parts {
+		p = strings.TrimSpace(p)
+		if vs[idx], err = strconv.Atoi(p); err != nil {
+			err = fmt.Errorf("astisub: atoi of %s failed: %w", p, err)
+			return
+		}
+	}
+
+	// Invalid frames
+	if vs[3] >= framerate {
+		err = fmt.Errorf("astisub: frames of %s exceed framerate %d", i, framerate)
+		return
+	}
+
+	// Generate output
+	o = timecodeToDuration(vs[0], vs[1], vs[2], vs[3], framerate, dropFrame)
+	return
+}
+
+// formatDuration formats a duration, the fractional part being truncated to numberOfMillisecondDigits digits.
+// Use roundDuration beforehand to round it instead.
 func formatDuration(i time.Duration, millisecondSep string, numberOfMillisecondDigits int) (s string) {
 	// Parse hours
 	var hours = int(i / time.Hour)
@@ -902,9 +4127,79 @@ func formatDuration(i time.Duration, millisecondSep string, numberOfMillisecondD
 	}
 	s += strconv.Itoa(seconds) + millisecondSep
 
-	// Parse milliseconds
-	var milliseconds = math.Floor(float64(n) / float64(time.Millisecond) / float64(math.Pow(10, 3-float64(numberOfMillisecondDigits))))
-	s += astikit.StrPad(strconv.FormatFloat(milliseconds, 'f', 0, 64), '0', numberOfMillisecondDigits, astikit.PadLeft)
+	// Parse milliseconds, using integers so that no precision is lost
+	var milliseconds = int64(n / durationUnit(numberOfMillisecondDigits))
+	s += astikit.StrPad(strconv.FormatInt(milliseconds, 10), '0', numberOfMillisecondDigits, astikit.PadLeft)
+	return
+}
+
+// durationUnit returns the duration of the last digit of a fractional part of numberOfMillisecondDigits digits
+func durationUnit(numberOfMillisecondDigits int) time.Duration {
+	var u = time.Second
+	for idx := 0; idx < numberOfMillisecondDigits; idx++ {
+		u /= 10
+	}
+	return u
+}
+
+// roundDuration rounds a duration half up to numberOfMillisecondDigits digits of its fractional part
+func roundDuration(i time.Duration, numberOfMillisecondDigits int) time.Duration {
+	return i.Round(durationUnit(numberOfMillisecondDigits))
+}
+
+// timecodeDroppedFrames returns the number of frame numbers dropped at the start of each minute but every tenth minute
+// by drop frame timecodes, which only exist for 29.97 and 59.94 fps videos whose nominal framerate is 30 and 60
+func timecodeDroppedFrames(framerate int, dropFrame bool) int {
+	if !dropFrame || framerate <= 0 || framerate%30 != 0 {
+		return 0
+	}
+	return framerate / 15
+}
+
+// timecodeToDuration converts a "HH:MM:SS:FF" timecode to a duration.
+// Drop frame timecodes skip frame numbers so that they stay in sync with the real time of a 1000/1001 slower video.
+func timecodeToDuration(hours, minutes, seconds, frames, framerate int, dropFrame bool) time.Duration {
+	// Non drop frame
+	var dropped = timecodeDroppedFrames(framerate, dropFrame)
+	if dropped == 0 {
+		return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second + time.Duration(1e9*frames/framerate)*time.Nanosecond
+	}
+
+	// Drop frame
+	var totalMinutes = 60*hours + minutes
+	var frameNumber = (60*totalMinutes+seconds)*framerate + frames - dropped*(totalMinutes-totalMinutes/10)
+	return time.Duration(int64(frameNumber) * 1001 * int64(time.Second) / int64(framerate*1000))
+}
+
+// durationToTimecode converts a duration to a "HH:MM:SS:FF" timecode, see timecodeToDuration
+func durationToTimecode(d time.Duration, framerate int, dropFrame bool) (hours, minutes, seconds, frames int) {
+	// Non drop frame
+	var dropped = timecodeDroppedFrames(framerate, dropFrame)
+	if dropped == 0 {
+		hours = int(d / time.Hour)
+		minutes = int(d % time.Hour / time.Minute)
+		seconds = int(d % time.Minute / time.Second)
+		frames = int(d%time.Second) * framerate / 1e9
+		return
+	}
+
+	// Get the frame number in the real time of the video
+	var frameNumber = int(math.Round(float64(d) * float64(framerate*1000) / float64(1001*time.Second)))
+
+	// Add dropped frame numbers
+	var framesPerMinute = 60*framerate - dropped
+	var framesPer10Minutes = 10*framesPerMinute + dropped
+	var tens, remainder = frameNumber / framesPer10Minutes, frameNumber % framesPer10Minutes
+	frameNumber += 9 * dropped * tens
+	if remainder > dropped {
+		frameNumber += dropped * ((remainder - dropped) / framesPerMinute)
+	}
+
+	// Split
+	hours = frameNumber / (3600 * framerate)
+	minutes = frameNumber / (60 * framerate) % 60
+	seconds = frameNumber / framerate % 60
+	frames = frameNumber % framerate
 	return
 }
 
@@ -926,8 +4221,9 @@ func htmlTokenAttribute(t *html.Token, key string) *string {
 	return nil
 }
 
+// escapeHTML escapes HTML special characters, entities being kept as is
 func escapeHTML(i string) string {
-	return htmlEscaper.Replace(i)
+	return htmlEscaper.Replace(escapeStrayAmpersands(i))
 }
 
 func unescapeHTML(i string) string {
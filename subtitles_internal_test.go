@@ -18,6 +18,40 @@ func TestColor(t *testing.T) {
 	assert.Equal(t, "12345678", c.SSAString())
 }
 
+func TestColorHexAndName(t *testing.T) {
+	c, err := NewColorFromHexString("#785634")
+	assert.NoError(t, err)
+	assert.Equal(t, Color{Blue: 0x34, Green: 0x56, Red: 0x78}, *c)
+	assert.Equal(t, "#785634", c.HexString())
+	c, err = NewColorFromHexString("#785634ed")
+	assert.NoError(t, err)
+	assert.Equal(t, Color{Alpha: 0x12, Blue: 0x34, Green: 0x56, Red: 0x78}, *c)
+	assert.Equal(t, "#785634ed", c.HexString())
+	_, err = NewColorFromHexString("#7856")
+	assert.Error(t, err)
+	_, err = NewColorFromHexString("#78563z")
+	assert.Error(t, err)
+
+	c, ok := NewColorFromName("Red")
+	assert.True(t, ok)
+	assert.Equal(t, *ColorRed, *c)
+	assert.False(t, c == ColorRed)
+	_, ok = NewColorFromName("unknown")
+	assert.False(t, ok)
+}
+
+func TestColorEqual(t *testing.T) {
+	var n *Color
+	assert.True(t, n.Equal(nil))
+	assert.False(t, n.Equal(ColorRed))
+	assert.False(t, ColorRed.Equal(nil))
+	assert.True(t, ColorRed.Equal(&Color{Red: 0xff}))
+	assert.False(t, ColorRed.Equal(&Color{Alpha: 0x80, Red: 0xff}))
+	assert.True(t, n.IsZero())
+	assert.False(t, (&Color{}).IsZero())
+	assert.False(t, ColorRed.IsZero())
+}
+
 func TestParseDuration(t *testing.T) {
 	_, err := parseDuration("12:34:56,1234", ",", 3)
 	assert.EqualError(t, err, "astisub: Invalid number of millisecond digits detected in 12:34:56,1234")
@@ -38,6 +72,22 @@ func TestParseDuration(t *testing.T) {
 	d, err = parseDuration("1:23:45.67", ".", 2)
 	assert.NoError(t, err)
 	assert.Equal(t, time.Hour+23*time.Minute+45*time.Second+67*time.Millisecond, d)
+
+	// Timecodes
+	_, err = parseDuration("01:02:03:12", ".", 3)
+	assert.EqualError(t, err, "astisub: no framerate to parse frames of 01:02:03:12")
+	d, err = parseDurationWithFramerate("01:02:03:12", ".", 3, 25)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour+2*time.Minute+3*time.Second+480*time.Millisecond, d)
+	d, err = parseDurationWithFramerate("00:01:00;02", ".", 3, 30)
+	assert.NoError(t, err)
+	assert.Equal(t, timecodeToDuration(0, 1, 0, 2, 30, true), d)
+	assert.Equal(t, time.Duration(1800)*1001*time.Second/30000, d)
+	_, err = parseDurationWithFramerate("01:02:03:25", ".", 3, 25)
+	assert.EqualError(t, err, "astisub: frames of 01:02:03:25 exceed framerate 25")
+	d, err = parseDurationWithFramerate("01:02:03.120", ".", 3, 25)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour+2*time.Minute+3*time.Second+120*time.Millisecond, d)
 }
 
 func TestFormatDuration(t *testing.T) {
@@ -65,4 +115,16 @@ func TestFormatDuration(t *testing.T) {
 	assert.Equal(t, "34:17:36,789", s)
 	s = formatDuration(12*time.Hour+34*time.Minute+56*time.Second+999*time.Millisecond, ",", 2)
 	assert.Equal(t, "12:34:56,99", s)
+
+	// Rounding
+	s = formatDuration(999600*time.Microsecond, ",", 3)
+	assert.Equal(t, "00:00:00,999", s)
+	s = formatDuration(roundDuration(999600*time.Microsecond, 3), ",", 3)
+	assert.Equal(t, "00:00:01,000", s)
+	s = formatDuration(roundDuration(time.Second+234500*time.Microsecond, 3), ",", 3)
+	assert.Equal(t, "00:00:01,235", s)
+	s = formatDuration(roundDuration(time.Second+234400*time.Microsecond, 3), ",", 3)
+	assert.Equal(t, "00:00:01,234", s)
+	s = formatDuration(roundDuration(59*time.Minute+59*time.Second+995*time.Millisecond, 2), ".", 2)
+	assert.Equal(t, "01:00:00.00", s)
 }
@@ -2,11 +2,14 @@ package astisub_test
 
 import (
 	"bytes"
+	"math"
 	"os"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/5rahim/go-astisub"
+	"github.com/asticode/go-astikit"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -42,6 +45,14 @@ func assertSubtitleItems(t *testing.T, i *astisub.Subtitles) {
 	assert.Equal(t, "electronic melody)", i.Items[5].Lines[1].String())
 }
 
+// itemText returns lines made of a single line item with each text
+func itemText(s ...string) (ls []astisub.Line) {
+	for _, v := range s {
+		ls = append(ls, astisub.Line{Items: []astisub.LineItem{{Text: v}}})
+	}
+	return
+}
+
 func mockSubtitles() *astisub.Subtitles {
 	return &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 3 * time.Second, StartAt: time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "subtitle-1"}}}}}, {EndAt: 7 * time.Second, StartAt: 3 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "subtitle-2"}}}}}}}
 }
@@ -63,16 +74,257 @@ func TestSubtitles_Add(t *testing.T) {
 	assert.Equal(t, "subtitle-2", s.Items[0].Lines[0].Items[0].Text)
 }
 
+func TestSubtitles_ShiftToStartAt(t *testing.T) {
+	var s = mockSubtitles()
+	s.Items[0], s.Items[1] = s.Items[1], s.Items[0]
+	s.ShiftToStartAt(0)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, time.Duration(0), s.Items[1].StartAt)
+	assert.Equal(t, 2*time.Second, s.Items[1].EndAt)
+	assert.Equal(t, 2*time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 6*time.Second, s.Items[0].EndAt)
+	s.ShiftToStartAt(10 * time.Second)
+	assert.Equal(t, 10*time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 16*time.Second, s.Items[0].EndAt)
+
+	// No items
+	s = &astisub.Subtitles{}
+	s.ShiftToStartAt(time.Second)
+	assert.Empty(t, s.Items)
+}
+
+func TestSubtitles_ShiftFrom(t *testing.T) {
+	var s = mockSubtitles()
+	s.ShiftFrom(2*time.Second, time.Second)
+	assert.Len(t, s.Items, 2)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 3*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, 4*time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 8*time.Second, s.Items[1].EndAt)
+	s.ShiftFrom(0, -2*time.Second)
+	assert.Len(t, s.Items, 2)
+	assert.Equal(t, time.Duration(0), s.Items[0].StartAt)
+	assert.Equal(t, time.Second, s.Items[0].EndAt)
+	s.ShiftFrom(0, -time.Second)
+	assert.Len(t, s.Items, 1)
+	assert.Equal(t, "subtitle-2", s.Items[0].Lines[0].Items[0].Text)
+}
+
+func TestSubtitles_SSACommentsTiming(t *testing.T) {
+	s := &astisub.Subtitles{Metadata: &astisub.Metadata{SSAComments: []astisub.SSAComment{
+		{EndAt: 2 * time.Second, StartAt: time.Second},
+		{EndAt: 6 * time.Second, StartAt: 5 * time.Second},
+	}}}
+	s.Add(time.Second)
+	assert.Equal(t, 2*time.Second, s.Metadata.SSAComments[0].StartAt)
+	assert.Equal(t, 3*time.Second, s.Metadata.SSAComments[0].EndAt)
+	s.ShiftFrom(4*time.Second, -time.Second)
+	assert.Equal(t, 2*time.Second, s.Metadata.SSAComments[0].StartAt)
+	assert.Equal(t, 5*time.Second, s.Metadata.SSAComments[1].StartAt)
+	assert.Equal(t, 6*time.Second, s.Metadata.SSAComments[1].EndAt)
+	s.Stretch(2)
+	assert.Equal(t, 4*time.Second, s.Metadata.SSAComments[0].StartAt)
+	assert.Equal(t, 12*time.Second, s.Metadata.SSAComments[1].EndAt)
+	s.Add(-5 * time.Second)
+	assert.Equal(t, time.Duration(0), s.Metadata.SSAComments[0].StartAt)
+	assert.Equal(t, time.Second, s.Metadata.SSAComments[0].EndAt)
+}
+
+func TestSubtitles_ShiftRange(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, StartAt: time.Second},
+		{EndAt: 4 * time.Second, StartAt: 3 * time.Second},
+		{EndAt: 6 * time.Second, StartAt: 5 * time.Second},
+		{EndAt: 8 * time.Second, StartAt: 7 * time.Second},
+	}}
+	s.ShiftRange(3*time.Second, 7*time.Second, -3*time.Second)
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: time.Second, StartAt: 0},
+		{EndAt: 2 * time.Second, StartAt: time.Second},
+		{EndAt: 3 * time.Second, StartAt: 2 * time.Second},
+		{EndAt: 8 * time.Second, StartAt: 7 * time.Second},
+	}, s.Items)
+	s.ShiftRange(0, 2*time.Second, -time.Second)
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: time.Second, StartAt: 0},
+		{EndAt: 3 * time.Second, StartAt: 2 * time.Second},
+		{EndAt: 8 * time.Second, StartAt: 7 * time.Second},
+	}, s.Items)
+}
+
+func TestSubtitles_Retime(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, StartAt: time.Second},
+		{EndAt: 5 * time.Second, StartAt: 4 * time.Second},
+		{EndAt: 12 * time.Second, StartAt: 10 * time.Second},
+		{EndAt: 21 * time.Second, StartAt: 19 * time.Second},
+		{EndAt: 27 * time.Second, StartAt: 26 * time.Second},
+	}}
+	dropped := s.Retime([]astisub.RetimeSegment{
+		// Kept as is
+		{NewFrom: 0, NewTo: 3 * time.Second, OldFrom: 0, OldTo: 3 * time.Second},
+		// Moved after the next segment and slowed down
+		{NewFrom: 20 * time.Second, NewTo: 40 * time.Second, OldFrom: 10 * time.Second, OldTo: 20 * time.Second},
+		// Moved earlier
+		{NewFrom: 3 * time.Second, NewTo: 8 * time.Second, OldFrom: 25 * time.Second, OldTo: 30 * time.Second},
+	})
+	assert.Equal(t, []*astisub.Item{{EndAt: 5 * time.Second, StartAt: 4 * time.Second}}, dropped)
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: 2 * time.Second, StartAt: time.Second},
+		{EndAt: 5 * time.Second, StartAt: 4 * time.Second},
+		{EndAt: 24 * time.Second, StartAt: 20 * time.Second},
+		// Truncated to the end of the segment
+		{EndAt: 40 * time.Second, StartAt: 38 * time.Second},
+	}, s.Items)
+}
+
+func TestSubtitles_ClampToDuration(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, StartAt: time.Second},
+		{EndAt: 6 * time.Second, StartAt: 4 * time.Second},
+		{EndAt: 8 * time.Second, StartAt: 5 * time.Second},
+		{EndAt: 10 * time.Second, StartAt: 9 * time.Second},
+	}}
+	truncated, removed := s.ClampToDuration(5 * time.Second)
+	assert.Equal(t, 1, truncated)
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: 2 * time.Second, StartAt: time.Second},
+		{EndAt: 5 * time.Second, StartAt: 4 * time.Second},
+	}, s.Items)
+
+	// Nothing to clamp
+	truncated, removed = s.ClampToDuration(time.Minute)
+	assert.Equal(t, 0, truncated)
+	assert.Equal(t, 0, removed)
+	assert.Len(t, s.Items, 2)
+}
+
 func TestSubtitles_Duration(t *testing.T) {
 	assert.Equal(t, time.Duration(0), astisub.Subtitles{}.Duration())
 	assert.Equal(t, 7*time.Second, mockSubtitles().Duration())
 }
 
+func TestSubtitles_DeduplicateStyles(t *testing.T) {
+	def := &astisub.Style{ID: "Default", InlineStyle: &astisub.StyleAttributes{SSAFontName: "Arial", SSAFontSize: astikit.Float64Ptr(20)}}
+	s1 := &astisub.Style{ID: "Style1", InlineStyle: &astisub.StyleAttributes{SSAFontName: "Arial", SSAFontSize: astikit.Float64Ptr(20)}}
+	s2 := &astisub.Style{ID: "Style2", InlineStyle: &astisub.StyleAttributes{SSAFontName: "Verdana"}}
+	c1 := &astisub.Style{ID: "Child1", InlineStyle: &astisub.StyleAttributes{SSABold: astikit.BoolPtr(true)}, Style: def}
+	c2 := &astisub.Style{ID: "Child2", InlineStyle: &astisub.StyleAttributes{SSABold: astikit.BoolPtr(true)}, Style: s1}
+	r := &astisub.Region{ID: "r", Style: s1}
+	s := &astisub.Subtitles{
+		Items: []*astisub.Item{
+			{Lines: []astisub.Line{{Items: []astisub.LineItem{{Style: c2}}}}, Region: r, Style: s1},
+			{Style: s2},
+		},
+		Regions: map[string]*astisub.Region{"r": r},
+		Styles:  map[string]*astisub.Style{"Child1": c1, "Child2": c2, "Default": def, "Style1": s1, "Style2": s2},
+	}
+	assert.Equal(t, map[string]string{"Child2": "Child1", "Style1": "Default"}, s.DeduplicateStyles())
+	assert.Equal(t, map[string]*astisub.Style{"Child1": c1, "Default": def, "Style2": s2}, s.Styles)
+	assert.True(t, s.Items[0].Style == def)
+	assert.True(t, s.Items[0].Lines[0].Items[0].Style == c1)
+	assert.True(t, r.Style == def)
+	assert.True(t, c1.Style == def)
+	assert.True(t, s.Items[1].Style == s2)
+
+	// Nothing to merge
+	assert.Empty(t, s.DeduplicateStyles())
+
+	// Attributes are resolved against parent styles
+	p := &astisub.Style{ID: "Parent", InlineStyle: &astisub.StyleAttributes{SSAFontName: "Arial", SSAPrimaryColour: &astisub.Color{Red: 255}}}
+	flat := &astisub.Style{ID: "Flat", InlineStyle: &astisub.StyleAttributes{SSAFontName: "Arial", SSAPrimaryColour: &astisub.Color{Red: 255}, SSABold: astikit.BoolPtr(true)}}
+	inherited := &astisub.Style{ID: "Inherited", InlineStyle: &astisub.StyleAttributes{SSABold: astikit.BoolPtr(true)}, Style: p}
+	a := &astisub.Style{ID: "A", Style: p}
+	z := &astisub.Style{ID: "Z", InlineStyle: &astisub.StyleAttributes{}, Style: p}
+	s = &astisub.Subtitles{
+		Items:  []*astisub.Item{{Style: inherited}, {Style: z}},
+		Styles: map[string]*astisub.Style{"A": a, "Flat": flat, "Inherited": inherited, "Parent": p, "Z": z},
+	}
+	assert.Equal(t, map[string]string{"Inherited": "Flat", "Z": "A"}, s.DeduplicateStyles())
+	assert.Equal(t, map[string]*astisub.Style{"A": a, "Flat": flat, "Parent": p}, s.Styles)
+	assert.True(t, s.Items[0].Style == flat)
+	assert.True(t, s.Items[1].Style == a)
+	assert.True(t, a.Style == p)
+}
+
+func TestSubtitles_DetectFramerateMismatch(t *testing.T) {
+	// No mismatch
+	r, suspected := mockSubtitles().DetectFramerateMismatch(7 * time.Second)
+	assert.False(t, suspected)
+	assert.Equal(t, 1.0, r)
+
+	// 25 -> 23.976 fps
+	r, suspected = mockSubtitles().DetectFramerateMismatch(6713 * time.Millisecond)
+	assert.True(t, suspected)
+	assert.Equal(t, 25/23.976, r)
+
+	// 23.976 -> 25 fps
+	r, suspected = mockSubtitles().DetectFramerateMismatch(7299 * time.Millisecond)
+	assert.True(t, suspected)
+	assert.Equal(t, 23.976/25, r)
+
+	// Nothing to compare
+	_, suspected = astisub.Subtitles{}.DetectFramerateMismatch(time.Second)
+	assert.False(t, suspected)
+}
+
 func TestSubtitles_IsEmpty(t *testing.T) {
 	assert.True(t, astisub.Subtitles{}.IsEmpty())
 	assert.False(t, mockSubtitles().IsEmpty())
 }
 
+func TestSubtitles_FixSubFrameCues(t *testing.T) {
+	s := &astisub.Subtitles{
+		Items: []*astisub.Item{
+			{EndAt: time.Second + 10*time.Millisecond, StartAt: time.Second},
+			{EndAt: 3 * time.Second, StartAt: 2 * time.Second},
+			{EndAt: 4 * time.Second, StartAt: 4 * time.Second},
+			{EndAt: 4*time.Second + 50*time.Millisecond, StartAt: 4*time.Second + 20*time.Millisecond},
+		},
+		Metadata: &astisub.Metadata{Framerate: 25},
+	}
+	assert.Equal(t, []*astisub.Item{s.Items[0], s.Items[2], s.Items[3]}, s.SubFrameCues(0))
+	assert.Equal(t, []*astisub.Item{s.Items[2]}, s.SubFrameCues(1000))
+	assert.Equal(t, 3, s.FixSubFrameCues(0))
+	assert.Equal(t, time.Second+40*time.Millisecond, s.Items[0].EndAt)
+	assert.Equal(t, 3*time.Second, s.Items[1].EndAt)
+	assert.Equal(t, 4*time.Second+20*time.Millisecond, s.Items[2].EndAt)
+	assert.Equal(t, 4*time.Second+60*time.Millisecond, s.Items[3].EndAt)
+	assert.Len(t, s.SubFrameCues(0), 1)
+}
+
+func TestSubtitles_SnapToFrames(t *testing.T) {
+	assert.Equal(t, time.Second, astisub.RoundToFrame(1010*time.Millisecond, 25))
+	assert.Equal(t, 1040*time.Millisecond, astisub.RoundToFrame(1030*time.Millisecond, 25))
+	assert.Equal(t, 100100*time.Microsecond, astisub.RoundToFrame(100100*time.Microsecond, 30000.0/1001))
+	assert.Equal(t, 1010*time.Millisecond, astisub.RoundToFrame(1010*time.Millisecond, 0))
+
+	items := func() []*astisub.Item {
+		return []*astisub.Item{
+			{EndAt: 2030 * time.Millisecond, StartAt: 1010 * time.Millisecond},
+			{EndAt: 3010 * time.Millisecond, StartAt: 2030 * time.Millisecond},
+			{EndAt: 3040 * time.Millisecond, StartAt: 3000 * time.Millisecond},
+		}
+	}
+	s := &astisub.Subtitles{Items: items()}
+	s.SnapToFrames(25, astisub.SnapToFramesModeNearest)
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: 2040 * time.Millisecond, StartAt: 1000 * time.Millisecond},
+		{EndAt: 3000 * time.Millisecond, StartAt: 2040 * time.Millisecond},
+		{EndAt: 3040 * time.Millisecond, StartAt: 3000 * time.Millisecond},
+	}, s.Items)
+
+	// Expand and metadata framerate
+	s = &astisub.Subtitles{Items: items(), Metadata: &astisub.Metadata{Framerate: 25}}
+	s.SnapToFrames(0, astisub.SnapToFramesModeExpand)
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: 2040 * time.Millisecond, StartAt: 1000 * time.Millisecond},
+		{EndAt: 3040 * time.Millisecond, StartAt: 2000 * time.Millisecond},
+		{EndAt: 3040 * time.Millisecond, StartAt: 3000 * time.Millisecond},
+	}, s.Items)
+}
+
 func TestSubtitles_ForceDuration(t *testing.T) {
 	var s = mockSubtitles()
 	s.ForceDuration(10*time.Second, false)
@@ -91,6 +343,53 @@ func TestSubtitles_ForceDuration(t *testing.T) {
 	assert.Equal(t, 7*time.Second, s.Items[2].StartAt)
 }
 
+func TestSubtitles_ApplySafeArea(t *testing.T) {
+	sa := &astisub.StyleAttributes{
+		SSAMarginLeft:        astikit.IntPtr(0),
+		SSAMarginVertical:    astikit.IntPtr(100),
+		TTMLExtent:           astikit.StrPtr("100% 50%"),
+		TTMLOrigin:           astikit.StrPtr("0% 50%"),
+		WebVTTLine:           "0",
+		WebVTTPosition:       "50%,line-left",
+		WebVTTViewportAnchor: "0%,100%",
+		WebVTTWidth:          "40%",
+	}
+	s := &astisub.Subtitles{
+		Items:    []*astisub.Item{{InlineStyle: sa}},
+		Metadata: &astisub.Metadata{SSAPlayResX: astikit.IntPtr(1920), SSAPlayResY: astikit.IntPtr(1080)},
+		Regions:  map[string]*astisub.Region{"1": {ID: "1", InlineStyle: sa}},
+	}
+	s.ApplySafeArea(10)
+	assert.Equal(t, &astisub.StyleAttributes{
+		SSAMarginLeft:        astikit.IntPtr(192),
+		SSAMarginVertical:    astikit.IntPtr(188),
+		TTMLExtent:           astikit.StrPtr("80% 40%"),
+		TTMLOrigin:           astikit.StrPtr("10% 50%"),
+		WebVTTLine:           "0",
+		WebVTTPosition:       "50%,line-left",
+		WebVTTViewportAnchor: "10%,90%",
+		WebVTTWidth:          "32%",
+	}, sa)
+}
+
+func TestSubtitles_Fingerprint(t *testing.T) {
+	s1, err := astisub.ReadFromSRT(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:02,000\nHello  world \nSecond line\n\n2\n00:00:03,000 --> 00:00:04,000\nBye\n")))
+	require.NoError(t, err)
+	s2, err := astisub.ReadFromSRT(bytes.NewReader([]byte("10\r\n00:00:01,000 --> 00:00:02,000\r\nHello world\r\nSecond line  \r\n\r\n20\r\n00:00:03,000 --> 00:00:04,000\r\n<i>Bye</i>\r\n")))
+	require.NoError(t, err)
+	assert.Equal(t, s1.Fingerprint(), s2.Fingerprint())
+	assert.Len(t, s1.Fingerprint(), 64)
+
+	// Time boundaries matter
+	s2.Items[1].EndAt += time.Millisecond
+	assert.NotEqual(t, s1.Fingerprint(), s2.Fingerprint())
+
+	// Text matters
+	s2.Items[1].EndAt -= time.Millisecond
+	s2.Items[1].Lines[0].Items[0].Text = "Bye!"
+	assert.NotEqual(t, s1.Fingerprint(), s2.Fingerprint())
+}
+
 func TestSubtitles_Fragment(t *testing.T) {
 	// Init
 	var s = mockSubtitles()
@@ -129,10 +428,87 @@ func TestSubtitles_Fragment(t *testing.T) {
 	assert.Equal(t, 5*time.Second, s.Items[2].EndAt)
 }
 
-func TestSubtitles_Unfragment(t *testing.T) {
-	itemText := func(s string) []astisub.Line {
-		return []astisub.Line{{Items: []astisub.LineItem{{Text: s}}}}
+func TestSubtitles_StackOverlaps(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 4 * time.Second, Lines: itemText("speaker-2"), StartAt: 2 * time.Second},
+		{EndAt: 3 * time.Second, Lines: itemText("speaker-1"), StartAt: time.Second},
+		{EndAt: 6 * time.Second, Lines: itemText("alone"), StartAt: 5 * time.Second},
+	}}
+	s.StackOverlaps()
+	require.Len(t, s.Items, 4)
+	for idx, v := range []struct {
+		endAt, startAt time.Duration
+		text           string
+	}{
+		{endAt: 2 * time.Second, startAt: time.Second, text: "speaker-1"},
+		{endAt: 3 * time.Second, startAt: 2 * time.Second, text: "speaker-1 - speaker-2"},
+		{endAt: 4 * time.Second, startAt: 3 * time.Second, text: "speaker-2"},
+		{endAt: 6 * time.Second, startAt: 5 * time.Second, text: "alone"},
+	} {
+		assert.Equal(t, v.startAt, s.Items[idx].StartAt)
+		assert.Equal(t, v.endAt, s.Items[idx].EndAt)
+		assert.Equal(t, v.text, s.Items[idx].String())
+	}
+}
+
+func TestSubtitles_MergeAdjacent(t *testing.T) {
+	st := &astisub.Style{ID: "style"}
+	newSubtitles := func() *astisub.Subtitles {
+		return &astisub.Subtitles{Items: []*astisub.Item{
+			{EndAt: 2 * time.Second, Lines: itemText("1"), StartAt: time.Second},
+			{EndAt: 3 * time.Second, Lines: itemText("2"), StartAt: 2*time.Second + 100*time.Millisecond},
+			{EndAt: 4 * time.Second, Lines: itemText("3"), StartAt: 3 * time.Second},
+			{EndAt: 5 * time.Second, Lines: itemText("4"), StartAt: 4 * time.Second, Style: st},
+			{EndAt: 7 * time.Second, Lines: itemText("5"), StartAt: 6 * time.Second, Style: st},
+			{EndAt: 8 * time.Second, Lines: itemText("6"), StartAt: 6*time.Second + 500*time.Millisecond, Style: st},
+		}}
+	}
+
+	// Gap
+	s := newSubtitles()
+	assert.Equal(t, 2, s.MergeAdjacent(astisub.MergeAdjacentOptions{MaxGap: 200 * time.Millisecond}))
+	require.Len(t, s.Items, 4)
+	assert.Equal(t, "1 - 2 - 3", s.Items[0].String())
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 4*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, "4", s.Items[1].String())
+	assert.Equal(t, "5", s.Items[2].String())
+
+	// Max lines and duration
+	s = newSubtitles()
+	assert.Equal(t, 1, s.MergeAdjacent(astisub.MergeAdjacentOptions{MaxGap: 500 * time.Millisecond, MaxLines: 2}))
+	assert.Equal(t, "1 - 2", s.Items[0].String())
+	s = newSubtitles()
+	assert.Equal(t, 0, s.MergeAdjacent(astisub.MergeAdjacentOptions{MaxDuration: 1500 * time.Millisecond, MaxGap: time.Second}))
+}
+
+func TestSubtitles_RemoveDuplicates(t *testing.T) {
+	item := func(startAt, endAt time.Duration, text string) *astisub.Item {
+		return &astisub.Item{
+			EndAt:   endAt,
+			Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: text}}}},
+			StartAt: startAt,
+		}
 	}
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		item(3*time.Second, 4*time.Second, "b"),
+		item(time.Second, 2*time.Second, "a"),
+		item(3*time.Second, 4*time.Second, "b"),
+		item(time.Second, 3*time.Second, "a"),
+		item(time.Second, 2*time.Second, "c"),
+		item(time.Second, 2*time.Second, "a"),
+	}}
+	assert.Equal(t, 2, s.RemoveDuplicates())
+	assert.Equal(t, []*astisub.Item{
+		item(3*time.Second, 4*time.Second, "b"),
+		item(time.Second, 2*time.Second, "a"),
+		item(time.Second, 3*time.Second, "a"),
+		item(time.Second, 2*time.Second, "c"),
+	}, s.Items)
+	assert.Equal(t, 0, s.RemoveDuplicates())
+}
+
+func TestSubtitles_Unfragment(t *testing.T) {
 	items := []*astisub.Item{{
 		Lines:   itemText("subtitle-1"),
 		StartAt: 1 * time.Second,
@@ -195,6 +571,593 @@ func TestSubtitles_Unfragment(t *testing.T) {
 	}
 }
 
+func TestSubtitles_InferEndTimes(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{Lines: itemText("short"), StartAt: time.Second},
+		{EndAt: 4 * time.Second, Lines: itemText("valid"), StartAt: 3 * time.Second},
+		{EndAt: 10 * time.Second, Lines: itemText("a line requiring some time to be read"), StartAt: 10 * time.Second},
+		{Lines: itemText("a very long line requiring a lot of time to be read, much more than allowed"), StartAt: 20 * time.Second},
+		{Lines: itemText("next"), StartAt: 30 * time.Second},
+	}}
+	s.InferEndTimes(astisub.InferEndTimesOptions{CharactersPerSecond: 10, MaxDuration: 5 * time.Second})
+	assert.Equal(t, 2*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, 4*time.Second, s.Items[1].EndAt)
+	assert.Equal(t, 13700*time.Millisecond, s.Items[2].EndAt)
+	assert.Equal(t, 25*time.Second, s.Items[3].EndAt)
+	assert.Equal(t, 31*time.Second, s.Items[4].EndAt)
+
+	// Next item starts before the end of the reading duration
+	s = &astisub.Subtitles{Items: []*astisub.Item{
+		{Lines: itemText("a line requiring some time to be read"), StartAt: time.Second},
+		{Lines: itemText("next"), StartAt: 2 * time.Second},
+	}}
+	s.InferEndTimes(astisub.InferEndTimesOptions{})
+	assert.Equal(t, 2*time.Second, s.Items[0].EndAt)
+}
+
+func TestVisibleLength(t *testing.T) {
+	for _, v := range []struct {
+		expected int
+		text     string
+	}{
+		{expected: 0, text: ""},
+		{expected: 5, text: "hello"},
+		{expected: 4, text: "Cafe\u0301"},
+		{expected: 2, text: "a\tb\n"},
+		{expected: 2, text: "\U0001f44d\U0001f3fd!"},
+		{expected: 1, text: "\U0001f468\u200d\U0001f469\u200d\U0001f467"},
+		{expected: 3, text: "\U0001f1eb\U0001f1f7\U0001f1ef\U0001f1f5\U0001f1e9"},
+		{expected: 1, text: "\u2764\ufe0f"},
+		{expected: 1, text: "\U0001f3f4\U000e0067\U000e0062\U000e0065\U000e006e\U000e0067\U000e007f"},
+		{expected: 2, text: "\u1112\u1161\u11ab\uac00"},
+	} {
+		assert.Equal(t, v.expected, astisub.VisibleLength(v.text), v.text)
+	}
+}
+
+func TestSubtitles_ReadingSpeed(t *testing.T) {
+	// Character count
+	i := astisub.Item{Lines: []astisub.Line{
+		{Items: []astisub.LineItem{{Text: "<i>Cafe\u0301</i> "}, {Text: "{\\an8}ok"}}},
+		{Items: []astisub.LineItem{{Text: "m 0 0 l 10 0 10 10", InlineStyle: &astisub.StyleAttributes{SSADrawing: true}}}},
+		{Items: []astisub.LineItem{{Text: "a\tb"}}},
+		{Items: []astisub.LineItem{{Text: "\U0001f44b\U0001f3fb"}}},
+	}}
+	assert.Equal(t, 10, i.CharacterCount())
+	i = astisub.Item{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "a < b > c {sic}"}}}}}
+	assert.Equal(t, 15, i.CharacterCount())
+
+	// Reading speed
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, Lines: itemText("ten chars!"), StartAt: time.Second},
+		{EndAt: 3 * time.Second, Lines: itemText("twenty characters!!!"), StartAt: 2 * time.Second},
+		{EndAt: 3 * time.Second, Lines: itemText("no duration"), StartAt: 3 * time.Second},
+		{EndAt: 4 * time.Second, StartAt: 3 * time.Second},
+	}}
+	assert.Equal(t, []astisub.ReadingSpeedReport{
+		{CPS: 10, Index: 0},
+		{CPS: 20, Exceeded: true, Index: 1},
+		{CPS: math.Inf(1), Exceeded: true, Index: 2},
+		{Index: 3},
+	}, s.ReadingSpeed(astisub.ReadingSpeedOptions{}))
+	assert.False(t, s.ReadingSpeed(astisub.ReadingSpeedOptions{MaxCharactersPerSecond: 25})[1].Exceeded)
+}
+
+func TestSubtitles_Validate(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 3 * time.Second, Lines: itemText("valid"), StartAt: time.Second},
+		{EndAt: 2 * time.Second, Lines: itemText("overlap", " "), StartAt: 2500 * time.Millisecond},
+		{EndAt: 0, Lines: itemText("negative"), StartAt: -time.Second},
+		{EndAt: 20 * time.Second, StartAt: 5 * time.Second},
+		{EndAt: 5100 * time.Millisecond, Lines: itemText("short"), StartAt: 5 * time.Second},
+	}}
+	es := s.Validate()
+	var codes [][2]interface{}
+	for _, e := range es {
+		codes = append(codes, [2]interface{}{e.Index, e.Code})
+	}
+	assert.Equal(t, [][2]interface{}{
+		{1, astisub.ValidationErrorCodeInvalidTimeRange},
+		{1, astisub.ValidationErrorCodeEmptyLine},
+		{2, astisub.ValidationErrorCodeNegativeTime},
+		{2, astisub.ValidationErrorCodeOutOfOrder},
+		{3, astisub.ValidationErrorCodeTooLong},
+		{3, astisub.ValidationErrorCodeEmptyItem},
+		{4, astisub.ValidationErrorCodeTooShort},
+		{4, astisub.ValidationErrorCodeOverlap},
+	}, codes)
+	assert.Equal(t, "astisub: item #2: line 2 is empty", es[1].Error())
+	assert.Equal(t, "overlaps item #4", es[7].Message)
+
+	// Thresholds
+	es = s.ValidateWithOptions(astisub.ValidateOptions{MaxDuration: 20 * time.Second, MinDuration: 100 * time.Millisecond})
+	for _, e := range es {
+		assert.NotEqual(t, astisub.ValidationErrorCodeTooLong, e.Code)
+		assert.NotEqual(t, astisub.ValidationErrorCodeTooShort, e.Code)
+	}
+
+	// Sub-frame cues
+	s = &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 1010 * time.Millisecond, Lines: itemText("sub-frame"), StartAt: time.Second},
+		{EndAt: 3 * time.Second, Lines: itemText("valid"), StartAt: 2 * time.Second},
+	}}
+	es = s.ValidateWithOptions(astisub.ValidateOptions{Framerate: 25})
+	require.Len(t, es, 1)
+	assert.Equal(t, astisub.ValidationErrorCodeSubFrame, es[0].Code)
+	assert.Equal(t, 0, es[0].Index)
+	assert.Equal(t, "lasts 10ms which is less than a frame at 25 fps", es[0].Message)
+	assert.Equal(t, astisub.ValidationErrorCodeTooShort, s.Validate()[0].Code)
+}
+
+func TestSubtitles_ReplaceRegexInRange(t *testing.T) {
+	s := mockSubtitles()
+	s.Items = append(s.Items, &astisub.Item{StartAt: 7 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "sub-sub"}}}}})
+	n := s.ReplaceRegexInRange(2*time.Second, 8*time.Second, regexp.MustCompile(`sub(title)?`), "cue")
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "subtitle-1", s.Items[0].Lines[0].Items[0].Text)
+	assert.Equal(t, "cue-2", s.Items[1].Lines[0].Items[0].Text)
+	assert.Equal(t, "cue-cue", s.Items[2].Lines[0].Items[0].Text)
+
+	// End of range is excluded
+	n = s.ReplaceRegexInRange(0, 3*time.Second, regexp.MustCompile(`cue`), "sub")
+	assert.Equal(t, 0, n)
+}
+
+func TestSubtitles_SanitizeText(t *testing.T) {
+	newSubtitles := func() *astisub.Subtitles {
+		return &astisub.Subtitles{Items: []*astisub.Item{{Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{Text: "Café “ok” 😀…"},
+			{RubyText: "かんじ", Text: "Œuvre"},
+		}}}}}}
+	}
+
+	// Strip
+	s := newSubtitles()
+	assert.Equal(t, 5, s.SanitizeText(astisub.SanitizeTextOptions{}))
+	assert.Equal(t, "Café “ok” ", s.Items[0].Lines[0].Items[0].Text)
+	assert.Equal(t, "Œuvre", s.Items[0].Lines[0].Items[1].Text)
+	assert.Equal(t, "", s.Items[0].Lines[0].Items[1].RubyText)
+
+	// Replace and transliterate
+	s = newSubtitles()
+	assert.Equal(t, 9, s.SanitizeText(astisub.SanitizeTextOptions{
+		CharacterSet:  astisub.CharacterSetASCII,
+		Replacement:   "?",
+		Transliterate: true,
+	}))
+	assert.Equal(t, "Cafe \"ok\" ?...", s.Items[0].Lines[0].Items[0].Text)
+	assert.Equal(t, "?uvre", s.Items[0].Lines[0].Items[1].Text)
+	assert.Equal(t, "???", s.Items[0].Lines[0].Items[1].RubyText)
+
+	// Tabs
+	newTabSubtitles := func() *astisub.Subtitles {
+		return &astisub.Subtitles{Items: []*astisub.Item{{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "a\tb\t"}}}}}}}
+	}
+	s = newTabSubtitles()
+	assert.Equal(t, 2, s.SanitizeText(astisub.SanitizeTextOptions{TabWidth: 2}))
+	assert.Equal(t, "a  b  ", s.Items[0].Lines[0].Items[0].Text)
+	s = newTabSubtitles()
+	assert.Equal(t, 2, s.SanitizeText(astisub.SanitizeTextOptions{Replacement: "?", TabWidth: -1}))
+	assert.Equal(t, "ab", s.Items[0].Lines[0].Items[0].Text)
+	s = newTabSubtitles()
+	assert.Equal(t, 2, s.SanitizeText(astisub.SanitizeTextOptions{Replacement: "?"}))
+	assert.Equal(t, "a?b?", s.Items[0].Lines[0].Items[0].Text)
+}
+
+func TestSubtitles_Clone(t *testing.T) {
+	st := &astisub.Style{ID: "s", InlineStyle: &astisub.StyleAttributes{
+		TTMLColor:  astikit.StrPtr("red"),
+		WebVTTTags: []astisub.WebVTTTag{{Name: "c", Classes: []string{"yellow"}}},
+	}}
+	r := &astisub.Region{ID: "r", Style: st}
+	s := astisub.Subtitles{
+		Items: []*astisub.Item{{
+			Comments: []string{"comment"},
+			EndAt:    2 * time.Second,
+			Lines:    []astisub.Line{{Items: []astisub.LineItem{{Style: st, Text: "text"}}, VoiceName: "voice"}},
+			Region:   r,
+			StartAt:  time.Second,
+		}},
+		Metadata: &astisub.Metadata{SSAPlayResX: astikit.IntPtr(640), Title: "title"},
+		Regions:  map[string]*astisub.Region{"r": r},
+		Styles:   map[string]*astisub.Style{"s": st},
+	}
+	c := s.Clone()
+
+	// Pointers are relinked
+	require.Len(t, c.Items, 1)
+	require.Equal(t, s.Items[0].String(), c.Items[0].String())
+	assert.True(t, s.Items[0] != c.Items[0])
+	assert.True(t, st != c.Styles["s"])
+	assert.True(t, r != c.Regions["r"])
+	assert.True(t, c.Styles["s"] == c.Regions["r"].Style)
+	assert.True(t, c.Regions["r"] == c.Items[0].Region)
+	assert.True(t, c.Styles["s"] == c.Items[0].Lines[0].Items[0].Style)
+
+	// Modifying the copy doesn't modify the original
+	*c.Styles["s"].InlineStyle.TTMLColor = "blue"
+	c.Styles["s"].InlineStyle.WebVTTTags[0].Classes[0] = "blue"
+	*c.Metadata.SSAPlayResX = 1280
+	c.Metadata.Title = "new title"
+	c.Items[0].Comments[0] = "new comment"
+	c.Items[0].Lines[0].Items[0].Text = "new text"
+	c.Items[0].StartAt = 0
+	assert.Equal(t, "red", *st.InlineStyle.TTMLColor)
+	assert.Equal(t, "yellow", st.InlineStyle.WebVTTTags[0].Classes[0])
+	assert.Equal(t, 640, *s.Metadata.SSAPlayResX)
+	assert.Equal(t, "title", s.Metadata.Title)
+	assert.Equal(t, "comment", s.Items[0].Comments[0])
+	assert.Equal(t, "text", s.Items[0].String())
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+}
+
+func TestSubtitles_Split(t *testing.T) {
+	st := &astisub.Style{ID: "s", InlineStyle: &astisub.StyleAttributes{TTMLColor: astikit.StrPtr("red")}}
+	r := &astisub.Region{ID: "r", Style: st}
+	line := func(s string) []astisub.Line {
+		return []astisub.Line{{Items: []astisub.LineItem{{Style: st, Text: s}}}}
+	}
+	s := astisub.Subtitles{
+		Items: []*astisub.Item{
+			{EndAt: 12 * time.Second, Lines: line("after"), Region: r, StartAt: 11 * time.Second},
+			{EndAt: 2 * time.Second, Lines: line("before"), StartAt: time.Second},
+			{EndAt: 13 * time.Second, Lines: line("straddling"), StartAt: 9 * time.Second},
+			{EndAt: 10 * time.Second, Lines: line("ending at"), StartAt: 8 * time.Second},
+		},
+		Metadata: &astisub.Metadata{Title: "title"},
+		Regions:  map[string]*astisub.Region{"r": r},
+		Styles:   map[string]*astisub.Style{"s": st},
+	}
+	left, right := s.Split(10 * time.Second)
+
+	// Left
+	require.Len(t, left.Items, 3)
+	assert.Equal(t, "before", left.Items[0].String())
+	assert.Equal(t, "ending at", left.Items[1].String())
+	assert.Equal(t, "straddling", left.Items[2].String())
+	assert.Equal(t, 9*time.Second, left.Items[2].StartAt)
+	assert.Equal(t, 10*time.Second, left.Items[2].EndAt)
+
+	// Right
+	require.Len(t, right.Items, 2)
+	assert.Equal(t, "straddling", right.Items[0].String())
+	assert.Equal(t, time.Duration(0), right.Items[0].StartAt)
+	assert.Equal(t, 3*time.Second, right.Items[0].EndAt)
+	assert.Equal(t, "after", right.Items[1].String())
+	assert.Equal(t, time.Second, right.Items[1].StartAt)
+	assert.Equal(t, 2*time.Second, right.Items[1].EndAt)
+
+	// Deep copies
+	assert.Equal(t, "title", right.Metadata.Title)
+	assert.False(t, s.Metadata == right.Metadata)
+	assert.False(t, left.Styles["s"] == right.Styles["s"])
+	assert.True(t, right.Regions["r"] == right.Items[1].Region)
+	assert.True(t, right.Styles["s"] == right.Regions["r"].Style)
+	assert.True(t, right.Styles["s"] == right.Items[1].Lines[0].Items[0].Style)
+	right.Styles["s"].InlineStyle.TTMLColor = astikit.StrPtr("blue")
+	left.Items[0].Lines[0].Items[0].Text = "changed"
+	assert.Equal(t, "red", *st.InlineStyle.TTMLColor)
+	assert.Equal(t, "red", *left.Styles["s"].InlineStyle.TTMLColor)
+	assert.Equal(t, "before", s.Items[1].String())
+}
+
+func TestItem_SplitAt(t *testing.T) {
+	italic := &astisub.StyleAttributes{SRTItalics: true}
+	i := astisub.Item{
+		Comments:    []string{"comment"},
+		EndAt:       5 * time.Second,
+		InlineStyle: &astisub.StyleAttributes{SRTPosition: 8},
+		Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "One two "}, {InlineStyle: italic, Text: "three"}}},
+			{Items: []astisub.LineItem{{Text: "four five six"}}},
+		},
+		StartAt: time.Second,
+	}
+
+	// Out of bounds
+	_, _, err := i.SplitAt(time.Second, astisub.SplitAtModeProportional)
+	assert.EqualError(t, err, "astisub: split time 1s is not between 1s and 5s")
+
+	// Proportional
+	first, second, err := i.SplitAt(2*time.Second, astisub.SplitAtModeProportional)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, first.StartAt)
+	assert.Equal(t, 2*time.Second, first.EndAt)
+	assert.Equal(t, 2*time.Second, second.StartAt)
+	assert.Equal(t, 5*time.Second, second.EndAt)
+	assert.Equal(t, []string{"comment"}, first.Comments)
+	assert.Empty(t, second.Comments)
+	assert.Equal(t, &astisub.StyleAttributes{SRTPosition: 8}, second.InlineStyle)
+	assert.Equal(t, "One two", first.String())
+	assert.Equal(t, "three - four five six", second.String())
+	assert.Equal(t, italic, second.Lines[0].Items[0].InlineStyle)
+	first, second, err = i.SplitAt(4*time.Second, astisub.SplitAtModeProportional)
+	require.NoError(t, err)
+	assert.Equal(t, "One two three - four", first.String())
+	assert.Equal(t, "five six", second.String())
+
+	// First
+	first, second, err = i.SplitAt(2*time.Second, astisub.SplitAtModeFirst)
+	require.NoError(t, err)
+	assert.Equal(t, i.String(), first.String())
+	assert.Empty(t, second.Lines)
+
+	// Word-level timings
+	i = astisub.Item{
+		EndAt: 3 * time.Second,
+		Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{StartAt: time.Second, Text: "Hello"},
+			{StartAt: 1500 * time.Millisecond, Text: " there"},
+			{StartAt: 2 * time.Second, Text: " world"},
+		}}},
+		StartAt: time.Second,
+	}
+	first, second, err = i.SplitAt(1900*time.Millisecond, astisub.SplitAtModeFirst)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello there", first.String())
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{StartAt: 2 * time.Second, Text: "world"}}}}, second.Lines)
+}
+
+func TestSubtitles_SplitAtSentences(t *testing.T) {
+	newSubtitles := func() *astisub.Subtitles {
+		return &astisub.Subtitles{Items: []*astisub.Item{
+			{EndAt: 10 * time.Second, ID: "asr", Lines: []astisub.Line{
+				{Items: []astisub.LineItem{{Text: "Hi Mr. Smith, I met J. Doe... "}, {InlineStyle: &astisub.StyleAttributes{SRTItalics: true}, Text: "Really? Yes."}}},
+				{Items: []astisub.LineItem{{Text: "It costs 3.5 dollars! ok"}}},
+			}, StartAt: 0},
+			{EndAt: 14 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "你好。再见！"}}}}, StartAt: 10 * time.Second},
+		}}
+	}
+
+	// No minimum duration
+	s := newSubtitles()
+	assert.Equal(t, 3, s.SplitAtSentences(0))
+	require.Len(t, s.Items, 5)
+	assert.Equal(t, "Hi Mr. Smith, I met J. Doe... Really?", s.Items[0].String())
+	assert.Equal(t, "asr", s.Items[0].ID)
+	assert.Equal(t, time.Duration(0), s.Items[0].StartAt)
+	assert.Equal(t, s.Items[1].StartAt, s.Items[0].EndAt)
+	assert.Equal(t, "Yes.", s.Items[1].String())
+	assert.True(t, s.Items[1].Lines[0].Items[0].InlineStyle.SRTItalics)
+	assert.Equal(t, "", s.Items[1].ID)
+	assert.Equal(t, "It costs 3.5 dollars! ok", s.Items[2].String())
+	assert.Equal(t, 10*time.Second, s.Items[2].EndAt)
+	assert.Equal(t, "你好。", s.Items[3].String())
+	assert.Equal(t, 12*time.Second, s.Items[3].EndAt)
+	assert.Equal(t, "再见！", s.Items[4].String())
+
+	// Minimum duration
+	s = newSubtitles()
+	assert.Equal(t, 1, s.SplitAtSentences(3*time.Second))
+	require.Len(t, s.Items, 3)
+	assert.Equal(t, "Hi Mr. Smith, I met J. Doe... Really?", s.Items[0].String())
+	assert.Equal(t, "Yes. - It costs 3.5 dollars! ok", s.Items[1].String())
+}
+
+func TestSubtitles_SeparateByLine(t *testing.T) {
+	itemLines := func(ss ...string) (o []astisub.Line) {
+		for _, s := range ss {
+			o = append(o, astisub.Line{Items: []astisub.LineItem{{Text: s}}})
+		}
+		return
+	}
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, Lines: itemLines("native-1", "translation-1"), StartAt: time.Second},
+		{EndAt: 4 * time.Second, Lines: itemLines("native-2"), StartAt: 3 * time.Second},
+		{EndAt: 6 * time.Second, Lines: itemLines("native-3", "translation-3"), StartAt: 5 * time.Second},
+	}, Metadata: &astisub.Metadata{Title: "title"}}
+	ss := s.SeparateByLine(1)
+	require.Len(t, ss, 2)
+	require.Len(t, ss[0].Items, 3)
+	assert.Equal(t, "native-1", ss[0].Items[0].String())
+	assert.Equal(t, "native-2", ss[0].Items[1].String())
+	assert.Equal(t, "native-3", ss[0].Items[2].String())
+	assert.Equal(t, 5*time.Second, ss[0].Items[2].StartAt)
+	require.Len(t, ss[1].Items, 2)
+	assert.Equal(t, "translation-1", ss[1].Items[0].String())
+	assert.Equal(t, "translation-3", ss[1].Items[1].String())
+	assert.Equal(t, 6*time.Second, ss[1].Items[1].EndAt)
+	assert.Equal(t, s.Metadata, ss[1].Metadata)
+	assert.Len(t, s.Items[0].Lines, 2)
+
+	// Several lines per track
+	ss = s.SeparateByLine(2)
+	require.Len(t, ss, 1)
+	assert.Equal(t, "native-1 - translation-1", ss[0].Items[0].String())
+}
+
+func TestSubtitles_SplitByCount(t *testing.T) {
+	s1, s2 := &astisub.Style{ID: "1"}, &astisub.Style{ID: "2"}
+	s := astisub.Subtitles{
+		Items: []*astisub.Item{
+			{Index: 3, StartAt: 3 * time.Second, Style: s2},
+			{Index: 1, StartAt: time.Second, Style: s1},
+			{Index: 2, StartAt: 2 * time.Second, Style: s1},
+		},
+		Metadata: &astisub.Metadata{Title: "title"},
+		Regions:  map[string]*astisub.Region{},
+		Styles:   map[string]*astisub.Style{"1": s1, "2": s2},
+	}
+	cs := s.SplitByCount(2)
+	require.Len(t, cs, 2)
+	assert.Equal(t, []*astisub.Item{
+		{Index: 1, StartAt: time.Second, Style: s1},
+		{Index: 2, StartAt: 2 * time.Second, Style: s1},
+	}, cs[0].Items)
+	assert.Equal(t, map[string]*astisub.Style{"1": s1}, cs[0].Styles)
+	assert.Equal(t, []*astisub.Item{{Index: 1, StartAt: 3 * time.Second, Style: s2}}, cs[1].Items)
+	assert.Equal(t, map[string]*astisub.Style{"2": s2}, cs[1].Styles)
+	assert.Equal(t, s.Metadata, cs[1].Metadata)
+	assert.False(t, s.Metadata == cs[1].Metadata)
+
+	// Original subtitles are not modified
+	assert.Equal(t, 3, s.Items[0].Index)
+	assert.Len(t, s.Styles, 2)
+}
+
+func TestSubtitles_LimitSimultaneous(t *testing.T) {
+	items := func() []*astisub.Item {
+		return []*astisub.Item{
+			{EndAt: 5 * time.Second, InlineStyle: &astisub.StyleAttributes{SSALayer: astikit.IntPtr(1)}, Lines: itemText("1"), StartAt: time.Second},
+			{EndAt: 4 * time.Second, Lines: itemText("2"), StartAt: 2 * time.Second},
+			{EndAt: 6 * time.Second, InlineStyle: &astisub.StyleAttributes{SSALayer: astikit.IntPtr(2)}, Lines: itemText("3"), StartAt: 3 * time.Second},
+			{EndAt: 8 * time.Second, Lines: itemText("4"), StartAt: 7 * time.Second},
+		}
+	}
+
+	// Drop
+	s := &astisub.Subtitles{Items: items()}
+	s.LimitSimultaneous(1, astisub.LimitSimultaneousModeDrop)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, "3", s.Items[0].String())
+	assert.Equal(t, "4", s.Items[1].String())
+
+	s = &astisub.Subtitles{Items: items()}
+	s.LimitSimultaneous(2, astisub.LimitSimultaneousModeDrop)
+	require.Len(t, s.Items, 3)
+	assert.Equal(t, "1", s.Items[0].String())
+	assert.Equal(t, "3", s.Items[1].String())
+	assert.Equal(t, "4", s.Items[2].String())
+
+	// Merge
+	s = &astisub.Subtitles{Items: items()}
+	s.LimitSimultaneous(1, astisub.LimitSimultaneousModeMerge)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, "1 - 2 - 3", s.Items[0].String())
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 6*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, "4", s.Items[1].String())
+}
+
+func TestSubtitles_Concat(t *testing.T) {
+	r1 := &astisub.Region{ID: "r"}
+	st1 := &astisub.Style{ID: "Default"}
+	s1 := &astisub.Subtitles{
+		Items:   []*astisub.Item{{EndAt: 2 * time.Second, Index: 5, Region: r1, StartAt: time.Second, Style: st1}},
+		Regions: map[string]*astisub.Region{"r": r1},
+		Styles:  map[string]*astisub.Style{"Default": st1},
+	}
+	r2 := &astisub.Region{ID: "r"}
+	st2 := &astisub.Style{ID: "Default"}
+	st3 := &astisub.Style{ID: "Default_2"}
+	s2 := &astisub.Subtitles{
+		Items: []*astisub.Item{
+			{EndAt: 3 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{StartAt: 2500 * time.Millisecond, Style: st3, Text: "t"}}}}, Region: r2, StartAt: 2 * time.Second, Style: st2},
+		},
+		Regions: map[string]*astisub.Region{"r": r2},
+		Styles:  map[string]*astisub.Style{"Default": st2, "Default_2": st3},
+	}
+	s1.Concat(s2, time.Second)
+
+	// Other subtitles are not modified
+	assert.Equal(t, 2*time.Second, s2.Items[0].StartAt)
+	assert.Equal(t, "r", r2.ID)
+	assert.Equal(t, "Default", st2.ID)
+
+	// Items
+	require.Len(t, s1.Items, 2)
+	assert.Equal(t, 1, s1.Items[0].Index)
+	assert.Equal(t, 2, s1.Items[1].Index)
+	assert.Equal(t, 5*time.Second, s1.Items[1].StartAt)
+	assert.Equal(t, 6*time.Second, s1.Items[1].EndAt)
+	assert.Equal(t, 5500*time.Millisecond, s1.Items[1].Lines[0].Items[0].StartAt)
+
+	// Regions and styles
+	assert.Equal(t, []string{"r", "r_2"}, []string{s1.Items[0].Region.ID, s1.Items[1].Region.ID})
+	assert.Len(t, s1.Regions, 2)
+	assert.Equal(t, s1.Items[1].Region, s1.Regions["r_2"])
+	assert.Len(t, s1.Styles, 3)
+	assert.Equal(t, s1.Items[1].Style, s1.Styles["Default_3"])
+	assert.Equal(t, s1.Items[1].Lines[0].Items[0].Style, s1.Styles["Default_2"])
+	for id, st := range s1.Styles {
+		assert.Equal(t, id, st.ID)
+	}
+}
+
+func TestSubtitles_Renumber(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{{Index: 3}, {}, {Index: 1}}}
+	s.Renumber()
+	assert.Equal(t, []*astisub.Item{{Index: 1}, {Index: 2}, {Index: 3}}, s.Items)
+}
+
+func TestSubtitles_StripTags(t *testing.T) {
+	sa := &astisub.StyleAttributes{SRTBold: true}
+	i := &astisub.Item{Lines: []astisub.Line{
+		{Items: []astisub.LineItem{{InlineStyle: sa, Text: "<i>Tom</i> &amp; "}, {Text: "{\\an8}Jerry"}}},
+		{Items: []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{SSADrawing: true}, Text: "m 0 0 l 10 0"}}},
+		{Items: []astisub.LineItem{{Text: "<font color=\"red\">1 &lt; 2</font>"}}},
+	}}
+	assert.Equal(t, "Tom & Jerry 1 < 2", i.PlainText())
+
+	s := &astisub.Subtitles{Items: []*astisub.Item{i}}
+	s.StripTags()
+	assert.Equal(t, []astisub.Line{
+		{Items: []astisub.LineItem{{InlineStyle: sa, Text: "Tom & "}, {Text: "Jerry"}}},
+		{Items: []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{SSADrawing: true}, Text: "m 0 0 l 10 0"}}},
+		{Items: []astisub.LineItem{{Text: "1 < 2"}}},
+	}, i.Lines)
+}
+
+func TestSubtitles_ClampDurations(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 1500 * time.Millisecond, StartAt: time.Second},
+		{EndAt: 3300 * time.Millisecond, StartAt: 3 * time.Second},
+		{EndAt: 4 * time.Second, StartAt: 3500 * time.Millisecond},
+		{EndAt: 15 * time.Second, StartAt: 5 * time.Second},
+		{EndAt: 18 * time.Second, StartAt: 16 * time.Second},
+	}}
+	assert.Equal(t, []astisub.ClampDurationsReport{
+		{Index: 0},
+		{Index: 1, LimitedByNextItem: true},
+		{Index: 2},
+		{Index: 3},
+	}, s.ClampDurations(time.Second, 7*time.Second))
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: 2 * time.Second, StartAt: time.Second},
+		{EndAt: 3500 * time.Millisecond, StartAt: 3 * time.Second},
+		{EndAt: 4500 * time.Millisecond, StartAt: 3500 * time.Millisecond},
+		{EndAt: 12 * time.Second, StartAt: 5 * time.Second},
+		{EndAt: 18 * time.Second, StartAt: 16 * time.Second},
+	}, s.Items)
+
+	// Disabled bounds
+	assert.Empty(t, s.ClampDurations(0, 0))
+}
+
+func TestSubtitles_EnforceMinGap(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 4 * time.Second, StartAt: 3 * time.Second},
+		{EndAt: 2 * time.Second, StartAt: time.Second},
+		{EndAt: 4500 * time.Millisecond, StartAt: 4100 * time.Millisecond},
+		{EndAt: 6 * time.Second, StartAt: 4600 * time.Millisecond},
+	}}
+	assert.Equal(t, []int{2}, s.EnforceMinGap(200*time.Millisecond, 400*time.Millisecond))
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: 2 * time.Second, StartAt: time.Second},
+		{EndAt: 3900 * time.Millisecond, StartAt: 3 * time.Second},
+		{EndAt: 4500 * time.Millisecond, StartAt: 4100 * time.Millisecond},
+		{EndAt: 6 * time.Second, StartAt: 4600 * time.Millisecond},
+	}, s.Items)
+}
+
+func TestSubtitles_Filter(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, Lines: itemText("1"), StartAt: time.Second},
+		{EndAt: 4 * time.Second, Lines: itemText(" "), StartAt: 3 * time.Second},
+		{EndAt: 5100 * time.Millisecond, Lines: itemText("[Music]"), StartAt: 5 * time.Second},
+		{EndAt: 6100 * time.Millisecond, Lines: itemText("4"), StartAt: 6 * time.Second},
+		{EndAt: 8 * time.Second, Lines: itemText("[Music]"), StartAt: 7 * time.Second},
+		{EndAt: 10 * time.Second, Lines: itemText("6"), StartAt: 9 * time.Second},
+	}}
+	assert.Equal(t, 1, s.Filter(astisub.FilterEmpty()))
+	assert.Equal(t, 2, s.Filter(astisub.FilterShorterThan(500*time.Millisecond)))
+	assert.Equal(t, 1, s.Filter(func(i *astisub.Item) bool { return i.String() != "[Music]" }))
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: 2 * time.Second, Lines: itemText("1"), StartAt: time.Second},
+		{EndAt: 10 * time.Second, Lines: itemText("6"), StartAt: 9 * time.Second},
+	}, s.Items)
+}
+
 func TestSubtitles_Merge(t *testing.T) {
 	var s1 = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 3 * time.Second, StartAt: time.Second}, {EndAt: 8 * time.Second, StartAt: 5 * time.Second}, {EndAt: 12 * time.Second, StartAt: 10 * time.Second}}, Regions: map[string]*astisub.Region{"region_0": {ID: "region_0"}, "region_1": {ID: "region_1"}}, Styles: map[string]*astisub.Style{"style_0": {ID: "style_0"}, "style_1": {ID: "style_1"}}}
 	var s2 = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 4 * time.Second, StartAt: 2 * time.Second}, {EndAt: 7 * time.Second, StartAt: 6 * time.Second}, {EndAt: 11 * time.Second, StartAt: 9 * time.Second}, {EndAt: 14 * time.Second, StartAt: 13 * time.Second}}, Regions: map[string]*astisub.Region{"region_1": {ID: "region_1"}, "region_2": {ID: "region_2"}}, Styles: map[string]*astisub.Style{"style_1": {ID: "style_1"}, "style_2": {ID: "style_2"}}}
@@ -211,6 +1174,153 @@ func TestSubtitles_Merge(t *testing.T) {
 	assert.Equal(t, len(s1.Styles), 3)
 }
 
+func TestSubtitles_PreserveOrder(t *testing.T) {
+	// Merge
+	var s1 = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 8 * time.Second, StartAt: 5 * time.Second}, {EndAt: 3 * time.Second, StartAt: time.Second}}, PreserveOrder: true}
+	var s2 = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 4 * time.Second, StartAt: 2 * time.Second}}}
+	s1.Merge(s2)
+	assert.Len(t, s1.Items, 3)
+	assert.Equal(t, 5*time.Second, s1.Items[0].StartAt)
+	assert.Equal(t, time.Second, s1.Items[1].StartAt)
+	assert.Equal(t, 2*time.Second, s1.Items[2].StartAt)
+
+	// Unfragment
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 8 * time.Second, Lines: itemText("subtitle-2"), StartAt: 7 * time.Second},
+		{EndAt: 2 * time.Second, Lines: itemText("subtitle-1"), StartAt: time.Second},
+		{EndAt: 3 * time.Second, Lines: itemText("subtitle-1"), StartAt: 2 * time.Second},
+	}, PreserveOrder: true}
+	s.Unfragment()
+	assert.Len(t, s.Items, 2)
+	assert.Equal(t, "subtitle-2", s.Items[0].String())
+	assert.Equal(t, "subtitle-1", s.Items[1].String())
+	assert.Equal(t, time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 3*time.Second, s.Items[1].EndAt)
+}
+
+func TestSubtitles_NormalizeLineBreaks(t *testing.T) {
+	lines := []astisub.Line{
+		{Items: []astisub.LineItem{{Text: " "}}},
+		{Items: []astisub.LineItem{{Text: "1"}}},
+		{},
+		{Items: []astisub.LineItem{{Text: ""}}},
+		{Items: []astisub.LineItem{{Text: "2"}}},
+		{Items: []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{SSADrawing: true}, Text: "m 0 0"}}},
+		{},
+	}
+	s := &astisub.Subtitles{Items: []*astisub.Item{{Lines: append([]astisub.Line{}, lines...)}}}
+	s.NormalizeLineBreaks(false)
+	assert.Equal(t, []astisub.Line{lines[1], lines[4], lines[5]}, s.Items[0].Lines)
+
+	s = &astisub.Subtitles{Items: []*astisub.Item{{Lines: append([]astisub.Line{}, lines...)}}}
+	s.NormalizeLineBreaks(true)
+	assert.Equal(t, []astisub.Line{lines[1], {}, lines[4], lines[5]}, s.Items[0].Lines)
+}
+
+func TestSubtitles_Overlaps(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 5 * time.Second, Lines: itemText("1"), StartAt: time.Second},
+		{EndAt: 4 * time.Second, Lines: itemText("2"), StartAt: 3 * time.Second},
+		{EndAt: 7 * time.Second, Lines: itemText("1"), StartAt: 4 * time.Second},
+		{EndAt: 8 * time.Second, Lines: itemText("3"), StartAt: 8 * time.Second},
+		{EndAt: 10 * time.Second, Lines: itemText("4"), StartAt: 9 * time.Second},
+		{EndAt: 11 * time.Second, Lines: itemText("5"), StartAt: 9 * time.Second},
+	}}
+	assert.Equal(t, [][2]int{{0, 1}, {0, 2}, {4, 5}}, s.Overlaps())
+
+	assert.Equal(t, 1, s.FixOverlaps(500*time.Millisecond))
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: 2500 * time.Millisecond, Lines: itemText("1"), StartAt: time.Second},
+		{EndAt: 4 * time.Second, Lines: itemText("2"), StartAt: 3 * time.Second},
+		{EndAt: 7 * time.Second, Lines: itemText("1"), StartAt: 4 * time.Second},
+		{EndAt: 8 * time.Second, Lines: itemText("3"), StartAt: 8 * time.Second},
+		{EndAt: 10 * time.Second, Lines: itemText("4"), StartAt: 9 * time.Second},
+		{EndAt: 11 * time.Second, Lines: itemText("5"), StartAt: 9 * time.Second},
+	}, s.Items)
+	assert.Equal(t, [][2]int{{4, 5}}, s.Overlaps())
+
+	// Same text and reduced gap
+	s = &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 5 * time.Second, Lines: itemText("1"), StartAt: time.Second},
+		{EndAt: 7 * time.Second, Lines: itemText("1"), StartAt: 4 * time.Second},
+		{EndAt: 9 * time.Second, Lines: itemText("2"), StartAt: 6800 * time.Millisecond},
+		{EndAt: 10 * time.Second, Lines: itemText("3"), StartAt: 7 * time.Second},
+	}}
+	assert.Equal(t, 3, s.FixOverlaps(time.Second))
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: 5800 * time.Millisecond, Lines: itemText("1"), StartAt: time.Second},
+		{EndAt: 7 * time.Second, Lines: itemText("2"), StartAt: 6800 * time.Millisecond},
+		{EndAt: 10 * time.Second, Lines: itemText("3"), StartAt: 7 * time.Second},
+	}, s.Items)
+	assert.Empty(t, s.Overlaps())
+}
+
+func TestSubtitles_MergeLines(t *testing.T) {
+	italic := &astisub.StyleAttributes{SRTItalics: true}
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "First"}, {InlineStyle: italic, Text: " line"}}, VoiceName: "Bob"},
+			{VoiceName: "Bob"},
+			{Items: []astisub.LineItem{{Text: "Second line"}}, VoiceName: "Bob"},
+		}},
+		{Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "- Hi"}}, VoiceName: "Bob"},
+			{Items: []astisub.LineItem{{Text: "- Hello"}}, VoiceName: "Alice"},
+		}},
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Single line"}}}}},
+	}}
+	assert.Equal(t, []int{1}, s.MergeLines(" "))
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{
+		{Text: "First"},
+		{InlineStyle: italic, Text: " line"},
+		{Text: " "},
+		{Text: "Second line"},
+	}, VoiceName: "Bob"}}, s.Items[0].Lines)
+	assert.Len(t, s.Items[1].Lines, 2)
+	assert.Equal(t, "Single line", s.Items[2].String())
+}
+
+func TestSubtitles_WrapLines(t *testing.T) {
+	i := &astisub.StyleAttributes{SSAItalic: astikit.BoolPtr(true)}
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "short"}}}}},
+		{Lines: []astisub.Line{{VoiceName: "Bob", Items: []astisub.LineItem{
+			{Text: "The quick brown "},
+			{InlineStyle: i, Text: "fox jumps"},
+			{Text: " over the lazy dog"},
+		}}}},
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "a supercalifragilisticexpialidocious word"}}}}},
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "\U0001f44d\U0001f3fd\U0001f44d\U0001f3fd and \U0001f1eb\U0001f1f7 fit"}}}}},
+	}}
+	s.WrapLines(25)
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{Text: "short"}}}}, s.Items[0].Lines)
+	assert.Equal(t, []astisub.Line{
+		{VoiceName: "Bob", Items: []astisub.LineItem{{Text: "The quick brown "}, {InlineStyle: i, Text: "fox"}}},
+		{VoiceName: "Bob", Items: []astisub.LineItem{{InlineStyle: i, Text: "jumps "}, {Text: "over the lazy dog"}}},
+	}, s.Items[1].Lines)
+	assert.Equal(t, []astisub.Line{
+		{Items: []astisub.LineItem{{Text: "a"}}},
+		{Items: []astisub.LineItem{{Text: "supercalifragilisticexpialidocious"}}},
+		{Items: []astisub.LineItem{{Text: "word"}}},
+	}, s.Items[2].Lines)
+	assert.Len(t, s.Items[3].Lines, 1)
+	s.WrapLines(8)
+	assert.Equal(t, []astisub.Line{
+		{Items: []astisub.LineItem{{Text: "\U0001f44d\U0001f3fd\U0001f44d\U0001f3fd and"}}},
+		{Items: []astisub.LineItem{{Text: "\U0001f1eb\U0001f1f7 fit"}}},
+	}, s.Items[3].Lines)
+
+	// Max lines
+	s = &astisub.Subtitles{Items: []*astisub.Item{
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "one two three four five six"}}}}},
+	}}
+	s.WrapLinesWithOptions(astisub.WrapLinesOptions{MaxChars: 8, MaxLines: 2})
+	assert.Equal(t, []astisub.Line{
+		{Items: []astisub.LineItem{{Text: "one two three"}}},
+		{Items: []astisub.LineItem{{Text: "four five six"}}},
+	}, s.Items[0].Lines)
+}
+
 func TestSubtitles_Optimize(t *testing.T) {
 	var s = &astisub.Subtitles{
 		Items: []*astisub.Item{
@@ -280,6 +1390,161 @@ func TestSubtitles_RemoveStyling(t *testing.T) {
 	}, s)
 }
 
+func TestSubtitles_SetTextColor(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 2 * time.Second,
+		Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{InlineStyle: &astisub.StyleAttributes{SSAPrimaryColour: astisub.ColorRed, TTMLColor: astikit.StrPtr("red")}, Text: "red"},
+			{Text: "plain"},
+		}}},
+		StartAt: time.Second,
+	}}}
+
+	// Set
+	s.SetTextColor(astisub.ColorYellow)
+	sa := s.Items[0].InlineStyle
+	require.NotNil(t, sa)
+	assert.Equal(t, "#ffff00", *sa.SRTColor)
+	assert.Equal(t, *astisub.ColorYellow, *sa.SSAPrimaryColour)
+	assert.Equal(t, "#ffff00", *sa.TTMLColor)
+	assert.Equal(t, *astisub.ColorYellow, *sa.TeletextColor)
+	sa.TeletextColor.Blue = 255
+	assert.Equal(t, uint8(0), astisub.ColorYellow.Blue)
+	lis := s.Items[0].Lines[0].Items
+	assert.Equal(t, *astisub.ColorYellow, *lis[0].InlineStyle.SSAPrimaryColour)
+	assert.Equal(t, "#ffff00", *lis[0].InlineStyle.TTMLColor)
+	require.NotNil(t, lis[1].InlineStyle)
+	assert.Nil(t, lis[1].InlineStyle.SSAPrimaryColour)
+	assert.Nil(t, lis[1].InlineStyle.TTMLColor)
+	w := &bytes.Buffer{}
+	require.NoError(t, s.WriteToSRT(w))
+	assert.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000\n<font color=\"#ffff00\">red</font><font color=\"#ffff00\">plain</font>\n", w.String())
+
+	// Remove
+	s.SetTextColor(nil)
+	assert.Nil(t, s.Items[0].InlineStyle.SRTColor)
+	assert.Nil(t, s.Items[0].InlineStyle.TeletextColor)
+	assert.Nil(t, lis[0].InlineStyle.SSAPrimaryColour)
+	assert.Nil(t, lis[1].InlineStyle.SRTColor)
+}
+
+func TestSubtitles_RemoveComments(t *testing.T) {
+	s := &astisub.Subtitles{
+		Items: []*astisub.Item{
+			{Comments: []string{"comment 1"}},
+			{},
+		},
+		Metadata: &astisub.Metadata{
+			Comments:    []string{"comment 2"},
+			SSAComments: []astisub.SSAComment{{Text: "comment 3"}},
+			Title:       "title",
+		},
+	}
+	s.RemoveComments()
+	assert.Equal(t, &astisub.Subtitles{
+		Items:    []*astisub.Item{{}, {}},
+		Metadata: &astisub.Metadata{Title: "title"},
+	}, s)
+
+	// No metadata
+	s = &astisub.Subtitles{Items: []*astisub.Item{{Comments: []string{"comment"}}}}
+	s.RemoveComments()
+	assert.Equal(t, &astisub.Subtitles{Items: []*astisub.Item{{}}}, s)
+}
+
+func TestSubtitles_Voices(t *testing.T) {
+	line := func(voice string) astisub.Line {
+		return astisub.Line{Items: []astisub.LineItem{{Text: "text"}}, VoiceName: voice}
+	}
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{Lines: []astisub.Line{line("Bob"), line("")}},
+		{Lines: []astisub.Line{line("Alice"), line("Bob")}},
+		{Lines: []astisub.Line{line("Alice")}},
+	}}
+	assert.Equal(t, []string{"Bob", "Alice"}, s.Voices())
+
+	st := &astisub.Style{ID: "alice"}
+	s.AssignStyleToVoice("Alice", st)
+	assert.Nil(t, s.Items[0].Style)
+	assert.Nil(t, s.Items[0].Lines[0].Items[0].Style)
+	assert.Nil(t, s.Items[1].Style)
+	assert.Equal(t, st, s.Items[1].Lines[0].Items[0].Style)
+	assert.Nil(t, s.Items[1].Lines[1].Items[0].Style)
+	assert.Equal(t, st, s.Items[2].Style)
+	assert.Equal(t, st, s.Items[2].Lines[0].Items[0].Style)
+	assert.Equal(t, map[string]*astisub.Style{"alice": st}, s.Styles)
+
+	// Unknown voice
+	s.AssignStyleToVoice("Carol", &astisub.Style{ID: "carol"})
+	assert.Equal(t, map[string]*astisub.Style{"alice": st}, s.Styles)
+
+	// Same style
+	s.AssignStyleToVoice("Alice", st)
+	assert.Equal(t, map[string]*astisub.Style{"alice": st}, s.Styles)
+
+	// Colliding style ID
+	st2 := &astisub.Style{ID: "alice", InlineStyle: &astisub.StyleAttributes{SRTBold: true}}
+	s.AssignStyleToVoice("Bob", st2)
+	assert.Equal(t, "alice", st2.ID)
+	require.Len(t, s.Styles, 2)
+	assert.True(t, s.Styles["alice"] == st)
+	c := s.Styles["alice_2"]
+	require.NotNil(t, c)
+	assert.False(t, c == st2)
+	assert.Equal(t, "alice_2", c.ID)
+	assert.Equal(t, st2.InlineStyle, c.InlineStyle)
+	assert.False(t, c.InlineStyle == st2.InlineStyle)
+	assert.True(t, s.Items[0].Lines[0].Items[0].Style == c)
+}
+
+func TestSubtitles_Stretch(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{
+			EndAt: 4 * time.Second,
+			Lines: []astisub.Line{{Items: []astisub.LineItem{
+				{Text: "a"},
+				{StartAt: 3 * time.Second, Text: "b"},
+			}}},
+			StartAt: 2 * time.Second,
+		},
+		{EndAt: time.Duration(math.MaxInt64 / 2), StartAt: time.Duration(math.MaxInt64 / 4)},
+	}}
+	s.Stretch(1.5)
+	assert.Equal(t, 3*time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 6*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, time.Duration(0), s.Items[0].Lines[0].Items[0].StartAt)
+	assert.Equal(t, 4500*time.Millisecond, s.Items[0].Lines[0].Items[1].StartAt)
+	s.Stretch(3)
+	assert.Equal(t, time.Duration(math.MaxInt64), s.Items[1].EndAt)
+	assert.Equal(t, time.Duration(math.MaxInt64), s.Items[1].StartAt)
+	s.Stretch(-1)
+	assert.Equal(t, time.Duration(0), s.Items[0].StartAt)
+	assert.Equal(t, time.Duration(0), s.Items[0].EndAt)
+
+	s = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 50 * time.Second, StartAt: 25 * time.Second}}}
+	s.ConvertFramerate(25, 0)
+	assert.Equal(t, 25*time.Second, s.Items[0].StartAt)
+	s.ConvertFramerate(25, 50)
+	assert.Equal(t, 12500*time.Millisecond, s.Items[0].StartAt)
+	assert.Equal(t, 25*time.Second, s.Items[0].EndAt)
+}
+
+func TestSubtitles_ApplyCadence(t *testing.T) {
+	s := mockSubtitles()
+	s.Items[1].Lines[0].Items[0].StartAt = 5 * time.Second
+	assert.False(t, s.ApplyCadence(2*time.Second, 500*time.Millisecond, 0))
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 3*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, 3500*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, 5500*time.Millisecond, s.Items[1].EndAt)
+	assert.Equal(t, time.Duration(0), s.Items[1].Lines[0].Items[0].StartAt)
+	assert.Equal(t, "subtitle-2", s.Items[1].Lines[0].Items[0].Text)
+
+	// Maximum duration
+	assert.False(t, s.ApplyCadence(2*time.Second, 500*time.Millisecond, 5500*time.Millisecond))
+	assert.True(t, s.ApplyCadence(2*time.Second, time.Second, 5500*time.Millisecond))
+}
+
 func TestSubtitles_ApplyLinearCorrection(t *testing.T) {
 	s := &astisub.Subtitles{Items: []*astisub.Item{
 		{
@@ -363,3 +1628,55 @@ func TestNewScanner(t *testing.T) {
 		assert.Equal(t, "else for that matter.", s.Items[2].Lines[1].String())
 	}
 }
+
+func TestSubtitles_ValidateMarkup(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{{Lines: []astisub.Line{
+		{Items: []astisub.LineItem{
+			{Text: "Tom & Jerry"},
+			{Text: "Tom &amp; Jerry&#33;"},
+			{RubyText: "<rt>&lt;", Text: "Rock&Roll;"},
+		}},
+		{Items: []astisub.LineItem{{Text: "<font color=\"red\">Hello <i>world</font> again"}}},
+		{Items: []astisub.LineItem{{Text: "Stray</b> {\\an8}<u>end"}}},
+	}}}}
+	fixes := s.ValidateMarkup()
+	require.Len(t, fixes, 5)
+
+	// Stray ampersands are escaped, entities being kept
+	assert.Equal(t, astisub.MarkupFix{Fixed: "Tom &amp; Jerry", Item: s.Items[0], Original: "Tom & Jerry"}, fixes[0])
+	assert.Equal(t, astisub.MarkupFix{Fixed: "<rt>&lt;</rt>", Item: s.Items[0], Original: "<rt>&lt;"}, fixes[1])
+	assert.Equal(t, "Rock&amp;Roll;", fixes[2].Fixed)
+	lis := s.Items[0].Lines[0].Items
+	require.Len(t, lis, 3)
+	assert.Equal(t, "Tom &amp; Jerry", lis[0].Text)
+	assert.Equal(t, "Tom &amp; Jerry&#33;", lis[1].Text)
+	assert.Equal(t, "<rt>&lt;</rt>", lis[2].RubyText)
+
+	// Style tags are converted into inline styles and other tags are balanced
+	assert.Equal(t, `<font color="red">Hello </font><font color="red">world</font> again`, fixes[3].Fixed)
+	lis = s.Items[0].Lines[1].Items
+	require.Len(t, lis, 2)
+	assert.Equal(t, `<font color="red">Hello </font>`, lis[0].Text)
+	assert.Nil(t, lis[0].InlineStyle)
+	assert.Equal(t, `<font color="red">world</font> again`, lis[1].Text)
+	assert.True(t, lis[1].InlineStyle.SRTItalics)
+	assert.True(t, lis[1].InlineStyle.WebVTTItalics)
+	lis = s.Items[0].Lines[2].Items
+	require.Len(t, lis, 2)
+	assert.Equal(t, "Stray {\\an8}", lis[0].Text)
+	assert.Nil(t, lis[0].InlineStyle)
+	assert.Equal(t, "end", lis[1].Text)
+	assert.True(t, lis[1].InlineStyle.SRTUnderline)
+
+	// Nothing left to fix
+	assert.Empty(t, s.ValidateMarkup())
+
+	// Entities are not escaped twice
+	w := &bytes.Buffer{}
+	s.Items[0].Lines = s.Items[0].Lines[:1]
+	require.NoError(t, s.WriteToSRT(w))
+	assert.Contains(t, w.String(), "\nTom &amp; JerryTom &amp; Jerry&#33;Rock&amp;Roll;\n")
+	w.Reset()
+	require.NoError(t, s.WriteToTTML(w))
+	assert.Contains(t, w.String(), "<span>Tom &amp; Jerry!</span>")
+}
@@ -321,10 +321,17 @@ const (
 	teletextPESDataUnitIDStuffing           = 0xff
 )
 
+// Teletext displayable subtitle rows range from 1 to teletextMaxRows
+const teletextMaxRows = 23
+
 // TeletextOptions represents teletext options
 type TeletextOptions struct {
+	// Page is the page number, its hundreds being the magazine (e.g. 888 is page 88 of magazine 8).
+	// When reading, default is the first subtitle page found. When writing, default is 888.
 	Page int
-	PID  int
+	// PID is the PID of the teletext elementary stream.
+	// When reading, default is the first teletext PID found in the PMT. When writing, default is 0x100.
+	PID int
 }
 
 // ReadFromTeletext parses a teletext content
@@ -836,7 +843,15 @@ func (p *teletextPage) parse(s *Subtitles, d *teletextCharacterDecoder, firstTim
 
 	// Loop through rows
 	for _, idxRow := range p.rows {
+		// Parse row
+		n := len(i.Lines)
 		parseTeletextRow(i, d, nil, p.data[uint8(idxRow)])
+
+		// Store the row of the first line
+		if n == 0 && len(i.Lines) > 0 {
+			i.InlineStyle = &StyleAttributes{TeletextRow: astikit.IntPtr(idxRow)}
+			i.InlineStyle.propagateTeletextAttributes()
+		}
 	}
 
 	// Append item
@@ -861,7 +876,7 @@ func parseTeletextRow(i *Item, d decoder, fs func() styler, row []byte) {
 	var li = LineItem{InlineStyle: &StyleAttributes{}}
 	var started bool
 	var s styler
-	for _, v := range row {
+	for column, v := range row {
 		// Create specific styler
 		if fs != nil {
 			s = fs()
@@ -942,8 +957,14 @@ func parseTeletextRow(i *Item, d decoder, fs func() styler, row []byte) {
 				}
 			}
 		} else if started {
+			// Store the column of the first displayed character
+			text := string(d.decode(v))
+			if strings.TrimSpace(li.Text) == "" && strings.TrimSpace(text) != "" {
+				li.InlineStyle.TeletextColumn = astikit.IntPtr(column)
+			}
+
 			// Append text
-			li.Text += string(d.decode(v))
+			li.Text += text
 		}
 	}
 
@@ -995,3 +1016,318 @@ func appendTeletextLineItem(l *Line, li LineItem, s styler) {
 		l.Items = append(l.Items, li)
 	}
 }
+
+// Teletext write defaults
+const (
+	teletextDefaultPage = 888
+	teletextDefaultPID  = 0x100
+)
+
+// Teletext control codes
+const (
+	teletextControlCodeDoubleHeight = 0xd
+	teletextControlCodeEndBox       = 0xa
+	teletextControlCodeNormalSize   = 0xc
+	teletextControlCodeStartBox     = 0xb
+)
+
+// teletextColumns is the number of characters of a row
+const teletextColumns = 40
+
+// teletextColors are the colors set by the 0x0 to 0x7 control codes
+var teletextColors = []*Color{ColorBlack, ColorRed, ColorGreen, ColorYellow, ColorBlue, ColorMagenta, ColorCyan, ColorWhite}
+
+// teletextHamming84Codes are the hamming 8/4 codewords of nibbles, in the order bytes are stored in PES data
+var teletextHamming84Codes = [16]byte{
+	0xa8, 0x40, 0x92, 0x7a, 0x26, 0xce, 0x1c, 0xf4, 0x0b, 0xe3, 0x31, 0xd9, 0x85, 0x6d, 0xbf, 0x57,
+}
+
+// teletextLanguageMapping maps languages to their ISO 639-2 code
+var teletextLanguageMapping = astikit.NewBiMap().
+	Set("chi", LanguageChinese).
+	Set("eng", LanguageEnglish).
+	Set("fra", LanguageFrench).
+	Set("jpn", LanguageJapanese).
+	Set("nor", LanguageNorwegian)
+
+// WriteToTeletext writes subtitles as a single EBU teletext subtitle page muxed in a .ts content.
+// Items are displayed one at a time, the page being erased when they end, and their color and double height are
+// converted to spacing attributes. Characters are written in the G0 latin charset.
+// http://www.etsi.org/deliver/etsi_en/300400_300499/300472/01.03.01_60/en_300472v010301p.pdf
+func (s Subtitles) WriteToTeletext(o io.Writer, opts TeletextOptions) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		err = ErrNoSubtitlesToWrite
+		return
+	}
+
+	// Get options
+	var page, pid = opts.Page, opts.PID
+	if page == 0 {
+		page = teletextDefaultPage
+	}
+	if pid == 0 {
+		pid = teletextDefaultPID
+	}
+	if page < 100 || page > 899 {
+		err = fmt.Errorf("astisub: invalid teletext page %d", page)
+		return
+	}
+	var magazineNumber, pageNumber = uint8(page / 100), uint8(page % 100)
+
+	// Get language
+	var language = "und"
+	if s.Metadata != nil {
+		if v, ok := teletextLanguageMapping.GetInverse(s.Metadata.Language); ok {
+			language = v.(string)
+		}
+	}
+
+	// Create muxer
+	var mx = astits.NewMuxer(context.Background(), o)
+	if err = mx.AddElementaryStream(astits.PMTElementaryStream{
+		ElementaryPID: uint16(pid),
+		ElementaryStreamDescriptors: []*astits.Descriptor{{
+			Tag: astits.DescriptorTagTeletext,
+			Teletext: &astits.DescriptorTeletext{Items: []*astits.DescriptorTeletextItem{{
+				Language: []byte(language),
+				Magazine: magazineNumber % 8,
+				Page:     pageNumber,
+				Type:     0x2, // Teletext subtitle page
+			}}},
+		}},
+		StreamType: astits.StreamTypePrivateData,
+	}); err != nil {
+		err = fmt.Errorf("astisub: adding teletext elementary stream failed: %w", err)
+		return
+	}
+	mx.SetPCRPID(uint16(pid))
+
+	// Order items without modifying the subtitles
+	var items = append([]*Item(nil), s.Items...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].StartAt < items[j].StartAt })
+
+	// Create character encoder
+	var e = newTeletextCharacterEncoder()
+
+	// Write pages
+	// An empty page is written first so that times are relative to 0
+	var write = func(t time.Duration, rows map[uint8][]byte) (err error) {
+		if _, err = mx.WriteData(&astits.MuxerData{
+			PES: newTeletextPESData(t, magazineNumber, pageNumber, rows),
+			PID: uint16(pid),
+		}); err != nil {
+			err = fmt.Errorf("astisub: writing teletext page at %s failed: %w", t, err)
+			return
+		}
+		return
+	}
+	if items[0].StartAt > 0 {
+		if err = write(0, nil); err != nil {
+			return
+		}
+	}
+	for idx, i := range items {
+		// Display item
+		if err = write(i.StartAt, i.teletextRows(e)); err != nil {
+			return
+		}
+
+		// Erase page unless next item replaces it
+		if idx == len(items)-1 || items[idx+1].StartAt > i.EndAt {
+			if err = write(i.EndAt, nil); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// teletextCharacterEncoder maps UTF-8 characters to G0 latin charset characters
+type teletextCharacterEncoder map[string]byte
+
+func newTeletextCharacterEncoder() teletextCharacterEncoder {
+	var d = newTeletextCharacterDecoder()
+	d.updateCharset(astikit.UInt8Ptr(0), false)
+	var e = make(teletextCharacterEncoder)
+	for idx, c := range d.c {
+		if _, ok := e[string(c)]; !ok {
+			e[string(c)] = byte(idx + 0x20)
+		}
+	}
+	return e
+}
+
+// encode encodes characters, unknown ones being replaced with "?"
+func (e teletextCharacterEncoder) encode(s string) (o []byte) {
+	for _, r := range s {
+		if v, ok := e[string(r)]; ok {
+			o = append(o, v)
+		} else {
+			o = append(o, '?')
+		}
+	}
+	return
+}
+
+// teletextRows returns the rows of the item indexed by row number
+// Rows start at the item's teletext row if any, and otherwise end at the bottom of the page
+func (i Item) teletextRows(e teletextCharacterEncoder) (rows map[uint8][]byte) {
+	// Get step
+	var step = 1
+	for _, l := range i.Lines {
+		for _, li := range l.Items {
+			if li.InlineStyle != nil && li.InlineStyle.TeletextDoubleHeight != nil && *li.InlineStyle.TeletextDoubleHeight {
+				step = 2
+			}
+		}
+	}
+
+	// Get first row
+	var row = teletextMaxRows - step*len(i.Lines) + 1
+	if i.InlineStyle != nil && i.InlineStyle.TeletextRow != nil {
+		row = *i.InlineStyle.TeletextRow
+	}
+	if row < 1 {
+		row = 1
+	}
+
+	// Loop through lines
+	rows = make(map[uint8][]byte)
+	for _, l := range i.Lines {
+		if row > teletextMaxRows {
+			break
+		}
+		rows[uint8(row)] = l.teletextRow(e)
+		row += step
+	}
+	return
+}
+
+// teletextRow returns the line as a boxed row centered horizontally
+func (l Line) teletextRow(e teletextCharacterEncoder) []byte {
+	// Loop through line items
+	var b []byte
+	var color *Color
+	var doubleHeight bool
+	for _, li := range l.Items {
+		// Get attributes
+		var c = ColorWhite
+		var dh bool
+		if li.InlineStyle != nil {
+			if li.InlineStyle.TeletextColor != nil {
+				c = li.InlineStyle.TeletextColor
+			}
+			dh = li.InlineStyle.TeletextDoubleHeight != nil && *li.InlineStyle.TeletextDoubleHeight
+		}
+
+		// Spacing attributes are displayed as spaces and therefore replace the previous one
+		var codes []byte
+		if dh != doubleHeight {
+			if dh {
+				codes = append(codes, teletextControlCodeDoubleHeight)
+			} else {
+				codes = append(codes, teletextControlCodeNormalSize)
+			}
+			doubleHeight = dh
+		}
+		if !c.Equal(color) {
+			for idx, tc := range teletextColors {
+				if tc.Equal(c) {
+					codes = append(codes, byte(idx))
+				}
+			}
+			color = c
+		}
+		if len(codes) > 0 && len(b) > 0 && b[len(b)-1] == ' ' {
+			b = b[:len(b)-1]
+		}
+		b = append(b, codes...)
+
+		// Start box once the attributes of the first line item have been set
+		if len(b) == len(codes) {
+			b = append(b, teletextControlCodeStartBox, teletextControlCodeStartBox)
+		}
+		b = append(b, e.encode(li.Text)...)
+	}
+	b = append(b, teletextControlCodeEndBox, teletextControlCodeEndBox)
+
+	// Truncate
+	if len(b) > teletextColumns {
+		b = append(b[:teletextColumns-2], teletextControlCodeEndBox, teletextControlCodeEndBox)
+	}
+
+	// Center
+	var o = []byte(strings.Repeat(" ", (teletextColumns-len(b))/2))
+	o = append(o, b...)
+	return append(o, []byte(strings.Repeat(" ", teletextColumns-len(o)))...)
+}
+
+// newTeletextPESData returns a PES containing a page header erasing the page followed by rows
+func newTeletextPESData(t time.Duration, magazineNumber, pageNumber uint8, rows map[uint8][]byte) *astits.PESData {
+	// Data identifier
+	var d = []byte{0x10}
+
+	// Page header
+	var h = []byte{
+		teletextHamming84Codes[pageNumber%10],
+		teletextHamming84Codes[pageNumber/10],
+		teletextHamming84Codes[0],
+		teletextHamming84Codes[0x8], // C4: erase page
+		teletextHamming84Codes[0],
+		teletextHamming84Codes[0x8], // C6: subtitle
+		teletextHamming84Codes[0x3], // C7: suppress header, C8: update indicator
+		teletextHamming84Codes[0x1], // C11: magazine serial, C12 --> C14: charset
+	}
+	h = append(h, teletextParityBytes([]byte(strings.Repeat(" ", 32)))...)
+	d = append(d, newTeletextDataUnit(magazineNumber, 0, h)...)
+
+	// Rows
+	var ns []int
+	for n := range rows {
+		ns = append(ns, int(n))
+	}
+	sort.Ints(ns)
+	for _, n := range ns {
+		d = append(d, newTeletextDataUnit(magazineNumber, uint8(n), teletextParityBytes(rows[uint8(n)]))...)
+	}
+	return &astits.PESData{
+		Data: d,
+		Header: &astits.PESHeader{
+			OptionalHeader: &astits.PESOptionalHeader{
+				DataAlignmentIndicator: true,
+				MarkerBits:             2,
+				PTS:                    &astits.ClockReference{Base: int64(t/time.Microsecond) * 9 / 100},
+				PTSDTSIndicator:        astits.PTSDTSIndicatorOnlyPTS,
+			},
+			StreamID: astits.StreamIDPrivateStream1,
+		},
+	}
+}
+
+// newTeletextDataUnit returns an EBU teletext subtitle data unit containing a packet
+func newTeletextDataUnit(magazineNumber, packetNumber uint8, packet []byte) []byte {
+	var h = (packetNumber << 3) | (magazineNumber % 8)
+	var o = []byte{
+		teletextPESDataUnitIDEBUSubtitleData,
+		44,   // Data unit length
+		0xe0, // Reserved bits, field parity and undefined line offset
+		0xe4, // Framing code
+		teletextHamming84Codes[h&0xf],
+		teletextHamming84Codes[h>>4],
+	}
+	return append(o, packet...)
+}
+
+// teletextParityBytes adds the odd parity bit to bytes and reverses their bits as they're stored in PES data
+func teletextParityBytes(i []byte) (o []byte) {
+	o = make([]byte, len(i))
+	for idx, b := range i {
+		b &= 0x7f
+		if bits.OnesCount8(b)%2 == 0 {
+			b |= 0x80
+		}
+		o[idx] = bits.Reverse8(b)
+	}
+	return
+}
@@ -1,11 +1,13 @@
 package astisub
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
 	"github.com/asticode/go-astikit"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTeletextPESDataType(t *testing.T) {
@@ -32,10 +34,11 @@ func TestTeletextPageParse(t *testing.T) {
 	d.updateCharset(astikit.UInt8Ptr(0), false)
 	p.parse(&s, d, time.Unix(5, 0))
 	assert.Equal(t, []*Item{{
-		EndAt: 10 * time.Second,
+		EndAt:       10 * time.Second,
+		InlineStyle: &StyleAttributes{TeletextRow: astikit.IntPtr(1), WebVTTLine: "0%"},
 		Lines: []Line{
-			{Items: []LineItem{{InlineStyle: &StyleAttributes{TeletextSpacesAfter: astikit.IntPtr(0), TeletextSpacesBefore: astikit.IntPtr(0)}, Text: "test1"}}},
-			{Items: []LineItem{{InlineStyle: &StyleAttributes{TeletextSpacesAfter: astikit.IntPtr(0), TeletextSpacesBefore: astikit.IntPtr(0)}, Text: "test2"}}},
+			{Items: []LineItem{{InlineStyle: &StyleAttributes{TeletextColumn: astikit.IntPtr(1), TeletextSpacesAfter: astikit.IntPtr(0), TeletextSpacesBefore: astikit.IntPtr(0)}, Text: "test1"}}},
+			{Items: []LineItem{{InlineStyle: &StyleAttributes{TeletextColumn: astikit.IntPtr(1), TeletextSpacesAfter: astikit.IntPtr(0), TeletextSpacesBefore: astikit.IntPtr(0)}, Text: "test2"}}},
 		},
 		StartAt: 5 * time.Second,
 	}}, s.Items)
@@ -77,54 +80,63 @@ func TestParseTeletextRow(t *testing.T) {
 	assert.Equal(t, []LineItem{
 		{Text: "black", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorBlack,
+			TeletextColumn:       astikit.IntPtr(7),
 			TeletextSpacesAfter:  astikit.IntPtr(0),
 			TeletextSpacesBefore: astikit.IntPtr(0),
 			TTMLColor:            astikit.StrPtr("#000000"),
 		}},
 		{Text: "red", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorRed,
+			TeletextColumn:       astikit.IntPtr(13),
 			TeletextSpacesAfter:  astikit.IntPtr(0),
 			TeletextSpacesBefore: astikit.IntPtr(0),
 			TTMLColor:            astikit.StrPtr("#ff0000"),
 		}},
 		{Text: "green", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorGreen,
+			TeletextColumn:       astikit.IntPtr(17),
 			TeletextSpacesAfter:  astikit.IntPtr(0),
 			TeletextSpacesBefore: astikit.IntPtr(0),
 			TTMLColor:            astikit.StrPtr("#008000"),
 		}},
 		{Text: "yellow", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorYellow,
+			TeletextColumn:       astikit.IntPtr(23),
 			TeletextSpacesAfter:  astikit.IntPtr(0),
 			TeletextSpacesBefore: astikit.IntPtr(0),
 			TTMLColor:            astikit.StrPtr("#ffff00"),
 		}},
 		{Text: "blue", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorBlue,
+			TeletextColumn:       astikit.IntPtr(30),
 			TeletextSpacesAfter:  astikit.IntPtr(0),
 			TeletextSpacesBefore: astikit.IntPtr(0),
 			TTMLColor:            astikit.StrPtr("#0000ff"),
 		}},
 		{Text: "magenta", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorMagenta,
+			TeletextColumn:       astikit.IntPtr(35),
 			TeletextSpacesAfter:  astikit.IntPtr(0),
 			TeletextSpacesBefore: astikit.IntPtr(0),
 			TTMLColor:            astikit.StrPtr("#ff00ff"),
 		}},
 		{Text: "cyan", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorCyan,
+			TeletextColumn:       astikit.IntPtr(43),
 			TeletextSpacesAfter:  astikit.IntPtr(0),
 			TeletextSpacesBefore: astikit.IntPtr(0),
 			TTMLColor:            astikit.StrPtr("#00ffff"),
 		}},
 		{Text: "white", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorWhite,
+			TeletextColumn:       astikit.IntPtr(48),
 			TeletextSpacesAfter:  astikit.IntPtr(0),
 			TeletextSpacesBefore: astikit.IntPtr(0),
 			TTMLColor:            astikit.StrPtr("#ffffff"),
 		}},
 		{Text: "double height", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorWhite,
+			TeletextColumn:       astikit.IntPtr(54),
 			TeletextDoubleHeight: astikit.BoolPtr(true),
 			TeletextSpacesAfter:  astikit.IntPtr(0),
 			TeletextSpacesBefore: astikit.IntPtr(0),
@@ -132,6 +144,7 @@ func TestParseTeletextRow(t *testing.T) {
 		}},
 		{Text: "double width", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorWhite,
+			TeletextColumn:       astikit.IntPtr(68),
 			TeletextDoubleHeight: astikit.BoolPtr(true),
 			TeletextDoubleWidth:  astikit.BoolPtr(true),
 			TeletextSpacesAfter:  astikit.IntPtr(0),
@@ -140,6 +153,7 @@ func TestParseTeletextRow(t *testing.T) {
 		}},
 		{Text: "double size", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorWhite,
+			TeletextColumn:       astikit.IntPtr(81),
 			TeletextDoubleHeight: astikit.BoolPtr(true),
 			TeletextDoubleWidth:  astikit.BoolPtr(true),
 			TeletextDoubleSize:   astikit.BoolPtr(true),
@@ -149,6 +163,7 @@ func TestParseTeletextRow(t *testing.T) {
 		}},
 		{Text: "reset", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorWhite,
+			TeletextColumn:       astikit.IntPtr(93),
 			TeletextDoubleHeight: astikit.BoolPtr(false),
 			TeletextDoubleWidth:  astikit.BoolPtr(false),
 			TeletextDoubleSize:   astikit.BoolPtr(false),
@@ -175,3 +190,59 @@ func TestAppendTeletextLineItem(t *testing.T) {
 		TeletextSpacesBefore: astikit.IntPtr(1),
 	}, *l.Items[0].InlineStyle)
 }
+
+func TestTeletextHamming84Codes(t *testing.T) {
+	for v, c := range teletextHamming84Codes {
+		d, ok := astikit.ByteHamming84Decode(c)
+		assert.True(t, ok)
+		assert.Equal(t, uint8(v), d)
+	}
+}
+
+func TestWriteToTeletext(t *testing.T) {
+	s := &Subtitles{
+		Items: []*Item{
+			{
+				EndAt: 3 * time.Second,
+				Lines: []Line{
+					{Items: []LineItem{{Text: "Hello"}, {InlineStyle: &StyleAttributes{TeletextColor: ColorRed}, Text: " world"}}},
+					{Items: []LineItem{{Text: "Second line"}}},
+				},
+				StartAt: time.Second,
+			},
+			{
+				EndAt:   5500 * time.Millisecond,
+				Lines:   []Line{{Items: []LineItem{{InlineStyle: &StyleAttributes{TeletextDoubleHeight: astikit.BoolPtr(true)}, Text: "€Double"}}}},
+				StartAt: 3 * time.Second,
+			},
+		},
+		Metadata: &Metadata{Language: LanguageEnglish},
+	}
+	w := &bytes.Buffer{}
+	require.NoError(t, s.WriteToTeletext(w, TeletextOptions{}))
+	assert.Equal(t, 0, w.Len()%188)
+
+	// Read
+	r, err := ReadFromTeletext(bytes.NewReader(w.Bytes()), TeletextOptions{})
+	require.NoError(t, err)
+	require.Len(t, r.Items, 2)
+	assert.Equal(t, time.Second, r.Items[0].StartAt)
+	assert.Equal(t, 3*time.Second, r.Items[0].EndAt)
+	assert.Equal(t, 22, *r.Items[0].InlineStyle.TeletextRow)
+	require.Len(t, r.Items[0].Lines, 2)
+	require.Len(t, r.Items[0].Lines[0].Items, 2)
+	assert.Equal(t, "Hello", r.Items[0].Lines[0].Items[0].Text)
+	assert.Equal(t, ColorWhite, r.Items[0].Lines[0].Items[0].InlineStyle.TeletextColor)
+	assert.Equal(t, "world", r.Items[0].Lines[0].Items[1].Text)
+	assert.Equal(t, ColorRed, r.Items[0].Lines[0].Items[1].InlineStyle.TeletextColor)
+	assert.Equal(t, "Second line", r.Items[0].Lines[1].String())
+	assert.Equal(t, 3*time.Second, r.Items[1].StartAt)
+	assert.Equal(t, 5500*time.Millisecond, r.Items[1].EndAt)
+	assert.Equal(t, 22, *r.Items[1].InlineStyle.TeletextRow)
+	require.Len(t, r.Items[1].Lines, 1)
+	assert.Equal(t, "?Double", r.Items[1].Lines[0].String())
+	assert.Equal(t, astikit.BoolPtr(true), r.Items[1].Lines[0].Items[0].InlineStyle.TeletextDoubleHeight)
+
+	// Invalid page
+	assert.Error(t, s.WriteToTeletext(w, TeletextOptions{Page: 999}))
+}
@@ -0,0 +1,57 @@
+package astisub
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteToTextOptions represents plain text write options
+type WriteToTextOptions struct {
+	// Timestamps prefixes each paragraph with its item's start time as "[00:00:00.000] "
+	Timestamps bool
+}
+
+// WriteToText writes the plain text of the subtitles, one paragraph per item separated by empty lines, without
+// time boundaries nor markup. Items without text are skipped.
+func (s Subtitles) WriteToText(o io.Writer) (err error) {
+	return s.WriteToTextWithOptions(o, WriteToTextOptions{})
+}
+
+// WriteToTextWithOptions writes the plain text of the subtitles with options
+func (s Subtitles) WriteToTextWithOptions(o io.Writer, opts WriteToTextOptions) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		return ErrNoSubtitlesToWrite
+	}
+
+	// Loop through items
+	var c []byte
+	for _, item := range s.Items {
+		// Get text
+		var t = item.PlainText()
+		if t == "" {
+			continue
+		}
+
+		// Add empty line
+		if len(c) > 0 {
+			c = append(c, bytesLineSeparator...)
+		}
+
+		// Add timestamp
+		if opts.Timestamps {
+			c = append(c, []byte("["+formatDuration(item.StartAt, ".", 3)+"] ")...)
+		}
+
+		// Add text
+		c = append(c, []byte(t)...)
+		c = append(c, bytesLineSeparator...)
+	}
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = fmt.Errorf("astisub: writing failed: %w", err)
+		return
+	}
+	return
+}
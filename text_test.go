@@ -0,0 +1,35 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteToText(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "<i>Hello</i>"}}},
+			{Items: []astisub.LineItem{{Text: "world &amp; "}, {Text: "{\\an8}friends"}}},
+		}, StartAt: time.Second},
+		{EndAt: 4 * time.Second, StartAt: 3 * time.Second},
+		{EndAt: time.Hour + 6*time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Bye"}}}}, StartAt: time.Hour + 5*time.Second + 500*time.Millisecond},
+	}}
+
+	// Default
+	w := &bytes.Buffer{}
+	require.NoError(t, s.WriteToText(w))
+	assert.Equal(t, "Hello world & friends\n\nBye\n", w.String())
+
+	// Timestamps
+	w.Reset()
+	require.NoError(t, s.WriteToTextWithOptions(w, astisub.WriteToTextOptions{Timestamps: true}))
+	assert.Equal(t, "[00:00:01.000] Hello world & friends\n\n[01:00:05.500] Bye\n", w.String())
+
+	// No subtitles
+	assert.Equal(t, astisub.ErrNoSubtitlesToWrite, astisub.Subtitles{}.WriteToText(w))
+}
@@ -3,7 +3,9 @@ package astisub
 import (
 	"encoding/xml"
 	"fmt"
+	"html"
 	"io"
+	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
@@ -27,6 +29,25 @@ const (
 	ttmlLanguageNorwegian = "no"
 )
 
+// TTML namespaces
+const (
+	ttmlNamespaceEBUTTM = "urn:ebu:tt:metadata"
+	ttmlNamespaceEBUTTS = "urn:ebu:tt:style"
+	ttmlNamespaceTTP    = "http://www.w3.org/ns/ttml#parameter"
+)
+
+// EBU-TT-D region used by subtitles without region
+const ebuttdDefaultRegionID = "astisub-default"
+
+// EBU-TT-D default cell resolution
+const ebuttdDefaultCellResolution = "32 15"
+
+// iTT regions used by subtitles without region
+const (
+	ittRegionIDBottom = "bottom"
+	ittRegionIDTop    = "top"
+)
+
 // TTML language mapping
 var ttmlLanguageMapping = astikit.NewBiMap().
 	Set(ttmlLanguageChinese, LanguageChinese).
@@ -47,6 +68,7 @@ type TTMLIn struct {
 	Framerate int              `xml:"frameRate,attr"`
 	Lang      string           `xml:"lang,attr"`
 	Metadata  TTMLInMetadata   `xml:"head>metadata"`
+	Profile   string           `xml:"profile,attr"`
 	Regions   []TTMLInRegion   `xml:"head>layout>region"`
 	Styles    []TTMLInStyle    `xml:"head>styling>style"`
 	Subtitles []TTMLInSubtitle `xml:"body>div>p"`
@@ -60,6 +82,8 @@ func (t TTMLIn) metadata() (m *Metadata) {
 		Framerate:     t.Framerate,
 		Title:         t.Metadata.Title,
 		TTMLCopyright: t.Metadata.Copyright,
+		TTMLProfile:   t.Profile,
+		TTMLTickrate:  t.Tickrate,
 	}
 	if v, ok := ttmlLanguageMapping.Get(astikit.StrPad(t.Lang, ' ', 2, astikit.PadCut)); ok {
 		m.Language = v.(string)
@@ -86,6 +110,8 @@ type TTMLInStyleAttributes struct {
 	FontStyle       *string `xml:"fontStyle,attr,omitempty"`
 	FontWeight      *string `xml:"fontWeight,attr,omitempty"`
 	LineHeight      *string `xml:"lineHeight,attr,omitempty"`
+	LinePadding     *string `xml:"linePadding,attr,omitempty"`   // EBU-TT
+	MultiRowAlign   *string `xml:"multiRowAlign,attr,omitempty"` // EBU-TT
 	Opacity         *string `xml:"opacity,attr,omitempty"`
 	Origin          *string `xml:"origin,attr,omitempty"`
 	Overflow        *string `xml:"overflow,attr,omitempty"`
@@ -103,7 +129,16 @@ type TTMLInStyleAttributes struct {
 
 // StyleAttributes converts TTMLInStyleAttributes into a StyleAttributes
 func (s TTMLInStyleAttributes) styleAttributes() (o *StyleAttributes) {
-	o = &StyleAttributes{
+	o = s.ttmlStyleAttributes()
+	o.propagateTTMLAttributes()
+	return
+}
+
+// ttmlStyleAttributes converts TTMLInStyleAttributes into a StyleAttributes without propagating them
+func (s TTMLInStyleAttributes) ttmlStyleAttributes() *StyleAttributes {
+	return &StyleAttributes{
+		EBUTTLinePadding:    s.LinePadding,
+		EBUTTMultiRowAlign:  s.MultiRowAlign,
 		TTMLBackgroundColor: s.BackgroundColor,
 		TTMLColor:           s.Color,
 		TTMLDirection:       s.Direction,
@@ -129,7 +164,187 @@ func (s TTMLInStyleAttributes) styleAttributes() (o *StyleAttributes) {
 		TTMLWritingMode:     s.WritingMode,
 		TTMLZIndex:          s.ZIndex,
 	}
-	o.propagateTTMLAttributes()
+}
+
+// TTMLAnimation represents a TTML2 animate or set element
+// Timing attributes are kept as is and animated style attributes hold semicolon separated values
+type TTMLAnimation struct {
+	Begin       string
+	CalcMode    string
+	Dur         string
+	End         string
+	Fill        string
+	KeySplines  string
+	KeyTimes    string
+	RepeatCount string
+	Set         bool // whether it's a discrete <set> element rather than an <animate> one
+	Style       *StyleAttributes
+}
+
+// beginsImmediately returns whether the animation begins as soon as its parent does
+func (a TTMLAnimation) beginsImmediately() bool {
+	if a.Begin == "" {
+		return true
+	}
+	var d TTMLInDuration
+	if err := d.UnmarshalText([]byte(a.Begin)); err != nil {
+		return false
+	}
+	return d.d == 0 && d.frames == 0 && d.ticks == 0
+}
+
+// ApplyTTMLAnimationsStartState sets animated TTML style attributes of items and regions to their start state, which
+// is the first value of animations beginning as soon as their parent does, so that they can be rendered statically.
+// Animations are kept so that they're still written.
+func (s *Subtitles) ApplyTTMLAnimationsStartState() {
+	for _, r := range s.Regions {
+		r.InlineStyle.applyTTMLAnimationsStartState()
+	}
+	for _, i := range s.Items {
+		i.InlineStyle.applyTTMLAnimationsStartState()
+	}
+}
+
+// applyTTMLAnimationsStartState sets animated TTML style attributes to their start state
+func (sa *StyleAttributes) applyTTMLAnimationsStartState() {
+	// Nothing to do
+	if sa == nil || len(sa.TTMLAnimations) == 0 {
+		return
+	}
+
+	// Loop through animations
+	var dst = reflect.ValueOf(sa).Elem()
+	for _, a := range sa.TTMLAnimations {
+		if a.Style == nil || !a.beginsImmediately() {
+			continue
+		}
+
+		// Loop through animated attributes
+		var src = reflect.ValueOf(a.Style).Elem()
+		for idx := 0; idx < src.NumField(); idx++ {
+			// Only TTML attributes can be animated
+			var n = src.Type().Field(idx).Name
+			var f = src.Field(idx)
+			if (!strings.HasPrefix(n, "TTML") && !strings.HasPrefix(n, "EBUTT")) || f.Kind() != reflect.Ptr || f.IsNil() {
+				continue
+			}
+
+			// Values are separated by semicolons
+			if v, ok := f.Interface().(*string); ok {
+				f = reflect.ValueOf(astikit.StrPtr(strings.TrimSpace(strings.Split(*v, ";")[0])))
+			}
+			dst.Field(idx).Set(f)
+		}
+	}
+	sa.propagateTTMLAttributes()
+}
+
+// ResolveTTMLStyles sets the inheritable TTML style attributes (e.g. color or font) line items don't specify to the ones
+// they inherit, in order, from their styles, their item, their item's styles, their item's region and its styles,
+// parent styles being followed, so that they can be rendered without resolving the cascade.
+// Styles are kept so that they're still written.
+func (s *Subtitles) ResolveTTMLStyles() {
+	for _, i := range s.Items {
+		// Get what the item's line items inherit
+		var ia = &StyleAttributes{}
+		ia.ttmlInherit(i.InlineStyle)
+		ia.ttmlInheritStyle(i.Style)
+		if i.Region != nil {
+			ia.ttmlInherit(i.Region.InlineStyle)
+			ia.ttmlInheritStyle(i.Region.Style)
+		}
+
+		// Loop through line items
+		for idxLine, l := range i.Lines {
+			for idxLineItem, li := range l.Items {
+				var sa = copyStyleAttributes(li.InlineStyle)
+				if sa == nil {
+					sa = &StyleAttributes{}
+				}
+				var inheritedFromStyle = sa.ttmlInheritStyle(li.Style)
+				if inheritedFromItem := sa.ttmlInherit(ia); inheritedFromStyle || inheritedFromItem {
+					sa.propagateTTMLAttributes()
+					i.Lines[idxLine].Items[idxLineItem].InlineStyle = sa
+				}
+			}
+		}
+	}
+}
+
+// ttmlInheritStyle inherits the TTML style attributes of the style and its parents
+func (sa *StyleAttributes) ttmlInheritStyle(s *Style) (inherited bool) {
+	var visited = make(map[*Style]bool)
+	for ; s != nil && !visited[s]; s = s.Style {
+		visited[s] = true
+		if sa.ttmlInherit(s.InlineStyle) {
+			inherited = true
+		}
+	}
+	return
+}
+
+// ttmlInherit sets the inheritable TTML style attributes that are not set to the ones of p and returns whether some
+// were set
+func (sa *StyleAttributes) ttmlInherit(p *StyleAttributes) (inherited bool) {
+	if p == nil {
+		return
+	}
+	for _, a := range []struct {
+		dst **string
+		src *string
+	}{
+		{dst: &sa.EBUTTLinePadding, src: p.EBUTTLinePadding},
+		{dst: &sa.EBUTTMultiRowAlign, src: p.EBUTTMultiRowAlign},
+		{dst: &sa.TTMLColor, src: p.TTMLColor},
+		{dst: &sa.TTMLDirection, src: p.TTMLDirection},
+		{dst: &sa.TTMLFontFamily, src: p.TTMLFontFamily},
+		{dst: &sa.TTMLFontSize, src: p.TTMLFontSize},
+		{dst: &sa.TTMLFontStyle, src: p.TTMLFontStyle},
+		{dst: &sa.TTMLFontWeight, src: p.TTMLFontWeight},
+		{dst: &sa.TTMLLineHeight, src: p.TTMLLineHeight},
+		{dst: &sa.TTMLTextAlign, src: p.TTMLTextAlign},
+		{dst: &sa.TTMLTextDecoration, src: p.TTMLTextDecoration},
+		{dst: &sa.TTMLTextOutline, src: p.TTMLTextOutline},
+		{dst: &sa.TTMLVisibility, src: p.TTMLVisibility},
+		{dst: &sa.TTMLWrapOption, src: p.TTMLWrapOption},
+	} {
+		if *a.dst == nil && a.src != nil {
+			*a.dst = a.src
+			inherited = true
+		}
+	}
+	return
+}
+
+// TTMLInAnimate represents an input TTML2 animate element
+type TTMLInAnimate struct {
+	Begin       string `xml:"begin,attr,omitempty"`
+	CalcMode    string `xml:"calcMode,attr,omitempty"`
+	Dur         string `xml:"dur,attr,omitempty"`
+	End         string `xml:"end,attr,omitempty"`
+	Fill        string `xml:"fill,attr,omitempty"`
+	KeySplines  string `xml:"keySplines,attr,omitempty"`
+	KeyTimes    string `xml:"keyTimes,attr,omitempty"`
+	RepeatCount string `xml:"repeatCount,attr,omitempty"`
+	TTMLInStyleAttributes
+}
+
+// ttmlAnimations converts input animate and set elements into animations
+func ttmlAnimations(animates, sets []TTMLInAnimate) (o []TTMLAnimation) {
+	for idx, a := range append(append([]TTMLInAnimate{}, animates...), sets...) {
+		o = append(o, TTMLAnimation{
+			Begin:       a.Begin,
+			CalcMode:    a.CalcMode,
+			Dur:         a.Dur,
+			End:         a.End,
+			Fill:        a.Fill,
+			KeySplines:  a.KeySplines,
+			KeyTimes:    a.KeyTimes,
+			RepeatCount: a.RepeatCount,
+			Set:         idx >= len(animates),
+			Style:       a.TTMLInStyleAttributes.ttmlStyleAttributes(),
+		})
+	}
 	return
 }
 
@@ -142,10 +357,34 @@ type TTMLInHeader struct {
 
 // TTMLInRegion represents an input TTML region
 type TTMLInRegion struct {
+	Animations []TTMLInAnimate `xml:"animate"`
+	Sets       []TTMLInAnimate `xml:"set"`
+	// Style attributes may be defined in style children rather than inline
+	Styles []TTMLInStyleAttributes `xml:"style"`
 	TTMLInHeader
 	XMLName xml.Name `xml:"region"`
 }
 
+// styleAttributes returns the region style attributes, inline ones taking precedence over style children ones
+func (r TTMLInRegion) styleAttributes() *StyleAttributes {
+	var sa = r.TTMLInStyleAttributes
+	for _, c := range r.Styles {
+		sa.inherit(c)
+	}
+	return sa.styleAttributes()
+}
+
+// inherit sets the attributes that are not set to the ones of p
+func (s *TTMLInStyleAttributes) inherit(p TTMLInStyleAttributes) {
+	var dst = reflect.ValueOf(s).Elem()
+	var src = reflect.ValueOf(p)
+	for idx := 0; idx < src.NumField(); idx++ {
+		if dst.Field(idx).IsNil() {
+			dst.Field(idx).Set(src.Field(idx))
+		}
+	}
+}
+
 // TTMLInStyle represents an input TTML style
 type TTMLInStyle struct {
 	TTMLInHeader
@@ -154,14 +393,17 @@ type TTMLInStyle struct {
 
 // TTMLInSubtitle represents an input TTML subtitle
 type TTMLInSubtitle struct {
-	Begin *TTMLInDuration `xml:"begin,attr,omitempty"`
-	End   *TTMLInDuration `xml:"end,attr,omitempty"`
-	ID    string          `xml:"id,attr,omitempty"`
+	Animations []TTMLInAnimate `xml:"animate"`
+	Begin      *TTMLInDuration `xml:"begin,attr,omitempty"`
+	End        *TTMLInDuration `xml:"end,attr,omitempty"`
+	ID         string          `xml:"id,attr,omitempty"`
 	// We must store inner XML temporarily here since there's no tag to describe both any tag and chardata
 	// Real unmarshal will be done manually afterwards
-	Items  string `xml:",innerxml"`
-	Region string `xml:"region,attr,omitempty"`
-	Style  string `xml:"style,attr,omitempty"`
+	Items  string          `xml:",innerxml"`
+	Lang   string          `xml:"lang,attr,omitempty"`
+	Region string          `xml:"region,attr,omitempty"`
+	Sets   []TTMLInAnimate `xml:"set"`
+	Style  string          `xml:"style,attr,omitempty"`
 	TTMLInStyleAttributes
 }
 
@@ -238,12 +480,105 @@ func newTTMLXmlDecoder(s string) *xml.Decoder {
 
 // TTMLInItem represents an input TTML item
 type TTMLInItem struct {
-	Style string `xml:"style,attr,omitempty"`
-	Text  string `xml:",chardata"`
+	// Children contains the nested items and texts, in order
+	Children TTMLInItems  `xml:"-"`
+	Items    []TTMLInItem `xml:"-"`
+	Lang     string       `xml:"lang,attr,omitempty"`
+	Ruby     string       `xml:"ruby,attr,omitempty"`
+	Style    string       `xml:"style,attr,omitempty"`
+	Text     string       `xml:",chardata"`
 	TTMLInStyleAttributes
 	XMLName xml.Name
 }
 
+// UnmarshalXML implements the XML unmarshaler interface
+func (i *TTMLInItem) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err error) {
+	// Buffer tokens since the element needs to be decoded both as a struct and as ordered children
+	var inner []xml.Token
+	var shallow = []xml.Token{start.Copy()}
+	for depth := 1; ; {
+		// Get next token
+		var t xml.Token
+		if t, err = d.Token(); err != nil {
+			err = fmt.Errorf("astisub: getting next token failed: %w", err)
+			return
+		}
+		t = xml.CopyToken(t)
+
+		// Update depth
+		switch t.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 1 {
+				shallow = append(shallow, t)
+			}
+		}
+		if depth == 0 {
+			shallow = append(shallow, t)
+			break
+		}
+		inner = append(inner, t)
+	}
+
+	// Decode attributes and text
+	type item TTMLInItem
+	var v item
+	if err = xml.NewTokenDecoder(&ttmlTokenSlice{tokens: shallow}).Decode(&v); err != nil {
+		err = fmt.Errorf("astisub: decoding item failed: %w", err)
+		return
+	}
+	*i = TTMLInItem(v)
+
+	// Decode children
+	var wrapper = xml.Name{Local: "p"}
+	inner = append(append([]xml.Token{xml.StartElement{Name: wrapper}}, inner...), xml.EndElement{Name: wrapper})
+	if err = xml.NewTokenDecoder(&ttmlTokenSlice{tokens: inner}).Decode(&i.Children); err != nil {
+		err = fmt.Errorf("astisub: decoding children failed: %w", err)
+		return
+	}
+	for _, c := range i.Children {
+		if c.XMLName.Local == "span" {
+			i.Items = append(i.Items, c)
+		}
+	}
+	return
+}
+
+// ttmlTokenSlice is a token reader replaying buffered tokens
+type ttmlTokenSlice struct {
+	tokens []xml.Token
+}
+
+// Token implements the TokenReader interface
+func (s *ttmlTokenSlice) Token() (xml.Token, error) {
+	if len(s.tokens) == 0 {
+		return nil, io.EOF
+	}
+	t := s.tokens[0]
+	s.tokens = s.tokens[1:]
+	return t, nil
+}
+
+// rubyTexts returns the base and ruby texts of a ruby container
+func (i TTMLInItem) rubyTexts() (base, text string) {
+	for _, c := range i.Items {
+		switch c.Ruby {
+		case "base":
+			base += c.Text
+		case "text":
+			text += c.Text
+		case "baseContainer", "textContainer":
+			b, t := c.rubyTexts()
+			base += b
+			text += t
+		}
+	}
+	return
+}
+
 // TTMLInDuration represents an input TTML duration
 type TTMLInDuration struct {
 	d                 time.Duration
@@ -334,6 +669,19 @@ func (d TTMLInDuration) duration() (o time.Duration) {
 
 // ReadFromTTML parses a .ttml content
 func ReadFromTTML(i io.Reader) (o *Subtitles, err error) {
+	return ReadFromTTMLWithOptions(i, TTMLOptions{})
+}
+
+// TTMLOptions represents TTML read options
+type TTMLOptions struct {
+	// ResolveStyles resolves the style cascade once the content has been read, see ResolveTTMLStyles.
+	// Default is to only resolve nested spans, line items keeping the attributes of their styles, item and region
+	// unresolved.
+	ResolveStyles bool
+}
+
+// ReadFromTTMLWithOptions parses a .ttml content with options
+func ReadFromTTMLWithOptions(i io.Reader, opts TTMLOptions) (o *Subtitles, err error) {
 	// Init
 	o = NewSubtitles()
 
@@ -348,33 +696,33 @@ func ReadFromTTML(i io.Reader) (o *Subtitles, err error) {
 	o.Metadata = ttml.metadata()
 
 	// Loop through styles
-	var parentStyles = make(map[string]*Style)
 	for _, ts := range ttml.Styles {
-		var s = &Style{
+		o.Styles[ts.ID] = &Style{
 			ID:          ts.ID,
 			InlineStyle: ts.TTMLInStyleAttributes.styleAttributes(),
 		}
-		o.Styles[s.ID] = s
-		if len(ts.Style) > 0 {
-			parentStyles[ts.Style] = s
-		}
 	}
 
 	// Take care of parent styles
-	for id, s := range parentStyles {
-		if _, ok := o.Styles[id]; !ok {
-			err = fmt.Errorf("astisub: Style %s requested by style %s doesn't exist", id, s.ID)
+	// Several styles can share the same parent
+	for _, ts := range ttml.Styles {
+		if len(ts.Style) == 0 {
+			continue
+		}
+		if _, ok := o.Styles[ts.Style]; !ok {
+			err = fmt.Errorf("astisub: Style %s requested by style %s doesn't exist", ts.Style, ts.ID)
 			return
 		}
-		s.Style = o.Styles[id]
+		o.Styles[ts.ID].Style = o.Styles[ts.Style]
 	}
 
 	// Loop through regions
 	for _, tr := range ttml.Regions {
 		var r = &Region{
 			ID:          tr.ID,
-			InlineStyle: tr.TTMLInStyleAttributes.styleAttributes(),
+			InlineStyle: tr.styleAttributes(),
 		}
+		r.InlineStyle.TTMLAnimations = ttmlAnimations(tr.Animations, tr.Sets)
 		if len(tr.Style) > 0 {
 			if _, ok := o.Styles[tr.Style]; !ok {
 				err = fmt.Errorf("astisub: Style %s requested by region %s doesn't exist", tr.Style, r.ID)
@@ -395,9 +743,11 @@ func ReadFromTTML(i io.Reader) (o *Subtitles, err error) {
 
 		var s = &Item{
 			EndAt:       ts.End.duration(),
+			ID:          ts.ID,
 			InlineStyle: ts.TTMLInStyleAttributes.styleAttributes(),
 			StartAt:     ts.Begin.duration(),
 		}
+		s.InlineStyle.TTMLAnimations = ttmlAnimations(ts.Animations, ts.Sets)
 
 		// Add region
 		if len(ts.Region) > 0 {
@@ -432,69 +782,176 @@ func ReadFromTTML(i io.Reader) (o *Subtitles, err error) {
 
 		// Loop through texts
 		var l = &Line{}
-		for _, tt := range items {
-			// New line specified with the "br" tag
-			if strings.ToLower(tt.XMLName.Local) == "br" {
-				s.Lines = append(s.Lines, *l)
-				l = &Line{}
-				continue
-			}
+		var parseTexts func(items TTMLInItems, parent TTMLInItem) error
+		parseTexts = func(items TTMLInItems, parent TTMLInItem) (err error) {
+			for _, tt := range items {
+				// Animations have already been parsed
+				if tt.XMLName.Local == "animate" || tt.XMLName.Local == "set" {
+					continue
+				}
 
-			// New line decoded as a line break. This can happen if there's a "br" tag within the text since
-			// since the go xml unmarshaler will unmarshal a "br" tag as a line break if the field has the
-			// chardata xml tag.
-			for idx, li := range strings.Split(tt.Text, "\n") {
-				// New line
-				if idx > 0 {
+				// New line specified with the "br" tag
+				if strings.ToLower(tt.XMLName.Local) == "br" {
 					s.Lines = append(s.Lines, *l)
 					l = &Line{}
+					continue
 				}
 
-				// Init line item
-				var t = LineItem{
-					InlineStyle: tt.TTMLInStyleAttributes.styleAttributes(),
-					Text:        li,
+				// Nested spans inherit the attributes, style and language of their parent
+				tt.TTMLInStyleAttributes.inherit(parent.TTMLInStyleAttributes)
+				if tt.Style == "" {
+					tt.Style = parent.Style
 				}
-
-				// Add style
-				if len(tt.Style) > 0 {
-					if _, ok := o.Styles[tt.Style]; !ok {
-						err = fmt.Errorf("astisub: Style %s requested by item with text %s doesn't exist", tt.Style, tt.Text)
+				if tt.Lang == "" {
+					tt.Lang = parent.Lang
+				}
+				if tt.Ruby == "" && len(tt.Items) > 0 {
+					if err = parseTexts(tt.Children, tt); err != nil {
 						return
 					}
-					t.Style = o.Styles[tt.Style]
+					continue
 				}
 
-				// Append items
-				l.Items = append(l.Items, t)
-			}
+				// Ruby annotations are stored in the line item
+				var rubyText string
+				if tt.Ruby == "container" {
+					tt.Text, rubyText = tt.rubyTexts()
+				}
+
+				// New line decoded as a line break. This can happen if there's a "br" tag within the text since
+				// since the go xml unmarshaler will unmarshal a "br" tag as a line break if the field has the
+				// chardata xml tag.
+				for idx, li := range strings.Split(tt.Text, "\n") {
+					// New line
+					if idx > 0 {
+						s.Lines = append(s.Lines, *l)
+						l = &Line{}
+					}
+
+					// Init line item
+					var t = LineItem{
+						InlineStyle: tt.TTMLInStyleAttributes.styleAttributes(),
+						Language:    tt.Lang,
+						RubyText:    rubyText,
+						Text:        li,
+					}
+					if t.Language == "" {
+						t.Language = ts.Lang
+					}
+
+					// Add style
+					if len(tt.Style) > 0 {
+						if _, ok := o.Styles[tt.Style]; !ok {
+							err = fmt.Errorf("astisub: Style %s requested by item with text %s doesn't exist", tt.Style, tt.Text)
+							return
+						}
+						t.Style = o.Styles[tt.Style]
+					}
 
+					// Append items
+					l.Items = append(l.Items, t)
+				}
+			}
+			return
+		}
+		if err = parseTexts(items, TTMLInItem{}); err != nil {
+			return
 		}
 		s.Lines = append(s.Lines, *l)
 
 		// Append subtitle
 		o.Items = append(o.Items, s)
 	}
+
+	// Resolve styles
+	if opts.ResolveStyles {
+		o.ResolveTTMLStyles()
+	}
 	return
 }
 
+// ttmlID returns the item ID, or its index if it has none, sanitized into a valid xml:id that is not used yet
+// Invalid characters are replaced with "_" and IDs not starting with a letter or "_" are prefixed with "_".
+func (i Item) ttmlID(used map[string]bool) (o string) {
+	// Get ID
+	var id = i.ID
+	if id == "" {
+		if i.Index <= 0 {
+			return
+		}
+		id = strconv.Itoa(i.Index)
+	}
+
+	// Sanitize
+	var b strings.Builder
+	for idx, r := range id {
+		if idx == 0 && !ttmlIsNameStartChar(r) {
+			b.WriteRune('_')
+		}
+		if ttmlIsNameChar(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+
+	// Make sure it's unique
+	o = concatID(b.String(), func(v string) bool { return used[v] })
+	used[o] = true
+	return
+}
+
+// ttmlIsNameStartChar returns whether the rune can start an NCName
+func ttmlIsNameStartChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// ttmlIsNameChar returns whether the rune can be part of an NCName
+func ttmlIsNameChar(r rune) bool {
+	return ttmlIsNameStartChar(r) || r == '-' || r == '.' || unicode.IsDigit(r) || r == '\u00B7' ||
+		unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r)
+}
+
 // TTMLOut represents an output TTML that must be marshaled
 // We split it from the input TTML as this time we'll add strict namespaces
 type TTMLOut struct {
-	Lang            string            `xml:"xml:lang,attr,omitempty"`
-	Metadata        *TTMLOutMetadata  `xml:"head>metadata,omitempty"`
-	Styles          []TTMLOutStyle    `xml:"head>styling>style,omitempty"` //!\\ Order is important! Keep Styling above Layout
-	Regions         []TTMLOutRegion   `xml:"head>layout>region,omitempty"`
-	Subtitles       []TTMLOutSubtitle `xml:"body>div>p,omitempty"`
-	XMLName         xml.Name          `xml:"http://www.w3.org/ns/ttml tt"`
-	XMLNamespaceTTM string            `xml:"xmlns:ttm,attr"`
-	XMLNamespaceTTS string            `xml:"xmlns:tts,attr"`
+	CellResolution     string            `xml:"ttp:cellResolution,attr,omitempty"`
+	Framerate          int               `xml:"ttp:frameRate,attr,omitempty"`
+	Lang               string            `xml:"xml:lang,attr,omitempty"`
+	Metadata           *TTMLOutMetadata  `xml:"head>metadata,omitempty"`
+	Profile            string            `xml:"ttp:profile,attr,omitempty"`
+	Styles             []TTMLOutStyle    `xml:"head>styling>style,omitempty"` //!\\ Order is important! Keep Styling above Layout
+	Regions            []TTMLOutRegion   `xml:"head>layout>region,omitempty"`
+	Subtitles          []TTMLOutSubtitle `xml:"body>div>p,omitempty"`
+	Tickrate           int               `xml:"ttp:tickRate,attr,omitempty"`
+	TimeBase           string            `xml:"ttp:timeBase,attr,omitempty"`
+	XMLName            xml.Name          `xml:"http://www.w3.org/ns/ttml tt"`
+	XMLNamespaceEBUTTM string            `xml:"xmlns:ebuttm,attr,omitempty"`
+	XMLNamespaceEBUTTS string            `xml:"xmlns:ebutts,attr,omitempty"`
+	XMLNamespaceTTM    string            `xml:"xmlns:ttm,attr"`
+	XMLNamespaceTTP    string            `xml:"xmlns:ttp,attr,omitempty"`
+	XMLNamespaceTTS    string            `xml:"xmlns:tts,attr"`
+	// divs are written instead of subtitles when not empty
+	divs []TTMLOutDiv
+}
+
+// ttmlOutWithDivs represents an output TTML whose subtitles are grouped in divs
+// Divs being shallower than the embedded subtitles, they take precedence when marshaling
+type ttmlOutWithDivs struct {
+	TTMLOut
+	Divs []TTMLOutDiv `xml:"body>div"`
 }
 
 // TTMLOutMetadata represents an output TTML Metadata
 type TTMLOutMetadata struct {
-	Copyright string `xml:"ttm:copyright,omitempty"`
-	Title     string `xml:"ttm:title,omitempty"`
+	Copyright             string                        `xml:"ttm:copyright,omitempty"`
+	EBUTTDocumentMetadata *TTMLOutEBUTTDocumentMetadata `xml:"ebuttm:documentMetadata,omitempty"`
+	Title                 string                        `xml:"ttm:title,omitempty"`
+}
+
+// TTMLOutEBUTTDocumentMetadata represents an output EBU-TT document metadata
+type TTMLOutEBUTTDocumentMetadata struct {
+	ConformsToStandard string `xml:"ebuttm:conformsToStandard,omitempty"`
 }
 
 // TTMLOutStyleAttributes represents output TTML style attributes
@@ -510,6 +967,8 @@ type TTMLOutStyleAttributes struct {
 	FontStyle       *string `xml:"tts:fontStyle,attr,omitempty"`
 	FontWeight      *string `xml:"tts:fontWeight,attr,omitempty"`
 	LineHeight      *string `xml:"tts:lineHeight,attr,omitempty"`
+	LinePadding     *string `xml:"ebutts:linePadding,attr,omitempty"`
+	MultiRowAlign   *string `xml:"ebutts:multiRowAlign,attr,omitempty"`
 	Opacity         *string `xml:"tts:opacity,attr,omitempty"`
 	Origin          *string `xml:"tts:origin,attr,omitempty"`
 	Overflow        *string `xml:"tts:overflow,attr,omitempty"`
@@ -542,6 +1001,8 @@ func ttmlOutStyleAttributesFromStyleAttributes(s *StyleAttributes) TTMLOutStyleA
 		FontStyle:       s.TTMLFontStyle,
 		FontWeight:      s.TTMLFontWeight,
 		LineHeight:      s.TTMLLineHeight,
+		LinePadding:     s.EBUTTLinePadding,
+		MultiRowAlign:   s.EBUTTMultiRowAlign,
 		Opacity:         s.TTMLOpacity,
 		Origin:          s.TTMLOrigin,
 		Overflow:        s.TTMLOverflow,
@@ -558,6 +1019,43 @@ func ttmlOutStyleAttributesFromStyleAttributes(s *StyleAttributes) TTMLOutStyleA
 	}
 }
 
+// TTMLOutAnimate represents an output TTML2 animate element
+type TTMLOutAnimate struct {
+	Begin       string `xml:"begin,attr,omitempty"`
+	CalcMode    string `xml:"calcMode,attr,omitempty"`
+	Dur         string `xml:"dur,attr,omitempty"`
+	End         string `xml:"end,attr,omitempty"`
+	Fill        string `xml:"fill,attr,omitempty"`
+	KeySplines  string `xml:"keySplines,attr,omitempty"`
+	KeyTimes    string `xml:"keyTimes,attr,omitempty"`
+	RepeatCount string `xml:"repeatCount,attr,omitempty"`
+	TTMLOutStyleAttributes
+}
+
+// ttmlOutAnimatesFromStyleAttributes converts StyleAttributes animations into output animate or set elements
+func ttmlOutAnimatesFromStyleAttributes(s *StyleAttributes, set bool) (o []TTMLOutAnimate) {
+	if s == nil {
+		return
+	}
+	for _, a := range s.TTMLAnimations {
+		if a.Set != set {
+			continue
+		}
+		o = append(o, TTMLOutAnimate{
+			Begin:                  a.Begin,
+			CalcMode:               a.CalcMode,
+			Dur:                    a.Dur,
+			End:                    a.End,
+			Fill:                   a.Fill,
+			KeySplines:             a.KeySplines,
+			KeyTimes:               a.KeyTimes,
+			RepeatCount:            a.RepeatCount,
+			TTMLOutStyleAttributes: ttmlOutStyleAttributesFromStyleAttributes(a.Style),
+		})
+	}
+	return
+}
+
 // TTMLOutHeader represents an output TTML header
 type TTMLOutHeader struct {
 	ID    string `xml:"xml:id,attr,omitempty"`
@@ -568,7 +1066,9 @@ type TTMLOutHeader struct {
 // TTMLOutRegion represents an output TTML region
 type TTMLOutRegion struct {
 	TTMLOutHeader
-	XMLName xml.Name `xml:"region"`
+	Animations []TTMLOutAnimate `xml:"animate,omitempty"`
+	Sets       []TTMLOutAnimate `xml:"set,omitempty"`
+	XMLName    xml.Name         `xml:"region"`
 }
 
 // TTMLOutStyle represents an output TTML style
@@ -577,19 +1077,31 @@ type TTMLOutStyle struct {
 	XMLName xml.Name `xml:"style"`
 }
 
+// TTMLOutDiv represents an output TTML div
+type TTMLOutDiv struct {
+	Region    string            `xml:"region,attr,omitempty"`
+	Subtitles []TTMLOutSubtitle `xml:"p"`
+}
+
 // TTMLOutSubtitle represents an output TTML subtitle
 type TTMLOutSubtitle struct {
-	Begin  TTMLOutDuration `xml:"begin,attr"`
-	End    TTMLOutDuration `xml:"end,attr"`
-	ID     string          `xml:"id,attr,omitempty"`
-	Items  []TTMLOutItem
-	Region string `xml:"region,attr,omitempty"`
-	Style  string `xml:"style,attr,omitempty"`
+	// Animations must be written before items
+	Animations []TTMLOutAnimate `xml:"animate,omitempty"`
+	Begin      TTMLOutDuration  `xml:"begin,attr"`
+	End        TTMLOutDuration  `xml:"end,attr"`
+	ID         string           `xml:"xml:id,attr,omitempty"`
+	Sets       []TTMLOutAnimate `xml:"set,omitempty"`
+	Items      []TTMLOutItem
+	Region     string `xml:"region,attr,omitempty"`
+	Style      string `xml:"style,attr,omitempty"`
 	TTMLOutStyleAttributes
 }
 
 // TTMLOutItem represents an output TTML Item
 type TTMLOutItem struct {
+	Items []TTMLOutItem
+	Lang  string `xml:"xml:lang,attr,omitempty"`
+	Ruby  string `xml:"tts:ruby,attr,omitempty"`
 	Style string `xml:"style,attr,omitempty"`
 	Text  string `xml:",chardata"`
 	TTMLOutStyleAttributes
@@ -606,7 +1118,8 @@ func (t TTMLOutDuration) MarshalText() ([]byte, error) {
 
 // WriteToTTMLOptions represents TTML write options.
 type WriteToTTMLOptions struct {
-	Indent string // Default is 4 spaces.
+	CellResolution string // EBU-TT-D only. Default is "32 15".
+	Indent         string // Default is 4 spaces.
 }
 
 // WriteToTTMLOption represents a WriteToTTML option.
@@ -619,6 +1132,13 @@ func WriteToTTMLWithIndentOption(indent string) WriteToTTMLOption {
 	}
 }
 
+// WriteToTTMLWithCellResolutionOption sets the cell resolution option.
+func WriteToTTMLWithCellResolutionOption(columns, rows int) WriteToTTMLOption {
+	return func(o *WriteToTTMLOptions) {
+		o.CellResolution = strconv.Itoa(columns) + " " + strconv.Itoa(rows)
+	}
+}
+
 // WriteToTTML writes subtitles in .ttml format
 func (s Subtitles) WriteToTTML(o io.Writer, opts ...WriteToTTMLOption) (err error) {
 	// Create write options
@@ -632,8 +1152,20 @@ func (s Subtitles) WriteToTTML(o io.Writer, opts ...WriteToTTMLOption) (err erro
 		return ErrNoSubtitlesToWrite
 	}
 
+	// Build TTML
+	ttml := s.ttmlOut()
+
+	// EBU-TT style attributes may have been read
+	if ttml.usesEBUTTStyleAttributes() {
+		ttml.XMLNamespaceEBUTTS = ttmlNamespaceEBUTTS
+	}
+	return ttml.write(o, wo)
+}
+
+// ttmlOut builds the output TTML
+func (s Subtitles) ttmlOut() (ttml TTMLOut) {
 	// Init TTML
-	var ttml = TTMLOut{
+	ttml = TTMLOut{
 		XMLNamespaceTTM: "http://www.w3.org/ns/ttml#metadata",
 		XMLNamespaceTTS: "http://www.w3.org/ns/ttml#styling",
 	}
@@ -643,6 +1175,9 @@ func (s Subtitles) WriteToTTML(o io.Writer, opts ...WriteToTTMLOption) (err erro
 		if v, ok := ttmlLanguageMapping.GetInverse(s.Metadata.Language); ok {
 			ttml.Lang = v.(string)
 		}
+		ttml.Framerate = s.Metadata.Framerate
+		ttml.Profile = s.Metadata.TTMLProfile
+		ttml.Tickrate = s.Metadata.TTMLTickrate
 		if len(s.Metadata.TTMLCopyright) > 0 || len(s.Metadata.Title) > 0 {
 			ttml.Metadata = &TTMLOutMetadata{
 				Copyright: s.Metadata.TTMLCopyright,
@@ -658,10 +1193,14 @@ func (s Subtitles) WriteToTTML(o io.Writer, opts ...WriteToTTMLOption) (err erro
 	}
 	sort.Strings(k)
 	for _, id := range k {
-		var ttmlRegion = TTMLOutRegion{TTMLOutHeader: TTMLOutHeader{
-			ID:                     s.Regions[id].ID,
-			TTMLOutStyleAttributes: ttmlOutStyleAttributesFromStyleAttributes(s.Regions[id].InlineStyle),
-		}}
+		var ttmlRegion = TTMLOutRegion{
+			Animations: ttmlOutAnimatesFromStyleAttributes(s.Regions[id].InlineStyle, false),
+			Sets:       ttmlOutAnimatesFromStyleAttributes(s.Regions[id].InlineStyle, true),
+			TTMLOutHeader: TTMLOutHeader{
+				ID:                     s.Regions[id].ID,
+				TTMLOutStyleAttributes: ttmlOutStyleAttributesFromStyleAttributes(s.Regions[id].InlineStyle),
+			},
+		}
 		if s.Regions[id].Style != nil {
 			ttmlRegion.Style = s.Regions[id].Style.ID
 		}
@@ -686,11 +1225,15 @@ func (s Subtitles) WriteToTTML(o io.Writer, opts ...WriteToTTMLOption) (err erro
 	}
 
 	// Add items
+	var ids = make(map[string]bool)
 	for _, item := range s.Items {
 		// Init subtitle
 		var ttmlSubtitle = TTMLOutSubtitle{
+			Animations:             ttmlOutAnimatesFromStyleAttributes(item.InlineStyle, false),
 			Begin:                  TTMLOutDuration(item.StartAt),
 			End:                    TTMLOutDuration(item.EndAt),
+			ID:                     item.ttmlID(ids),
+			Sets:                   ttmlOutAnimatesFromStyleAttributes(item.InlineStyle, true),
 			TTMLOutStyleAttributes: ttmlOutStyleAttributesFromStyleAttributes(item.InlineStyle),
 		}
 
@@ -706,11 +1249,18 @@ func (s Subtitles) WriteToTTML(o io.Writer, opts ...WriteToTTMLOption) (err erro
 
 		// Add lines
 		for _, line := range item.Lines {
+			// Drawings can't be expressed in TTML
+			var ok bool
+			if line, ok = line.textLine(); !ok {
+				continue
+			}
+
 			// Loop through line items
 			for _, lineItem := range line.Items {
-				// Init ttml item
+				// Init ttml item, entities being decoded since text is escaped when marshaled
 				var ttmlItem = TTMLOutItem{
-					Text:                   lineItem.Text,
+					Lang:                   lineItem.Language,
+					Text:                   html.UnescapeString(lineItem.Text),
 					TTMLOutStyleAttributes: ttmlOutStyleAttributesFromStyleAttributes(lineItem.InlineStyle),
 					XMLName:                xml.Name{Local: "span"},
 				}
@@ -720,6 +1270,16 @@ func (s Subtitles) WriteToTTML(o io.Writer, opts ...WriteToTTMLOption) (err erro
 					ttmlItem.Style = lineItem.Style.ID
 				}
 
+				// Add ruby annotation
+				if lineItem.RubyText != "" {
+					ttmlItem.Items = []TTMLOutItem{
+						{Ruby: "base", Text: html.UnescapeString(lineItem.Text), XMLName: xml.Name{Local: "span"}},
+						{Ruby: "text", Text: html.UnescapeString(lineItem.RubyText), XMLName: xml.Name{Local: "span"}},
+					}
+					ttmlItem.Ruby = "container"
+					ttmlItem.Text = ""
+				}
+
 				// Add ttml item
 				ttmlSubtitle.Items = append(ttmlSubtitle.Items, ttmlItem)
 			}
@@ -736,16 +1296,329 @@ func (s Subtitles) WriteToTTML(o io.Writer, opts ...WriteToTTMLOption) (err erro
 		// Append subtitle
 		ttml.Subtitles = append(ttml.Subtitles, ttmlSubtitle)
 	}
+	return
+}
+
+// usesEBUTTStyleAttributes returns whether EBU-TT style attributes are used
+func (t TTMLOut) usesEBUTTStyleAttributes() bool {
+	var as []TTMLOutStyleAttributes
+	for _, r := range t.Regions {
+		as = append(as, r.TTMLOutStyleAttributes)
+		for _, a := range append(append([]TTMLOutAnimate{}, r.Animations...), r.Sets...) {
+			as = append(as, a.TTMLOutStyleAttributes)
+		}
+	}
+	for _, st := range t.Styles {
+		as = append(as, st.TTMLOutStyleAttributes)
+	}
+	for _, sb := range t.Subtitles {
+		as = append(as, sb.TTMLOutStyleAttributes)
+		for _, a := range append(append([]TTMLOutAnimate{}, sb.Animations...), sb.Sets...) {
+			as = append(as, a.TTMLOutStyleAttributes)
+		}
+		for _, i := range sb.Items {
+			as = append(as, i.TTMLOutStyleAttributes)
+		}
+	}
+	for _, a := range as {
+		if a.LinePadding != nil || a.MultiRowAlign != nil {
+			return true
+		}
+	}
+	return false
+}
 
+// write marshals the TTML
+func (t TTMLOut) write(o io.Writer, wo *WriteToTTMLOptions) (err error) {
 	// Marshal XML
 	var e = xml.NewEncoder(o)
 
 	// Set indent
 	e.Indent("", wo.Indent)
 
-	if err = e.Encode(ttml); err != nil {
+	// The parameter namespace is needed as soon as a parameter attribute is written
+	if t.CellResolution != "" || t.Framerate > 0 || t.Profile != "" || t.Tickrate > 0 || t.TimeBase != "" {
+		t.XMLNamespaceTTP = ttmlNamespaceTTP
+	}
+
+	// Subtitles may be grouped in divs
+	var v interface{} = t
+	if len(t.divs) > 0 {
+		v = ttmlOutWithDivs{TTMLOut: t, Divs: t.divs}
+	}
+
+	if err = e.Encode(v); err != nil {
 		err = fmt.Errorf("astisub: xml encoding failed: %w", err)
 		return
 	}
 	return
 }
+
+// WriteToEBUTTD writes subtitles in the EBU-TT-D profile of .ttml
+// https://tech.ebu.ch/publications/tech3380
+func (s Subtitles) WriteToEBUTTD(o io.Writer, opts ...WriteToTTMLOption) (err error) {
+	// Create write options
+	wo := &WriteToTTMLOptions{
+		CellResolution: ebuttdDefaultCellResolution,
+		Indent:         "    ",
+	}
+	for _, opt := range opts {
+		opt(wo)
+	}
+
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		return ErrNoSubtitlesToWrite
+	}
+
+	// Build TTML
+	ttml := s.ttmlOut()
+	ttml.CellResolution = wo.CellResolution
+	ttml.Profile = "" // the document is expected to conform to EBU-TT-D rather than to the profile it was read with
+	ttml.TimeBase = "media"
+	ttml.XMLNamespaceEBUTTM = ttmlNamespaceEBUTTM
+	ttml.XMLNamespaceEBUTTS = ttmlNamespaceEBUTTS
+
+	// Language is mandatory
+	if ttml.Lang == "" {
+		ttml.Lang = "und"
+	}
+
+	// Add conformance
+	if ttml.Metadata == nil {
+		ttml.Metadata = &TTMLOutMetadata{}
+	}
+	ttml.Metadata.EBUTTDocumentMetadata = &TTMLOutEBUTTDocumentMetadata{ConformsToStandard: "urn:ebu:tt:distribution:2018-04"}
+
+	// Animations are not part of the profile
+	for idx := range ttml.Regions {
+		ttml.Regions[idx].Animations = nil
+		ttml.Regions[idx].Sets = nil
+	}
+	for idx := range ttml.Subtitles {
+		ttml.Subtitles[idx].Animations = nil
+		ttml.Subtitles[idx].Sets = nil
+	}
+
+	// STL positions and justifications are converted into regions whose rows are as high as cells
+	var cellRows int
+	if _, err = fmt.Sscanf(wo.CellResolution, "%d %d", new(int), &cellRows); err != nil || cellRows <= 0 {
+		err = fmt.Errorf("astisub: invalid cell resolution %s", wo.CellResolution)
+		return
+	}
+	var stlRegions = make(map[string]string)
+	for idx, item := range s.Items {
+		if item.Region != nil {
+			continue
+		}
+		a, ok := ebuttdRegionStyleAttributesFromSTL(item.InlineStyle, cellRows)
+		if !ok {
+			continue
+		}
+		var k = *a.DisplayAlign + "|" + *a.Extent + "|" + *a.Origin + "|" + *a.TextAlign
+		id, ok := stlRegions[k]
+		if !ok {
+			id = fmt.Sprintf("astisub-stl-%d", len(stlRegions)+1)
+			stlRegions[k] = id
+			ttml.Regions = append(ttml.Regions, TTMLOutRegion{TTMLOutHeader: TTMLOutHeader{
+				ID:                     id,
+				TTMLOutStyleAttributes: a,
+			}})
+		}
+		ttml.Subtitles[idx].Region = id
+	}
+
+	// Subtitles must be in a region
+	var addDefaultRegion bool
+	for idx := range ttml.Subtitles {
+		if ttml.Subtitles[idx].Region == "" {
+			ttml.Subtitles[idx].Region = ebuttdDefaultRegionID
+			addDefaultRegion = true
+		}
+	}
+	if addDefaultRegion {
+		ttml.Regions = append(ttml.Regions, TTMLOutRegion{TTMLOutHeader: TTMLOutHeader{
+			ID: ebuttdDefaultRegionID,
+			TTMLOutStyleAttributes: TTMLOutStyleAttributes{
+				DisplayAlign: astikit.StrPtr("after"),
+				Extent:       astikit.StrPtr("80% 80%"),
+				Origin:       astikit.StrPtr("10% 10%"),
+				TextAlign:    astikit.StrPtr("center"),
+			},
+		}})
+	}
+
+	// Subtitles are grouped in one div per region
+	var divs = make(map[string]int)
+	for _, sb := range ttml.Subtitles {
+		idx, ok := divs[sb.Region]
+		if !ok {
+			idx = len(ttml.divs)
+			divs[sb.Region] = idx
+			ttml.divs = append(ttml.divs, TTMLOutDiv{Region: sb.Region})
+		}
+		ttml.divs[idx].Subtitles = append(ttml.divs[idx].Subtitles, sb)
+	}
+	return ttml.write(o, wo)
+}
+
+// ebuttdRegionStyleAttributesFromSTL converts STL position and justification into EBU-TT-D region style attributes
+func ebuttdRegionStyleAttributesFromSTL(sa *StyleAttributes, cellRows int) (a TTMLOutStyleAttributes, ok bool) {
+	// Nothing to convert
+	if sa == nil || (sa.STLJustification == nil && sa.STLPosition == nil) {
+		return
+	}
+	ok = true
+
+	// Text align
+	var textAlign = "center"
+	if sa.STLJustification != nil {
+		switch *sa.STLJustification {
+		case JustificationLeft:
+			textAlign = "left"
+		case JustificationRight:
+			textAlign = "right"
+		}
+	}
+	a.TextAlign = astikit.StrPtr(textAlign)
+
+	// Without vertical position, subtitles are displayed at the bottom
+	if sa.STLPosition == nil || sa.STLPosition.MaxRows <= 0 {
+		a.DisplayAlign = astikit.StrPtr("after")
+		a.Extent = astikit.StrPtr("80% 80%")
+		a.Origin = astikit.StrPtr("10% 10%")
+		return
+	}
+
+	// Vertical position is the row of the first subtitle row, teletext rows starting at 1
+	var top = sa.STLPosition.VerticalPosition
+	if sa.STLPosition.MaxRows == 23 && top > 0 {
+		top--
+	}
+	var rows = sa.STLPosition.Rows
+	if rows <= 0 {
+		rows = 1
+	}
+	var height = rows * 100 / cellRows
+	if height > 100 {
+		height = 100
+	}
+	var y = top * 100 / sa.STLPosition.MaxRows
+	if y+height > 100 {
+		y = 100 - height
+	}
+	a.DisplayAlign = astikit.StrPtr("before")
+	a.Extent = astikit.StrPtr(fmt.Sprintf("80%% %d%%", height))
+	a.Origin = astikit.StrPtr(fmt.Sprintf("10%% %d%%", y))
+	return
+}
+
+// WriteToITT writes subtitles in .itt format, the iTunes Timed Text profile of .ttml exported by Final Cut Pro
+// Subtitles without region are placed in a "top" or "bottom" region depending on their position attributes
+func (s Subtitles) WriteToITT(o io.Writer, opts ...WriteToTTMLOption) (err error) {
+	// Create write options
+	wo := &WriteToTTMLOptions{Indent: "    "}
+	for _, opt := range opts {
+		opt(wo)
+	}
+
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		return ErrNoSubtitlesToWrite
+	}
+
+	// Build TTML
+	ttml := s.ttmlOut()
+	ttml.Profile = ""
+	ttml.TimeBase = "media"
+
+	// Language is mandatory
+	if ttml.Lang == "" {
+		ttml.Lang = "und"
+	}
+
+	// Animations are not part of the profile
+	for idx := range ttml.Regions {
+		ttml.Regions[idx].Animations = nil
+		ttml.Regions[idx].Sets = nil
+	}
+	for idx := range ttml.Subtitles {
+		ttml.Subtitles[idx].Animations = nil
+		ttml.Subtitles[idx].Sets = nil
+	}
+
+	// Subtitles must be in a region
+	var regions = make(map[string]bool)
+	for _, r := range ttml.Regions {
+		regions[r.ID] = true
+	}
+	for idx, item := range s.Items {
+		if item.Region != nil {
+			continue
+		}
+		var id = ittRegionIDBottom
+		if ittIsTop(item.InlineStyle) {
+			id = ittRegionIDTop
+		}
+		ttml.Subtitles[idx].Region = id
+		if regions[id] {
+			continue
+		}
+		regions[id] = true
+		ttml.Regions = append(ttml.Regions, ittRegion(id))
+	}
+	return ttml.write(o, wo)
+}
+
+// ittRegion returns the iTT region with the provided id, as defined by Final Cut Pro
+func ittRegion(id string) TTMLOutRegion {
+	var displayAlign, origin = "after", "0% 85%"
+	if id == ittRegionIDTop {
+		displayAlign, origin = "before", "0% 0%"
+	}
+	return TTMLOutRegion{TTMLOutHeader: TTMLOutHeader{
+		ID: id,
+		TTMLOutStyleAttributes: TTMLOutStyleAttributes{
+			DisplayAlign: astikit.StrPtr(displayAlign),
+			Extent:       astikit.StrPtr("100% 15%"),
+			Origin:       astikit.StrPtr(origin),
+			TextAlign:    astikit.StrPtr("center"),
+		},
+	}}
+}
+
+// ittIsTop checks whether position attributes place the subtitle in the top half of the screen
+func ittIsTop(sa *StyleAttributes) bool {
+	// No position
+	if sa == nil {
+		return false
+	}
+
+	// SRT and SSA positions use the numpad layout
+	if sa.SRTPosition > 0 {
+		return sa.SRTPosition >= 7
+	}
+	if sa.SSAAlignment != nil {
+		return *sa.SSAAlignment >= 7
+	}
+
+	// STL and teletext positions are rows
+	if sa.STLPosition != nil && sa.STLPosition.MaxRows > 0 {
+		return sa.STLPosition.VerticalPosition*2 < sa.STLPosition.MaxRows
+	}
+	if sa.TeletextRow != nil && *sa.TeletextRow > 0 {
+		return *sa.TeletextRow*2 <= teletextMaxRows
+	}
+
+	// WebVTT lines are either percentages or line numbers, negative line numbers starting from the bottom
+	if sa.WebVTTLine != "" {
+		var l = strings.TrimSpace(strings.Split(sa.WebVTTLine, ",")[0])
+		if strings.HasSuffix(l, "%") {
+			p, err := strconv.ParseFloat(strings.TrimSuffix(l, "%"), 64)
+			return err == nil && p < 50
+		}
+		n, err := strconv.Atoi(l)
+		return err == nil && n >= 0
+	}
+	return false
+}
@@ -5,10 +5,12 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/5rahim/go-astisub"
 	"github.com/asticode/go-astikit"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTTML(t *testing.T) {
@@ -66,6 +68,28 @@ func TestTTMLBreakLines(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, strings.TrimSpace(string(c)), strings.TrimSpace(w.String()))
+
+	// Styling is preserved on each side of breaks
+	s, err = astisub.ReadFromTTML(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling">
+	<head><styling><style xml:id="red" tts:color="red"/></styling></head>
+	<body><div><p begin="00:00:01.000" end="00:00:02.000"><span style="red">First<br/>Second</span><br/><span tts:fontStyle="italic">Third</span> line</p></div></body>
+</tt>`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	require.Len(t, s.Items[0].Lines, 3)
+	for idx, text := range []string{"First", "Second"} {
+		require.Len(t, s.Items[0].Lines[idx].Items, 1)
+		assert.Equal(t, text, s.Items[0].Lines[idx].Items[0].Text)
+		assert.Equal(t, s.Styles["red"], s.Items[0].Lines[idx].Items[0].Style)
+	}
+	require.Len(t, s.Items[0].Lines[2].Items, 2)
+	assert.Equal(t, "Third", s.Items[0].Lines[2].Items[0].Text)
+	assert.Equal(t, astikit.StrPtr("italic"), s.Items[0].Lines[2].Items[0].InlineStyle.TTMLFontStyle)
+	assert.Equal(t, " line", s.Items[0].Lines[2].Items[1].Text)
+	w.Reset()
+	require.NoError(t, s.WriteToTTML(w))
+	assert.Equal(t, 2, strings.Count(w.String(), "<br></br>"))
 }
 
 func TestWriteToTTMLWithIndentOption(t *testing.T) {
@@ -84,3 +108,352 @@ func TestWriteToTTMLWithIndentOption(t *testing.T) {
 
 	assert.Equal(t, strings.TrimSpace(string(c)), strings.TrimSpace(w.String()))
 }
+
+func TestTTMLRuby(t *testing.T) {
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling">
+    <body>
+        <div>
+            <p begin="00:00:01.000" end="00:00:02.000">
+                <span tts:ruby="container"><span tts:ruby="base">漢字</span><span tts:ruby="text">かんじ</span></span><span>です</span>
+            </p>
+        </div>
+    </body>
+</tt>`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	require.Len(t, s.Items[0].Lines, 1)
+	require.Len(t, s.Items[0].Lines[0].Items, 2)
+	assert.Equal(t, "漢字", s.Items[0].Lines[0].Items[0].Text)
+	assert.Equal(t, "かんじ", s.Items[0].Lines[0].Items[0].RubyText)
+	assert.Equal(t, "です", s.Items[0].Lines[0].Items[1].Text)
+
+	w := &bytes.Buffer{}
+	err = s.WriteToTTML(w, astisub.WriteToTTMLWithIndentOption(""))
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `<span tts:ruby="container"><span tts:ruby="base">漢字</span><span tts:ruby="text">かんじ</span></span><span>です</span>`)
+}
+
+func TestTTMLLang(t *testing.T) {
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xml:lang="en">
+    <body>
+        <div>
+            <p begin="00:00:01.000" end="00:00:02.000" xml:lang="fr"><span>Bonjour</span><span xml:lang="en">Hello</span></p>
+        </div>
+    </body>
+</tt>`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	require.Len(t, s.Items[0].Lines[0].Items, 2)
+	assert.Equal(t, "fr", s.Items[0].Lines[0].Items[0].Language)
+	assert.Equal(t, "en", s.Items[0].Lines[0].Items[1].Language)
+
+	w := &bytes.Buffer{}
+	err = s.WriteToTTML(w, astisub.WriteToTTMLWithIndentOption(""))
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `<span xml:lang="fr">Bonjour</span><span xml:lang="en">Hello</span>`)
+}
+
+func TestTTMLID(t *testing.T) {
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml">
+    <body>
+        <div>
+            <p xml:id="intro" begin="00:00:01.000" end="00:00:02.000">Hello</p>
+            <p begin="00:00:03.000" end="00:00:04.000">Bye</p>
+        </div>
+    </body>
+</tt>`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, "intro", s.Items[0].ID)
+	assert.Equal(t, "", s.Items[1].ID)
+
+	// TTML
+	w := &bytes.Buffer{}
+	err = s.WriteToTTML(w, astisub.WriteToTTMLWithIndentOption(""))
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `<p begin="00:00:01.000" end="00:00:02.000" xml:id="intro">`)
+	assert.Contains(t, w.String(), `<p begin="00:00:03.000" end="00:00:04.000">`)
+
+	// WebVTT
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	require.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\nintro\n00:00:01.000 --> 00:00:02.000\nHello\n\n2\n00:00:03.000 --> 00:00:04.000\nBye\n", w.String())
+
+	// WebVTT identifiers are read back
+	s, err = astisub.ReadFromWebVTT(w)
+	require.NoError(t, err)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, "intro", s.Items[0].ID)
+	assert.Equal(t, "", s.Items[1].ID)
+	assert.Equal(t, 2, s.Items[1].Index)
+
+	// IDs are sanitized into unique NCNames and indexes are used when there's none
+	s = &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: time.Second, ID: "my cue"},
+		{EndAt: time.Second, ID: "my_cue"},
+		{EndAt: time.Second, ID: "1st"},
+		{EndAt: time.Second, Index: 4},
+		{EndAt: time.Second},
+	}}
+	w.Reset()
+	err = s.WriteToTTML(w, astisub.WriteToTTMLWithIndentOption(""))
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `<p begin="00:00:00.000" end="00:00:01.000" xml:id="my_cue"></p>`+
+		`<p begin="00:00:00.000" end="00:00:01.000" xml:id="my_cue_2"></p>`+
+		`<p begin="00:00:00.000" end="00:00:01.000" xml:id="_1st"></p>`+
+		`<p begin="00:00:00.000" end="00:00:01.000" xml:id="_4"></p>`+
+		`<p begin="00:00:00.000" end="00:00:01.000"></p>`)
+}
+
+func TestTTMLAnimate(t *testing.T) {
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling">
+    <head>
+        <layout>
+            <region xml:id="r1" tts:origin="10% 80%">
+                <animate begin="1s" dur="2s" fill="freeze" tts:origin="10% 80%;10% 10%"/>
+            </region>
+        </layout>
+    </head>
+    <body>
+        <div>
+            <p begin="00:00:01.000" end="00:00:03.000" region="r1">
+                <animate calcMode="discrete" keyTimes="0;0.5" tts:color="red;blue"/>
+                Hello
+            </p>
+        </div>
+    </body>
+</tt>`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	assert.Equal(t, "Hello", s.Items[0].String())
+	require.Len(t, s.Items[0].InlineStyle.TTMLAnimations, 1)
+	a := s.Items[0].InlineStyle.TTMLAnimations[0]
+	assert.Equal(t, "discrete", a.CalcMode)
+	assert.Equal(t, "0;0.5", a.KeyTimes)
+	assert.Equal(t, astikit.StrPtr("red;blue"), a.Style.TTMLColor)
+	require.Len(t, s.Regions["r1"].InlineStyle.TTMLAnimations, 1)
+	assert.Equal(t, "1s", s.Regions["r1"].InlineStyle.TTMLAnimations[0].Begin)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToTTML(w, astisub.WriteToTTMLWithIndentOption(""))
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `<region xml:id="r1" tts:origin="10% 80%"><animate begin="1s" dur="2s" fill="freeze" tts:origin="10% 80%;10% 10%"></animate></region>`)
+	assert.Contains(t, w.String(), `<p begin="00:00:01.000" end="00:00:03.000" region="r1"><animate calcMode="discrete" keyTimes="0;0.5" tts:color="red;blue"></animate><span>Hello</span></p>`)
+
+	// Start state
+	s.ApplyTTMLAnimationsStartState()
+	assert.Equal(t, astikit.StrPtr("red"), s.Items[0].InlineStyle.TTMLColor)
+	assert.Len(t, s.Items[0].InlineStyle.TTMLAnimations, 1)
+	// Region animation doesn't begin right away
+	assert.Equal(t, astikit.StrPtr("10% 80%"), s.Regions["r1"].InlineStyle.TTMLOrigin)
+}
+
+func TestTTMLResolveStyles(t *testing.T) {
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling">
+    <head>
+        <styling>
+            <style xml:id="base" tts:color="red" tts:fontFamily="serif"/>
+            <style xml:id="italic" style="base" tts:fontStyle="italic"/>
+            <style xml:id="bold" style="base" tts:fontWeight="bold"/>
+        </styling>
+        <layout>
+            <region xml:id="r1" style="bold" tts:extent="80% 20%" tts:fontSize="80%"/>
+        </layout>
+    </head>
+    <body>
+        <div>
+            <p begin="00:00:01.000" end="00:00:03.000" region="r1"><span>Region</span><span tts:color="blue">Blue</span><span style="italic">Italic</span></p>
+        </div>
+    </body>
+</tt>`))
+	require.NoError(t, err)
+
+	// Styles sharing a parent are all linked to it
+	assert.Equal(t, s.Styles["base"], s.Styles["italic"].Style)
+	assert.Equal(t, s.Styles["base"], s.Styles["bold"].Style)
+
+	s.ResolveTTMLStyles()
+	require.Len(t, s.Items, 1)
+	require.Len(t, s.Items[0].Lines, 1)
+	lis := s.Items[0].Lines[0].Items
+	require.Len(t, lis, 3)
+	assert.Equal(t, astikit.StrPtr("red"), lis[0].InlineStyle.TTMLColor)
+	assert.Equal(t, astikit.StrPtr("serif"), lis[0].InlineStyle.TTMLFontFamily)
+	assert.Equal(t, astikit.StrPtr("80%"), lis[0].InlineStyle.TTMLFontSize)
+	assert.Equal(t, astikit.StrPtr("bold"), lis[0].InlineStyle.TTMLFontWeight)
+	assert.Nil(t, lis[0].InlineStyle.TTMLExtent)
+	assert.Equal(t, astikit.StrPtr("blue"), lis[1].InlineStyle.TTMLColor)
+	assert.Equal(t, astikit.StrPtr("serif"), lis[1].InlineStyle.TTMLFontFamily)
+	assert.Equal(t, astikit.StrPtr("red"), lis[2].InlineStyle.TTMLColor)
+	assert.Equal(t, astikit.StrPtr("italic"), lis[2].InlineStyle.TTMLFontStyle)
+	assert.Equal(t, astikit.StrPtr("bold"), lis[2].InlineStyle.TTMLFontWeight)
+	assert.Equal(t, s.Styles["italic"], lis[2].Style)
+}
+
+func TestTTMLNestedSpans(t *testing.T) {
+	// Nested spans inherit their parent's attributes and style
+	s, err := astisub.OpenFile("./testdata/example-in-nested-spans.ttml")
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	require.Len(t, s.Items[0].Lines, 1)
+	lis := s.Items[0].Lines[0].Items
+	require.Len(t, lis, 3)
+	assert.Equal(t, "outer ", lis[0].Text)
+	assert.Equal(t, astikit.StrPtr("red"), lis[0].InlineStyle.TTMLColor)
+	assert.Nil(t, lis[0].InlineStyle.TTMLFontStyle)
+	assert.Equal(t, s.Styles["bold"], lis[0].Style)
+	assert.Equal(t, "inner", lis[1].Text)
+	assert.Equal(t, astikit.StrPtr("red"), lis[1].InlineStyle.TTMLColor)
+	assert.Equal(t, astikit.StrPtr("italic"), lis[1].InlineStyle.TTMLFontStyle)
+	assert.Equal(t, s.Styles["bold"], lis[1].Style)
+	assert.Equal(t, " after", lis[2].Text)
+	assert.Nil(t, lis[2].InlineStyle.TTMLColor)
+	assert.Nil(t, lis[2].Style)
+	assert.Nil(t, lis[1].InlineStyle.TTMLFontFamily)
+
+	// Resolve styles while reading
+	s, err = astisub.Open(astisub.Options{
+		Filename: "./testdata/example-in-nested-spans.ttml",
+		TTML:     astisub.TTMLOptions{ResolveStyles: true},
+	})
+	require.NoError(t, err)
+	lis = s.Items[0].Lines[0].Items
+	require.Len(t, lis, 3)
+	assert.Equal(t, astikit.StrPtr("red"), lis[1].InlineStyle.TTMLColor)
+	assert.Equal(t, astikit.StrPtr("italic"), lis[1].InlineStyle.TTMLFontStyle)
+	assert.Equal(t, astikit.StrPtr("bold"), lis[1].InlineStyle.TTMLFontWeight)
+	assert.Equal(t, astikit.StrPtr("serif"), lis[1].InlineStyle.TTMLFontFamily)
+	assert.Equal(t, astikit.StrPtr("serif"), lis[2].InlineStyle.TTMLFontFamily)
+	assert.Nil(t, lis[2].InlineStyle.TTMLFontWeight)
+}
+
+func TestEBUTTD(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.ebuttd.ttml")
+	require.NoError(t, err)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, astikit.StrPtr("0.5c"), s.Styles["s1"].InlineStyle.EBUTTLinePadding)
+	assert.Equal(t, astikit.StrPtr("center"), s.Styles["s1"].InlineStyle.EBUTTMultiRowAlign)
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToEBUTTD(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	c, err := ioutil.ReadFile("./testdata/example-out.ebuttd.ttml")
+	require.NoError(t, err)
+	err = s.WriteToEBUTTD(w)
+	require.NoError(t, err)
+	assert.Equal(t, string(c), w.String())
+
+	// EBU-TT style attributes are kept in TTML
+	w.Reset()
+	err = s.WriteToTTML(w)
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `xmlns:ebutts="urn:ebu:tt:style"`)
+	assert.Contains(t, w.String(), `ebutts:linePadding="0.5c" ebutts:multiRowAlign="center"`)
+}
+
+func TestEBUTTDFromSTL(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.stl")
+	require.NoError(t, err)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToEBUTTD(w, astisub.WriteToTTMLWithIndentOption(""))
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `ttp:cellResolution="32 15"`)
+	assert.Contains(t, w.String(), `<region xml:id="astisub-stl-1" tts:displayAlign="before" tts:extent="80% 6%" tts:origin="10% 82%" tts:textAlign="left"></region>`)
+	assert.Contains(t, w.String(), `<region xml:id="astisub-stl-2" tts:displayAlign="before" tts:extent="80% 13%" tts:origin="10% 82%" tts:textAlign="left"></region>`)
+	assert.Contains(t, w.String(), `<body><div region="astisub-stl-1"><p begin="00:01:39.000" end="00:01:41.040" region="astisub-stl-1">`)
+	assert.Contains(t, w.String(), `</div><div region="astisub-stl-2"><p begin="00:02:04.080" end="00:02:07.120" region="astisub-stl-2">`)
+
+	// Cell resolution
+	w.Reset()
+	err = s.WriteToEBUTTD(w, astisub.WriteToTTMLWithCellResolutionOption(40, 24))
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `ttp:cellResolution="40 24"`)
+	assert.Contains(t, w.String(), `tts:extent="80% 4%"`)
+}
+
+func TestDFXP(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.dfxp")
+	require.NoError(t, err)
+	assert.Equal(t, 30, s.Metadata.Framerate)
+	assert.Equal(t, "http://www.w3.org/ns/ttml/profile/dfxp-full", s.Metadata.TTMLProfile)
+	assert.Equal(t, 10000000, s.Metadata.TTMLTickrate)
+	require.Contains(t, s.Regions, "bottom")
+	assert.Equal(t, astikit.StrPtr("10% 80%"), s.Regions["bottom"].InlineStyle.TTMLOrigin)
+	assert.Equal(t, astikit.StrPtr("80% 20%"), s.Regions["bottom"].InlineStyle.TTMLExtent)
+	assert.Equal(t, astikit.StrPtr("black"), s.Regions["bottom"].InlineStyle.TTMLBackgroundColor)
+	assert.Equal(t, astikit.StrPtr("after"), s.Regions["bottom"].InlineStyle.TTMLDisplayAlign)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 2*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, "First line", s.Items[0].String())
+	require.Len(t, s.Items[0].InlineStyle.TTMLAnimations, 1)
+	assert.True(t, s.Items[0].InlineStyle.TTMLAnimations[0].Set)
+	assert.Equal(t, "5000000t", s.Items[0].InlineStyle.TTMLAnimations[0].Begin)
+	assert.Equal(t, astikit.StrPtr("red"), s.Items[0].InlineStyle.TTMLAnimations[0].Style.TTMLColor)
+	assert.Equal(t, 3500*time.Millisecond, s.Items[1].StartAt)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToTTML(w, astisub.WriteToTTMLWithIndentOption(""))
+	require.NoError(t, err)
+	assert.Equal(t, `<tt xmlns="http://www.w3.org/ns/ttml" ttp:frameRate="30" xml:lang="en" ttp:profile="http://www.w3.org/ns/ttml/profile/dfxp-full" ttp:tickRate="10000000" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xmlns:tts="http://www.w3.org/ns/ttml#styling"><head><styling></styling><layout><region xml:id="bottom" tts:backgroundColor="black" tts:displayAlign="after" tts:extent="80% 20%" tts:origin="10% 80%"></region></layout></head><body><div><p begin="00:00:01.000" end="00:00:02.000" region="bottom"><set begin="5000000t" tts:color="red"></set><span>First line</span></p><p begin="00:00:03.500" end="00:00:04.000" region="bottom"><span>Second line</span></p></div></body></tt>`, w.String())
+}
+
+func TestITT(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.itt")
+	require.NoError(t, err)
+	assert.Equal(t, 25, s.Metadata.Framerate)
+	assert.Equal(t, astisub.LanguageFrench, s.Metadata.Language)
+	require.Contains(t, s.Regions, "top")
+	assert.Equal(t, astikit.StrPtr("0% 0%"), s.Regions["top"].InlineStyle.TTMLOrigin)
+	assert.Equal(t, astikit.StrPtr("100% 15%"), s.Regions["top"].InlineStyle.TTMLExtent)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 2480*time.Millisecond, s.Items[0].EndAt)
+	assert.Equal(t, "bottom", s.Items[0].Region.ID)
+	assert.Equal(t, "top", s.Items[1].Region.ID)
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToITT(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	err = s.WriteToITT(w, astisub.WriteToTTMLWithIndentOption(""))
+	require.NoError(t, err)
+	assert.Equal(t, `<tt xmlns="http://www.w3.org/ns/ttml" ttp:frameRate="25" xml:lang="fr" ttp:timeBase="media" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xmlns:tts="http://www.w3.org/ns/ttml#styling"><head><styling><style xml:id="normal" tts:color="white" tts:fontFamily="sansSerif" tts:fontSize="100%" tts:fontStyle="normal" tts:fontWeight="normal"></style></styling><layout><region xml:id="bottom" tts:displayAlign="after" tts:extent="100% 15%" tts:origin="0% 85%" tts:textAlign="center"></region><region xml:id="top" tts:displayAlign="before" tts:extent="100% 15%" tts:origin="0% 0%" tts:textAlign="center"></region></layout></head><body><div><p begin="00:00:01.000" end="00:00:02.480" region="bottom" style="normal"><span>First line</span></p><p begin="00:00:03.000" end="00:00:04.000" region="top" style="normal"><span>Second line</span></p></div></body></tt>`, w.String())
+
+	// Regions are derived from position attributes
+	s = astisub.NewSubtitles()
+	s.Items = []*astisub.Item{
+		{EndAt: time.Second, InlineStyle: &astisub.StyleAttributes{SRTPosition: 8}, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "1"}}}}},
+		{EndAt: 2 * time.Second, InlineStyle: &astisub.StyleAttributes{WebVTTLine: "-1"}, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "2"}}}}, StartAt: time.Second},
+		{EndAt: 3 * time.Second, InlineStyle: &astisub.StyleAttributes{TeletextRow: astikit.IntPtr(2)}, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "3"}}}}, StartAt: 2 * time.Second},
+		{EndAt: 4 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "4"}}}}, StartAt: 3 * time.Second},
+	}
+	w.Reset()
+	err = s.WriteToITT(w, astisub.WriteToTTMLWithIndentOption(""))
+	require.NoError(t, err)
+	assert.Contains(t, w.String(), `xml:lang="und" ttp:timeBase="media"`)
+	assert.Contains(t, w.String(), `<layout><region xml:id="top" tts:displayAlign="before" tts:extent="100% 15%" tts:origin="0% 0%" tts:textAlign="center"></region><region xml:id="bottom" tts:displayAlign="after" tts:extent="100% 15%" tts:origin="0% 85%" tts:textAlign="center"></region></layout>`)
+	assert.Contains(t, w.String(), `<p begin="00:00:00.000" end="00:00:01.000" region="top">`)
+	assert.Contains(t, w.String(), `<p begin="00:00:01.000" end="00:00:02.000" region="bottom">`)
+	assert.Contains(t, w.String(), `<p begin="00:00:02.000" end="00:00:03.000" region="top">`)
+	assert.Contains(t, w.String(), `<p begin="00:00:03.000" end="00:00:04.000" region="bottom">`)
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"golang.org/x/net/html"
@@ -84,7 +85,7 @@ func parseWebVTTTimestampMap(line string) (timestampMap *WebVTTTimestampMap, err
 	for _, split := range strings.Split(right, ",") {
 		splits := strings.SplitN(split, ":", 2)
 		if len(splits) <= 1 {
-			err = fmt.Errorf("astisub: invalid X-TIMESTAMP-MAP, part %q didn't contain ':'", right)
+			err = fmt.Errorf("astisub: invalid X-TIMESTAMP-MAP, part %q didn't contain ':'", split)
 			return
 		}
 
@@ -96,7 +97,7 @@ func parseWebVTTTimestampMap(line string) (timestampMap *WebVTTTimestampMap, err
 				return
 			}
 		case "mpegts":
-			mpegts, err = strconv.ParseInt(splits[1], 10, 0)
+			mpegts, err = strconv.ParseInt(strings.TrimSpace(splits[1]), 10, 64)
 			if err != nil {
 				err = fmt.Errorf("astisub: parsing int %s failed: %w", splits[1], err)
 				return
@@ -132,6 +133,11 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 			return
 		}
 		if fs := strings.Fields(line); len(fs) > 0 && fs[0] == "WEBVTT" {
+			// Header may contain a description after "WEBVTT", usually as "WEBVTT - Title"
+			if title := webVTTHeaderTitle(line); title != "" {
+				o.Metadata.Title = title
+				o.Metadata.WebVTTTitle = title
+			}
 			break
 		}
 	}
@@ -140,7 +146,9 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 	var item = &Item{}
 	var blockName string
 	var comments []string
+	var id string
 	var index int
+	var region *Region
 	var sa = &StyleAttributes{}
 
 	for scanner.Scan() {
@@ -154,9 +162,11 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 
 		switch {
 		// Comment
-		case strings.HasPrefix(line, "NOTE "):
+		case line == "NOTE" || strings.HasPrefix(line, "NOTE ") || strings.HasPrefix(line, "NOTE\t"):
 			blockName = webvttBlockNameComment
-			comments = append(comments, strings.TrimPrefix(line, "NOTE "))
+			if c := strings.TrimSpace(strings.TrimPrefix(line, "NOTE")); c != "" {
+				comments = append(comments, c)
+			}
 		// Empty line
 		case len(line) == 0:
 			// Reset block name, if we are not in the middle of CSS.
@@ -171,45 +181,44 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 			// Reset WebVTTTags
 			sa.WebVTTTags = []WebVTTTag{}
 
+			// Add region block
+			if region != nil {
+				o.addWebVTTRegion(region)
+				region = nil
+			}
+
+		// Region block
+		case line == "REGION":
+			blockName = webvttBlockNameRegion
+			region = &Region{InlineStyle: &StyleAttributes{}}
+
+			// Comments preceding the region belong to the file
+			o.Metadata.Comments = append(o.Metadata.Comments, comments...)
+			comments = []string{}
+
 		// Region
 		case strings.HasPrefix(line, "Region: "):
 			// Add region styles
 			var r = &Region{InlineStyle: &StyleAttributes{}}
-			for _, part := range strings.Split(strings.TrimPrefix(line, "Region: "), " ") {
-				// Split on "="
-				var split = strings.Split(part, "=")
-				if len(split) <= 1 {
-					err = fmt.Errorf("astisub: line %d: Invalid region style %s", lineNum, part)
-					return
-				}
-
-				// Switch on key
-				switch split[0] {
-				case "id":
-					r.ID = split[1]
-				case "lines":
-					if r.InlineStyle.WebVTTLines, err = strconv.Atoi(split[1]); err != nil {
-						err = fmt.Errorf("atoi of %s failed: %w", split[1], err)
-						return
-					}
-				case "regionanchor":
-					r.InlineStyle.WebVTTRegionAnchor = split[1]
-				case "scroll":
-					r.InlineStyle.WebVTTScroll = split[1]
-				case "viewportanchor":
-					r.InlineStyle.WebVTTViewportAnchor = split[1]
-				case "width":
-					r.InlineStyle.WebVTTWidth = split[1]
-				}
+			if err = parseWebVTTRegionSettings(r, strings.TrimPrefix(line, "Region: "), "="); err != nil {
+				err = fmt.Errorf("astisub: line %d: %w", lineNum, err)
+				return
 			}
-			r.InlineStyle.propagateWebVTTAttributes()
 
 			// Add region
-			o.Regions[r.ID] = r
+			o.addWebVTTRegion(r)
+
+			// Comments preceding the region belong to the file
+			o.Metadata.Comments = append(o.Metadata.Comments, comments...)
+			comments = []string{}
 		// Style
 		case strings.HasPrefix(line, "STYLE"):
 			blockName = webvttBlockNameStyle
 
+			// Comments preceding the style belong to the file
+			o.Metadata.Comments = append(o.Metadata.Comments, comments...)
+			comments = []string{}
+
 			if _, ok := o.Styles[webvttDefaultStyleID]; !ok {
 				sa = &StyleAttributes{}
 				o.Styles[webvttDefaultStyleID] = &Style{
@@ -226,11 +235,13 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 			// Init new item
 			item = &Item{
 				Comments:    comments,
+				ID:          id,
 				Index:       index,
 				InlineStyle: &StyleAttributes{},
 			}
 
-			// Reset index
+			// Reset identifier
+			id = ""
 			index = 0
 
 			// Split line on time boundaries
@@ -250,43 +261,10 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 			}
 
 			// Parse style
-			if len(right) > 1 {
-				// Add styles
-				for index := 1; index < len(right); index++ {
-					// Empty
-					if right[index] == "" {
-						continue
-					}
-
-					// Split line on ":"
-					var split = strings.Split(right[index], ":")
-					if len(split) <= 1 {
-						err = fmt.Errorf("astisub: line %d: Invalid inline style '%s'", lineNum, right[index])
-						return
-					}
-
-					// Switch on key
-					switch split[0] {
-					case "align":
-						item.InlineStyle.WebVTTAlign = split[1]
-					case "line":
-						item.InlineStyle.WebVTTLine = split[1]
-					case "position":
-						item.InlineStyle.WebVTTPosition = split[1]
-					case "region":
-						if _, ok := o.Regions[split[1]]; !ok {
-							err = fmt.Errorf("astisub: line %d: Unknown region %s", lineNum, split[1])
-							return
-						}
-						item.Region = o.Regions[split[1]]
-					case "size":
-						item.InlineStyle.WebVTTSize = split[1]
-					case "vertical":
-						item.InlineStyle.WebVTTVertical = split[1]
-					}
-				}
+			if err = parseWebVTTCueSettings(item, right[1:], o.Regions); err != nil {
+				err = fmt.Errorf("astisub: line %d: %w", lineNum, err)
+				return
 			}
-			item.InlineStyle.propagateWebVTTAttributes()
 
 			// Reset comments
 			comments = []string{}
@@ -317,27 +295,149 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 			switch blockName {
 			case webvttBlockNameComment:
 				comments = append(comments, line)
+			case webvttBlockNameRegion:
+				if err = parseWebVTTRegionSettings(region, line, ":"); err != nil {
+					err = fmt.Errorf("astisub: line %d: %w", lineNum, err)
+					return
+				}
 			case webvttBlockNameStyle:
 				sa.WebVTTStyles = append(sa.WebVTTStyles, line)
 			case webvttBlockNameText:
+				// Store raw payload
+				if item.RawPayload != "" {
+					item.RawPayload += "\n"
+				}
+				item.RawPayload += scanner.Text()
+
 				// Parse line
 				if l := parseTextWebVTT(line, sa); len(l.Items) > 0 {
 					item.Lines = append(item.Lines, l)
 				}
 			default:
-				// This is the ID
-				index, _ = strconv.Atoi(line)
+				// This is the ID, numeric IDs being indexes
+				var errAtoi error
+				if index, errAtoi = strconv.Atoi(line); errAtoi != nil {
+					id = line
+				}
+			}
+		}
+	}
+
+	// Add last region block
+	if region != nil {
+		o.addWebVTTRegion(region)
+	}
+
+	// Comments following the last cue are kept apart so that they're written back after it
+	if len(o.Items) > 0 {
+		o.Metadata.WebVTTTrailingComments = comments
+	} else {
+		o.Metadata.Comments = append(o.Metadata.Comments, comments...)
+	}
+	return
+}
+
+// parseWebVTTRegionSettings parses region settings separated by spaces (e.g. "width:40% lines:3") into the region,
+// keys and values being separated by sep
+func parseWebVTTRegionSettings(r *Region, settings, sep string) (err error) {
+	for _, s := range strings.Fields(settings) {
+		// Split on separator
+		var split = strings.SplitN(s, sep, 2)
+		if len(split) <= 1 {
+			err = fmt.Errorf("Invalid region style %s", s)
+			return
+		}
+
+		// Switch on key
+		switch split[0] {
+		case "id":
+			r.ID = split[1]
+		case "lines":
+			if r.InlineStyle.WebVTTLines, err = strconv.Atoi(split[1]); err != nil {
+				err = fmt.Errorf("atoi of %s failed: %w", split[1], err)
+				return
 			}
+		case "regionanchor":
+			r.InlineStyle.WebVTTRegionAnchor = split[1]
+		case "scroll":
+			r.InlineStyle.WebVTTScroll = split[1]
+		case "viewportanchor":
+			r.InlineStyle.WebVTTViewportAnchor = split[1]
+		case "width":
+			r.InlineStyle.WebVTTWidth = split[1]
 		}
 	}
 	return
 }
 
+// addWebVTTRegion adds a parsed region so that cues can reference it by its ID
+func (s *Subtitles) addWebVTTRegion(r *Region) {
+	r.InlineStyle.propagateWebVTTAttributes()
+	s.Regions[r.ID] = r
+}
+
+// webVTTNote builds a NOTE block
+func webVTTNote(comments []string) (c []byte) {
+	c = append(c, []byte("NOTE ")...)
+	for _, comment := range comments {
+		c = append(c, []byte(comment)...)
+		c = append(c, bytesLineSeparator...)
+	}
+	c = append(c, bytesLineSeparator...)
+	return
+}
+
+// webVTTHeaderTitle extracts the description following "WEBVTT" in the header line
+func webVTTHeaderTitle(line string) string {
+	t := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "WEBVTT"))
+	return strings.TrimSpace(strings.TrimPrefix(t, "-"))
+}
+
+// parseWebVTTCueSettings parses cue settings (e.g. "align:start") into the item
+func parseWebVTTCueSettings(item *Item, settings []string, regions map[string]*Region) error {
+	for _, s := range settings {
+		// Empty
+		if s == "" {
+			continue
+		}
+
+		// Split setting on ":"
+		var split = strings.Split(s, ":")
+		if len(split) <= 1 {
+			return fmt.Errorf("astisub: Invalid inline style '%s'", s)
+		}
+
+		// Switch on key
+		switch split[0] {
+		case "align":
+			item.InlineStyle.WebVTTAlign = split[1]
+		case "line":
+			item.InlineStyle.WebVTTLine = split[1]
+		case "position":
+			item.InlineStyle.WebVTTPosition = split[1]
+		case "region":
+			// Unknown regions are ignored as the spec requires
+			if r, ok := regions[split[1]]; ok {
+				item.Region = r
+			}
+		case "size":
+			item.InlineStyle.WebVTTSize = split[1]
+		case "vertical":
+			item.InlineStyle.WebVTTVertical = split[1]
+		}
+	}
+	item.InlineStyle.propagateWebVTTAttributes()
+	return nil
+}
+
 // parseTextWebVTT parses the input line to fill the Line
 func parseTextWebVTT(i string, sa *StyleAttributes) (o Line) {
 	// Create tokenizer
 	tr := html.NewTokenizer(strings.NewReader(i))
 
+	// Ruby annotations are not pushed to the stack but stored in the line item instead
+	var inRuby, inRubyText bool
+
 	// Loop
 	for {
 		// Get next tag
@@ -349,9 +449,18 @@ func parseTextWebVTT(i string, sa *StyleAttributes) (o Line) {
 
 		switch t {
 		case html.EndTagToken:
-			// Pop the top of stack if we meet end tag
-			if len(sa.WebVTTTags) > 0 {
-				sa.WebVTTTags = sa.WebVTTTags[:len(sa.WebVTTTags)-1]
+			switch tagName, _ := tr.TagName(); string(tagName) {
+			case "ruby":
+				inRuby, inRubyText = false, false
+			case "rt":
+				inRubyText = false
+			case "v":
+				// Voice spans are not pushed to the stack
+			default:
+				// Pop the top of stack if we meet end tag
+				if len(sa.WebVTTTags) > 0 {
+					sa.WebVTTTags = sa.WebVTTTags[:len(sa.WebVTTTags)-1]
+				}
 			}
 		case html.StartTagToken:
 			if matches := webVTTRegexpTag.FindStringSubmatch(string(tr.Raw())); len(matches) > 4 {
@@ -371,6 +480,7 @@ func parseTextWebVTT(i string, sa *StyleAttributes) (o Line) {
 					if o.VoiceName == "" {
 						// Only get voicename of the first <v> appears in the line
 						o.VoiceName = annotation
+						o.VoiceClasses = classes
 					} else {
 						// TODO: do something with other <v> instead of ignoring
 						log.Printf("astisub: found another voice name %q in %q. Ignore", annotation, i)
@@ -378,6 +488,15 @@ func parseTextWebVTT(i string, sa *StyleAttributes) (o Line) {
 					continue
 				}
 
+				switch tagName {
+				case "ruby":
+					inRuby = true
+					continue
+				case "rt":
+					inRubyText = inRuby
+					continue
+				}
+
 				// Push the tag to stack
 				sa.WebVTTTags = append(sa.WebVTTTags, WebVTTTag{
 					Name:       tagName,
@@ -387,6 +506,14 @@ func parseTextWebVTT(i string, sa *StyleAttributes) (o Line) {
 			}
 
 		case html.TextToken:
+			// Ruby text is attached to the base text preceding it
+			if inRubyText {
+				if len(o.Items) > 0 {
+					o.Items[len(o.Items)-1].RubyText += unescapeHTML(string(tr.Raw()))
+				}
+				continue
+			}
+
 			// Get style attribute
 			var styleAttributes *StyleAttributes
 			if len(sa.WebVTTTags) > 0 {
@@ -398,8 +525,19 @@ func parseTextWebVTT(i string, sa *StyleAttributes) (o Line) {
 				styleAttributes.propagateWebVTTAttributes()
 			}
 
+			// Get language from the innermost <lang> tag
+			var language string
+			for _, tag := range sa.WebVTTTags {
+				if tag.Name == "lang" {
+					language = tag.Annotation
+				}
+			}
+
 			// Append items
-			o.Items = append(o.Items, parseTextWebVTTTextToken(styleAttributes, string(tr.Raw()))...)
+			for _, li := range parseTextWebVTTTextToken(styleAttributes, string(tr.Raw())) {
+				li.Language = language
+				o.Items = append(o.Items, li)
+			}
 		}
 	}
 	return
@@ -456,8 +594,41 @@ func formatDurationWebVTT(i time.Duration) string {
 	return formatDuration(i, ".", 3)
 }
 
+// WriteToWebVTTOptions represents WebVTT write options
+type WriteToWebVTTOptions struct {
+	// MillisecondDigits is the number of digits of time boundaries' fractional part, between 1 and 3.
+	// Default is 3, which is the only value allowed by the WebVTT specification.
+	MillisecondDigits int
+	// RawPayload writes items' RawPayload verbatim instead of building their text from their lines, items without raw
+	// payload still being built from their lines
+	RawPayload bool
+	// RoundTimecodes rounds time boundaries half up to the last digit instead of truncating them
+	RoundTimecodes bool
+	// UseStyleBlocks writes a "::cue(.class)" rule in the STYLE block for each style used by line items, or by items
+	// for line items without style, and references it with a "<c.class>" tag instead of repeating its styling.
+	// Class names are built from style IDs sanitized into valid CSS identifiers.
+	UseStyleBlocks bool
+}
+
+// duration formats a duration based on options
+func (o WriteToWebVTTOptions) duration(d time.Duration) string {
+	var digits = 3
+	if o.MillisecondDigits > 0 && o.MillisecondDigits < 3 {
+		digits = o.MillisecondDigits
+	}
+	if o.RoundTimecodes {
+		d = roundDuration(d, digits)
+	}
+	return formatDuration(d, ".", digits)
+}
+
 // WriteToWebVTT writes subtitles in .vtt format
 func (s Subtitles) WriteToWebVTT(o io.Writer) (err error) {
+	return s.WriteToWebVTTWithOptions(o, WriteToWebVTTOptions{})
+}
+
+// WriteToWebVTTWithOptions writes subtitles in .vtt format with options
+func (s Subtitles) WriteToWebVTTWithOptions(o io.Writer, opts WriteToWebVTTOptions) (err error) {
 	// Do not write anything if no subtitles
 	if len(s.Items) == 0 {
 		err = ErrNoSubtitlesToWrite
@@ -468,8 +639,13 @@ func (s Subtitles) WriteToWebVTT(o io.Writer) (err error) {
 	var c []byte
 	c = append(c, []byte("WEBVTT")...)
 
-	// Write X-TIMESTAMP-MAP if set
+	// Write title and X-TIMESTAMP-MAP if set, titles read from other formats not being written since they're not
+	// meant to be a header description
 	if s.Metadata != nil {
+		if s.Metadata.WebVTTTitle != "" {
+			c = append(c, []byte(" - "+s.Metadata.WebVTTTitle)...)
+		}
+
 		webVTTTimestampMap := s.Metadata.WebVTTTimestampMap
 		if webVTTTimestampMap != nil {
 			c = append(c, []byte("\n")...)
@@ -478,6 +654,11 @@ func (s Subtitles) WriteToWebVTT(o io.Writer) (err error) {
 	}
 	c = append(c, []byte("\n\n")...)
 
+	// Add file comments
+	if s.Metadata != nil && len(s.Metadata.Comments) > 0 {
+		c = append(c, webVTTNote(s.Metadata.Comments)...)
+	}
+
 	var style []string
 	for _, s := range s.Styles {
 		if s.InlineStyle != nil {
@@ -485,6 +666,14 @@ func (s Subtitles) WriteToWebVTT(o io.Writer) (err error) {
 		}
 	}
 
+	// Add style blocks
+	var classes map[*Style]string
+	if opts.UseStyleBlocks {
+		var rules []string
+		classes, rules = s.webVTTStyleClasses()
+		style = append(style, rules...)
+	}
+
 	if len(style) > 0 {
 		c = append(c, []byte(fmt.Sprintf("STYLE\n%s\n\n", strings.Join(style, "\n")))...)
 	}
@@ -497,45 +686,38 @@ func (s Subtitles) WriteToWebVTT(o io.Writer) (err error) {
 
 	sort.Strings(k)
 	for _, id := range k {
-		c = append(c, []byte("Region: id="+s.Regions[id].ID)...)
-		if s.Regions[id].InlineStyle.WebVTTLines != 0 {
-			c = append(c, bytesSpace...)
-			c = append(c, []byte("lines="+strconv.Itoa(s.Regions[id].InlineStyle.WebVTTLines))...)
-		} else if s.Regions[id].Style != nil && s.Regions[id].Style.InlineStyle != nil && s.Regions[id].Style.InlineStyle.WebVTTLines != 0 {
-			c = append(c, bytesSpace...)
-			c = append(c, []byte("lines="+strconv.Itoa(s.Regions[id].Style.InlineStyle.WebVTTLines))...)
-		}
-		if s.Regions[id].InlineStyle.WebVTTRegionAnchor != "" {
-			c = append(c, bytesSpace...)
-			c = append(c, []byte("regionanchor="+s.Regions[id].InlineStyle.WebVTTRegionAnchor)...)
-		} else if s.Regions[id].Style != nil && s.Regions[id].Style.InlineStyle != nil && s.Regions[id].Style.InlineStyle.WebVTTRegionAnchor != "" {
-			c = append(c, bytesSpace...)
-			c = append(c, []byte("regionanchor="+s.Regions[id].Style.InlineStyle.WebVTTRegionAnchor)...)
-		}
-		if s.Regions[id].InlineStyle.WebVTTScroll != "" {
-			c = append(c, bytesSpace...)
-			c = append(c, []byte("scroll="+s.Regions[id].InlineStyle.WebVTTScroll)...)
-		} else if s.Regions[id].Style != nil && s.Regions[id].Style.InlineStyle != nil && s.Regions[id].Style.InlineStyle.WebVTTScroll != "" {
-			c = append(c, bytesSpace...)
-			c = append(c, []byte("scroll="+s.Regions[id].Style.InlineStyle.WebVTTScroll)...)
-		}
-		if s.Regions[id].InlineStyle.WebVTTViewportAnchor != "" {
-			c = append(c, bytesSpace...)
-			c = append(c, []byte("viewportanchor="+s.Regions[id].InlineStyle.WebVTTViewportAnchor)...)
-		} else if s.Regions[id].Style != nil && s.Regions[id].Style.InlineStyle != nil && s.Regions[id].Style.InlineStyle.WebVTTViewportAnchor != "" {
-			c = append(c, bytesSpace...)
-			c = append(c, []byte("viewportanchor="+s.Regions[id].Style.InlineStyle.WebVTTViewportAnchor)...)
-		}
-		if s.Regions[id].InlineStyle.WebVTTWidth != "" {
-			c = append(c, bytesSpace...)
-			c = append(c, []byte("width="+s.Regions[id].InlineStyle.WebVTTWidth)...)
-		} else if s.Regions[id].Style != nil && s.Regions[id].Style.InlineStyle != nil && s.Regions[id].Style.InlineStyle.WebVTTWidth != "" {
-			c = append(c, bytesSpace...)
-			c = append(c, []byte("width="+s.Regions[id].Style.InlineStyle.WebVTTWidth)...)
+		// Region attributes fall back on the region style's ones
+		var r = s.Regions[id]
+		var value = func(fn func(sa *StyleAttributes) string) string {
+			if r.InlineStyle != nil {
+				if v := fn(r.InlineStyle); v != "" {
+					return v
+				}
+			}
+			if r.Style != nil && r.Style.InlineStyle != nil {
+				return fn(r.Style.InlineStyle)
+			}
+			return ""
+		}
+
+		// Add region block
+		c = append(c, []byte("REGION\nid:"+r.ID+"\n")...)
+		for _, v := range [][2]string{
+			{"width", value(func(sa *StyleAttributes) string { return sa.WebVTTWidth })},
+			{"lines", value(func(sa *StyleAttributes) string {
+				if sa.WebVTTLines == 0 {
+					return ""
+				}
+				return strconv.Itoa(sa.WebVTTLines)
+			})},
+			{"regionanchor", value(func(sa *StyleAttributes) string { return sa.WebVTTRegionAnchor })},
+			{"viewportanchor", value(func(sa *StyleAttributes) string { return sa.WebVTTViewportAnchor })},
+			{"scroll", value(func(sa *StyleAttributes) string { return sa.WebVTTScroll })},
+		} {
+			if v[1] != "" {
+				c = append(c, []byte(v[0]+":"+v[1]+"\n")...)
+			}
 		}
-		c = append(c, bytesLineSeparator...)
-	}
-	if len(s.Regions) > 0 {
 		c = append(c, bytesLineSeparator...)
 	}
 
@@ -543,20 +725,21 @@ func (s Subtitles) WriteToWebVTT(o io.Writer) (err error) {
 	for index, item := range s.Items {
 		// Add comments
 		if len(item.Comments) > 0 {
-			c = append(c, []byte("NOTE ")...)
-			for _, comment := range item.Comments {
-				c = append(c, []byte(comment)...)
-				c = append(c, bytesLineSeparator...)
-			}
-			c = append(c, bytesLineSeparator...)
+			c = append(c, webVTTNote(item.Comments)...)
 		}
 
-		// Add time boundaries
-		c = append(c, []byte(strconv.Itoa(index+1))...)
+		// Add identifier
+		if item.ID != "" {
+			c = append(c, []byte(item.ID)...)
+		} else {
+			c = append(c, []byte(strconv.Itoa(index+1))...)
+		}
 		c = append(c, bytesLineSeparator...)
-		c = append(c, []byte(formatDurationWebVTT(item.StartAt))...)
+
+		// Add time boundaries
+		c = append(c, []byte(opts.duration(item.StartAt))...)
 		c = append(c, bytesWebVTTTimeBoundariesSeparator...)
-		c = append(c, []byte(formatDurationWebVTT(item.EndAt))...)
+		c = append(c, []byte(opts.duration(item.EndAt))...)
 
 		// Add styles
 		if item.InlineStyle != nil {
@@ -604,15 +787,28 @@ func (s Subtitles) WriteToWebVTT(o io.Writer) (err error) {
 		// Add new line
 		c = append(c, bytesLineSeparator...)
 
-		// Loop through lines
-		for _, l := range item.Lines {
-			c = append(c, l.webVTTBytes()...)
+		// Add raw payload
+		if opts.RawPayload && item.RawPayload != "" {
+			c = append(c, []byte(item.RawPayload)...)
+			c = append(c, bytesLineSeparator...)
+		} else {
+			// Loop through lines
+			for _, l := range item.Lines {
+				if tl, ok := l.textLine(); ok {
+					c = append(c, tl.webVTTBytes(classes, item.Style, opts)...)
+				}
+			}
 		}
 
 		// Add new line
 		c = append(c, bytesLineSeparator...)
 	}
 
+	// Add trailing comments
+	if s.Metadata != nil && len(s.Metadata.WebVTTTrailingComments) > 0 {
+		c = append(c, webVTTNote(s.Metadata.WebVTTTrailingComments)...)
+	}
+
 	// Remove last new line
 	c = c[:len(c)-1]
 
@@ -624,9 +820,15 @@ func (s Subtitles) WriteToWebVTT(o io.Writer) (err error) {
 	return
 }
 
-func (l Line) webVTTBytes() (c []byte) {
+// webVTTBytes returns the line's cue text, line items' styles, or the item's style for line items without style,
+// being referenced by their class if any
+func (l Line) webVTTBytes(classes map[*Style]string, itemStyle *Style, opts WriteToWebVTTOptions) (c []byte) {
 	if l.VoiceName != "" {
-		c = append(c, []byte("<v "+l.VoiceName+">")...)
+		var classes string
+		if len(l.VoiceClasses) > 0 {
+			classes = "." + strings.Join(l.VoiceClasses, ".")
+		}
+		c = append(c, []byte("<v"+classes+" "+l.VoiceName+">")...)
 	}
 	for idx := 0; idx < len(l.Items); idx++ {
 		var previous, next *LineItem
@@ -636,52 +838,224 @@ func (l Line) webVTTBytes() (c []byte) {
 		if idx < len(l.Items)-1 {
 			next = &l.Items[idx+1]
 		}
-		c = append(c, l.Items[idx].webVTTBytes(previous, next)...)
+		var st = l.Items[idx].Style
+		if st == nil {
+			st = itemStyle
+		}
+		c = append(c, l.Items[idx].webVTTBytes(previous, next, classes[st], opts)...)
 	}
 	c = append(c, bytesLineSeparator...)
 	return
 }
 
-func (li LineItem) webVTTBytes(previous, next *LineItem) (c []byte) {
+func (li LineItem) webVTTBytes(previous, next *LineItem, class string, opts WriteToWebVTTOptions) (c []byte) {
 	// Add timestamp
 	if li.StartAt > 0 {
-		c = append(c, []byte("<"+formatDurationWebVTT(li.StartAt)+">")...)
+		c = append(c, []byte("<"+opts.duration(li.StartAt)+">")...)
 	}
 
-	// Get color
-	var color string
+	// Get classes
+	var cs []string
+	if class != "" {
+		cs = append(cs, class)
+	}
 	if li.InlineStyle != nil && li.InlineStyle.TTMLColor != nil {
-		color = cssColor(*li.InlineStyle.TTMLColor)
+		if color := cssColor(*li.InlineStyle.TTMLColor); color != "" {
+			cs = append(cs, color)
+		}
 	}
 
 	// Append
-	if color != "" {
-		c = append(c, []byte("<c."+color+">")...)
+	if len(cs) > 0 {
+		c = append(c, []byte("<c."+strings.Join(cs, ".")+">")...)
 	}
 	if li.InlineStyle != nil {
+		var shared = webVTTSharedTagsCount(previous, &li)
 		for idx, tag := range li.InlineStyle.WebVTTTags {
-			if previous != nil && previous.InlineStyle != nil && len(previous.InlineStyle.WebVTTTags) > idx && tag.Name == previous.InlineStyle.WebVTTTags[idx].Name {
+			// <lang> tags are written based on the line item's language
+			if tag.Name == "lang" {
+				continue
+			}
+			if idx < shared {
 				continue
 			}
 			c = append(c, []byte(tag.startTag())...)
 		}
 	}
-	c = append(c, []byte(escapeHTML(li.Text))...)
+	if li.Language != "" {
+		c = append(c, []byte("<lang "+li.Language+">")...)
+	}
+	if li.RubyText != "" {
+		c = append(c, []byte("<ruby>"+escapeHTML(li.Text)+"<rt>"+escapeHTML(li.RubyText)+"</rt></ruby>")...)
+	} else {
+		c = append(c, []byte(escapeHTML(li.Text))...)
+	}
+	if li.Language != "" {
+		c = append(c, []byte("</lang>")...)
+	}
 	if li.InlineStyle != nil {
+		var shared = webVTTSharedTagsCount(&li, next)
 		for i := len(li.InlineStyle.WebVTTTags) - 1; i >= 0; i-- {
 			tag := li.InlineStyle.WebVTTTags[i]
-			if next != nil && next.InlineStyle != nil && len(next.InlineStyle.WebVTTTags) > i && tag.Name == next.InlineStyle.WebVTTTags[i].Name {
+			if tag.Name == "lang" {
+				continue
+			}
+			if i < shared {
 				continue
 			}
 			c = append(c, []byte(tag.endTag())...)
 		}
 	}
-	if color != "" {
+	if len(cs) > 0 {
 		c = append(c, []byte("</c>")...)
 	}
 	return
 }
 
+// webVTTStyleClasses returns the classes of the styles used by items and line items, as well as their
+// "::cue(.class)" rules, sorted by style ID. Styles without CSS equivalent styling are skipped.
+func (s Subtitles) webVTTStyleClasses() (classes map[*Style]string, rules []string) {
+	// Get used styles
+	var styles []*Style
+	var used = make(map[*Style]bool)
+	var use = func(st *Style) {
+		if st != nil && st.ID != webvttDefaultStyleID && !used[st] {
+			used[st] = true
+			styles = append(styles, st)
+		}
+	}
+	for _, i := range s.Items {
+		for _, l := range i.Lines {
+			for _, li := range l.Items {
+				if li.Style != nil {
+					use(li.Style)
+				} else {
+					use(i.Style)
+				}
+			}
+		}
+	}
+	sort.SliceStable(styles, func(i, j int) bool { return styles[i].ID < styles[j].ID })
+
+	// Loop through styles
+	classes = make(map[*Style]string)
+	var names = make(map[string]bool)
+	for _, st := range styles {
+		// Get declarations
+		var ds = st.webVTTCSSDeclarations()
+		if len(ds) == 0 {
+			continue
+		}
+
+		// Get unique class name
+		var name = concatID(webVTTClassName(st.ID), func(v string) bool { return names[v] })
+		names[name] = true
+
+		// Add rule
+		classes[st] = name
+		rules = append(rules, "::cue(."+name+") { "+strings.Join(ds, " ")+" }")
+	}
+	return
+}
+
+// webVTTCSSDeclarations returns the CSS declarations, allowed by the "::cue" pseudo-element, equivalent to the style
+// and its parents' attributes, the closest ones taking precedence
+func (s *Style) webVTTCSSDeclarations() (ds []string) {
+	var color, backgroundColor, fontFamily, fontSize, fontStyle, fontWeight, textDecoration string
+	var set = func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	var visited = make(map[*Style]bool)
+	for st := s; st != nil && !visited[st]; st = st.Style {
+		visited[st] = true
+		sa := st.InlineStyle
+		if sa == nil {
+			continue
+		}
+		if sa.TTMLColor != nil {
+			set(&color, *sa.TTMLColor)
+		}
+		if sa.TTMLBackgroundColor != nil {
+			set(&backgroundColor, *sa.TTMLBackgroundColor)
+		}
+		if sa.TTMLFontFamily != nil {
+			set(&fontFamily, *sa.TTMLFontFamily)
+		}
+		if sa.TTMLFontSize != nil {
+			set(&fontSize, *sa.TTMLFontSize)
+		}
+		if sa.TTMLFontStyle != nil {
+			set(&fontStyle, *sa.TTMLFontStyle)
+		} else if sa.WebVTTItalics {
+			set(&fontStyle, "italic")
+		}
+		if sa.TTMLFontWeight != nil {
+			set(&fontWeight, *sa.TTMLFontWeight)
+		} else if sa.WebVTTBold {
+			set(&fontWeight, "bold")
+		}
+		if sa.TTMLTextDecoration != nil {
+			set(&textDecoration, cssTextDecoration(*sa.TTMLTextDecoration))
+		} else if sa.WebVTTUnderline {
+			set(&textDecoration, "underline")
+		}
+	}
+	for _, d := range []struct{ property, value string }{
+		{property: "color", value: color},
+		{property: "background-color", value: backgroundColor},
+		{property: "font-family", value: fontFamily},
+		{property: "font-size", value: fontSize},
+		{property: "font-style", value: fontStyle},
+		{property: "font-weight", value: fontWeight},
+		{property: "text-decoration", value: textDecoration},
+	} {
+		if d.value != "" {
+			ds = append(ds, d.property+": "+d.value+";")
+		}
+	}
+	return
+}
+
+// cssTextDecoration converts a TTML text decoration (e.g. "underline lineThrough") into a CSS one, negative values
+// such as "noUnderline" being dropped
+func cssTextDecoration(i string) string {
+	var vs []string
+	for _, v := range strings.Fields(i) {
+		switch v {
+		case "lineThrough":
+			vs = append(vs, "line-through")
+		case "overline", "underline":
+			vs = append(vs, v)
+		}
+	}
+	if len(vs) == 0 {
+		return ""
+	}
+	return strings.Join(vs, " ")
+}
+
+// webVTTClassName sanitizes a style ID into a valid CSS identifier, invalid characters being replaced with "_"
+func webVTTClassName(id string) string {
+	var b strings.Builder
+	for _, r := range id {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' ||
+			(r >= 0x80 && !unicode.IsSpace(r)) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+
+	// Identifiers can't be empty nor start with a digit or a hyphen followed by a digit or another hyphen
+	var n = b.String()
+	if n == "" || (n[0] >= '0' && n[0] <= '9') || (n[0] == '-' && (len(n) == 1 || (n[1] >= '0' && n[1] <= '9') || n[1] == '-')) {
+		n = "s" + n
+	}
+	return n
+}
+
 func cssColor(rgb string) string {
 	colors := map[string]string{
 		"#00ffff": "cyan",    // narrator, thought
@@ -692,3 +1066,15 @@ func cssColor(rgb string) string {
 	}
 	return colors[strings.ToLower(rgb)] // returning the empty string is ok
 }
+
+// webVTTSharedTagsCount returns the number of leading tags that are identical in both line items and can therefore
+// be kept open between them. Only a common prefix can be shared, otherwise tags wouldn't be properly nested.
+func webVTTSharedTagsCount(a, b *LineItem) (n int) {
+	if a == nil || b == nil || a.InlineStyle == nil || b.InlineStyle == nil {
+		return
+	}
+	for n < len(a.InlineStyle.WebVTTTags) && n < len(b.InlineStyle.WebVTTTags) && a.InlineStyle.WebVTTTags[n].startTag() == b.InlineStyle.WebVTTTags[n].startTag() {
+		n++
+	}
+	return
+}
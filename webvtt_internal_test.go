@@ -185,5 +185,5 @@ func TestLineWebVTTBytes(t *testing.T) {
 			}},
 			Text: " 3",
 		},
-	}}.webVTTBytes()))
+	}}.webVTTBytes(nil, nil, WriteToWebVTTOptions{})))
 }
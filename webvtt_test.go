@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/5rahim/go-astisub"
+	"github.com/asticode/go-astikit"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -18,7 +19,8 @@ func TestWebVTT(t *testing.T) {
 	assert.NoError(t, err)
 	assertSubtitleItems(t, s)
 	// Comments
-	assert.Equal(t, []string{"this a nice example", "of a VTT"}, s.Items[0].Comments)
+	assert.Equal(t, []string{"this a nice example", "of a VTT"}, s.Metadata.Comments)
+	assert.Empty(t, s.Items[0].Comments)
 	assert.Equal(t, []string{"This a comment inside the VTT", "and this is the second line"}, s.Items[1].Comments)
 	// Regions
 	assert.Equal(t, 2, len(s.Regions))
@@ -72,16 +74,27 @@ func TestWebVTTWithVoiceName(t *testing.T) {
 
 	4
 	00:00:04.000 --> 00:00:08.000
-	<v Bob>Incorrect tag?</vi>`
+	<v Bob>Incorrect tag?</vi>
+
+	5
+	00:00:08.000 --> 00:00:10.000
+	<i><v.loud Ann>Nested <b>tags</b></v> after voice</i>`
 
 	s, err := astisub.ReadFromWebVTT(strings.NewReader(testData))
 	assert.NoError(t, err)
 
-	assert.Len(t, s.Items, 4)
+	assert.Len(t, s.Items, 5)
 	assert.Equal(t, "Roger Bingham", s.Items[0].Lines[0].VoiceName)
+	assert.Equal(t, []string{"first", "local"}, s.Items[0].Lines[0].VoiceClasses)
 	assert.Equal(t, "Bingham", s.Items[1].Lines[0].VoiceName)
+	assert.Nil(t, s.Items[1].Lines[0].VoiceClasses)
 	assert.Equal(t, "Lee", s.Items[2].Lines[0].VoiceName)
 	assert.Equal(t, "Bob", s.Items[3].Lines[0].VoiceName)
+	assert.Equal(t, "Ann", s.Items[4].Lines[0].VoiceName)
+	assert.Equal(t, []string{"loud"}, s.Items[4].Lines[0].VoiceClasses)
+	require.Len(t, s.Items[4].Lines[0].Items, 3)
+	assert.Equal(t, []astisub.WebVTTTag{{Name: "i"}, {Name: "b"}}, s.Items[4].Lines[0].Items[1].InlineStyle.WebVTTTags)
+	assert.Equal(t, []astisub.WebVTTTag{{Name: "i"}}, s.Items[4].Lines[0].Items[2].InlineStyle.WebVTTTags)
 
 	b := &bytes.Buffer{}
 	err = s.WriteToWebVTT(b)
@@ -92,7 +105,7 @@ NOTE this a example with voicename
 
 1
 00:02:34.000 --> 00:02:35.000
-<v Roger Bingham>I'm the fist speaker
+<v.first.local Roger Bingham>I'm the fist speaker
 
 2
 00:02:34.000 --> 00:02:35.000
@@ -105,6 +118,10 @@ NOTE this a example with voicename
 4
 00:00:04.000 --> 00:00:08.000
 <v Bob>Incorrect tag?
+
+5
+00:00:08.000 --> 00:00:10.000
+<v.loud Ann><i>Nested <b>tags</b> after voice</i>
 `, b.String())
 }
 
@@ -145,6 +162,26 @@ Evening.
 `, b.String())
 }
 
+func TestWebVTTTimestampMapHLS(t *testing.T) {
+	s, err := astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:900000,LOCAL:00:00:00.000\n\n00:00:10.000 --> 00:00:12.000\nHello\n"))
+	require.NoError(t, err)
+	require.NotNil(t, s.Metadata.WebVTTTimestampMap)
+	assert.Equal(t, astisub.WebVTTTimestampMap{Local: 0, MpegTS: 900000}, *s.Metadata.WebVTTTimestampMap)
+	assert.Equal(t, 10*time.Second, s.Metadata.WebVTTTimestampMap.Offset())
+
+	// Local time and spaces
+	s, err = astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\nX-TIMESTAMP-MAP=LOCAL:01:00:00.500, MPEGTS: 8589934592\n\n01:00:01.000 --> 01:00:02.000\nHello\n"))
+	require.NoError(t, err)
+	assert.Equal(t, astisub.WebVTTTimestampMap{Local: time.Hour + 500*time.Millisecond, MpegTS: 8589934592}, *s.Metadata.WebVTTTimestampMap)
+	b := &bytes.Buffer{}
+	require.NoError(t, s.WriteToWebVTT(b))
+	assert.True(t, strings.HasPrefix(b.String(), "WEBVTT\nX-TIMESTAMP-MAP=LOCAL:01:00:00.500,MPEGTS:8589934592\n\n"))
+
+	// Invalid
+	_, err = astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:900000,LOCAL\n"))
+	assert.EqualError(t, err, "astisub: parsing webvtt timestamp map failed: astisub: invalid X-TIMESTAMP-MAP, part \"LOCAL\" didn't contain ':'")
+}
+
 func TestWebVTTTags(t *testing.T) {
 	testData := `WEBVTT
 
@@ -177,12 +214,15 @@ func TestWebVTTTags(t *testing.T) {
 	Do no fall to the next item
 	
 	00:12:00.000 --> 00:13:00.000
-	<i>x</i>^3 * <i>x</i> = 100`
+	<i>x</i>^3 * <i>x</i> = 100
+
+	00:13:00.000 --> 00:14:00.000
+	<c.a><b>one</b></c><c.b><b>two</b></c>`
 
 	s, err := astisub.ReadFromWebVTT(strings.NewReader(testData))
 	require.NoError(t, err)
 
-	require.Len(t, s.Items, 10)
+	require.Len(t, s.Items, 11)
 
 	b := &bytes.Buffer{}
 	err = s.WriteToWebVTT(b)
@@ -229,6 +269,10 @@ Do no fall to the next item
 10
 00:12:00.000 --> 00:13:00.000
 <i>x</i>^3 * <i>x</i> = 100
+
+11
+00:13:00.000 --> 00:14:00.000
+<c.a><b>one</b></c><c.b><b>two</b></c>
 `, b.String())
 }
 
@@ -255,3 +299,306 @@ func TestWebVTTParseDuration(t *testing.T) {
 	assert.NotNil(t, s.Items[1].InlineStyle)
 	assert.Equal(t, s.Items[1].InlineStyle.WebVTTAlign, "middle")
 }
+
+func TestWebVTTHeaderTitle(t *testing.T) {
+	for _, header := range []string{"WEBVTT - Title test", "WEBVTT\tTitle test", "WEBVTT Title test"} {
+		s, err := astisub.ReadFromWebVTT(strings.NewReader(header + `
+
+00:00:01.000 --> 00:00:02.000
+Text`))
+		require.NoError(t, err)
+		assert.Equal(t, "Title test", s.Metadata.Title)
+		assert.Equal(t, "Title test", s.Metadata.WebVTTTitle)
+
+		b := &bytes.Buffer{}
+		err = s.WriteToWebVTT(b)
+		require.NoError(t, err)
+		assert.Equal(t, `WEBVTT - Title test
+
+1
+00:00:01.000 --> 00:00:02.000
+Text
+`, b.String())
+	}
+
+	// Titles from other formats are not written
+	s := &astisub.Subtitles{
+		Items:    []*astisub.Item{{EndAt: time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Text"}}}}}},
+		Metadata: &astisub.Metadata{Title: "Title test"},
+	}
+	b := &bytes.Buffer{}
+	err := s.WriteToWebVTT(b)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(b.String(), "WEBVTT\n\n"))
+}
+
+func TestWebVTTRuby(t *testing.T) {
+	s, err := astisub.ReadFromWebVTT(strings.NewReader(`WEBVTT
+
+00:01:00.000 --> 00:02:00.000
+<ruby>漢<rt>かん</rt>字<rt>じ</rt></ruby>です`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	require.Equal(t, []astisub.LineItem{
+		{RubyText: "かん", Text: "漢"},
+		{RubyText: "じ", Text: "字"},
+		{Text: "です"},
+	}, s.Items[0].Lines[0].Items)
+	require.Equal(t, "漢字です", s.Items[0].String())
+
+	b := &bytes.Buffer{}
+	err = s.WriteToWebVTT(b)
+	require.NoError(t, err)
+	require.Equal(t, `WEBVTT
+
+1
+00:01:00.000 --> 00:02:00.000
+<ruby>漢<rt>かん</rt></ruby><ruby>字<rt>じ</rt></ruby>です
+`, b.String())
+}
+
+func TestWebVTTLang(t *testing.T) {
+	s, err := astisub.ReadFromWebVTT(strings.NewReader(`WEBVTT
+
+00:01:00.000 --> 00:02:00.000
+<lang fr>Bonjour</lang> means <i><lang ja>こんにちは</lang></i>`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	items := s.Items[0].Lines[0].Items
+	require.Len(t, items, 3)
+	require.Equal(t, "fr", items[0].Language)
+	require.Equal(t, "", items[1].Language)
+	require.Equal(t, "ja", items[2].Language)
+
+	b := &bytes.Buffer{}
+	err = s.WriteToWebVTT(b)
+	require.NoError(t, err)
+	require.Equal(t, `WEBVTT
+
+1
+00:01:00.000 --> 00:02:00.000
+<lang fr>Bonjour</lang> means <i><lang ja>こんにちは</lang></i>
+`, b.String())
+
+	// Language set without tag
+	s.Items[0].Lines[0].Items = []astisub.LineItem{{Language: "en", Text: "Hello"}}
+	b.Reset()
+	err = s.WriteToWebVTT(b)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), "<lang en>Hello</lang>\n")
+}
+
+func TestWebVTTRawPayload(t *testing.T) {
+	s, err := astisub.ReadFromWebVTT(strings.NewReader(`WEBVTT
+
+00:01:00.000 --> 00:02:00.000
+<ruby>漢<rt>かん</rt>字<rt>じ</rt></ruby><c.yellow.bg_blue>です</c>
+<v Bob>second line`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	require.Equal(t, "<ruby>漢<rt>かん</rt>字<rt>じ</rt></ruby><c.yellow.bg_blue>です</c>\n<v Bob>second line", s.Items[0].RawPayload)
+
+	// Raw payload
+	s.Items = append(s.Items, &astisub.Item{
+		EndAt:   3 * time.Minute,
+		Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: "no payload"}}}},
+		StartAt: 2 * time.Minute,
+	})
+	b := &bytes.Buffer{}
+	err = s.WriteToWebVTTWithOptions(b, astisub.WriteToWebVTTOptions{RawPayload: true})
+	require.NoError(t, err)
+	require.Equal(t, `WEBVTT
+
+1
+00:01:00.000 --> 00:02:00.000
+<ruby>漢<rt>かん</rt>字<rt>じ</rt></ruby><c.yellow.bg_blue>です</c>
+<v Bob>second line
+
+2
+00:02:00.000 --> 00:03:00.000
+no payload
+`, b.String())
+
+	// Default
+	b.Reset()
+	err = s.WriteToWebVTT(b)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), "<ruby>漢<rt>かん</rt></ruby><ruby>字<rt>じ</rt></ruby>")
+}
+
+func TestWriteToWebVTTWithOptionsTimecodes(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 2*time.Second + 999600*time.Microsecond,
+		Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{Text: "text"},
+			{StartAt: 2*time.Second + 345600*time.Microsecond, Text: " more"},
+		}}},
+		StartAt: time.Second + 234500*time.Microsecond,
+	}}}
+	for _, v := range []struct {
+		expected  string
+		opts      astisub.WriteToWebVTTOptions
+		timestamp string
+	}{
+		{expected: "00:00:01.234 --> 00:00:02.999", opts: astisub.WriteToWebVTTOptions{}, timestamp: "00:00:02.345"},
+		{expected: "00:00:01.235 --> 00:00:03.000", opts: astisub.WriteToWebVTTOptions{RoundTimecodes: true}, timestamp: "00:00:02.346"},
+		{expected: "00:00:01.23 --> 00:00:02.99", opts: astisub.WriteToWebVTTOptions{MillisecondDigits: 2}, timestamp: "00:00:02.34"},
+		{expected: "00:00:01.23 --> 00:00:03.00", opts: astisub.WriteToWebVTTOptions{MillisecondDigits: 2, RoundTimecodes: true}, timestamp: "00:00:02.35"},
+	} {
+		b := &bytes.Buffer{}
+		require.NoError(t, s.WriteToWebVTTWithOptions(b, v.opts))
+		require.Equal(t, "WEBVTT\n\n1\n"+v.expected+"\ntext<"+v.timestamp+"> more\n", b.String())
+	}
+}
+
+func TestWebVTTComments(t *testing.T) {
+	s, err := astisub.ReadFromWebVTT(strings.NewReader(`WEBVTT
+
+NOTE
+file comment
+
+STYLE
+::cue { color: red; }
+
+NOTE cue comment
+
+00:00:01.000 --> 00:00:02.000
+text
+
+NOTE trailing comment
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file comment"}, s.Metadata.Comments)
+	assert.Equal(t, []string{"trailing comment"}, s.Metadata.WebVTTTrailingComments)
+	require.Len(t, s.Items, 1)
+	assert.Equal(t, []string{"cue comment"}, s.Items[0].Comments)
+
+	b := &bytes.Buffer{}
+	err = s.WriteToWebVTT(b)
+	require.NoError(t, err)
+	assert.Equal(t, `WEBVTT
+
+NOTE file comment
+
+STYLE
+::cue { color: red; }
+
+NOTE cue comment
+
+1
+00:00:01.000 --> 00:00:02.000
+text
+
+NOTE trailing comment
+`, b.String())
+}
+
+func TestWebVTTRegionBlocks(t *testing.T) {
+	s, err := astisub.ReadFromWebVTT(strings.NewReader(`WEBVTT
+
+NOTE regions
+
+REGION
+id:fred
+width:40%
+lines:3
+regionanchor:0%,100%
+viewportanchor:10%,90%
+scroll:up
+
+REGION
+id:bill width:40% lines:3 regionanchor:100%,100% viewportanchor:90%,90% scroll:up
+
+00:00:01.000 --> 00:00:02.000 region:fred align:left
+Hi, I'm Fred
+
+00:00:02.500 --> 00:00:04.000 region:bill align:right
+Hi, I'm Bill
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"regions"}, s.Metadata.Comments)
+	require.Len(t, s.Regions, 2)
+	assert.Equal(t, astisub.Region{ID: "fred", InlineStyle: &astisub.StyleAttributes{WebVTTLines: 3, WebVTTRegionAnchor: "0%,100%", WebVTTScroll: "up", WebVTTViewportAnchor: "10%,90%", WebVTTWidth: "40%"}}, *s.Regions["fred"])
+	assert.Equal(t, astisub.Region{ID: "bill", InlineStyle: &astisub.StyleAttributes{WebVTTLines: 3, WebVTTRegionAnchor: "100%,100%", WebVTTScroll: "up", WebVTTViewportAnchor: "90%,90%", WebVTTWidth: "40%"}}, *s.Regions["bill"])
+	require.Len(t, s.Items, 2)
+	assert.True(t, s.Items[0].Region == s.Regions["fred"])
+	assert.True(t, s.Items[1].Region == s.Regions["bill"])
+	assert.Equal(t, "Hi, I'm Fred", s.Items[0].String())
+
+	// Round trip
+	w := &bytes.Buffer{}
+	require.NoError(t, s.WriteToWebVTT(w))
+	assert.Contains(t, w.String(), "\n\nREGION\nid:fred\nwidth:40%\nlines:3\nregionanchor:0%,100%\nviewportanchor:10%,90%\nscroll:up\n\n")
+	s2, err := astisub.ReadFromWebVTT(w)
+	require.NoError(t, err)
+	assert.Equal(t, s.Regions, s2.Regions)
+	assert.True(t, s2.Items[1].Region == s2.Regions["bill"])
+
+	// Unknown region
+	s, err = astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\n\n00:00:01.000 --> 00:00:02.000 region:unknown align:left\nText\n"))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	assert.Nil(t, s.Items[0].Region)
+	assert.Equal(t, "left", s.Items[0].InlineStyle.WebVTTAlign)
+
+	// Invalid setting
+	_, err = astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\n\nREGION\nid:fred\nlines:three\n"))
+	assert.EqualError(t, err, "astisub: line 5: atoi of three failed: strconv.Atoi: parsing \"three\": invalid syntax")
+}
+
+func TestWriteToWebVTTWithOptionsStyleBlocks(t *testing.T) {
+	parent := &astisub.Style{ID: "parent", InlineStyle: &astisub.StyleAttributes{TTMLFontWeight: astikit.StrPtr("bold")}}
+	speaker := &astisub.Style{ID: "1 speaker.name", InlineStyle: &astisub.StyleAttributes{TTMLColor: astikit.StrPtr("#ffff00")}, Style: parent}
+	underline := &astisub.Style{ID: "u", InlineStyle: &astisub.StyleAttributes{TTMLTextDecoration: astikit.StrPtr("underline")}}
+	empty := &astisub.Style{ID: "empty", InlineStyle: &astisub.StyleAttributes{}}
+	s := &astisub.Subtitles{
+		Items: []*astisub.Item{
+			{EndAt: 2 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "speaker"}}}}, StartAt: time.Second, Style: speaker},
+			{EndAt: 4 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{
+				{Style: underline, Text: "underlined"},
+				{Style: empty, Text: " plain"},
+			}}}, StartAt: 3 * time.Second, Style: speaker},
+		},
+		Styles: map[string]*astisub.Style{"1 speaker.name": speaker, "empty": empty, "parent": parent, "u": underline},
+	}
+	w := &bytes.Buffer{}
+	require.NoError(t, s.WriteToWebVTTWithOptions(w, astisub.WriteToWebVTTOptions{UseStyleBlocks: true}))
+	assert.Equal(t, `WEBVTT
+
+STYLE
+::cue(.s1_speaker_name) { color: #ffff00; font-weight: bold; }
+::cue(.u) { text-decoration: underline; }
+
+1
+00:00:01.000 --> 00:00:02.000
+<c.s1_speaker_name>speaker</c>
+
+2
+00:00:03.000 --> 00:00:04.000
+<c.u>underlined</c> plain
+`, w.String())
+
+	// Option disabled
+	w.Reset()
+	require.NoError(t, s.WriteToWebVTT(w))
+	assert.NotContains(t, w.String(), "STYLE")
+	assert.NotContains(t, w.String(), "<c.")
+
+	// Colliding class names are suffixed
+	collision := &astisub.Style{ID: "s1_speaker_name", InlineStyle: &astisub.StyleAttributes{TTMLColor: astikit.StrPtr("#ff0000")}}
+	s.Items = append(s.Items, &astisub.Item{EndAt: 6 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "red"}}}}, StartAt: 5 * time.Second, Style: collision})
+	s.Styles[collision.ID] = collision
+	w.Reset()
+	require.NoError(t, s.WriteToWebVTTWithOptions(w, astisub.WriteToWebVTTOptions{UseStyleBlocks: true}))
+	assert.Contains(t, w.String(), "::cue(.s1_speaker_name) { color: #ffff00; font-weight: bold; }\n::cue(.s1_speaker_name_2) { color: #ff0000; }\n")
+}
+
+func TestWebVTTDialogueOnlyWordTimings(t *testing.T) {
+	s, err := astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\n\n00:00:01.000 --> 00:00:03.000\nHello <00:00:02.000>world\n"))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	require.True(t, s.Items[0].Lines[0].Items[len(s.Items[0].Lines[0].Items)-1].StartAt > 0)
+	assert.False(t, s.Items[0].IsSign())
+	assert.Equal(t, 0, s.DialogueOnly())
+	assert.Len(t, s.Items, 1)
+}
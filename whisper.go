@@ -0,0 +1,89 @@
+package astisub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// whisperJSON represents a Whisper JSON transcript.
+// Words can be either attached to their segment or listed at the root
+type whisperJSON struct {
+	Segments []whisperJSONSegment `json:"segments"`
+	Words    []whisperJSONWord    `json:"words"`
+}
+
+type whisperJSONSegment struct {
+	End   float64           `json:"end"`
+	Start float64           `json:"start"`
+	Text  string            `json:"text"`
+	Words []whisperJSONWord `json:"words"`
+}
+
+type whisperJSONWord struct {
+	End   float64 `json:"end"`
+	Start float64 `json:"start"`
+	Word  string  `json:"word"`
+}
+
+// ReadFromWhisperJSON parses a Whisper JSON transcript content
+func ReadFromWhisperJSON(i io.Reader) (o *Subtitles, err error) {
+	// Unmarshal
+	var w whisperJSON
+	if err = json.NewDecoder(i).Decode(&w); err != nil {
+		err = fmt.Errorf("astisub: unmarshaling whisper json failed: %w", err)
+		return
+	}
+
+	// Loop through segments
+	o = NewSubtitles()
+	var wordIdx int
+	for _, s := range w.Segments {
+		// Init item
+		var item = &Item{
+			EndAt:   whisperDuration(s.End),
+			StartAt: whisperDuration(s.Start),
+		}
+
+		// Root words are attached to the segment they start in
+		var words = s.Words
+		if len(words) == 0 {
+			for ; wordIdx < len(w.Words) && whisperDuration(w.Words[wordIdx].Start) < item.EndAt; wordIdx++ {
+				if whisperDuration(w.Words[wordIdx].Start) >= item.StartAt {
+					words = append(words, w.Words[wordIdx])
+				}
+			}
+		}
+
+		// Add line
+		var l Line
+		if len(words) > 0 {
+			for idx, wd := range words {
+				// Items must contain their own space
+				var t = wd.Word
+				if idx == 0 {
+					t = strings.TrimLeft(t, " ")
+				} else if !strings.HasPrefix(t, " ") && len(s.Words) == 0 {
+					// Root words don't always contain their leading space
+					t = " " + t
+				}
+				l.Items = append(l.Items, LineItem{StartAt: whisperDuration(wd.Start), Text: t})
+			}
+		} else {
+			l.Items = []LineItem{{Text: strings.TrimSpace(s.Text)}}
+		}
+		item.Lines = []Line{l}
+
+		// Append item
+		o.Items = append(o.Items, item)
+	}
+	return
+}
+
+// whisperDuration converts seconds into a duration
+func whisperDuration(i float64) time.Duration {
+	return time.Duration(math.Round(i * float64(time.Second)))
+}
@@ -0,0 +1,41 @@
+package astisub_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhisperJSON(t *testing.T) {
+	// Segments only
+	s, err := astisub.ReadFromWhisperJSON(strings.NewReader(`{"text":" Hello world. How are you?","segments":[{"id":0,"start":0.0,"end":2.5,"text":" Hello world."},{"id":1,"start":2.5,"end":4.1,"text":" How are you?"}],"language":"en"}`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, time.Duration(0), s.Items[0].StartAt)
+	assert.Equal(t, 2500*time.Millisecond, s.Items[0].EndAt)
+	assert.Equal(t, "Hello world.", s.Items[0].String())
+	assert.Equal(t, 2500*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, 4100*time.Millisecond, s.Items[1].EndAt)
+	assert.Equal(t, "How are you?", s.Items[1].String())
+
+	// Words in segments
+	s, err = astisub.ReadFromWhisperJSON(strings.NewReader(`{"segments":[{"start":0.0,"end":2.5,"text":" Hello world.","words":[{"word":" Hello","start":0.0,"end":0.8,"probability":0.9},{"word":" world.","start":1.1,"end":2.5,"probability":0.8}]}]}`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 1)
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{Text: "Hello"}, {StartAt: 1100 * time.Millisecond, Text: " world."}}}}, s.Items[0].Lines)
+
+	// Words at the root
+	s, err = astisub.ReadFromWhisperJSON(strings.NewReader(`{"segments":[{"start":0.0,"end":2.5,"text":"Hello world."},{"start":2.5,"end":4.1,"text":"Bye."}],"words":[{"word":"Hello","start":0.0,"end":0.8},{"word":"world.","start":1.1,"end":2.5},{"word":"Bye.","start":2.6,"end":3.0}]}`))
+	require.NoError(t, err)
+	require.Len(t, s.Items, 2)
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{Text: "Hello"}, {StartAt: 1100 * time.Millisecond, Text: " world."}}}}, s.Items[0].Lines)
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{StartAt: 2600 * time.Millisecond, Text: "Bye."}}}}, s.Items[1].Lines)
+
+	// Invalid
+	_, err = astisub.ReadFromWhisperJSON(strings.NewReader(`{`))
+	assert.Error(t, err)
+}
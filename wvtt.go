@@ -0,0 +1,171 @@
+package astisub
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WVTT box types
+// https://www.iso.org/standard/63107.html (ISO/IEC 14496-30)
+const (
+	wvttBoxTypeCue        = "vttc"
+	wvttBoxTypeCueID      = "iden"
+	wvttBoxTypeCuePayload = "payl"
+	wvttBoxTypeSettings   = "sttg"
+)
+
+// WVTTSample represents the data of a fragmented MP4 sample carrying WebVTT cues (wvtt) and its timing
+type WVTTSample struct {
+	Data     []byte
+	Duration time.Duration
+	StartAt  time.Duration
+}
+
+// wvttBox represents an ISO BMFF box
+type wvttBox struct {
+	data []byte
+	typ  string
+}
+
+// wvttCue represents a decoded vttc box
+type wvttCue struct {
+	id       string
+	payload  string
+	settings string
+}
+
+// ReadFromWVTTSamples parses WebVTT cues stored in fragmented MP4 samples.
+// A cue spanning several consecutive samples, which is how overlapping cues are stored, results in a single item.
+// Since regions are defined in the sample entry, cue settings referencing a region are not supported.
+func ReadFromWVTTSamples(samples []WVTTSample) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var previous map[wvttCue]*Item
+
+	// Loop through samples
+	for idx, sample := range samples {
+		// Parse boxes
+		var boxes []wvttBox
+		if boxes, err = parseWVTTBoxes(sample.Data); err != nil {
+			err = fmt.Errorf("astisub: sample #%d: parsing wvtt boxes failed: %w", idx+1, err)
+			return
+		}
+
+		// Loop through cues
+		var current = make(map[wvttCue]*Item)
+		for _, b := range boxes {
+			// Only cue boxes contain data to display
+			if b.typ != wvttBoxTypeCue {
+				continue
+			}
+
+			// Parse cue
+			var c wvttCue
+			if c, err = parseWVTTCue(b.data); err != nil {
+				err = fmt.Errorf("astisub: sample #%d: parsing wvtt cue failed: %w", idx+1, err)
+				return
+			}
+
+			// Cue continues from the previous sample
+			if i, ok := previous[c]; ok && i.EndAt == sample.StartAt {
+				i.EndAt = sample.StartAt + sample.Duration
+				current[c] = i
+				continue
+			}
+
+			// Create item
+			var i = &Item{
+				EndAt:       sample.StartAt + sample.Duration,
+				ID:          c.id,
+				InlineStyle: &StyleAttributes{},
+				StartAt:     sample.StartAt,
+			}
+
+			// Parse settings
+			if err = parseWebVTTCueSettings(i, strings.Fields(c.settings), o.Regions); err != nil {
+				err = fmt.Errorf("astisub: sample #%d: parsing wvtt cue settings failed: %w", idx+1, err)
+				return
+			}
+
+			// Parse payload
+			var sa = &StyleAttributes{}
+			for _, line := range strings.Split(c.payload, "\n") {
+				if l := parseTextWebVTT(line, sa); len(l.Items) > 0 {
+					i.Lines = append(i.Lines, l)
+				}
+			}
+
+			// Append item
+			o.Items = append(o.Items, i)
+			current[c] = i
+		}
+		previous = current
+	}
+	return
+}
+
+// parseWVTTCue parses the content of a vttc box
+func parseWVTTCue(i []byte) (c wvttCue, err error) {
+	// Parse boxes
+	var boxes []wvttBox
+	if boxes, err = parseWVTTBoxes(i); err != nil {
+		err = fmt.Errorf("astisub: parsing wvtt boxes failed: %w", err)
+		return
+	}
+
+	// Loop through boxes
+	for _, b := range boxes {
+		switch b.typ {
+		case wvttBoxTypeCueID:
+			c.id = string(b.data)
+		case wvttBoxTypeCuePayload:
+			c.payload = strings.TrimRight(string(b.data), "\r\n")
+		case wvttBoxTypeSettings:
+			c.settings = string(b.data)
+		}
+	}
+	return
+}
+
+// parseWVTTBoxes parses consecutive ISO BMFF boxes
+func parseWVTTBoxes(i []byte) (o []wvttBox, err error) {
+	for len(i) > 0 {
+		// Header is too short
+		if len(i) < 8 {
+			err = errors.New("astisub: box header is too short")
+			return
+		}
+
+		// Get size
+		var size, offset = uint64(binary.BigEndian.Uint32(i[:4])), uint64(8)
+		switch size {
+		case 0:
+			// Box extends to the end of the data
+			size = uint64(len(i))
+		case 1:
+			// Size is stored on 64 bits
+			if len(i) < 16 {
+				err = errors.New("astisub: box large size is too short")
+				return
+			}
+			size, offset = binary.BigEndian.Uint64(i[8:16]), 16
+		}
+
+		// Size is invalid
+		if size < offset || size > uint64(len(i)) {
+			err = fmt.Errorf("astisub: invalid box size %d", size)
+			return
+		}
+
+		// Append box
+		o = append(o, wvttBox{
+			data: i[offset:size],
+			typ:  string(i[4:8]),
+		})
+		i = i[size:]
+	}
+	return
+}
@@ -0,0 +1,69 @@
+package astisub_test
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/5rahim/go-astisub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func wvttBox(typ string, data ...[]byte) (o []byte) {
+	var l = 8
+	for _, d := range data {
+		l += len(d)
+	}
+	o = make([]byte, 4, l)
+	binary.BigEndian.PutUint32(o, uint32(l))
+	o = append(o, typ...)
+	for _, d := range data {
+		o = append(o, d...)
+	}
+	return
+}
+
+func TestWVTTSamples(t *testing.T) {
+	first := wvttBox("vttc",
+		wvttBox("iden", []byte("intro")),
+		wvttBox("sttg", []byte("align:left line:0")),
+		wvttBox("payl", []byte("Hello <b>world</b>\nSecond line")),
+	)
+	second := wvttBox("vttc", wvttBox("payl", []byte("Bye")))
+	s, err := astisub.ReadFromWVTTSamples([]astisub.WVTTSample{
+		{Data: first, Duration: time.Second, StartAt: time.Second},
+		{Data: append(append([]byte{}, first...), second...), Duration: time.Second, StartAt: 2 * time.Second},
+		{Data: wvttBox("vtte"), Duration: time.Second, StartAt: 3 * time.Second},
+		{Data: second, Duration: time.Second, StartAt: 4 * time.Second},
+	})
+	require.NoError(t, err)
+	require.Len(t, s.Items, 3)
+
+	// Cue spanning several samples
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 3*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, "intro", s.Items[0].ID)
+	assert.Equal(t, "left", s.Items[0].InlineStyle.WebVTTAlign)
+	assert.Equal(t, "0", s.Items[0].InlineStyle.WebVTTLine)
+	require.Len(t, s.Items[0].Lines, 2)
+	assert.Equal(t, "Hello world", s.Items[0].Lines[0].String())
+	assert.Equal(t, []string{"b"}, func() (o []string) {
+		for _, tag := range s.Items[0].Lines[0].Items[1].InlineStyle.WebVTTTags {
+			o = append(o, tag.Name)
+		}
+		return
+	}())
+	assert.Equal(t, "Second line", s.Items[0].Lines[1].String())
+
+	// Cues that don't follow each other are not merged
+	assert.Equal(t, 2*time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 3*time.Second, s.Items[1].EndAt)
+	assert.Equal(t, "Bye", s.Items[1].String())
+	assert.Equal(t, 4*time.Second, s.Items[2].StartAt)
+	assert.Equal(t, 5*time.Second, s.Items[2].EndAt)
+
+	// Invalid box
+	_, err = astisub.ReadFromWVTTSamples([]astisub.WVTTSample{{Data: []byte{0, 0, 0, 20, 'v', 't', 't', 'c'}}})
+	assert.Error(t, err)
+}